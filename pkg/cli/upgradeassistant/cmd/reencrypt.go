@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	codehostmodels "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	emailmodels "github.com/koderover/zadig/pkg/microservice/systemconfig/core/email/repository/models"
+	jiramodels "github.com/koderover/zadig/pkg/microservice/systemconfig/core/jira/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	"github.com/koderover/zadig/pkg/tool/log"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+// secretFields are the fields which hold credentials in each collection, keep it in sync with the repositories.
+// Fields of embedded documents are separated by dots, arrays on the path are walked through.
+var secretFields = []struct {
+	collection string
+	fields     []string
+}{
+	{models.HelmRepo{}.TableName(), []string{"password"}},
+	{models.PrivateKey{}.TableName(), []string{"private_key"}},
+	{models.RegistryNamespace{}.TableName(), []string{"secret_key"}},
+	{models.JenkinsIntegration{}.TableName(), []string{"password"}},
+	{models.Proxy{}.TableName(), []string{"password"}},
+	{models.GithubApp{}.TableName(), []string{"app_key"}},
+	{codehostmodels.CodeHost{}.TableName(), []string{"access_token", "refresh_token", "password", "client_secret"}},
+	{emailmodels.EmailHost{}.TableName(), []string{"password"}},
+	{jiramodels.Jira{}.TableName(), []string{"access_token"}},
+	{models.Workflow{}.TableName(), []string{"notify_ctl.notifiers.secret"}},
+	{models.Pipeline{}.TableName(), []string{"notify_ctl.notifiers.secret"}},
+}
+
+func init() {
+	rootCmd.AddCommand(reencryptCmd)
+
+	reencryptCmd.PersistentFlags().StringP("key-id", "k", "", "id of the key to encrypt credentials with, the primary key is used if it is empty")
+	_ = viper.BindPFlag("keyID", reencryptCmd.PersistentFlags().Lookup("key-id"))
+}
+
+var reencryptCmd = &cobra.Command{
+	Use:   "reencrypt",
+	Short: "re-encrypt stored credentials",
+	Long: `re-encrypt all stored credentials with the given key, credentials which are still saved in plaintext are encrypted as well.
+To rotate the key, mount the new key to all services, set it as the primary key and restart them, then run this command.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := reencrypt(viper.GetString("keyID")); err != nil {
+			log.Fatal(err)
+		}
+	},
+	PostRun: func(cmd *cobra.Command, args []string) {
+		if err := postRun(); err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+func reencrypt(keyID string) error {
+	kr := crypto.DefaultKeyring()
+	if keyID == "" {
+		keyID = kr.PrimaryKeyID()
+	}
+	if !kr.HasKey(keyID) {
+		return fmt.Errorf("key %s is not found", keyID)
+	}
+
+	log.Infof("Start to re-encrypt credentials with key %s", keyID)
+	for _, s := range secretFields {
+		count, err := reencryptCollection(kr, keyID, s.collection, s.fields)
+		if err != nil {
+			log.Errorf("Failed to re-encrypt collection %s, err: %s", s.collection, err)
+			return err
+		}
+		log.Infof("%d documents in collection %s are re-encrypted", count, s.collection)
+	}
+	log.Info("Re-encryption finished")
+
+	return nil
+}
+
+func reencryptCollection(kr *crypto.Keyring, keyID, collection string, fields []string) (int, error) {
+	ctx := context.Background()
+	coll := mongotool.Database(config.MongoDatabase()).Collection(collection)
+
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return count, err
+		}
+
+		change := bson.M{}
+		for _, field := range fields {
+			path := strings.Split(field, ".")
+			res, changed, err := reencryptValue(kr, keyID, doc[path[0]], path[1:])
+			if err != nil {
+				return count, fmt.Errorf("failed to re-encrypt field %s of %v: %s", field, doc["_id"], err)
+			}
+			if changed {
+				change[path[0]] = res
+			}
+		}
+		if len(change) == 0 {
+			continue
+		}
+
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": doc["_id"]}, bson.M{"$set": change}); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, cursor.Err()
+}
+
+// reencryptValue re-encrypts the string at the given path of the value in place, it returns the value and whether it is changed.
+func reencryptValue(kr *crypto.Keyring, keyID string, value interface{}, path []string) (interface{}, bool, error) {
+	if len(path) == 0 {
+		secret, ok := value.(string)
+		if !ok {
+			return value, false, nil
+		}
+		res, changed, err := kr.Reencrypt(keyID, secret)
+		if err != nil || !changed {
+			return value, false, err
+		}
+		return res, true, nil
+	}
+
+	switch v := value.(type) {
+	case bson.M:
+		res, changed, err := reencryptValue(kr, keyID, v[path[0]], path[1:])
+		if err != nil || !changed {
+			return value, false, err
+		}
+		v[path[0]] = res
+		return v, true, nil
+	case bson.D:
+		for i := range v {
+			if v[i].Key != path[0] {
+				continue
+			}
+			res, changed, err := reencryptValue(kr, keyID, v[i].Value, path[1:])
+			if err != nil || !changed {
+				return value, false, err
+			}
+			v[i].Value = res
+			return v, true, nil
+		}
+	case bson.A:
+		changed := false
+		for i := range v {
+			res, c, err := reencryptValue(kr, keyID, v[i], path)
+			if err != nil {
+				return value, false, err
+			}
+			if c {
+				v[i] = res
+				changed = true
+			}
+		}
+		return v, changed, nil
+	}
+
+	return value, false, nil
+}
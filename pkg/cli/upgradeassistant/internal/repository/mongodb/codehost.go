@@ -8,6 +8,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/cli/upgradeassistant/internal/repository/models"
 	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
@@ -79,6 +80,12 @@ func (c *CodehostColl) List() ([]*models.CodeHost, error) {
 		return nil, err
 	}
 
+	for _, codeHost := range codeHosts {
+		if err = crypto.DecryptSecrets(&codeHost.AccessToken); err != nil {
+			return nil, err
+		}
+	}
+
 	return codeHosts, nil
 }
 
@@ -17,8 +17,12 @@ limitations under the License.
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 
@@ -85,6 +89,19 @@ func SecretKey() string {
 	return viper.GetString(setting.ENVSecretKey)
 }
 
+// InternalToken is sent in the InternalTokenHeader by calls between services, it is derived from
+// SECRET_KEY so that it can be verified by the callee but not forged by users.
+// It is empty if SECRET_KEY is not set.
+func InternalToken() string {
+	key := SecretKey()
+	if key == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("zadig-internal-call"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func AslanServiceAddress() string {
 	s := AslanServiceInfo()
 	return GetServiceAddress(s.Name, s.Port)
@@ -226,6 +243,27 @@ func LocalChartTemplatePath(name string) string {
 	return LocalTemplatePath(name, setting.ChartTemplatesPath)
 }
 
+// VaultAddress is the address of the vault server which stores secrets referenced by secret://vault/...
+func VaultAddress() string {
+	return viper.GetString(setting.ENVVaultAddr)
+}
+
+func VaultToken() string {
+	return viper.GetString(setting.ENVVaultToken)
+}
+
+// VaultProjectToken returns the token of the project set by VAULT_TOKEN_<PROJECT>, e.g. VAULT_TOKEN_MY_APP for
+// project my-app, the global VAULT_TOKEN is returned if it is not set.
+func VaultProjectToken(project string) string {
+	if project != "" {
+		key := setting.ENVVaultToken + "_" + strings.ToUpper(strings.ReplaceAll(project, "-", "_"))
+		if token := viper.GetString(key); token != "" {
+			return token
+		}
+	}
+	return VaultToken()
+}
+
 func MongoURI() string {
 	return viper.GetString(setting.ENVMongoDBConnectionString)
 }
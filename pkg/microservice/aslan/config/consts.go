@@ -57,6 +57,8 @@ const (
 	TimingSchedule ScheduleType = "timing"
 	// GapSchedule 间隔循环
 	GapSchedule ScheduleType = "gap"
+	// CrontabSchedule cron 表达式
+	CrontabSchedule ScheduleType = "crontab"
 )
 
 type SlackNotifyType string
@@ -67,6 +69,25 @@ const (
 	SlackOnfailure SlackNotifyType = "onfailure"
 )
 
+// NotifierType 工作流事件通知渠道
+type NotifierType string
+
+const (
+	NotifierSlack   NotifierType = "slack"
+	NotifierTeams   NotifierType = "teams"
+	NotifierWebHook NotifierType = "webhook"
+)
+
+// NotifyEvent 触发通知的任务事件
+type NotifyEvent string
+
+const (
+	NotifyEventStarted   NotifyEvent = "started"
+	NotifyEventPassed    NotifyEvent = "passed"
+	NotifyEventFailed    NotifyEvent = "failed"
+	NotifyEventCancelled NotifyEvent = "cancelled"
+)
+
 // Type pipeline type
 type PipelineType string
 
@@ -98,6 +119,8 @@ const (
 	StatusQueued     Status = "queued"
 	StatusBlocked    Status = "blocked"
 	QueueItemPending Status = "pending"
+	// StatusWaitForApprove 任务在审批处暂停，已释放 warpdrive，审批后重新进入队列
+	StatusWaitForApprove Status = "waitforapprove"
 )
 
 type TaskStatus string
@@ -129,6 +152,16 @@ const (
 	TaskSecurity       TaskType = "security"
 	TaskResetImage     TaskType = "reset_image"
 	TaskDistribute     TaskType = "distribute"
+	TaskApproval       TaskType = "approval"
+	TaskReleaseChart   TaskType = "release_chart"
+)
+
+type ApprovalStatus string
+
+const (
+	ApprovalWaiting  ApprovalStatus = "waiting"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
 )
 
 type DistributeType string
@@ -136,6 +169,7 @@ type DistributeType string
 const (
 	File  DistributeType = "file"
 	Image DistributeType = "image"
+	Chart DistributeType = "chart"
 )
 
 type K8SClusterStatus string
@@ -172,7 +206,7 @@ const (
 	MinRequest = Request("min")
 )
 
-//ProductPermission ...
+// ProductPermission ...
 type ProductPermission string
 
 // ProductAuthType ...
@@ -114,7 +114,9 @@ func CreateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 	}
 
 	build.UpdateBy = username
-	correctFields(build)
+	if err := commonservice.CorrectFields(build); err != nil {
+		return e.ErrCreateBuildModule.AddErr(err)
+	}
 
 	if err := commonrepo.NewBuildColl().Create(build); err != nil {
 		log.Errorf("[Build.Upsert] %s error: %v", build.Name, err)
@@ -134,7 +136,9 @@ func UpdateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 		commonservice.EnsureSecretEnvs(existed.PreBuild.Envs, build.PreBuild.Envs)
 	}
 
-	correctFields(build)
+	if err := commonservice.CorrectFields(build); err != nil {
+		return e.ErrUpdateBuildModule.AddErr(err)
+	}
 	build.UpdateBy = username
 	build.UpdateTime = time.Now().Unix()
 
@@ -313,24 +317,6 @@ func UpdateBuildTargets(name, productName string, targets []*commonmodels.Servic
 	return nil
 }
 
-func correctFields(build *commonmodels.Build) {
-	// make sure cache has no empty field
-	caches := make([]string, 0)
-	for _, cache := range build.Caches {
-		cache = strings.Trim(cache, " /")
-		if cache != "" {
-			caches = append(caches, cache)
-		}
-	}
-	build.Caches = caches
-
-	// trim the docker file and context
-	if build.PostBuild != nil && build.PostBuild.DockerBuild != nil {
-		build.PostBuild.DockerBuild.DockerFile = strings.Trim(build.PostBuild.DockerBuild.DockerFile, " ")
-		build.PostBuild.DockerBuild.WorkDir = strings.Trim(build.PostBuild.DockerBuild.WorkDir, " ")
-	}
-}
-
 func verifyBuildTargets(name, productName string, targets []*commonmodels.ServiceModuleTarget, log *zap.SugaredLogger) error {
 	if hasDuplicateTargets(targets) {
 		return errors.New("duplicate target found")
@@ -24,10 +24,12 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	git "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/pkg/tool/codehub"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
 	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 func CodeHostListBranches(codeHostID int, projectName, namespace string, log *zap.SugaredLogger) ([]*Branch, error) {
@@ -62,6 +64,18 @@ func CodeHostListBranches(codeHostID int, projectName, namespace string, log *za
 			return nil, err
 		}
 		return ToBranches(branchList), nil
+	} else if ch.Type == codeHostGitee {
+		branches, err := gitee.NewClient(ch.Address, ch.AccessToken).ListBranches(namespace, projectName)
+		if err != nil {
+			return nil, err
+		}
+		return ToBranches(branches), nil
+	} else if ch.Type == codeHostBitbucket {
+		branches, err := bitbucket.NewClient(ch.Address, ch.AccessToken).ListBranches(namespace, projectName)
+		if err != nil {
+			return nil, err
+		}
+		return ToBranches(branches), nil
 	} else {
 		//	github
 		gh := git.NewClient(ch.AccessToken, config.ProxyHTTPSAddr())
@@ -25,9 +25,11 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	git "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
 	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 func CodeHostListPRs(codeHostID int, projectName, namespace, targetBr string, log *zap.SugaredLogger) ([]*PullRequest, error) {
@@ -55,6 +57,20 @@ func CodeHostListPRs(codeHostID int, projectName, namespace, targetBr string, lo
 		return nil, nil
 	} else if ch.Type == CodeHostCodeHub {
 		return nil, nil
+	} else if ch.Type == codeHostGitee {
+		prs, err := gitee.NewClient(ch.Address, ch.AccessToken).ListOpenedPullRequests(namespace, projectName, targetBr)
+		if err != nil {
+			log.Error(err)
+			return nil, e.ErrCodehostListPrs.AddDesc(err.Error())
+		}
+		return ToPullRequests(prs), nil
+	} else if ch.Type == codeHostBitbucket {
+		prs, err := bitbucket.NewClient(ch.Address, ch.AccessToken).ListOpenedPullRequests(namespace, projectName, targetBr)
+		if err != nil {
+			log.Error(err)
+			return nil, e.ErrCodehostListPrs.AddDesc(err.Error())
+		}
+		return ToPullRequests(prs), nil
 	} else {
 		//	github
 		gh := git.NewClient(ch.AccessToken, config.ProxyHTTPSAddr())
@@ -24,10 +24,12 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	git "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/pkg/tool/codehub"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
 	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 const (
@@ -38,6 +40,9 @@ const (
 	page            = 1
 	perPage         = 100
 	CodeHostCodeHub = "codehub"
+
+	codeHostGitee     = "gitee"
+	codeHostBitbucket = "bitbucket"
 )
 
 func CodeHostListNamespaces(codeHostID int, keyword string, log *zap.SugaredLogger) ([]*Namespace, error) {
@@ -71,6 +76,30 @@ func CodeHostListNamespaces(codeHostID int, keyword string, log *zap.SugaredLogg
 			return nil, err
 		}
 		return ToNamespaces(codeHubNamespace), nil
+	} else if ch.Type == codeHostGitee {
+		client := gitee.NewClient(ch.Address, ch.AccessToken)
+		namespaces := make([]*Namespace, 0)
+
+		user, err := client.GetAuthenticatedUser()
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ToNamespaces(user)...)
+
+		organizations, err := client.ListOrganizations()
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ToNamespaces(organizations)...)
+
+		return namespaces, nil
+	} else if ch.Type == codeHostBitbucket {
+		// Bitbucket Server 的 project 作为命名空间
+		projects, err := bitbucket.NewClient(ch.Address, ch.AccessToken).ListProjects(keyword)
+		if err != nil {
+			return nil, err
+		}
+		return ToNamespaces(projects), nil
 	} else {
 		//	github
 		gh := git.NewClient(ch.AccessToken, config.ProxyHTTPSAddr())
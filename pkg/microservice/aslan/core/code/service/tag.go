@@ -24,10 +24,12 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	git "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/pkg/tool/codehub"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
 	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 func CodeHostListTags(codeHostID int, projectName string, namespace string, log *zap.SugaredLogger) ([]*Tag, error) {
@@ -64,6 +66,18 @@ func CodeHostListTags(codeHostID int, projectName string, namespace string, log
 			return nil, err
 		}
 		return ToTags(tags), nil
+	} else if ch.Type == codeHostGitee {
+		tags, err := gitee.NewClient(ch.Address, ch.AccessToken).ListTags(namespace, projectName)
+		if err != nil {
+			return nil, err
+		}
+		return ToTags(tags), nil
+	} else if ch.Type == codeHostBitbucket {
+		tags, err := bitbucket.NewClient(ch.Address, ch.AccessToken).ListTags(namespace, projectName)
+		if err != nil {
+			return nil, err
+		}
+		return ToTags(tags), nil
 	} else {
 		//	github
 		gh := git.NewClient(ch.AccessToken, config.ProxyHTTPSAddr())
@@ -21,8 +21,10 @@ import (
 	"github.com/google/go-github/v35/github"
 	"github.com/xanzy/go-gitlab"
 
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/pkg/tool/codehub"
 	gerrittool "github.com/koderover/zadig/pkg/tool/gerrit"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 type Branch struct {
@@ -103,6 +105,19 @@ func ToBranches(obj interface{}) []*Branch {
 				Merged:    o.Merged,
 			})
 		}
+	case []*gitee.Branch:
+		for _, o := range os {
+			res = append(res, &Branch{
+				Name:      o.Name,
+				Protected: o.Protected,
+			})
+		}
+	case []*bitbucket.Ref:
+		for _, o := range os {
+			res = append(res, &Branch{
+				Name: o.DisplayID,
+			})
+		}
 	}
 
 	return res
@@ -141,6 +156,48 @@ func ToPullRequests(obj interface{}) []*PullRequest {
 				AuthorUsername: o.Author.Username,
 			})
 		}
+	case []*gitee.PullRequest:
+		for _, o := range os {
+			pr := &PullRequest{
+				ID:        o.Number,
+				Number:    o.Number,
+				Title:     o.Title,
+				State:     o.State,
+				CreatedAt: o.CreatedAt.Unix(),
+				UpdatedAt: o.UpdatedAt.Unix(),
+			}
+			if o.User != nil {
+				pr.User = o.User.Login
+				pr.AuthorUsername = o.User.Login
+			}
+			if o.Head != nil {
+				pr.SourceBranch = o.Head.Ref
+			}
+			if o.Base != nil {
+				pr.TargetBranch = o.Base.Ref
+			}
+			res = append(res, pr)
+		}
+	case []*bitbucket.PullRequest:
+		for _, o := range os {
+			pr := &PullRequest{
+				ID:             o.ID,
+				Number:         o.ID,
+				Title:          o.Title,
+				State:          o.State,
+				CreatedAt:      o.CreatedDate / 1000,
+				UpdatedAt:      o.UpdatedDate / 1000,
+				User:           o.AuthorName(),
+				AuthorUsername: o.AuthorName(),
+			}
+			if o.FromRef != nil {
+				pr.SourceBranch = o.FromRef.DisplayID
+			}
+			if o.ToRef != nil {
+				pr.TargetBranch = o.ToRef.DisplayID
+			}
+			res = append(res, pr)
+		}
 	}
 
 	return res
@@ -189,6 +246,28 @@ func ToNamespaces(obj interface{}) []*Namespace {
 				ProjectUUID: o.ProjectUUID,
 			})
 		}
+	case *gitee.User:
+		res = append(res, &Namespace{
+			Name: os.Login,
+			Path: os.Login,
+			Kind: UserKind,
+		})
+	case []*gitee.Organization:
+		for _, o := range os {
+			res = append(res, &Namespace{
+				Name: o.Login,
+				Path: o.Login,
+				Kind: OrgKind,
+			})
+		}
+	case []*bitbucket.Project:
+		for _, o := range os {
+			res = append(res, &Namespace{
+				Name: o.Name,
+				Path: o.Key,
+				Kind: OrgKind,
+			})
+		}
 	}
 
 	return res
@@ -238,6 +317,32 @@ func ToProjects(obj interface{}) []*Project {
 				RepoID:        project.RepoID,
 			})
 		}
+	case []*gitee.Project:
+		for _, o := range os {
+			p := &Project{
+				ID:            o.ID,
+				Name:          o.Path,
+				Description:   o.Description,
+				DefaultBranch: o.DefaultBranch,
+			}
+			if o.Namespace != nil {
+				p.Namespace = o.Namespace.Path
+			}
+			res = append(res, p)
+		}
+	case []*bitbucket.Repository:
+		for _, o := range os {
+			p := &Project{
+				ID:            o.ID,
+				Name:          o.Slug,
+				Description:   o.Description,
+				DefaultBranch: "master",
+			}
+			if o.Project != nil {
+				p.Namespace = o.Project.Key
+			}
+			res = append(res, p)
+		}
 	}
 
 	return res
@@ -275,6 +380,19 @@ func ToTags(obj interface{}) []*Tag {
 				Name: o.Name,
 			})
 		}
+	case []*gitee.Tag:
+		for _, o := range os {
+			res = append(res, &Tag{
+				Name:    o.Name,
+				Message: o.Message,
+			})
+		}
+	case []*bitbucket.Ref:
+		for _, o := range os {
+			res = append(res, &Tag{
+				Name: o.DisplayID,
+			})
+		}
 	}
 
 	return res
@@ -45,6 +45,54 @@ type Build struct {
 	ProductName     string                 `bson:"product_name"                  json:"product_name"`
 	SSHs            []string               `bson:"sshs,omitempty"                json:"sshs,omitempty"`
 	PMDeployScripts string                 `bson:"pm_deploy_scripts"             json:"pm_deploy_scripts"`
+	// PMDeployStrategy 物理机分批部署策略，为空时一次性部署到所有主机
+	PMDeployStrategy *PMDeployStrategy `bson:"pm_deploy_strategy,omitempty" json:"pm_deploy_strategy,omitempty"`
+
+	// CacheKeyFiles 非空时按这些文件（如 go.sum、package-lock.json）的内容哈希保存和恢复 Caches 中的目录，此时 Caches 不能为空
+	CacheKeyFiles []string `bson:"cache_key_files,omitempty" json:"cache_key_files,omitempty"`
+	// Matrix 构建矩阵，非空时按镜像和变量的组合并行运行多个构建
+	Matrix *BuildMatrix `bson:"matrix,omitempty" json:"matrix,omitempty"`
+}
+
+// PMDeployStrategy 每批主机部署后检查服务的 PmHealthChecks，通过后再部署下一批。
+// 开启后 PMDeployScripts 和 RollbackScripts 在每台主机上各执行一次，脚本需要通过以下变量操作当前主机：
+// PM_HOST_NAME 主机名，PM_HOST_IP 主机 IP，PM_HOST_USERNAME 登录用户，PM_HOST_PK 私钥文件路径
+type PMDeployStrategy struct {
+	Enabled bool `bson:"enabled"                    json:"enabled"`
+	// BatchSize 每批部署的主机数，为 0 时按 BatchPercent 计算
+	BatchSize    int `bson:"batch_size"                 json:"batch_size"`
+	BatchPercent int `bson:"batch_percent"              json:"batch_percent"`
+	// PauseSeconds 两批部署之间的等待时间
+	PauseSeconds int `bson:"pause_seconds"              json:"pause_seconds"`
+	// InitialDelaySeconds 每批部署完成后等待服务启动的时间，之后再开始健康检查
+	InitialDelaySeconds int `bson:"initial_delay_seconds"      json:"initial_delay_seconds"`
+	// RollbackOnFailure 某一批失败时在已部署的主机上执行 RollbackScripts
+	RollbackOnFailure bool   `bson:"rollback_on_failure"        json:"rollback_on_failure"`
+	RollbackScripts   string `bson:"rollback_scripts,omitempty" json:"rollback_scripts,omitempty"`
+}
+
+// BuildMatrix 构建矩阵定义，Images 和 Envs 的笛卡尔积为所有组合
+type BuildMatrix struct {
+	// Images 镜像维度，为空时使用 PreBuild/PreTest 中的镜像
+	Images []*MatrixImage `bson:"images,omitempty"         json:"images,omitempty"`
+	// Envs 变量维度，每个变量的每个取值都会与其它维度组合
+	Envs []*MatrixEnv `bson:"envs,omitempty"           json:"envs,omitempty"`
+	// Exclude 不运行的组合，key 为变量名或 IMAGE，匹配所有 key 的组合会被排除
+	Exclude []map[string]string `bson:"exclude,omitempty"        json:"exclude,omitempty"`
+	// AllowFailures 允许失败的组合，匹配规则同 Exclude
+	AllowFailures []map[string]string `bson:"allow_failures,omitempty" json:"allow_failures,omitempty"`
+}
+
+type MatrixImage struct {
+	Name      string `bson:"name"       json:"name"`
+	BuildOS   string `bson:"build_os"   json:"build_os"`
+	ImageFrom string `bson:"image_from" json:"image_from"`
+	ImageID   string `bson:"image_id"   json:"image_id"`
+}
+
+type MatrixEnv struct {
+	Key    string   `bson:"key"    json:"key"`
+	Values []string `bson:"values" json:"values"`
 }
 
 // PreBuild prepares an environment for a job
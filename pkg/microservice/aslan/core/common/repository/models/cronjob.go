@@ -28,6 +28,7 @@ type Cronjob struct {
 	Frequency    string             `bson:"frequency"`
 	Time         string             `bson:"time"`
 	Cron         string             `bson:"cron"`
+	TimeZone     string             `bson:"time_zone,omitempty"`
 	ProductName  string             `bson:"product_name,omitempty"`
 	MaxFailure   int                `bson:"max_failures,omitempty"`
 	TaskArgs     *TaskArgs          `bson:"task_args,omitempty"`
@@ -0,0 +1,41 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EnvSnapshot 环境快照，记录某一时刻环境中各服务的版本、镜像以及渲染配置集版本（helm 环境的 values 保存在渲染配置集中）
+type EnvSnapshot struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty"          json:"id,omitempty"`
+	Name        string              `bson:"name"                   json:"name"`
+	ProductName string              `bson:"product_name"           json:"product_name"`
+	EnvName     string              `bson:"env_name"               json:"env_name"`
+	Namespace   string              `bson:"namespace"              json:"namespace"`
+	Source      string              `bson:"source"                 json:"source"`
+	Services    [][]*ProductService `bson:"services"               json:"services"`
+	Render      *RenderInfo         `bson:"render"                 json:"render"`
+	// Auto 为 true 表示更新环境前自动创建的快照
+	Auto       bool   `bson:"auto"                   json:"auto"`
+	CreatedBy  string `bson:"created_by"             json:"created_by"`
+	CreateTime int64  `bson:"create_time"            json:"create_time"`
+}
+
+func (EnvSnapshot) TableName() string {
+	return "env_snapshot"
+}
@@ -24,6 +24,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/setting"
 )
 
 var namePattern = regexp.MustCompile(`^[0-9a-zA-Z_.-]{1,32}$`)
@@ -44,6 +45,8 @@ type K8SCluster struct {
 	Disconnected bool                    `json:"-" bson:"disconnected"`
 	Token        string                  `json:"token" bson:"-"`
 	Provider     int8                    `json:"provider"       bson:"provider"`
+	// PodExecPolicy 仅对生产集群生效
+	PodExecPolicy setting.PodExecPolicy `json:"pod_exec_policy" bson:"pod_exec_policy"`
 }
 
 type K8SClusterInfo struct {
@@ -74,5 +77,11 @@ func (k *K8SCluster) Clean() error {
 		return fmt.Errorf("集群名称不符合规则")
 	}
 
+	switch k.PodExecPolicy {
+	case setting.PodExecAllowed, setting.PodExecRecorded, setting.PodExecDisabled:
+	default:
+		return fmt.Errorf("不支持的终端策略 %s", k.PodExecPolicy)
+	}
+
 	return nil
 }
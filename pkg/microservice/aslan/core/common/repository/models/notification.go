@@ -51,17 +51,34 @@ type PrTaskInfo struct {
 }
 
 type NotificationTask struct {
-	ProductName  string            `bson:"product_name"    json:"product_name"`
-	WorkflowName string            `bson:"workflow_name"   json:"workflow_name"`
-	PipelineName string            `bson:"pipeline_name"   json:"pipeline_name"`
-	TestName     string            `bson:"test_name"       json:"test_name"`
-	ID           int64             `bson:"id"              json:"id"`
-	Status       config.TaskStatus `bson:"status"          json:"status"`
-	TestReports  []*TestSuite      `bson:"test_reports,omitempty" json:"test_reports,omitempty"`
+	ProductName  string                  `bson:"product_name"    json:"product_name"`
+	WorkflowName string                  `bson:"workflow_name"   json:"workflow_name"`
+	PipelineName string                  `bson:"pipeline_name"   json:"pipeline_name"`
+	TestName     string                  `bson:"test_name"       json:"test_name"`
+	ID           int64                   `bson:"id"              json:"id"`
+	Status       config.TaskStatus       `bson:"status"          json:"status"`
+	TestReports  []*TestSuite            `bson:"test_reports,omitempty" json:"test_reports,omitempty"`
+	Coverages    []*NotificationCoverage `bson:"coverages,omitempty"    json:"coverages,omitempty"`
 
 	FirstCommented bool `json:"first_commented,omitempty" bson:"first_commented,omitempty"`
 }
 
+// NotificationCoverage 测试的覆盖率，BaseLineRate 为 PR 目标分支最近一次的行覆盖率
+type NotificationCoverage struct {
+	TestName     string   `bson:"test_name"                json:"test_name"`
+	LineRate     float64  `bson:"line_rate"                json:"line_rate"`
+	BranchRate   float64  `bson:"branch_rate"              json:"branch_rate"`
+	BaseLineRate *float64 `bson:"base_line_rate,omitempty" json:"base_line_rate,omitempty"`
+}
+
+// LineRateDiff 相对目标分支行覆盖率的变化，没有基准时返回 -
+func (c NotificationCoverage) LineRateDiff() string {
+	if c.BaseLineRate == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f%%", c.LineRate-*c.BaseLineRate)
+}
+
 func (t NotificationTask) StatusVerbose() string {
 	switch t.Status {
 	case config.TaskStatusReady:
@@ -133,6 +150,18 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 		}
 	}
 
+	hasCoverage := false
+	for _, task := range n.Tasks {
+		if len(task.Coverages) != 0 {
+			hasCoverage = true
+			break
+		}
+	}
+	if hasCoverage {
+		tmplSource +=
+			"\n\n|测试覆盖率|行覆盖率|分支覆盖率|相对目标分支| \n |---|---|---|---| \n {{range $task := .Tasks}}{{range .Coverages}}|{{.TestName}}#{{$task.ID}} | {{printf \"%.2f\" .LineRate}}% | {{printf \"%.2f\" .BranchRate}}% | {{.LineRateDiff}} | \n {{end}}{{end}}"
+	}
+
 	if n.PrTask != nil {
 		if n.PrTask.EnvName != "" {
 			content := fmt.Sprintf("生成基准环境：[%s]({{$.BaseURI}}/v1/projects/detail/%s/envs/detail?envName=%s) 状态：%s \n\n", n.PrTask.EnvName, n.PrTask.ProductName, n.PrTask.EnvName, n.PrTask.EnvStatus)
@@ -46,6 +46,8 @@ type PipelineStatusCtx struct {
 	Type         config.PipelineType `bson:"type"                      json:"type"`
 	Status       config.Status       `bson:"status"                    json:"status,omitempty"`
 	TeamName     string              `bson:"team"                      json:"team"`
+	// RolledBack 健康检查失败后自动回滚的部署
+	RolledBack []string `bson:"rolled_back,omitempty"       json:"rolled_back,omitempty"`
 }
 
 type MessageCtx struct {
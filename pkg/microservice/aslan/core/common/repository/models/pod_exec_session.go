@@ -0,0 +1,43 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// PodExecSession 容器终端的会话录像，录像以 asciinema v2 格式保存在对象存储中
+type PodExecSession struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	Username      string             `bson:"username"       json:"username"`
+	UserID        string             `bson:"user_id"        json:"user_id"`
+	ProductName   string             `bson:"product_name"   json:"product_name"`
+	ClusterID     string             `bson:"cluster_id"     json:"cluster_id"`
+	Namespace     string             `bson:"namespace"      json:"namespace"`
+	PodName       string             `bson:"pod_name"       json:"pod_name"`
+	ContainerName string             `bson:"container_name" json:"container_name"`
+	StartTime     int64              `bson:"start_time"     json:"start_time"`
+	EndTime       int64              `bson:"end_time"       json:"end_time"`
+	// Truncated 录像超过大小限制后不再记录
+	Truncated bool  `bson:"truncated"      json:"truncated"`
+	Size      int64 `bson:"size"           json:"size"`
+	// S3StorageID 为空时录像保存在系统内置的对象存储中
+	S3StorageID string `bson:"s3_storage_id"  json:"-"`
+	ObjectKey   string `bson:"object_key"     json:"-"`
+}
+
+func (PodExecSession) TableName() string {
+	return "pod_exec_session"
+}
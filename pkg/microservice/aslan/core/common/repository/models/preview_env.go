@@ -0,0 +1,43 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PreviewEnv 记录 PR 与其预览环境的对应关系
+type PreviewEnv struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"       json:"id,omitempty"`
+	ProductName string             `bson:"product_name"        json:"product_name"`
+	EnvName     string             `bson:"env_name"            json:"env_name"`
+	BaseEnv     string             `bson:"base_env"            json:"base_env"`
+	Source      string             `bson:"source"              json:"source"`
+	CodehostID  int                `bson:"codehost_id"         json:"codehost_id"`
+	RepoOwner   string             `bson:"repo_owner"          json:"repo_owner"`
+	RepoName    string             `bson:"repo_name"           json:"repo_name"`
+	PR          int                `bson:"pr"                  json:"pr"`
+	Branch      string             `bson:"branch"              json:"branch"`
+	CommitID    string             `bson:"commit_id"           json:"commit_id"`
+	CommentID   string             `bson:"comment_id"          json:"comment_id"`
+	CreateTime  int64              `bson:"create_time"         json:"create_time"`
+	UpdateTime  int64              `bson:"update_time"         json:"update_time"`
+}
+
+func (PreviewEnv) TableName() string {
+	return "preview_env"
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package models
 
 import (
+	"go.mongodb.org/mongo-driver/bson"
+
 	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 )
 
@@ -85,6 +87,13 @@ type CreateFromChartTemplate struct {
 	Variables    []*Variable                `bson:"variables" json:"variables"`
 }
 
+type CreateFromChartRepo struct {
+	ChartRepoName string `bson:"chart_repo_name"   json:"chart_repo_name"`
+	ChartName     string `bson:"chart_name"        json:"chart_name"`
+	ChartVersion  string `bson:"chart_version"     json:"chart_version"`
+	ValuesYAML    string `bson:"values_yaml"       json:"values_yaml"`
+}
+
 type GUIConfig struct {
 	Deployment interface{} `bson:"deployment,omitempty"           json:"deployment,omitempty"`
 	Ingress    interface{} `bson:"ingress,omitempty"              json:"ingress,omitempty"`
@@ -207,6 +216,8 @@ type HelmVersions struct {
 	CurrentValuesYaml string `json:"current_values_yaml"`
 	LatestVersion     string `json:"latest_version"`
 	LatestValuesYaml  string `json:"latest_values_yaml"`
+	// AvailableVersion chart仓库中已发布的比服务当前版本更新的chart版本
+	AvailableVersion string `json:"available_version,omitempty"`
 }
 
 type HelmServiceRespArgs struct {
@@ -241,3 +252,17 @@ type PmHealthCheck struct {
 func (Service) TableName() string {
 	return "template_service"
 }
+
+// GetChartRepoSource 从数据库中取出的 CreateFrom 为 bson 文档，转换为 chart 仓库来源信息
+func (svc *Service) GetChartRepoSource() (*CreateFromChartRepo, error) {
+	bs, err := bson.Marshal(svc.CreateFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CreateFromChartRepo{}
+	if err = bson.Unmarshal(bs, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
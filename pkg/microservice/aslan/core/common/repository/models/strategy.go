@@ -24,6 +24,8 @@ type CapacityTarget string
 const (
 	// 工作流任务的留存
 	WorkflowTaskRetention CapacityTarget = "WorkflowTaskRetention"
+	// 审计日志的留存
+	AuditLogRetention CapacityTarget = "AuditLogRetention"
 )
 
 // RetentionConfig 资源留存相关的配置
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 )
 
 type Resource struct {
@@ -51,6 +52,11 @@ type Deploy struct {
 	SkipWaiting      bool            `bson:"skipWaiting"                   json:"skipWaiting"`
 	IsRestart        bool            `bson:"is_restart"                    json:"is_restart"`
 	ResetImage       bool            `bson:"reset_image"                   json:"reset_image"`
+	// AutoRollback 部署未通过健康检查时回滚到 ReplaceResources 中记录的原镜像
+	AutoRollback bool `bson:"auto_rollback"                 json:"auto_rollback"`
+	RolledBack   bool `bson:"rolled_back"                   json:"rolled_back"`
+	// RolloutStrategy 为空或 rolling 时原地更新镜像
+	RolloutStrategy *models.RolloutStrategy `bson:"rollout_strategy,omitempty" json:"rollout_strategy,omitempty"`
 }
 
 // SetNamespace ...
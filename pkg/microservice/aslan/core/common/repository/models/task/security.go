@@ -34,6 +34,8 @@ type Security struct {
 	EndTime    int64           `bson:"end_time,omitempty"            json:"end_time,omitempty"`
 	LogFile    string          `bson:"log_file"                      json:"log_file"`
 	Summary    map[string]int  `bson:"summary"                       json:"summary"`
+	// Thresholds 各漏洞等级允许的最大数量，超过时任务失败
+	Thresholds map[string]int `bson:"thresholds,omitempty"          json:"thresholds,omitempty"`
 }
 
 func (s *Security) SetImageName(imageName string) {
@@ -45,6 +45,18 @@ type Testing struct {
 	ReportReady    bool                        `bson:"report_ready"                    json:"report_ready"`
 	IsRestart      bool                        `bson:"is_restart"                      json:"is_restart"`
 	Registries     []*models.RegistryNamespace `bson:"-"                               json:"registries"`
+	// QuarantinedCases 被隔离的用例，失败时不影响测试结果
+	QuarantinedCases []string `bson:"quarantined_cases,omitempty"      json:"quarantined_cases,omitempty"`
+	// QuarantinedFailures 本次执行中失败的隔离用例
+	QuarantinedFailures []string `bson:"quarantined_failures,omitempty"   json:"quarantined_failures,omitempty"`
+	// CoverageGate 覆盖率门禁
+	CoverageGate *models.CoverageGate `bson:"coverage_gate,omitempty"          json:"coverage_gate,omitempty"`
+	// BaseCoverage PR 触发时目标分支最近一次的覆盖率
+	BaseCoverage *models.Coverage `bson:"base_coverage,omitempty"          json:"base_coverage,omitempty"`
+	// Coverage 本次测试的覆盖率
+	Coverage *models.Coverage `bson:"coverage,omitempty"               json:"coverage,omitempty"`
+	// Matrix 测试矩阵的所有组合及其结果，第一个组合即任务本身
+	Matrix []*MatrixCombination `bson:"matrix,omitempty"               json:"matrix,omitempty"`
 }
 
 func (t *Testing) ToSubTask() (map[string]interface{}, error) {
@@ -68,6 +68,9 @@ type Product struct {
 	CustomImageRule            *CustomRule `bson:"custom_image_rule,omitempty"         json:"custom_image_rule,omitempty"`
 	CustomTarRule              *CustomRule `bson:"custom_tar_rule,omitempty"           json:"custom_tar_rule,omitempty"`
 	Public                     bool        `bson:"public,omitempty"                              json:"public"`
+	PreviewEnv                 *PreviewEnv `bson:"preview_env,omitempty"               json:"preview_env,omitempty"`
+	// BuildCacheLimit 项目构建缓存的大小上限，单位 MB，为 0 时使用默认值
+	BuildCacheLimit int64 `bson:"build_cache_limit,omitempty"         json:"build_cache_limit,omitempty"`
 }
 
 type ServiceInfo struct {
@@ -128,6 +131,27 @@ type ProductFeature struct {
 	CreateEnvType string `bson:"create_env_type"           json:"create_env_type"`
 }
 
+// PreviewEnv PR 预览环境配置，PR 创建时从基准环境复制出新环境，只部署变更的服务，PR 合并或关闭后删除
+type PreviewEnv struct {
+	Enabled bool   `bson:"enabled"             json:"enabled"`
+	BaseEnv string `bson:"base_env"            json:"base_env"`
+	// Workflow 用于构建并部署变更服务的工作流
+	Workflow   string `bson:"workflow"            json:"workflow"`
+	CodehostID int    `bson:"codehost_id"         json:"codehost_id"`
+	RepoOwner  string `bson:"repo_owner"          json:"repo_owner"`
+	RepoName   string `bson:"repo_name"           json:"repo_name"`
+	// Branch PR 的目标分支，为空时不限制
+	Branch string `bson:"branch,omitempty"    json:"branch,omitempty"`
+	// Services 服务对应的代码目录，PR 变更的文件命中目录时才部署该服务，未配置的服务保持基准环境的版本
+	Services []*PreviewEnvService `bson:"services,omitempty"  json:"services,omitempty"`
+}
+
+// PreviewEnvService 预览环境中服务和代码目录的对应关系，MatchFolders 规则同工作流触发器，"/" 代表全部文件
+type PreviewEnvService struct {
+	ServiceName  string   `bson:"service_name"        json:"service_name"`
+	MatchFolders []string `bson:"match_folders"       json:"match_folders"`
+}
+
 type ForkProject struct {
 	EnvName      string         `json:"env_name"`
 	WorkflowName string         `json:"workflow_name"`
@@ -0,0 +1,40 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// TestCaseResult 测试任务中单个测试用例的执行结果，用于统计用例历史和识别不稳定用例
+type TestCaseResult struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	TestName     string             `bson:"test_name"       json:"test_name"`
+	CaseName     string             `bson:"case_name"       json:"case_name"`
+	PipelineName string             `bson:"pipeline_name"   json:"pipeline_name"`
+	TaskID       int64              `bson:"task_id"         json:"task_id"`
+	Status       config.Status      `bson:"status"          json:"status"`
+	Quarantined  bool               `bson:"quarantined"     json:"quarantined"`
+	Duration     float64            `bson:"duration"        json:"duration"`
+	CreateTime   int64              `bson:"create_time"     json:"create_time"`
+}
+
+func (TestCaseResult) TableName() string {
+	return "test_case_result"
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Coverage reaper 解析覆盖率报告得到的结果，比例为百分比
+type Coverage struct {
+	Format          string  `bson:"format"           json:"format"`
+	LinesCovered    int     `bson:"lines_covered"    json:"lines_covered"`
+	LinesValid      int     `bson:"lines_valid"      json:"lines_valid"`
+	BranchesCovered int     `bson:"branches_covered" json:"branches_covered"`
+	BranchesValid   int     `bson:"branches_valid"   json:"branches_valid"`
+	LineRate        float64 `bson:"line_rate"        json:"line_rate"`
+	BranchRate      float64 `bson:"branch_rate"      json:"branch_rate"`
+}
+
+// TestCoverage 一次测试任务的覆盖率，PR 为 0 时表示分支上的结果，作为 PR 对比的基准
+type TestCoverage struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	TestName     string             `bson:"test_name"       json:"test_name"`
+	PipelineName string             `bson:"pipeline_name"   json:"pipeline_name"`
+	TaskID       int64              `bson:"task_id"         json:"task_id"`
+	RepoOwner    string             `bson:"repo_owner"      json:"repo_owner"`
+	RepoName     string             `bson:"repo_name"       json:"repo_name"`
+	Branch       string             `bson:"branch"          json:"branch"`
+	PR           int                `bson:"pr"              json:"pr"`
+	Coverage     *Coverage          `bson:"coverage"        json:"coverage"`
+	CreateTime   int64              `bson:"create_time"     json:"create_time"`
+}
+
+func (TestCoverage) TableName() string {
+	return "test_coverage"
+}
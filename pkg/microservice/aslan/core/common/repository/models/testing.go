@@ -17,6 +17,8 @@ limitations under the License.
 package models
 
 import (
+	"fmt"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
@@ -38,19 +40,24 @@ type Testing struct {
 	// Junit 测试报告
 	TestResultPath string `bson:"test_result_path"         json:"test_result_path"`
 	// html 测试报告
-	TestReportPath  string           `bson:"test_report_path"         json:"test_report_path"`
-	Threshold       int              `bson:"threshold"                json:"threshold"`
-	TestType        string           `bson:"test_type"                json:"test_type"`
-	Caches          []string         `bson:"caches"                   json:"caches"`
-	ArtifactPaths   []string         `bson:"artifact_paths,omitempty" json:"artifact_paths,omitempty"`
-	TestCaseNum     int              `bson:"-"                        json:"test_case_num,omitempty"`
-	ExecuteNum      int              `bson:"-"                        json:"execute_num,omitempty"`
-	PassRate        float64          `bson:"-"                        json:"pass_rate,omitempty"`
-	AvgDuration     float64          `bson:"-"                        json:"avg_duration,omitempty"`
-	Workflows       []*Workflow      `bson:"-"                        json:"workflows,omitempty"`
-	Schedules       *ScheduleCtrl    `bson:"schedules,omitempty"      json:"schedules,omitempty"`
-	HookCtl         *TestingHookCtrl `bson:"hook_ctl"                 json:"hook_ctl"`
-	ScheduleEnabled bool             `bson:"schedule_enabled"         json:"-"`
+	TestReportPath string `bson:"test_report_path"         json:"test_report_path"`
+	// 覆盖率报告，支持 Cobertura、JaCoCo 和 Go coverprofile
+	CoverageReportPath string           `bson:"coverage_report_path"         json:"coverage_report_path,omitempty"`
+	CoverageGate       *CoverageGate    `bson:"coverage_gate"                json:"coverage_gate,omitempty"`
+	Threshold          int              `bson:"threshold"                json:"threshold"`
+	TestType           string           `bson:"test_type"                json:"test_type"`
+	Caches             []string         `bson:"caches"                   json:"caches"`
+	ArtifactPaths      []string         `bson:"artifact_paths,omitempty" json:"artifact_paths,omitempty"`
+	TestCaseNum        int              `bson:"-"                        json:"test_case_num,omitempty"`
+	ExecuteNum         int              `bson:"-"                        json:"execute_num,omitempty"`
+	PassRate           float64          `bson:"-"                        json:"pass_rate,omitempty"`
+	AvgDuration        float64          `bson:"-"                        json:"avg_duration,omitempty"`
+	Workflows          []*Workflow      `bson:"-"                        json:"workflows,omitempty"`
+	Schedules          *ScheduleCtrl    `bson:"schedules,omitempty"      json:"schedules,omitempty"`
+	HookCtl            *TestingHookCtrl `bson:"hook_ctl"                 json:"hook_ctl"`
+	ScheduleEnabled    bool             `bson:"schedule_enabled"         json:"-"`
+	// Matrix 测试矩阵，定义同构建矩阵
+	Matrix *BuildMatrix `bson:"matrix,omitempty" json:"matrix,omitempty"`
 }
 
 type TestingHookCtrl struct {
@@ -81,6 +88,30 @@ type PreTest struct {
 	EnableProxy bool `bson:"enable_proxy"           json:"enable_proxy"`
 }
 
+// CoverageGate 覆盖率门禁，所有值均为百分比，0 表示不检查
+type CoverageGate struct {
+	MinLineCoverage   float64 `bson:"min_line_coverage"   json:"min_line_coverage"`
+	MinBranchCoverage float64 `bson:"min_branch_coverage" json:"min_branch_coverage"`
+	// MaxDrop PR 触发的测试相对目标分支行覆盖率允许下降的最大值
+	MaxDrop float64 `bson:"max_drop"            json:"max_drop"`
+}
+
+func (g *CoverageGate) Validate() error {
+	if g == nil {
+		return nil
+	}
+	for name, value := range map[string]float64{
+		"min_line_coverage":   g.MinLineCoverage,
+		"min_branch_coverage": g.MinBranchCoverage,
+		"max_drop":            g.MaxDrop,
+	} {
+		if value < 0 || value > 100 {
+			return fmt.Errorf("%s must be between 0 and 100", name)
+		}
+	}
+	return nil
+}
+
 func (Testing) TableName() string {
 	return "module_testing"
 }
@@ -18,11 +18,13 @@ package models
 
 import (
 	"fmt"
+	"text/template"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/cron"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/types"
 )
@@ -47,6 +49,7 @@ type Workflow struct {
 	TestStage       *TestStage         `bson:"test_stage"                   json:"test_stage"`
 	SecurityStage   *SecurityStage     `bson:"security_stage"               json:"security_stage"`
 	DistributeStage *DistributeStage   `bson:"distribute_stage"             json:"distribute_stage"`
+	ApprovalStage   *ApprovalStage     `bson:"approval_stage,omitempty"     json:"approval_stage,omitempty"`
 	NotifyCtl       *NotifyCtl         `bson:"notify_ctl,omitempty"         json:"notify_ctl,omitempty"`
 	HookCtl         *WorkflowHookCtrl  `bson:"hook_ctl"                     json:"hook_ctl"`
 	IsFavorite      bool               `bson:"-"                            json:"is_favorite"`
@@ -61,6 +64,76 @@ type Workflow struct {
 	ResetImage bool `json:"reset_image" bson:"reset_image"`
 	// IsParallel 控制单一工作流的任务是否支持并行处理
 	IsParallel bool `json:"is_parallel" bson:"is_parallel"`
+	// AutoRollback 部署未通过健康检查时自动回滚到原镜像
+	AutoRollback bool `json:"auto_rollback" bson:"auto_rollback"`
+	// RolloutStrategy 容器部署的发布策略，为空时原地更新镜像
+	RolloutStrategy *RolloutStrategy `json:"rollout_strategy,omitempty" bson:"rollout_strategy,omitempty"`
+}
+
+// RolloutStrategy 部署任务的发布策略
+// Type: rolling(默认), canary, blue_green，canary 和 blue_green 只对 deployment 生效，statefulset 始终原地更新
+// CanaryWeights: 金丝雀发布每一步新版本的副本占比(1-100)，需递增，流量按副本数在新旧版本间分配，没有基于 ingress 的精确切流，
+// 只能取副本数可以表示的比例(例如 3 个副本时为 33、67)，无法表示的权重会让部署任务直接失败
+// StepInterval: 每一步的观察时间，单位秒
+type RolloutStrategy struct {
+	Type          string `bson:"type"                      json:"type"`
+	CanaryWeights []int  `bson:"canary_weights,omitempty"  json:"canary_weights,omitempty"`
+	StepInterval  int    `bson:"step_interval,omitempty"   json:"step_interval,omitempty"`
+}
+
+// Validate ...
+func (s *RolloutStrategy) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Type {
+	case "", setting.RolloutTypeRolling, setting.RolloutTypeBlueGreen:
+		return nil
+	case setting.RolloutTypeCanary:
+		last := 0
+		for _, weight := range s.CanaryWeights {
+			if weight <= last || weight > 100 {
+				return fmt.Errorf("canary weights must be increasing and between 1 and 100")
+			}
+			last = weight
+		}
+		if s.StepInterval < 0 {
+			return fmt.Errorf("step interval must not be negative")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported rollout type: %s", s.Type)
+	}
+}
+
+// ApprovalStage 人工审批，审批通过后才会继续执行部署
+// ProductionOnly: 仅在部署到生产集群的环境时需要审批
+// Timeout: 等待审批的超时时间，单位分钟
+type ApprovalStage struct {
+	Enabled        bool        `bson:"enabled"          json:"enabled"`
+	Approvers      []*Approver `bson:"approvers"        json:"approvers"`
+	ProductionOnly bool        `bson:"production_only"  json:"production_only"`
+	Timeout        int         `bson:"timeout"          json:"timeout"`
+}
+
+type Approver struct {
+	UID      string `bson:"uid"        json:"uid"`
+	UserName string `bson:"user_name"  json:"user_name"`
+}
+
+// Validate ...
+func (s *ApprovalStage) Validate() error {
+	if s == nil || !s.Enabled {
+		return nil
+	}
+	if len(s.Approvers) == 0 {
+		return fmt.Errorf("approval stage requires at least one approver")
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("approval timeout must not be negative")
+	}
+	return nil
 }
 
 type WorkflowHookCtrl struct {
@@ -114,7 +187,9 @@ type Schedule struct {
 	TestArgs     *TestTaskArgs       `bson:"test_args,omitempty"           json:"test_args,omitempty"`
 	Type         config.ScheduleType `bson:"type"                          json:"type"`
 	Cron         string              `bson:"cron"                          json:"cron"`
-	IsModified   bool                `bson:"-"                             json:"-"`
+	// TimeZone cron 表达式所在的 IANA 时区，为空时使用服务所在时区
+	TimeZone   string `bson:"time_zone,omitempty"           json:"time_zone,omitempty"`
+	IsModified bool   `bson:"-"                             json:"-"`
 	// 自由编排工作流的开关是放在schedule里面的
 	Enabled bool `bson:"enabled"                       json:"enabled"`
 }
@@ -197,6 +272,10 @@ type Slack struct {
 	Enabled    bool                   `bson:"enabled"      json:"enabled"`
 	Notifiers  []string               `bson:"notifiers"    json:"notifiers"`
 	NotifyType config.SlackNotifyType `bson:"notify_type"  json:"notify_type"`
+	// WebHook Slack incoming webhook 地址
+	WebHook string `bson:"webhook,omitempty"             json:"webhook,omitempty"`
+	// Templates 按事件自定义消息模板，为空时使用默认模板
+	Templates map[config.NotifyEvent]string `bson:"templates,omitempty"  json:"templates,omitempty"`
 }
 
 type BuildStage struct {
@@ -220,20 +299,52 @@ type ArtifactModule struct {
 	Target *ServiceModuleTarget `bson:"target"                 json:"target"`
 }
 
+// TestStage ...
+// QuarantineFlaky: 自动隔离历史结果中被识别为不稳定的用例，隔离用例失败不会导致测试失败，但仍会出现在报告中
 type TestStage struct {
-	Enabled   bool            `bson:"enabled"                    json:"enabled"`
-	TestNames []string        `bson:"test_names,omitempty"       json:"test_names,omitempty"`
-	Tests     []*TestExecArgs `bson:"tests,omitempty"     json:"tests,omitempty"`
+	Enabled         bool            `bson:"enabled"                    json:"enabled"`
+	TestNames       []string        `bson:"test_names,omitempty"       json:"test_names,omitempty"`
+	Tests           []*TestExecArgs `bson:"tests,omitempty"     json:"tests,omitempty"`
+	QuarantineFlaky bool            `bson:"quarantine_flaky,omitempty" json:"quarantine_flaky,omitempty"`
 }
 
 // TestExecArgs ...
+// QuarantinedCases: 手动隔离的用例，格式为 classname.name
 type TestExecArgs struct {
-	Name string    `bson:"test_name"             json:"test_name"`
-	Envs []*KeyVal `bson:"envs"           json:"envs"`
+	Name             string    `bson:"test_name"             json:"test_name"`
+	Envs             []*KeyVal `bson:"envs"           json:"envs"`
+	QuarantinedCases []string  `bson:"quarantined_cases,omitempty" json:"quarantined_cases,omitempty"`
 }
 
+// SecurityStage 镜像安全扫描
+// Thresholds: 各漏洞等级允许的最大数量，key 为 setting.SecuritySeverities 中的等级，超过时任务失败
 type SecurityStage struct {
-	Enabled bool `bson:"enabled"                    json:"enabled"`
+	Enabled    bool           `bson:"enabled"                    json:"enabled"`
+	Thresholds map[string]int `bson:"thresholds,omitempty"       json:"thresholds,omitempty"`
+}
+
+func (s *SecurityStage) Validate() error {
+	if s == nil || !s.Enabled {
+		return nil
+	}
+	for severity, max := range s.Thresholds {
+		if !isSecuritySeverity(severity) {
+			return fmt.Errorf("unsupported severity: %s", severity)
+		}
+		if max < 0 {
+			return fmt.Errorf("threshold of %s must not be negative", severity)
+		}
+	}
+	return nil
+}
+
+func isSecuritySeverity(severity string) bool {
+	for _, s := range setting.SecuritySeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
 }
 
 type DistributeStage struct {
@@ -245,6 +356,8 @@ type DistributeStage struct {
 
 	// repos to release images
 	Releases []RepoImage `bson:"releases" json:"releases"`
+	// ChartRepoName helm chart 仓库（可以是 OCI 仓库）的名称，用于分发环境中渲染后的 chart
+	ChartRepoName string `bson:"chart_repo_name,omitempty" json:"chart_repo_name,omitempty"`
 }
 
 type RepoImage struct {
@@ -261,6 +374,7 @@ type ProductDistribute struct {
 	ImageDistribute   bool                 `bson:"image_distribute"       json:"image_distribute"`
 	JumpBoxDistribute bool                 `bson:"jump_box_distribute"    json:"jump_box_distribute"`
 	QstackDistribute  bool                 `bson:"qstack_distribute"      json:"qstack_distribute"`
+	ChartDistribute   bool                 `bson:"chart_distribute"       json:"chart_distribute"`
 }
 
 type NotifyCtl struct {
@@ -272,6 +386,146 @@ type NotifyCtl struct {
 	AtMobiles       []string `bson:"at_mobiles,omitempty"             json:"at_mobiles,omitempty"`
 	IsAtAll         bool     `bson:"is_at_all,omitempty"              json:"is_at_all,omitempty"`
 	NotifyTypes     []string `bson:"notify_type"                      json:"notify_type"`
+	// Notifiers Slack、Teams 以及通用 webhook 通知
+	Notifiers []*Notifier `bson:"notifiers,omitempty"              json:"notifiers,omitempty"`
+}
+
+// Notifier 工作流事件通知配置
+type Notifier struct {
+	Type    config.NotifierType `bson:"type"                 json:"type"`
+	Name    string              `bson:"name"                 json:"name"`
+	WebHook string              `bson:"webhook"              json:"webhook"`
+	// Secret 通用 webhook 的签名密钥，请求体的 HMAC-SHA256 签名放在 X-Zadig-Signature 头中，查询工作流时返回 mask
+	Secret string `bson:"secret,omitempty"     json:"secret,omitempty"`
+	// Events 需要通知的事件，为空时通知所有事件
+	Events []config.NotifyEvent `bson:"events,omitempty"     json:"events,omitempty"`
+	// Templates 按事件自定义消息模板（Go template），为空时使用默认模板
+	Templates map[config.NotifyEvent]string `bson:"templates,omitempty"  json:"templates,omitempty"`
+}
+
+func (n *NotifyCtl) Validate() error {
+	if n == nil || !n.Enabled {
+		return nil
+	}
+	for _, notifier := range n.Notifiers {
+		if err := notifier.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) Validate() error {
+	switch n.Type {
+	case config.NotifierSlack, config.NotifierTeams, config.NotifierWebHook:
+	default:
+		return fmt.Errorf("unsupported notifier type: %s", n.Type)
+	}
+	if n.WebHook == "" {
+		return fmt.Errorf("webhook of %s notifier %s is required", n.Type, n.Name)
+	}
+	for _, event := range n.Events {
+		if !isNotifyEvent(event) {
+			return fmt.Errorf("unsupported notify event: %s", event)
+		}
+	}
+	return validateNotifyTemplates(n.Templates)
+}
+
+// MaskSecrets 返回给前端前隐藏通知的签名密钥，Slack incoming webhook 地址中包含凭证，也需要隐藏
+func (n *NotifyCtl) MaskSecrets() {
+	if n == nil {
+		return
+	}
+	for _, notifier := range n.Notifiers {
+		if notifier.Secret != "" {
+			notifier.Secret = setting.MaskValue
+		}
+		if notifier.Type == config.NotifierSlack && notifier.WebHook != "" {
+			notifier.WebHook = setting.MaskValue
+		}
+	}
+}
+
+// EnsureSecrets 签名密钥或 webhook 地址仍为 mask 时认为没有修改，使用原来的值，
+// 地址被隐藏时按类型和名称匹配原来的通知，否则按类型和地址匹配
+func (n *NotifyCtl) EnsureSecrets(existed *NotifyCtl) {
+	if n == nil {
+		return
+	}
+
+	for _, notifier := range n.Notifiers {
+		var old *Notifier
+		if existed != nil {
+			for _, e := range existed.Notifiers {
+				if e.Type != notifier.Type {
+					continue
+				}
+				if e.WebHook == notifier.WebHook || (notifier.WebHook == setting.MaskValue && e.Name == notifier.Name) {
+					old = e
+					break
+				}
+			}
+		}
+
+		if notifier.WebHook == setting.MaskValue {
+			notifier.WebHook = ""
+			if old != nil {
+				notifier.WebHook = old.WebHook
+			}
+		}
+		if notifier.Secret == setting.MaskValue {
+			notifier.Secret = ""
+			if old != nil {
+				notifier.Secret = old.Secret
+			}
+		}
+	}
+}
+
+// MaskSecrets 返回给前端前隐藏 Slack incoming webhook 地址
+func (s *Slack) MaskSecrets() {
+	if s != nil && s.WebHook != "" {
+		s.WebHook = setting.MaskValue
+	}
+}
+
+// EnsureSecrets webhook 地址仍为 mask 时认为没有修改，使用原来的地址
+func (s *Slack) EnsureSecrets(existed *Slack) {
+	if s == nil || s.WebHook != setting.MaskValue {
+		return
+	}
+	s.WebHook = ""
+	if existed != nil {
+		s.WebHook = existed.WebHook
+	}
+}
+
+func (s *Slack) Validate() error {
+	if s == nil || !s.Enabled {
+		return nil
+	}
+	return validateNotifyTemplates(s.Templates)
+}
+
+func validateNotifyTemplates(templates map[config.NotifyEvent]string) error {
+	for event, source := range templates {
+		if !isNotifyEvent(event) {
+			return fmt.Errorf("unsupported notify event: %s", event)
+		}
+		if _, err := template.New(string(event)).Parse(source); err != nil {
+			return fmt.Errorf("invalid %s template: %v", event, err)
+		}
+	}
+	return nil
+}
+
+func isNotifyEvent(event config.NotifyEvent) bool {
+	switch event {
+	case config.NotifyEventStarted, config.NotifyEventPassed, config.NotifyEventFailed, config.NotifyEventCancelled:
+		return true
+	}
+	return false
 }
 
 type TaskInfo struct {
@@ -426,12 +680,21 @@ func (schedule *Schedule) Validate() error {
 		//}
 		return nil
 
+	case config.CrontabSchedule:
+		if _, err := cron.Parse(schedule.Cron, schedule.TimeZone); err != nil {
+			return fmt.Errorf("%s %v", e.InvalidFormatErrMsg, err)
+		}
+		return nil
+
 	default:
 		return fmt.Errorf("%s 间隔任务模式未设置", e.InvalidFormatErrMsg)
 	}
 }
 
 func isValidJobTime(t string) error {
+	if len(t) != 5 || t[2] != ':' {
+		return fmt.Errorf("%s 时间格式: HH:MM", e.InvalidFormatErrMsg)
+	}
 
 	hour := int((t[0]-'0')*10 + (t[1] - '0'))
 	min := int((t[3]-'0')*10 + (t[4] - '0'))
@@ -0,0 +1,39 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// WorkflowTaskApproval 工作流任务的审批结果，由审批人通过接口写入，warpdrive 轮询读取
+type WorkflowTaskApproval struct {
+	ID           primitive.ObjectID    `bson:"_id,omitempty"   json:"id,omitempty"`
+	PipelineName string                `bson:"pipeline_name"   json:"pipeline_name"`
+	TaskID       int64                 `bson:"task_id"         json:"task_id"`
+	Status       config.ApprovalStatus `bson:"status"          json:"status"`
+	UID          string                `bson:"uid"             json:"uid"`
+	UserName     string                `bson:"user_name"       json:"user_name"`
+	Comment      string                `bson:"comment"         json:"comment"`
+	CreatedAt    int64                 `bson:"created_at"      json:"created_at"`
+}
+
+func (WorkflowTaskApproval) TableName() string {
+	return "workflow_task_approval"
+}
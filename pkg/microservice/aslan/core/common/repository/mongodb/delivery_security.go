@@ -26,6 +26,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -118,9 +119,8 @@ func (c *DeliverySecurityColl) FindStatistics(imageID string) (map[string]int, e
 	}
 	query := bson.M{"image_id": imageID, "deleted_at": 0}
 	severityMap := make(map[string]int)
-	severityTypes := []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
 	total := 0
-	for _, severityType := range severityTypes {
+	for _, severityType := range setting.SecuritySeverities {
 		query["severity"] = severityType
 		count, err := c.CountDocuments(context.TODO(), query)
 		if err != nil {
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvSnapshotColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvSnapshotColl() *EnvSnapshotColl {
+	name := models.EnvSnapshot{}.TableName()
+	return &EnvSnapshotColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *EnvSnapshotColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvSnapshotColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "product_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+			bson.E{Key: "create_time", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvSnapshotColl) Create(args *models.EnvSnapshot) error {
+	if args == nil {
+		return errors.New("nil EnvSnapshot")
+	}
+
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = id
+	}
+	return nil
+}
+
+// List 按创建时间倒序返回环境的快照
+func (c *EnvSnapshotColl) List(productName, envName string) ([]*models.EnvSnapshot, error) {
+	resp := make([]*models.EnvSnapshot, 0)
+	query := bson.M{"product_name": productName, "env_name": envName}
+	opts := options.Find().SetSort(bson.D{{"create_time", -1}})
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *EnvSnapshotColl) Find(productName, envName, id string) (*models.EnvSnapshot, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.EnvSnapshot)
+	query := bson.M{"_id": oid, "product_name": productName, "env_name": envName}
+	err = c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvSnapshotColl) Delete(productName, envName, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"_id": oid, "product_name": productName, "env_name": envName}
+	_, err = c.DeleteOne(context.TODO(), query)
+	return err
+}
+
+// DeleteByEnv 删除环境时清理该环境的所有快照
+func (c *EnvSnapshotColl) DeleteByEnv(productName, envName string) error {
+	query := bson.M{"product_name": productName, "env_name": envName}
+	_, err := c.DeleteMany(context.TODO(), query)
+	return err
+}
+
+// PruneAuto 只保留环境最近 keep 个自动快照，手动创建的快照不会被清理
+func (c *EnvSnapshotColl) PruneAuto(productName, envName string, keep int64) error {
+	query := bson.M{"product_name": productName, "env_name": envName, "auto": true}
+	opts := options.Find().SetSort(bson.D{{"create_time", -1}}).SetSkip(keep).SetProjection(bson.M{"_id": 1})
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return err
+	}
+
+	var expired []*models.EnvSnapshot
+	if err := cursor.All(context.TODO(), &expired); err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(expired))
+	for _, snapshot := range expired {
+		ids = append(ids, snapshot.ID)
+	}
+	_, err = c.DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
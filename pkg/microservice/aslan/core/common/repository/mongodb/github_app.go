@@ -28,6 +28,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -67,6 +68,12 @@ func (c *GithubAppColl) Find() ([]*models.GithubApp, error) {
 		return nil, err
 	}
 
+	for _, app := range resp {
+		if err = crypto.DecryptSecrets(&app.AppKey); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, err
 }
 
@@ -77,7 +84,12 @@ func (c *GithubAppColl) Create(args *models.GithubApp) error {
 
 	args.CreatedAt = time.Now().Unix()
 
-	_, err := c.InsertOne(context.TODO(), args)
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.AppKey); err != nil {
+		return err
+	}
+
+	_, err := c.InsertOne(context.TODO(), doc)
 	return err
 }
 
@@ -85,7 +97,12 @@ func (c *GithubAppColl) Upsert(args *models.GithubApp) error {
 	query := bson.M{"_id": args.ID}
 	args.CreatedAt = time.Now().Unix()
 
-	change := bson.M{"$set": args}
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.AppKey); err != nil {
+		return err
+	}
+
+	change := bson.M{"$set": doc}
 
 	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
 	return err
@@ -28,9 +28,14 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
+type HelmRepoFindOption struct {
+	RepoName string
+}
+
 type HelmRepoColl struct {
 	*mongo.Collection
 
@@ -60,7 +65,12 @@ func (c *HelmRepoColl) Create(args *models.HelmRepo) error {
 	args.CreatedAt = time.Now().Unix()
 	args.UpdatedAt = time.Now().Unix()
 
-	_, err := c.InsertOne(context.TODO(), args)
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.Password); err != nil {
+		return err
+	}
+
+	_, err := c.InsertOne(context.TODO(), doc)
 	return err
 }
 
@@ -70,12 +80,17 @@ func (c *HelmRepoColl) Update(id string, args *models.HelmRepo) error {
 		return err
 	}
 
+	password := args.Password
+	if err = crypto.EncryptSecrets(&password); err != nil {
+		return err
+	}
+
 	query := bson.M{"_id": oid}
 	change := bson.M{"$set": bson.M{
 		"repo_name":  args.RepoName,
 		"url":        args.URL,
 		"username":   args.Username,
-		"password":   args.Password,
+		"password":   password,
 		"update_by":  args.UpdateBy,
 		"updated_at": time.Now().Unix(),
 	}}
@@ -96,6 +111,23 @@ func (c *HelmRepoColl) Delete(id string) error {
 	return err
 }
 
+func (c *HelmRepoColl) Find(opt *HelmRepoFindOption) (*models.HelmRepo, error) {
+	if opt == nil {
+		return nil, errors.New("nil FindOption")
+	}
+
+	query := bson.M{"repo_name": opt.RepoName}
+	res := &models.HelmRepo{}
+	if err := c.FindOne(context.TODO(), query).Decode(res); err != nil {
+		return nil, err
+	}
+
+	if err := crypto.DecryptSecrets(&res.Password); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (c *HelmRepoColl) List() ([]*models.HelmRepo, error) {
 	resp := make([]*models.HelmRepo, 0)
 	query := bson.M{}
@@ -113,5 +145,11 @@ func (c *HelmRepoColl) List() ([]*models.HelmRepo, error) {
 		return nil, err
 	}
 
+	for _, repo := range resp {
+		if err = crypto.DecryptSecrets(&repo.Password); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
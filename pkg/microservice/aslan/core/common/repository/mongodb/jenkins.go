@@ -28,6 +28,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -59,7 +60,12 @@ func (c *JenkinsIntegrationColl) Create(args *models.JenkinsIntegration) error {
 
 	args.UpdatedAt = time.Now().Unix()
 
-	_, err := c.InsertOne(context.TODO(), args)
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.Password); err != nil {
+		return err
+	}
+
+	_, err := c.InsertOne(context.TODO(), doc)
 	return err
 }
 
@@ -69,11 +75,16 @@ func (c *JenkinsIntegrationColl) Update(ID string, args *models.JenkinsIntegrati
 		return err
 	}
 
+	password := args.Password
+	if err = crypto.EncryptSecrets(&password); err != nil {
+		return err
+	}
+
 	query := bson.M{"_id": oldID}
 	change := bson.M{"$set": bson.M{
 		"url":        args.URL,
 		"username":   args.Username,
-		"password":   args.Password,
+		"password":   password,
 		"update_by":  args.UpdateBy,
 		"updated_at": time.Now().Unix(),
 	}}
@@ -111,5 +122,11 @@ func (c *JenkinsIntegrationColl) List() ([]*models.JenkinsIntegration, error) {
 		return nil, err
 	}
 
+	for _, jenkins := range resp {
+		if err = crypto.DecryptSecrets(&jenkins.Password); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
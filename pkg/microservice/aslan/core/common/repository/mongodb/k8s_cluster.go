@@ -143,11 +143,12 @@ func (c *K8SClusterColl) FindByName(name string) (*models.K8SCluster, error) {
 func (c *K8SClusterColl) UpdateMutableFields(cluster *models.K8SCluster) error {
 	_, err := c.UpdateOne(context.TODO(),
 		bson.M{"_id": cluster.ID}, bson.M{"$set": bson.M{
-			"name":        cluster.Name,
-			"description": cluster.Description,
-			"tags":        cluster.Tags,
-			"namespace":   cluster.Namespace,
-			"production":  cluster.Production,
+			"name":            cluster.Name,
+			"description":     cluster.Description,
+			"tags":            cluster.Tags,
+			"namespace":       cluster.Namespace,
+			"production":      cluster.Production,
+			"pod_exec_policy": cluster.PodExecPolicy,
 		}},
 	)
 
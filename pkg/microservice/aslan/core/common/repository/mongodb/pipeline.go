@@ -112,6 +112,12 @@ func (c *PipelineColl) List(opt *PipelineListOption) ([]*models.Pipeline, error)
 		return nil, err
 	}
 
+	for _, pipeline := range resp {
+		if err = decryptNotifyCtl(pipeline.NotifyCtl); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
 
@@ -122,9 +128,11 @@ func (c *PipelineColl) Find(opt *PipelineFindOption) (*models.Pipeline, error) {
 
 	resp := &models.Pipeline{}
 	query := bson.M{"name": opt.Name, "is_deleted": false}
-	err := c.FindOne(context.TODO(), query).Decode(resp)
+	if err := c.FindOne(context.TODO(), query).Decode(resp); err != nil {
+		return resp, err
+	}
 
-	return resp, err
+	return resp, decryptNotifyCtl(resp.NotifyCtl)
 }
 
 func (c *PipelineColl) Delete(name string) error {
@@ -141,10 +149,16 @@ func (c *PipelineColl) Upsert(args *models.Pipeline) error {
 
 	query := bson.M{"name": args.Name, "is_deleted": false}
 
+	notifyCtl, err := encryptNotifyCtl(args.NotifyCtl)
+	if err != nil {
+		return err
+	}
+
 	change := bson.M{"$set": bson.M{
 		"product_name":     args.ProductName,
 		"description":      args.Description,
 		"notifiers":        args.Notifiers,
+		"notify_ctl":       notifyCtl,
 		"update_by":        args.UpdateBy,
 		"create_time":      time.Now().Unix(),
 		"update_time":      time.Now().Unix(),
@@ -158,7 +172,7 @@ func (c *PipelineColl) Upsert(args *models.Pipeline) error {
 		"build_module_ver": args.BuildModuleVer,
 	}}
 
-	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	_, err = c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
 	return err
 }
 
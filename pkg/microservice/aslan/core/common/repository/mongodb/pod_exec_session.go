@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type PodExecSessionListOption struct {
+	Username    string
+	ProductName string
+	ClusterID   string
+	Namespace   string
+	PodName     string
+	StartTime   int64
+	EndTime     int64
+	PerPage     int
+	Page        int
+}
+
+type PodExecSessionColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewPodExecSessionColl() *PodExecSessionColl {
+	name := models.PodExecSession{}.TableName()
+	return &PodExecSessionColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *PodExecSessionColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *PodExecSessionColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"start_time": -1},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "cluster_id", Value: 1},
+				bson.E{Key: "namespace", Value: 1},
+				bson.E{Key: "pod_name", Value: 1},
+				bson.E{Key: "start_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "username", Value: 1},
+				bson.E{Key: "start_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *PodExecSessionColl) Create(args *models.PodExecSession) error {
+	if args == nil {
+		return errors.New("nil PodExecSession")
+	}
+
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = id
+	}
+	return nil
+}
+
+func (c *PodExecSessionColl) Find(id string) (*models.PodExecSession, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.PodExecSession)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// UpdateRecording 录像上传后更新会话的结束时间和录像大小
+func (c *PodExecSessionColl) UpdateRecording(id string, size, endTime int64, truncated bool) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	change := bson.M{"size": size, "end_time": endTime, "truncated": truncated}
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": change})
+	return err
+}
+
+// List 按会话开始时间倒序返回
+func (c *PodExecSessionColl) List(opt *PodExecSessionListOption) ([]*models.PodExecSession, int, error) {
+	query := bson.M{}
+	if opt.Username != "" {
+		query["username"] = opt.Username
+	}
+	if opt.ProductName != "" {
+		query["product_name"] = opt.ProductName
+	}
+	if opt.ClusterID != "" {
+		query["cluster_id"] = opt.ClusterID
+	}
+	if opt.Namespace != "" {
+		query["namespace"] = opt.Namespace
+	}
+	if opt.PodName != "" {
+		query["pod_name"] = opt.PodName
+	}
+	startTime := bson.M{}
+	if opt.StartTime > 0 {
+		startTime["$gte"] = opt.StartTime
+	}
+	if opt.EndTime > 0 {
+		startTime["$lte"] = opt.EndTime
+	}
+	if len(startTime) > 0 {
+		query["start_time"] = startTime
+	}
+
+	opts := options.Find().SetSort(bson.D{{"start_time", -1}})
+	if opt.Page > 0 && opt.PerPage > 0 {
+		opts.SetSkip(int64(opt.PerPage * (opt.Page - 1))).SetLimit(int64(opt.PerPage))
+	}
+
+	resp := make([]*models.PodExecSession, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = cursor.All(context.TODO(), &resp); err != nil {
+		return nil, 0, err
+	}
+
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp, int(count), nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type PreviewEnvFindOption struct {
+	ProductName string
+	CodehostID  int
+	RepoOwner   string
+	RepoName    string
+	PR          int
+}
+
+type PreviewEnvColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewPreviewEnvColl() *PreviewEnvColl {
+	name := models.PreviewEnv{}.TableName()
+	return &PreviewEnvColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *PreviewEnvColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *PreviewEnvColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "product_name", Value: 1},
+			bson.E{Key: "codehost_id", Value: 1},
+			bson.E{Key: "repo_owner", Value: 1},
+			bson.E{Key: "repo_name", Value: 1},
+			bson.E{Key: "pr", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *PreviewEnvColl) Find(opt *PreviewEnvFindOption) (*models.PreviewEnv, error) {
+	resp := new(models.PreviewEnv)
+	query := bson.M{
+		"product_name": opt.ProductName,
+		"codehost_id":  opt.CodehostID,
+		"repo_owner":   opt.RepoOwner,
+		"repo_name":    opt.RepoName,
+		"pr":           opt.PR,
+	}
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *PreviewEnvColl) List(productName string) ([]*models.PreviewEnv, error) {
+	resp := make([]*models.PreviewEnv, 0)
+	query := bson.M{"product_name": productName}
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"create_time", -1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *PreviewEnvColl) Create(args *models.PreviewEnv) error {
+	if args == nil {
+		return errors.New("nil PreviewEnv")
+	}
+
+	now := time.Now().Unix()
+	args.CreateTime = now
+	args.UpdateTime = now
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// UpdateCommit 更新预览环境对应的最新提交，只修改 commit_id，避免覆盖并发写入的评论
+func (c *PreviewEnvColl) UpdateCommit(productName, envName, commitID string) error {
+	query := bson.M{"product_name": productName, "env_name": envName}
+	change := bson.M{"$set": bson.M{
+		"commit_id":   commitID,
+		"update_time": time.Now().Unix(),
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+// UpdateComment 更新预览环境在 PR 中的评论，只修改 comment_id
+func (c *PreviewEnvColl) UpdateComment(productName, envName, commentID string) error {
+	query := bson.M{"product_name": productName, "env_name": envName}
+	change := bson.M{"$set": bson.M{
+		"comment_id":  commentID,
+		"update_time": time.Now().Unix(),
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *PreviewEnvColl) Delete(productName, envName string) error {
+	query := bson.M{"product_name": productName, "env_name": envName}
+	_, err := c.DeleteOne(context.TODO(), query)
+	return err
+}
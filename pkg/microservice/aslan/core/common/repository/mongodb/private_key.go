@@ -28,6 +28,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -83,6 +84,11 @@ func (c *PrivateKeyColl) Find(option FindPrivateKeyOption) (*models.PrivateKey,
 	}
 
 	err := c.FindOne(context.TODO(), query).Decode(privateKey)
+	if err != nil {
+		return privateKey, err
+	}
+
+	err = crypto.DecryptSecrets(&privateKey.PrivateKey)
 	return privateKey, err
 }
 
@@ -104,6 +110,12 @@ func (c *PrivateKeyColl) List(args *PrivateKeyArgs) ([]*models.PrivateKey, error
 		return nil, err
 	}
 
+	for _, key := range resp {
+		if err = crypto.DecryptSecrets(&key.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, err
 }
 
@@ -115,7 +127,12 @@ func (c *PrivateKeyColl) Create(args *models.PrivateKey) error {
 	args.CreateTime = time.Now().Unix()
 	args.UpdateTime = time.Now().Unix()
 
-	_, err := c.InsertOne(context.TODO(), args)
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.PrivateKey); err != nil {
+		return err
+	}
+
+	_, err := c.InsertOne(context.TODO(), doc)
 
 	return err
 }
@@ -130,6 +147,11 @@ func (c *PrivateKeyColl) Update(id string, args *models.PrivateKey) error {
 		return err
 	}
 
+	privateKey := args.PrivateKey
+	if err = crypto.EncryptSecrets(&privateKey); err != nil {
+		return err
+	}
+
 	query := bson.M{"_id": oid}
 	change := bson.M{"$set": bson.M{
 		"name":        args.Name,
@@ -137,7 +159,7 @@ func (c *PrivateKeyColl) Update(id string, args *models.PrivateKey) error {
 		"ip":          args.IP,
 		"label":       args.Label,
 		"is_prod":     args.IsProd,
-		"private_key": args.PrivateKey,
+		"private_key": privateKey,
 		"provider":    args.Provider,
 		"update_by":   args.UpdateBy,
 		"update_time": time.Now().Unix(),
@@ -28,6 +28,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -70,7 +71,11 @@ func (c *ProxyColl) Find(id string) (*models.Proxy, error) {
 	res := &models.Proxy{}
 	query := bson.M{"_id": oid}
 	err = c.FindOne(context.TODO(), query).Decode(res)
+	if err != nil {
+		return res, err
+	}
 
+	err = crypto.DecryptSecrets(&res.Password)
 	return res, err
 }
 
@@ -90,6 +95,12 @@ func (c *ProxyColl) List(args *ProxyArgs) ([]*models.Proxy, error) {
 		return nil, err
 	}
 
+	for _, proxy := range res {
+		if err = crypto.DecryptSecrets(&proxy.Password); err != nil {
+			return nil, err
+		}
+	}
+
 	return res, err
 }
 
@@ -101,7 +112,12 @@ func (c *ProxyColl) Create(args *models.Proxy) error {
 	args.CreateTime = time.Now().Unix()
 	args.UpdateTime = time.Now().Unix()
 
-	_, err := c.InsertOne(context.TODO(), args)
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.Password); err != nil {
+		return err
+	}
+
+	_, err := c.InsertOne(context.TODO(), doc)
 	return err
 }
 
@@ -115,6 +131,11 @@ func (c *ProxyColl) Update(id string, args *models.Proxy) error {
 		return errors.New("nil proxy info")
 	}
 
+	password := args.Password
+	if err = crypto.EncryptSecrets(&password); err != nil {
+		return err
+	}
+
 	query := bson.M{"_id": oid}
 	change := bson.M{"$set": bson.M{
 		"type":                     args.Type,
@@ -122,7 +143,7 @@ func (c *ProxyColl) Update(id string, args *models.Proxy) error {
 		"port":                     args.Port,
 		"need_password":            args.NeedPassword,
 		"username":                 args.Username,
-		"password":                 args.Password,
+		"password":                 password,
 		"usage":                    args.Usage,
 		"enable_repo_proxy":        args.EnableRepoProxy,
 		"enable_application_proxy": args.EnableApplicationProxy,
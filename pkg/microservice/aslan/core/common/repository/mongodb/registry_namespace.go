@@ -28,6 +28,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -78,7 +79,12 @@ func (r *RegistryNamespaceColl) Create(args *models.RegistryNamespace) error {
 
 	args.UpdateTime = time.Now().Unix()
 
-	_, err := r.InsertOne(context.TODO(), args)
+	doc := *args
+	if err := crypto.EncryptSecrets(&doc.SecretKey); err != nil {
+		return err
+	}
+
+	_, err := r.InsertOne(context.TODO(), doc)
 	return err
 }
 
@@ -119,7 +125,11 @@ func (r *RegistryNamespaceColl) Find(opt *FindRegOps) (*models.RegistryNamespace
 
 	res := &models.RegistryNamespace{}
 	err := r.FindOne(context.TODO(), query).Decode(res)
+	if err != nil {
+		return res, err
+	}
 
+	err = crypto.DecryptSecrets(&res.SecretKey)
 	return res, err
 }
 
@@ -140,6 +150,12 @@ func (r *RegistryNamespaceColl) FindAll(opt *FindRegOps) ([]*models.RegistryName
 		return nil, err
 	}
 
+	for _, reg := range resp {
+		if err = crypto.DecryptSecrets(&reg.SecretKey); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, err
 }
 
@@ -157,7 +173,12 @@ func (r *RegistryNamespaceColl) Update(id string, args *models.RegistryNamespace
 	args.ID = oid
 	args.UpdateTime = time.Now().Unix()
 
-	change := bson.M{"$set": args}
+	doc := *args
+	if err = crypto.EncryptSecrets(&doc.SecretKey); err != nil {
+		return err
+	}
+
+	change := bson.M{"$set": doc}
 	_, err = r.UpdateOne(context.TODO(), query, change)
 	return err
 }
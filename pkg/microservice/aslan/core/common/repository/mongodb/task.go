@@ -381,6 +381,20 @@ func (c *TaskColl) InCompletedTasks() ([]*task.Task, error) {
 	return ret, nil
 }
 
+// ListWaitForApproveTasks 列出在审批处暂停的任务
+func (c *TaskColl) ListWaitForApproveTasks() ([]*task.Task, error) {
+	ret := make([]*task.Task, 0)
+	query := bson.M{"status": config.StatusWaitForApprove, "is_deleted": false}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &ret)
+
+	return ret, err
+}
+
 func (c *TaskColl) FindTodoTasks() ([]*task.Task, error) {
 	ret := make([]*task.Task, 0)
 	query := bson.M{"status": bson.M{"$in": []string{"waiting", "quened", "created", "running", "blocked"}}}
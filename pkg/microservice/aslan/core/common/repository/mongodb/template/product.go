@@ -254,6 +254,8 @@ func (c *ProductColl) Update(productName string, args *template.Product) error {
 		"custom_tar_rule":       args.CustomTarRule,
 		"custom_image_rule":     args.CustomImageRule,
 		"public":                args.Public,
+		"preview_env":           args.PreviewEnv,
+		"build_cache_limit":     args.BuildCacheLimit,
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, change)
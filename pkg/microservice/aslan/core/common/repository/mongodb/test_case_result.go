@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TestCaseResultColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTestCaseResultColl() *TestCaseResultColl {
+	name := models.TestCaseResult{}.TableName()
+	return &TestCaseResultColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TestCaseResultColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TestCaseResultColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "pipeline_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+				bson.E{Key: "test_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+
+	return err
+}
+
+// Replace 覆盖一次任务中某个测试的所有用例结果，任务重试时不会重复记录
+func (c *TestCaseResultColl) Replace(pipelineName string, taskID int64, testName string, results []*models.TestCaseResult) error {
+	query := bson.M{"pipeline_name": pipelineName, "task_id": taskID, "test_name": testName}
+	if _, err := c.DeleteMany(context.TODO(), query); err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		docs = append(docs, result)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+
+	return err
+}
+
+// List 按时间倒序返回测试最近的用例结果
+func (c *TestCaseResultColl) List(testName string, limit int64) ([]*models.TestCaseResult, error) {
+	resp := make([]*models.TestCaseResult, 0)
+	query := bson.M{"test_name": testName}
+	opts := options.Find().SetSort(bson.D{{"create_time", -1}}).SetLimit(limit)
+
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
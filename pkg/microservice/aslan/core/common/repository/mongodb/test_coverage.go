@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TestCoverageColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTestCoverageColl() *TestCoverageColl {
+	name := models.TestCoverage{}.TableName()
+	return &TestCoverageColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TestCoverageColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TestCoverageColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "repo_owner", Value: 1},
+				bson.E{Key: "repo_name", Value: 1},
+				bson.E{Key: "branch", Value: 1},
+				bson.E{Key: "pr", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+
+	return err
+}
+
+// Upsert 按任务记录覆盖率，任务重试时覆盖之前的结果
+func (c *TestCoverageColl) Upsert(args *models.TestCoverage) error {
+	query := bson.M{"pipeline_name": args.PipelineName, "task_id": args.TaskID, "test_name": args.TestName}
+	change := bson.M{"$set": bson.M{
+		"repo_owner":  args.RepoOwner,
+		"repo_name":   args.RepoName,
+		"branch":      args.Branch,
+		"pr":          args.PR,
+		"coverage":    args.Coverage,
+		"create_time": args.CreateTime,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// FindLatestBase 返回分支上最近一次非 PR 触发的覆盖率，作为 PR 的对比基准
+func (c *TestCoverageColl) FindLatestBase(testName, repoOwner, repoName, branch string) (*models.TestCoverage, error) {
+	resp := new(models.TestCoverage)
+	query := bson.M{
+		"test_name":  testName,
+		"repo_owner": repoOwner,
+		"repo_name":  repoName,
+		"branch":     branch,
+		"pr":         0,
+	}
+	opts := options.FindOne().SetSort(bson.D{{"create_time", -1}})
+
+	err := c.FindOne(context.TODO(), query, opts).Decode(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// List 按时间倒序返回测试最近的覆盖率
+func (c *TestCoverageColl) List(testName string, limit int64) ([]*models.TestCoverage, error) {
+	resp := make([]*models.TestCoverage, 0)
+	query := bson.M{"test_name": testName}
+	opts := options.Find().SetSort(bson.D{{"create_time", -1}}).SetLimit(limit)
+
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -84,6 +85,12 @@ func (c *WorkflowColl) List(opt *ListWorkflowOption) ([]*models.Workflow, error)
 		return nil, err
 	}
 
+	for _, workflow := range resp {
+		if err = decryptNotifyCtl(workflow.NotifyCtl); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
 
@@ -115,14 +122,22 @@ func (c *WorkflowColl) ListByTestName(testName string) ([]*models.Workflow, erro
 		return nil, err
 	}
 
+	for _, workflow := range resp {
+		if err = decryptNotifyCtl(workflow.NotifyCtl); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
 
 func (c *WorkflowColl) Find(name string) (*models.Workflow, error) {
 	res := &models.Workflow{}
 	query := bson.M{"name": name}
-	err := c.FindOne(context.TODO(), query).Decode(res)
-	return res, err
+	if err := c.FindOne(context.TODO(), query).Decode(res); err != nil {
+		return res, err
+	}
+	return res, decryptNotifyCtl(res.NotifyCtl)
 }
 
 func (c *WorkflowColl) Delete(name string) error {
@@ -139,7 +154,14 @@ func (c *WorkflowColl) Create(args *models.Workflow) error {
 	args.CreateTime = time.Now().Unix()
 	args.UpdateTime = time.Now().Unix()
 
-	_, err := c.InsertOne(context.TODO(), args)
+	doc := *args
+	notifyCtl, err := encryptNotifyCtl(args.NotifyCtl)
+	if err != nil {
+		return err
+	}
+	doc.NotifyCtl = notifyCtl
+
+	_, err = c.InsertOne(context.TODO(), doc)
 	return err
 }
 
@@ -152,7 +174,14 @@ func (c *WorkflowColl) Replace(args *models.Workflow) error {
 
 	args.UpdateTime = time.Now().Unix()
 
-	_, err := c.ReplaceOne(context.TODO(), query, args)
+	doc := *args
+	notifyCtl, err := encryptNotifyCtl(args.NotifyCtl)
+	if err != nil {
+		return err
+	}
+	doc.NotifyCtl = notifyCtl
+
+	_, err = c.ReplaceOne(context.TODO(), query, doc)
 	return err
 }
 
@@ -170,6 +199,12 @@ func (c *WorkflowColl) ListWithScheduleEnabled() ([]*models.Workflow, error) {
 		return nil, err
 	}
 
+	for _, workflow := range resp {
+		if err = decryptNotifyCtl(workflow.NotifyCtl); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
 
@@ -192,5 +227,43 @@ func (c *WorkflowColl) ListWorkflowsByProjects(projects []string) ([]*models.Wor
 		return nil, err
 	}
 
+	for _, workflow := range resp {
+		if err = decryptNotifyCtl(workflow.NotifyCtl); err != nil {
+			return nil, err
+		}
+	}
+
 	return resp, nil
 }
+
+// encryptNotifyCtl 返回通知签名密钥加密后的副本，不修改调用方的对象
+func encryptNotifyCtl(n *models.NotifyCtl) (*models.NotifyCtl, error) {
+	if n == nil || n.Notifiers == nil {
+		return n, nil
+	}
+
+	res := *n
+	res.Notifiers = make([]*models.Notifier, 0, len(n.Notifiers))
+	for _, notifier := range n.Notifiers {
+		doc := *notifier
+		if doc.Secret != "" {
+			if err := crypto.EncryptSecrets(&doc.Secret); err != nil {
+				return nil, err
+			}
+		}
+		res.Notifiers = append(res.Notifiers, &doc)
+	}
+	return &res, nil
+}
+
+func decryptNotifyCtl(n *models.NotifyCtl) error {
+	if n == nil {
+		return nil
+	}
+	for _, notifier := range n.Notifiers {
+		if err := crypto.DecryptSecrets(&notifier.Secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WorkflowTaskApprovalColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowTaskApprovalColl() *WorkflowTaskApprovalColl {
+	name := models.WorkflowTaskApproval{}.TableName()
+	return &WorkflowTaskApprovalColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WorkflowTaskApprovalColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowTaskApprovalColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "pipeline_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+
+	return err
+}
+
+// Create 每个任务只记录第一次审批结果，重复审批会返回 duplicate key 错误
+func (c *WorkflowTaskApprovalColl) Create(args *models.WorkflowTaskApproval) error {
+	if args == nil {
+		return errors.New("nil workflow task approval args")
+	}
+
+	args.CreatedAt = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+
+	return err
+}
+
+// Find 没有审批记录时返回 nil
+func (c *WorkflowTaskApprovalColl) Find(pipelineName string, taskID int64) (*models.WorkflowTaskApproval, error) {
+	query := bson.M{"pipeline_name": pipelineName, "task_id": taskID}
+
+	res := &models.WorkflowTaskApproval{}
+	err := c.FindOne(context.TODO(), query).Decode(res)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	return res, err
+}
+
+// Delete 任务重试时清除上一次的审批结果
+func (c *WorkflowTaskApprovalColl) Delete(pipelineName string, taskID int64) error {
+	query := bson.M{"pipeline_name": pipelineName, "task_id": taskID}
+	_, err := c.DeleteOne(context.TODO(), query)
+	return err
+}
@@ -108,6 +108,14 @@ func ToSecurityTask(sb map[string]interface{}) (*task.Security, error) {
 	return t, nil
 }
 
+func ToApprovalTask(sb map[string]interface{}) (*task.Approval, error) {
+	var t *task.Approval
+	if err := task.IToi(sb, &t); err != nil {
+		return nil, fmt.Errorf("convert interface to approvalTask error: %v", err)
+	}
+	return t, nil
+}
+
 func ToJenkinsBuildTask(sb map[string]interface{}) (*task.JenkinsBuild, error) {
 	var jenkinsBuild *task.JenkinsBuild
 	if err := task.IToi(sb, &jenkinsBuild); err != nil {
@@ -115,3 +123,11 @@ func ToJenkinsBuildTask(sb map[string]interface{}) (*task.JenkinsBuild, error) {
 	}
 	return jenkinsBuild, nil
 }
+
+func ToReleaseChartTask(sb map[string]interface{}) (*task.ReleaseChart, error) {
+	var t *task.ReleaseChart
+	if err := task.IToi(sb, &t); err != nil {
+		return nil, fmt.Errorf("convert interface to ReleaseChartTask error: %v", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// RolledBackDeploy 健康检查失败后自动回滚的部署
+type RolledBackDeploy struct {
+	ServiceName string `json:"service_name"`
+	Container   string `json:"container"`
+	Image       string `json:"image"`
+}
+
+// GetRolledBackDeploys 返回工作流任务中自动回滚的部署，供各通知渠道使用
+func GetRolledBackDeploys(t *task.Task) []*RolledBackDeploy {
+	if t.Type != config.WorkflowType {
+		return nil
+	}
+
+	var deploys []*RolledBackDeploy
+	for _, stage := range t.Stages {
+		if stage.TaskType != config.TaskDeploy {
+			continue
+		}
+
+		for _, subTask := range stage.SubTasks {
+			deployInfo, err := ToDeployTask(subTask)
+			if err != nil {
+				log.Errorf("parse deployInfo failed, err:%s", err)
+				continue
+			}
+
+			if !deployInfo.RolledBack {
+				continue
+			}
+			for _, res := range deployInfo.ReplaceResources {
+				deploys = append(deploys, &RolledBackDeploy{ServiceName: deployInfo.ServiceName, Container: res.Container, Image: res.Origin})
+			}
+		}
+	}
+
+	return deploys
+}
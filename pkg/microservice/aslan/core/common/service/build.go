@@ -17,6 +17,8 @@ limitations under the License.
 package service
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -35,7 +37,9 @@ func CreateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 	}
 
 	build.UpdateBy = username
-	correctFields(build)
+	if err := CorrectFields(build); err != nil {
+		return e.ErrCreateBuildModule.AddErr(err)
+	}
 
 	if err := commonrepo.NewBuildColl().Create(build); err != nil {
 		log.Errorf("[Build.Upsert] %s error: %v", build.Name, err)
@@ -55,7 +59,9 @@ func UpdateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 		EnsureSecretEnvs(existed.PreBuild.Envs, build.PreBuild.Envs)
 	}
 
-	correctFields(build)
+	if err := CorrectFields(build); err != nil {
+		return e.ErrUpdateBuildModule.AddErr(err)
+	}
 	build.UpdateBy = username
 	build.UpdateTime = time.Now().Unix()
 
@@ -67,7 +73,8 @@ func UpdateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 	return nil
 }
 
-func correctFields(build *commonmodels.Build) {
+// CorrectFields 清理构建中的空白字段并校验缓存与分批部署配置
+func CorrectFields(build *commonmodels.Build) error {
 	// make sure cache has no empty field
 	caches := make([]string, 0)
 	for _, cache := range build.Caches {
@@ -78,11 +85,54 @@ func correctFields(build *commonmodels.Build) {
 	}
 	build.Caches = caches
 
+	keyFiles := make([]string, 0)
+	for _, file := range build.CacheKeyFiles {
+		file = strings.Trim(file, " /")
+		if file != "" {
+			keyFiles = append(keyFiles, file)
+		}
+	}
+	build.CacheKeyFiles = keyFiles
+	// 未指定缓存目录时会缓存整个工作目录，恢复时旧代码会覆盖新拉取的代码
+	if len(build.CacheKeyFiles) > 0 && len(build.Caches) == 0 {
+		return errors.New("caches are required when cache key files are specified")
+	}
+
 	// trim the docker file and context
 	if build.PostBuild != nil && build.PostBuild.DockerBuild != nil {
 		build.PostBuild.DockerBuild.DockerFile = strings.Trim(build.PostBuild.DockerBuild.DockerFile, " ")
 		build.PostBuild.DockerBuild.WorkDir = strings.Trim(build.PostBuild.DockerBuild.WorkDir, " ")
 	}
+
+	return verifyPMDeployStrategy(build)
+}
+
+// pmHostEnvPrefix 分批部署时 reaper 为每台主机注入的环境变量前缀
+const pmHostEnvPrefix = "PM_HOST_"
+
+// verifyPMDeployStrategy 分批部署时脚本在每台主机上各执行一次，脚本中没有使用 PM_HOST_* 变量时
+// 每次执行的都是同样的操作，不能按批次部署
+func verifyPMDeployStrategy(build *commonmodels.Build) error {
+	strategy := build.PMDeployStrategy
+	if strategy == nil || !strategy.Enabled {
+		return nil
+	}
+	if strategy.BatchSize < 0 || strategy.PauseSeconds < 0 || strategy.InitialDelaySeconds < 0 {
+		return errors.New("batch size, pause seconds and initial delay seconds must not be negative")
+	}
+	if !strings.Contains(build.PMDeployScripts, pmHostEnvPrefix) {
+		return fmt.Errorf("deploy scripts must use the per-host variables %s* to deploy in batches", pmHostEnvPrefix)
+	}
+	if strategy.BatchPercent < 0 || strategy.BatchPercent > 100 {
+		return errors.New("batch percent must be between 0 and 100")
+	}
+	if strategy.RollbackOnFailure && strings.TrimSpace(strategy.RollbackScripts) == "" {
+		return errors.New("rollback scripts are required when rollback on failure is enabled")
+	}
+	if strategy.RollbackScripts != "" && !strings.Contains(strategy.RollbackScripts, pmHostEnvPrefix) {
+		return fmt.Errorf("rollback scripts must use the per-host variables %s* to roll back in batches", pmHostEnvPrefix)
+	}
+	return nil
 }
 
 // EnsureSecretEnvs 转换敏感信息前端传入的Mask内容为真实内容
@@ -325,6 +325,33 @@ func GetSubTaskContent(deliveryVersion *commonmodels.DeliveryVersion, pipelineTa
 					continue
 				}
 			}
+		case config.TaskReleaseChart:
+			if taskStatus != config.StatusPassed {
+				continue
+			}
+			for serviceName, subTask := range subStage.SubTasks {
+				releaseChartInfo, err := base.ToReleaseChartTask(subTask)
+				if err != nil {
+					log.Errorf("get releaseChart ToReleaseChartTask failed ! err:%v", err)
+					continue
+				}
+				deliveryDistribute := &commonmodels.DeliveryDistribute{
+					ReleaseID:      deliveryVersion.ID,
+					ServiceName:    serviceName,
+					DistributeType: config.Chart,
+					RegistryName:   releaseChartInfo.ChartRepoName,
+					PackageFile:    fmt.Sprintf("%s-%s.tgz", releaseChartInfo.ChartName, releaseChartInfo.ChartVersion),
+					StartTime:      releaseChartInfo.StartTime,
+					EndTime:        releaseChartInfo.EndTime,
+					CreatedAt:      time.Now().Unix(),
+				}
+
+				err = insertDeliveryDistribute(deliveryDistribute, log)
+				if err != nil {
+					log.Errorf("get releaseChart InsertDeliveryDistribute failed ! err:%v", err)
+					continue
+				}
+			}
 		case config.TaskDistributeToS3:
 			if taskStatus != config.StatusPassed {
 				continue
@@ -23,7 +23,9 @@ import (
 	"github.com/27149chen/afero"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	bitbucketservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/bitbucket"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	giteeservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
 	githubservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	gitlabservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitlab"
 	"github.com/koderover/zadig/pkg/setting"
@@ -112,6 +114,10 @@ func GetTreeGetter(codeHostID int) (TreeGetter, error) {
 		return githubservice.NewClient(ch.AccessToken, config.ProxyHTTPSAddr()), nil
 	case setting.SourceFromGitlab:
 		return gitlabservice.NewClient(ch.Address, ch.AccessToken)
+	case setting.SourceFromGitee:
+		return giteeservice.NewClient(ch.Address, ch.AccessToken), nil
+	case setting.SourceFromBitbucket:
+		return bitbucketservice.NewClient(ch.Address, ch.AccessToken), nil
 	default:
 		// should not have happened here
 		log.DPanicf("invalid source: %s", ch.Type)
@@ -19,6 +19,9 @@ package git
 import (
 	"github.com/google/go-github/v35/github"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 type TreeNode struct {
@@ -39,6 +42,10 @@ func ToRepositoryCommit(obj interface{}) *RepositoryCommit {
 		return &RepositoryCommit{SHA: o.GetSHA(), Message: o.GetCommit().GetMessage()}
 	case *gitlab.Commit:
 		return &RepositoryCommit{SHA: o.ID, Message: o.Message}
+	case *gitee.Commit:
+		return &RepositoryCommit{SHA: o.SHA, Message: o.Message()}
+	case *bitbucket.Commit:
+		return &RepositoryCommit{SHA: o.ID, Message: o.Message}
 	default:
 		return nil
 	}
@@ -60,6 +67,20 @@ func ToTreeNode(obj interface{}) *TreeNode {
 			IsDir:    o.Type == "tree",
 			FullPath: o.Path,
 		}
+	case *gitee.Content:
+		return &TreeNode{
+			Name:     o.Name,
+			Size:     o.Size,
+			IsDir:    o.IsDir(),
+			FullPath: o.Path,
+		}
+	case *bitbucket.TreeNode:
+		return &TreeNode{
+			Name:     o.Name,
+			Size:     o.Size,
+			IsDir:    o.IsDir,
+			FullPath: o.Path,
+		}
 	default:
 		return nil
 	}
@@ -17,15 +17,21 @@ limitations under the License.
 package service
 
 import (
+	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
 
 	"github.com/27149chen/afero"
+	"github.com/otiai10/copy"
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	fsservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/helmclient"
 	"github.com/koderover/zadig/pkg/tool/log"
 	fsutil "github.com/koderover/zadig/pkg/util/fs"
 )
@@ -56,9 +62,84 @@ func PreLoadServiceManifests(base string, svc *commonmodels.Service) error {
 
 	log.Warnf("Failed to download service from s3, err: %s", err)
 
+	if svc.Source == setting.SourceFromChartRepo {
+		return preLoadServiceManifestsFromChartRepo(svc)
+	}
 	return preLoadServiceManifestsFromSource(svc)
 }
 
+// NewChartRepoClient 根据配置的 helm 仓库名称创建 chart 仓库客户端，仓库地址以 oci:// 开头时为 OCI 仓库
+func NewChartRepoClient(repoName string) (*helmclient.ChartRepo, error) {
+	helmRepo, err := commonrepo.NewHelmRepoColl().Find(&commonrepo.HelmRepoFindOption{RepoName: repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find helm repo %s: %v", repoName, err)
+	}
+
+	return &helmclient.ChartRepo{
+		URL:      helmRepo.URL,
+		Username: helmRepo.Username,
+		Password: helmRepo.Password,
+	}, nil
+}
+
+// PullChartFromRepo 从 chart 仓库拉取指定版本的 chart 并解压到 dest 目录，version 为空时使用最新版本
+func PullChartFromRepo(repoName, chartName, version, dest string) error {
+	chartRepo, err := NewChartRepoClient(repoName)
+	if err != nil {
+		return err
+	}
+
+	data, err := chartRepo.PullChart(chartName, version)
+	if err != nil {
+		return fmt.Errorf("failed to pull chart %s:%s from %s: %v", chartName, version, repoName, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "chart-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	// chart 被解压到 tmpDir/<chart name>
+	if err = helmclient.ExpandChart(data, tmpDir); err != nil {
+		return fmt.Errorf("failed to expand chart %s:%s: %v", chartName, version, err)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 1 {
+		return fmt.Errorf("invalid chart package %s:%s", chartName, version)
+	}
+
+	if err = os.RemoveAll(dest); err != nil {
+		return err
+	}
+	return copy.Copy(filepath.Join(tmpDir, entries[0].Name()), dest)
+}
+
+// preLoadServiceManifestsFromChartRepo 重新拉取服务对应版本的 chart，values.yaml 使用服务中保存的合并后的 values
+func preLoadServiceManifestsFromChartRepo(svc *commonmodels.Service) error {
+	source, err := svc.GetChartRepoSource()
+	if err != nil {
+		return err
+	}
+
+	base := config.LocalServicePath(svc.ProductName, svc.ServiceName)
+	to := filepath.Join(base, svc.ServiceName)
+	if err = PullChartFromRepo(source.ChartRepoName, source.ChartName, svc.HelmChart.Version, to); err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(to, setting.ValuesYaml), []byte(svc.HelmChart.ValuesYaml), 0644); err != nil {
+		return err
+	}
+
+	s3Base := config.ObjectStorageServicePath(svc.ProductName, svc.ServiceName)
+	return fsservice.ArchiveAndUploadFilesToS3(os.DirFS(base), svc.ServiceName, s3Base, log.SugaredLogger())
+}
+
 func DownloadServiceManifests(base, projectName, serviceName string) error {
 	s3Base := config.ObjectStorageServicePath(projectName, serviceName)
 	return fsservice.DownloadAndExtractFilesFromS3(serviceName, base, s3Base, log.SugaredLogger())
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// Notifier 把工作流任务事件发送到外部渠道
+type Notifier interface {
+	Send(event *Event) error
+}
+
+// Event 任务事件，同时作为消息模板的数据
+type Event struct {
+	Type      config.NotifyEvent `json:"event"`
+	Task      *task.Task         `json:"-"`
+	URL       string             `json:"url"`
+	TotalTime int64              `json:"total_time"`
+	// RolledBack 健康检查失败后自动回滚的部署，渲染消息时追加在模板内容之后
+	RolledBack []*base.RolledBackDeploy `json:"rolled_back,omitempty"`
+}
+
+var defaultTemplates = map[config.NotifyEvent]string{
+	config.NotifyEventStarted:   "Workflow {{.Task.PipelineName}} #{{.Task.TaskID}} started by {{.Task.TaskCreator}}: {{.URL}}",
+	config.NotifyEventPassed:    "Workflow {{.Task.PipelineName}} #{{.Task.TaskID}} passed in {{.TotalTime}}s: {{.URL}}",
+	config.NotifyEventFailed:    "Workflow {{.Task.PipelineName}} #{{.Task.TaskID}} {{.Task.Status}} after {{.TotalTime}}s: {{.URL}}",
+	config.NotifyEventCancelled: "Workflow {{.Task.PipelineName}} #{{.Task.TaskID}} cancelled after {{.TotalTime}}s: {{.URL}}",
+}
+
+// New 根据配置创建对应渠道的 Notifier
+func New(n *models.Notifier, client *httpclient.Client) (Notifier, error) {
+	switch n.Type {
+	case config.NotifierSlack:
+		return &slackNotifier{webHook: n.WebHook, templates: n.Templates, client: client}, nil
+	case config.NotifierTeams:
+		return &teamsNotifier{webHook: n.WebHook, templates: n.Templates, client: client}, nil
+	case config.NotifierWebHook:
+		return &webHookNotifier{webHook: n.WebHook, secret: n.Secret, templates: n.Templates, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported notifier type %q", n.Type)
+	}
+}
+
+// EventOf 把任务状态转换为通知事件，非通知状态返回 false
+func EventOf(status config.Status) (config.NotifyEvent, bool) {
+	switch status {
+	case config.StatusRunning:
+		return config.NotifyEventStarted, true
+	case config.StatusPassed:
+		return config.NotifyEventPassed, true
+	case config.StatusFailed, config.StatusTimeout:
+		return config.NotifyEventFailed, true
+	case config.StatusCancelled:
+		return config.NotifyEventCancelled, true
+	default:
+		return "", false
+	}
+}
+
+// Subscribed 未配置事件时默认订阅所有事件
+func Subscribed(events []config.NotifyEvent, event config.NotifyEvent) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Render 使用用户模板渲染消息，未配置时使用默认模板
+func Render(templates map[config.NotifyEvent]string, event *Event) (string, error) {
+	source, ok := templates[event.Type]
+	if !ok || source == "" {
+		source = defaultTemplates[event.Type]
+	}
+
+	tmpl, err := template.New(string(event.Type)).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %v", event.Type, err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, event); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %v", event.Type, err)
+	}
+	for _, d := range event.RolledBack {
+		fmt.Fprintf(buf, "\n%s/%s is rolled back to %s", d.ServiceName, d.Container, d.Image)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+var _ = Describe("Testing notifier", func() {
+	event := &Event{
+		Type: config.NotifyEventFailed,
+		Task: &task.Task{
+			TaskID:       3,
+			ProductName:  "demo",
+			PipelineName: "demo-workflow",
+			Type:         config.WorkflowType,
+			Status:       config.StatusTimeout,
+			TaskCreator:  "admin",
+		},
+		URL:       "http://zadig/detail",
+		TotalTime: 42,
+	}
+
+	Context("map task status to event", func() {
+		It("maps final status", func() {
+			e, ok := EventOf(config.StatusTimeout)
+			Expect(ok).To(BeTrue())
+			Expect(e).To(Equal(config.NotifyEventFailed))
+
+			e, ok = EventOf(config.StatusRunning)
+			Expect(ok).To(BeTrue())
+			Expect(e).To(Equal(config.NotifyEventStarted))
+		})
+
+		It("ignores other status", func() {
+			_, ok := EventOf(config.StatusQueued)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("subscribes all events by default", func() {
+			Expect(Subscribed(nil, config.NotifyEventPassed)).To(BeTrue())
+			Expect(Subscribed([]config.NotifyEvent{config.NotifyEventFailed}, config.NotifyEventPassed)).To(BeFalse())
+		})
+	})
+
+	Context("render message", func() {
+		It("uses default template", func() {
+			msg, err := Render(nil, event)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg).To(Equal("Workflow demo-workflow #3 timeout after 42s: http://zadig/detail"))
+		})
+
+		It("uses user template", func() {
+			msg, err := Render(map[config.NotifyEvent]string{
+				config.NotifyEventFailed: "{{.Task.ProductName}}/{{.Task.PipelineName}} failed, by {{.Task.TaskCreator}}",
+			}, event)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg).To(Equal("demo/demo-workflow failed, by admin"))
+		})
+
+		It("appends rolled back deploys", func() {
+			rolledBack := *event
+			rolledBack.RolledBack = []*base.RolledBackDeploy{{ServiceName: "web", Container: "web", Image: "web:v1"}}
+			msg, err := Render(nil, &rolledBack)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg).To(Equal("Workflow demo-workflow #3 timeout after 42s: http://zadig/detail\nweb/web is rolled back to web:v1"))
+		})
+
+		It("reports template error", func() {
+			_, err := Render(map[config.NotifyEvent]string{
+				config.NotifyEventFailed: "{{.Unknown}}",
+			}, event)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("send generic webhook", func() {
+		It("signs the body", func() {
+			var (
+				body      []byte
+				signature string
+				eventType string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = ioutil.ReadAll(r.Body)
+				signature = r.Header.Get(SignatureHeader)
+				eventType = r.Header.Get(EventHeader)
+			}))
+			defer server.Close()
+
+			n, err := New(&models.Notifier{Type: config.NotifierWebHook, WebHook: server.URL, Secret: "s3cret"}, httpclient.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n.Send(event)).To(Succeed())
+
+			Expect(signature).To(Equal(Sign("s3cret", body)))
+			Expect(eventType).To(Equal(string(config.NotifyEventFailed)))
+
+			payload := &webHookPayload{}
+			Expect(json.Unmarshal(body, payload)).To(Succeed())
+			Expect(payload.Workflow).To(Equal("demo-workflow"))
+			Expect(payload.Status).To(Equal(config.StatusTimeout))
+		})
+
+		It("rejects unknown notifier", func() {
+			_, err := New(&models.Notifier{Type: "unknown"}, httpclient.New())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("notifier secrets", func() {
+		It("masks secrets and keeps unchanged ones on update", func() {
+			existed := &models.NotifyCtl{Notifiers: []*models.Notifier{
+				{Type: config.NotifierWebHook, WebHook: "http://a", Secret: "a-secret"},
+				{Type: config.NotifierWebHook, WebHook: "http://b", Secret: "b-secret"},
+			}}
+			masked := &models.NotifyCtl{Notifiers: []*models.Notifier{
+				{Type: config.NotifierWebHook, WebHook: "http://a", Secret: "a-secret"},
+				{Type: config.NotifierWebHook, WebHook: "http://b", Secret: "b-secret"},
+				{Type: config.NotifierWebHook, WebHook: "http://c"},
+			}}
+			masked.MaskSecrets()
+			Expect(masked.Notifiers[0].Secret).To(Equal(setting.MaskValue))
+			Expect(masked.Notifiers[2].Secret).To(BeEmpty())
+
+			masked.Notifiers[1].Secret = "new-secret"
+			masked.EnsureSecrets(existed)
+			Expect(masked.Notifiers[0].Secret).To(Equal("a-secret"))
+			Expect(masked.Notifiers[1].Secret).To(Equal("new-secret"))
+			Expect(masked.Notifiers[2].Secret).To(BeEmpty())
+		})
+
+		It("masks slack webhooks and keeps unchanged ones on update", func() {
+			existed := &models.NotifyCtl{Notifiers: []*models.Notifier{
+				{Type: config.NotifierSlack, Name: "ops", WebHook: "https://hooks.slack.com/services/a"},
+			}}
+			masked := &models.NotifyCtl{Enabled: true, Notifiers: []*models.Notifier{
+				{Type: config.NotifierSlack, Name: "ops", WebHook: "https://hooks.slack.com/services/a"},
+				{Type: config.NotifierSlack, Name: "dev", WebHook: "https://hooks.slack.com/services/b"},
+			}}
+			masked.MaskSecrets()
+			Expect(masked.Notifiers[0].WebHook).To(Equal(setting.MaskValue))
+
+			masked.EnsureSecrets(existed)
+			Expect(masked.Notifiers[0].WebHook).To(Equal("https://hooks.slack.com/services/a"))
+			Expect(masked.Notifiers[1].WebHook).To(BeEmpty())
+			Expect(masked.Validate()).NotTo(Succeed())
+
+			slack := &models.Slack{WebHook: "https://hooks.slack.com/services/a"}
+			slack.MaskSecrets()
+			Expect(slack.WebHook).To(Equal(setting.MaskValue))
+			slack.EnsureSecrets(&models.Slack{WebHook: "https://hooks.slack.com/services/a"})
+			Expect(slack.WebHook).To(Equal("https://hooks.slack.com/services/a"))
+		})
+	})
+})
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	singleInfo = "single"
+	multiInfo  = "multi"
+)
+
+type Service struct {
+	proxyColl    *mongodb.ProxyColl
+	workflowColl *mongodb.WorkflowColl
+	pipelineColl *mongodb.PipelineColl
+	taskColl     *mongodb.TaskColl
+}
+
+func NewService() *Service {
+	return &Service{
+		proxyColl:    mongodb.NewProxyColl(),
+		workflowColl: mongodb.NewWorkflowColl(),
+		pipelineColl: mongodb.NewPipelineColl(),
+		taskColl:     mongodb.NewTaskColl(),
+	}
+}
+
+// SendTaskNotification 按工作流的通知配置发送任务事件，单个渠道失败不影响其它渠道
+func (s *Service) SendTaskNotification(t *task.Task) error {
+	event, ok := EventOf(t.Status)
+	if !ok {
+		return nil
+	}
+
+	var (
+		notifyCtl *models.NotifyCtl
+		slack     *models.Slack
+		kind      string
+	)
+	switch t.Type {
+	case config.SingleType:
+		pipeline, err := s.pipelineColl.Find(&mongodb.PipelineFindOption{Name: t.PipelineName})
+		if err != nil {
+			log.Errorf("Pipeline find err :%v", err)
+			return err
+		}
+		notifyCtl, slack, kind = pipeline.NotifyCtl, pipeline.Slack, singleInfo
+	case config.WorkflowType:
+		workflow, err := s.workflowColl.Find(t.PipelineName)
+		if err != nil {
+			log.Errorf("Workflow find err :%v", err)
+			return err
+		}
+		notifyCtl, slack, kind = workflow.NotifyCtl, workflow.Slack, multiInfo
+	default:
+		return nil
+	}
+
+	notifiers := make(map[*models.Notifier]Notifier)
+	client := s.httpClient()
+	if notifyCtl != nil && notifyCtl.Enabled {
+		for _, cfg := range notifyCtl.Notifiers {
+			if !Subscribed(cfg.Events, event) {
+				continue
+			}
+			n, err := New(cfg, client)
+			if err != nil {
+				log.Errorf("failed to create %s notifier %s: %v", cfg.Type, cfg.Name, err)
+				continue
+			}
+			notifiers[cfg] = n
+		}
+	}
+	if slack != nil && slack.Enabled && slack.WebHook != "" && s.slackSubscribed(slack.NotifyType, t, event) {
+		notifiers[&models.Notifier{Type: config.NotifierSlack, Name: slack.Channel}] = &slackNotifier{
+			webHook:   slack.WebHook,
+			channel:   slack.Channel,
+			templates: slack.Templates,
+			client:    client,
+		}
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	e := &Event{
+		Type:       event,
+		Task:       t,
+		URL:        fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/%s/%s/%d", configbase.SystemAddress(), t.ProductName, kind, t.PipelineName, t.TaskID),
+		TotalTime:  time.Now().Unix() - t.StartTime,
+		RolledBack: base.GetRolledBackDeploys(t),
+	}
+	if event == config.NotifyEventStarted {
+		e.TotalTime = 0
+	}
+
+	var errs []string
+	for cfg, n := range notifiers {
+		if err := n.Send(e); err != nil {
+			log.Errorf("failed to send %s notification of %s#%d to %s: %v", cfg.Type, t.PipelineName, t.TaskID, cfg.Name, err)
+			errs = append(errs, fmt.Sprintf("%s %s: %v", cfg.Type, cfg.Name, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("send notification err: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// slackSubscribed onfailure 只通知失败，onchange 只在状态与上一次任务不同时通知
+func (s *Service) slackSubscribed(notifyType config.SlackNotifyType, t *task.Task, event config.NotifyEvent) bool {
+	switch notifyType {
+	case config.SlackOnfailure:
+		return event == config.NotifyEventFailed
+	case config.SlackOnChange:
+		if event == config.NotifyEventStarted {
+			return false
+		}
+		if t.TaskID <= 1 {
+			return true
+		}
+		last, err := s.taskColl.Find(t.TaskID-1, t.PipelineName, t.Type)
+		if err != nil {
+			return true
+		}
+		return last.Status != t.Status
+	default:
+		return true
+	}
+}
+
+func (s *Service) httpClient() *httpclient.Client {
+	// 使用代理
+	proxies, _ := s.proxyColl.List(&mongodb.ProxyArgs{})
+	if len(proxies) != 0 && proxies[0].EnableApplicationProxy {
+		return httpclient.New(httpclient.SetProxy(proxies[0].GetProxyURL()))
+	}
+	return httpclient.New()
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// slackMessage Slack incoming webhook 消息
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+type slackNotifier struct {
+	webHook   string
+	channel   string
+	templates map[config.NotifyEvent]string
+	client    *httpclient.Client
+}
+
+func (n *slackNotifier) Send(event *Event) error {
+	text, err := Render(n.templates, event)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.client.Post(n.webHook, httpclient.SetBody(&slackMessage{Channel: n.channel, Text: text}))
+	return err
+}
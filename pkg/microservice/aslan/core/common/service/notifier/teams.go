@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	teamsColorPassed  = "2EB886"
+	teamsColorFailed  = "E01E5A"
+	teamsColorDefault = "808080"
+)
+
+// teamsMessage Microsoft Teams incoming webhook 的 MessageCard 消息
+type teamsMessage struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+}
+
+type teamsNotifier struct {
+	webHook   string
+	templates map[config.NotifyEvent]string
+	client    *httpclient.Client
+}
+
+func (n *teamsNotifier) Send(event *Event) error {
+	text, err := Render(n.templates, event)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("%s #%d %s", event.Task.PipelineName, event.Task.TaskID, event.Type)
+	message := &teamsMessage{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    title,
+		ThemeColor: teamsColor(event.Type),
+		Title:      title,
+		Text:       text,
+	}
+
+	_, err = n.client.Post(n.webHook, httpclient.SetBody(message))
+	return err
+}
+
+func teamsColor(event config.NotifyEvent) string {
+	switch event {
+	case config.NotifyEventPassed:
+		return teamsColorPassed
+	case config.NotifyEventFailed:
+		return teamsColorFailed
+	default:
+		return teamsColorDefault
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	// SignatureHeader 请求体的 HMAC-SHA256 签名，格式为 sha256=<hex>
+	SignatureHeader = "X-Zadig-Signature"
+	// EventHeader 事件类型
+	EventHeader = "X-Zadig-Event"
+)
+
+// webHookPayload 通用 webhook 请求体
+type webHookPayload struct {
+	Event       config.NotifyEvent  `json:"event"`
+	ProductName string              `json:"product_name"`
+	Workflow    string              `json:"workflow"`
+	Type        config.PipelineType `json:"type"`
+	TaskID      int64               `json:"task_id"`
+	Status      config.Status       `json:"status"`
+	Creator     string              `json:"creator"`
+	URL         string              `json:"url"`
+	TotalTime   int64               `json:"total_time"`
+	Message     string              `json:"message"`
+	// RolledBack 健康检查失败后自动回滚的部署
+	RolledBack []*base.RolledBackDeploy `json:"rolled_back,omitempty"`
+}
+
+type webHookNotifier struct {
+	webHook   string
+	secret    string
+	templates map[config.NotifyEvent]string
+	client    *httpclient.Client
+}
+
+func (n *webHookNotifier) Send(event *Event) error {
+	message, err := Render(n.templates, event)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&webHookPayload{
+		Event:       event.Type,
+		ProductName: event.Task.ProductName,
+		Workflow:    event.Task.PipelineName,
+		Type:        event.Task.Type,
+		TaskID:      event.Task.TaskID,
+		Status:      event.Task.Status,
+		Creator:     event.Task.TaskCreator,
+		URL:         event.URL,
+		TotalTime:   event.TotalTime,
+		Message:     message,
+		RolledBack:  event.RolledBack,
+	})
+	if err != nil {
+		return err
+	}
+
+	rfs := []httpclient.RequestFunc{
+		httpclient.SetHeader("Content-Type", "application/json"),
+		httpclient.SetHeader(EventHeader, string(event.Type)),
+		httpclient.SetBody(body),
+	}
+	if n.secret != "" {
+		rfs = append(rfs, httpclient.SetHeader(SignatureHeader, Sign(n.secret, body)))
+	}
+
+	_, err = n.client.Post(n.webHook, rfs...)
+	return err
+}
+
+// Sign 计算请求体的签名，接收方使用相同的密钥校验
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
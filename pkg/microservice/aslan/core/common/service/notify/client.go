@@ -20,9 +20,13 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/hashicorp/go-multierror"
+
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/notifier"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/wechat"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -37,6 +41,7 @@ type client struct {
 	taskColl         *mongodb.TaskColl
 	scmNotifyService *scmnotify.Service
 	WeChatService    *wechat.Service
+	notifierService  *notifier.Service
 }
 
 func NewNotifyClient() *client {
@@ -47,6 +52,7 @@ func NewNotifyClient() *client {
 		taskColl:         mongodb.NewTaskColl(),
 		scmNotifyService: scmnotify.NewService(),
 		WeChatService:    wechat.NewWeChatClient(),
+		notifierService:  notifier.NewService(),
 	}
 }
 
@@ -178,6 +184,9 @@ func (c *client) ProccessNotify(notify *models.Notify) error {
 			return fmt.Errorf("get task #%d notify, status: %s", ctx.TaskID, ctx.Status)
 		}
 		ctx.ProductName = task.ProductName
+		for _, d := range base.GetRolledBackDeploys(task) {
+			ctx.RolledBack = append(ctx.RolledBack, fmt.Sprintf("%s/%s 已回滚至 %s", d.ServiceName, d.Container, d.Image))
+		}
 		notify.Content = ctx
 
 		receivers := []string{notify.Receiver}
@@ -202,16 +211,26 @@ func (c *client) ProccessNotify(notify *models.Notify) error {
 			_ = c.scmNotifyService.UpdateWebhookCommentForTest(task, logger)
 		}
 
+		// 各通知渠道相互独立，一个渠道失败不影响其他渠道，最后汇总返回错误
+		var errs *multierror.Error
+
 		//发送微信通知
-		err = c.WeChatService.SendWechatMessage(task)
-		if err != nil {
-			return fmt.Errorf("SendWechatMessage err : %v", err)
+		if err = c.WeChatService.SendWechatMessage(task); err != nil {
+			logger.Errorf("SendWechatMessage err : %v", err)
+			errs = multierror.Append(errs, fmt.Errorf("SendWechatMessage err : %v", err))
+		}
+
+		//发送 Slack、Teams 及 webhook 通知
+		if err = c.notifierService.SendTaskNotification(task); err != nil {
+			logger.Errorf("SendTaskNotification err : %v", err)
+			errs = multierror.Append(errs, fmt.Errorf("SendTaskNotification err : %v", err))
 		}
 
 		for _, receiver := range receivers {
 			subs, err := c.subscriptionColl.List(notify.Receiver)
 			if err != nil {
-				return fmt.Errorf("list subscribers error: %v", err)
+				errs = multierror.Append(errs, fmt.Errorf("list subscribers error: %v", err))
+				break
 			}
 			newNotify := &models.Notify{
 				Type:     config.PipelineStatus,
@@ -221,11 +240,13 @@ func (c *client) ProccessNotify(notify *models.Notify) error {
 			for _, sub := range subs {
 				if sub.Type == newNotify.Type && (sub.PipelineStatus == ctx.Status || sub.PipelineStatus == "*") {
 					if err := c.notifyColl.Create(newNotify); err != nil {
-						return fmt.Errorf("create notify error: %v", err)
+						errs = multierror.Append(errs, fmt.Errorf("create notify error: %v", err))
 					}
 				}
 			}
 		}
+
+		return errs.ErrorOrNil()
 	case config.Message:
 		b, err := json.Marshal(notify.Content)
 		if err != nil {
@@ -24,6 +24,7 @@ import (
 	"github.com/nsqio/go-nsq"
 
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/tool/nsqcli"
 )
@@ -62,7 +63,11 @@ func Init(agentID string, addresses []string) {
 }
 
 func (s *agent) Publish(topic string, payload []byte) error {
-	return s.sender.Publish(topic, payload)
+	if err := s.sender.Publish(topic, payload); err != nil {
+		metrics.NSQPublishFailures.WithLabelValues(topic).Inc()
+		return err
+	}
+	return nil
 }
 
 func (s *agent) SubScribeSimple(topic, channel string, handler nsq.Handler) error {
@@ -77,6 +77,9 @@ func DeleteProduct(username, envName, productName, requestID string, log *zap.Su
 		if err != nil {
 			log.Errorf("Product.Delete error: %v", err)
 		}
+		if err := mongodb.NewEnvSnapshotColl().DeleteByEnv(productName, envName); err != nil {
+			log.Errorf("EnvSnapshot.DeleteByEnv error: %v", err)
+		}
 
 		go func() {
 			var err error
@@ -124,6 +127,9 @@ func DeleteProduct(username, envName, productName, requestID string, log *zap.Su
 		if err != nil {
 			log.Errorf("Product.Delete error: %v", err)
 		}
+		if err := mongodb.NewEnvSnapshotColl().DeleteByEnv(productName, envName); err != nil {
+			log.Errorf("EnvSnapshot.DeleteByEnv error: %v", err)
+		}
 
 		// 删除workload数据
 		tempProduct, err := template.NewProductColl().Find(productName)
@@ -226,6 +232,9 @@ func DeleteProduct(username, envName, productName, requestID string, log *zap.Su
 			if err != nil {
 				log.Errorf("Product.Delete error: %v", err)
 			}
+			if err := mongodb.NewEnvSnapshotColl().DeleteByEnv(productName, envName); err != nil {
+				log.Errorf("EnvSnapshot.DeleteByEnv error: %v", err)
+			}
 		}()
 	}
 	return nil
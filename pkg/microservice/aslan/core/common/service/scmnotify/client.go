@@ -28,8 +28,10 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
 	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -154,9 +156,106 @@ func (c *Client) Comment(notify *models.Notification) error {
 				}
 			}
 		}
+	} else if strings.ToLower(codeHostDetail.Type) == systemconfig.GiteeProvider {
+		if err := c.commentGitee(codeHostDetail, notify, comment); err != nil {
+			return fmt.Errorf("failed to comment gitee due to %s/%d %v", notify.ProjectID, notify.PrID, err)
+		}
+	} else if strings.ToLower(codeHostDetail.Type) == systemconfig.BitbucketProvider {
+		if err := c.commentBitbucket(codeHostDetail, notify, comment); err != nil {
+			return fmt.Errorf("failed to comment bitbucket due to %s/%d %v", notify.ProjectID, notify.PrID, err)
+		}
 	} else {
 		return fmt.Errorf("non gitlab source not supported to comment")
 	}
 
 	return nil
 }
+
+func (c *Client) commentGitee(codeHostDetail *systemconfig.CodeHost, notify *models.Notification, comment string) error {
+	owner, repo := splitProjectID(notify.ProjectID)
+	cli := gitee.NewClient(codeHostDetail.Address, codeHostDetail.AccessToken)
+	if notify.CommentID == "" {
+		note, err := cli.CreatePullRequestComment(owner, repo, notify.PrID, comment)
+		if err != nil {
+			return err
+		}
+		notify.CommentID = strconv.Itoa(note.ID)
+		return nil
+	}
+
+	commentID, _ := strconv.Atoi(notify.CommentID)
+	return cli.UpdatePullRequestComment(owner, repo, commentID, comment)
+}
+
+// commentBitbucket 更新 PR 评论，并把每个任务的状态作为构建状态写到 PR 的最新提交上
+func (c *Client) commentBitbucket(codeHostDetail *systemconfig.CodeHost, notify *models.Notification, comment string) error {
+	project, repo := splitProjectID(notify.ProjectID)
+	cli := bitbucket.NewClient(codeHostDetail.Address, codeHostDetail.AccessToken)
+	if notify.CommentID == "" {
+		note, err := cli.CreatePullRequestComment(project, repo, notify.PrID, comment)
+		if err != nil {
+			return err
+		}
+		notify.CommentID = strconv.Itoa(note.ID)
+	} else {
+		commentID, _ := strconv.Atoi(notify.CommentID)
+		if _, err := cli.UpdatePullRequestComment(project, repo, notify.PrID, commentID, comment); err != nil {
+			return err
+		}
+	}
+
+	if notify.Revision == "" {
+		return nil
+	}
+	for _, task := range notify.Tasks {
+		status := toBitbucketBuildStatus(notify, task)
+		if status == nil {
+			continue
+		}
+		if err := cli.SetBuildStatus(notify.Revision, status); err != nil {
+			c.logger.Warnf("failed to set build status %v %v %v", task, notify, err)
+		}
+	}
+
+	return nil
+}
+
+func toBitbucketBuildStatus(notify *models.Notification, task *models.NotificationTask) *bitbucket.BuildStatus {
+	var state bitbucket.BuildState
+	switch task.Status {
+	case config.TaskStatusReady, config.TaskStatusRunning:
+		state = bitbucket.BuildStateInProgress
+	case config.TaskStatusPass:
+		state = bitbucket.BuildStateSuccessful
+	case config.TaskStatusFailed, config.TaskStatusTimeout, config.TaskStatusCancelled:
+		state = bitbucket.BuildStateFailed
+	default:
+		return nil
+	}
+
+	name := task.WorkflowName
+	url := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/multi/%s/%d", notify.BaseURI, task.ProductName, task.WorkflowName, task.ID)
+	if notify.IsTest {
+		name = task.TestName
+		url = fmt.Sprintf("%s/v1/projects/detail/%s/test/detail/function/%s/%d", notify.BaseURI, task.ProductName, task.TestName, task.ID)
+	} else if notify.IsPipeline {
+		name = task.PipelineName
+		url = fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/single/%s/%d", notify.BaseURI, task.ProductName, task.PipelineName, task.ID)
+	}
+
+	return &bitbucket.BuildStatus{
+		State: state,
+		Key:   fmt.Sprintf("zadig-%s-%s", task.ProductName, name),
+		Name:  fmt.Sprintf("%s#%d", name, task.ID),
+		URL:   url,
+	}
+}
+
+// splitProjectID 把 owner/repo 形式的 ProjectID 拆分为 owner 和 repo
+func splitProjectID(projectID string) (string, string) {
+	idx := strings.LastIndex(projectID, "/")
+	if idx < 0 {
+		return "", projectID
+	}
+	return projectID[:idx], projectID[idx+1:]
+}
@@ -199,6 +199,9 @@ func (s *Service) UpdateWebhookComment(task *task.Task, logger *zap.SugaredLogge
 				}
 				scmTask.TestReports = testReports
 			}
+			if status == config.TaskStatusPass || status == config.TaskStatusFailed {
+				scmTask.Coverages = taskCoverages(task)
+			}
 
 			tasks = append(tasks, scmTask)
 			taskExist = true
@@ -412,6 +415,32 @@ func DownloadTestReports(taskInfo *task.Task, logger *zap.SugaredLogger) ([]*mod
 	return nil, nil
 }
 
+// taskCoverages 从测试子任务中获取覆盖率
+func taskCoverages(pt *task.Task) []*models.NotificationCoverage {
+	var coverages []*models.NotificationCoverage
+	for _, stage := range pt.Stages {
+		if stage.TaskType != config.TaskTestingV2 {
+			continue
+		}
+		for _, subTask := range stage.SubTasks {
+			testInfo := new(task.Testing)
+			if err := task.IToi(subTask, testInfo); err != nil || testInfo.Coverage == nil {
+				continue
+			}
+			coverage := &models.NotificationCoverage{
+				TestName:   testInfo.TestModuleName,
+				LineRate:   testInfo.Coverage.LineRate,
+				BranchRate: testInfo.Coverage.BranchRate,
+			}
+			if testInfo.BaseCoverage != nil {
+				coverage.BaseLineRate = &testInfo.BaseCoverage.LineRate
+			}
+			coverages = append(coverages, coverage)
+		}
+	}
+	return coverages
+}
+
 // UpdateWebhookCommentForTest update the test comment to codehost when task status changes
 func (s *Service) UpdateWebhookCommentForTest(task *task.Task, logger *zap.SugaredLogger) (err error) {
 	if task.TestArgs.NotificationID == "" {
@@ -447,6 +476,9 @@ func (s *Service) UpdateWebhookCommentForTest(task *task.Task, logger *zap.Sugar
 				}
 				scmTask.TestReports = testReports
 			}
+			if status == config.TaskStatusPass || status == config.TaskStatusFailed {
+				scmTask.Coverages = taskCoverages(task)
+			}
 
 			tasks = append(tasks, scmTask)
 			taskExist = true
@@ -521,6 +553,9 @@ func (s *Service) UpdatePipelineWebhookComment(task *task.Task, logger *zap.Suga
 				}
 				scmTask.TestReports = testReports
 			}
+			if status == config.TaskStatusPass || status == config.TaskStatusFailed {
+				scmTask.Coverages = taskCoverages(task)
+			}
 
 			tasks = append(tasks, scmTask)
 			taskExist = true
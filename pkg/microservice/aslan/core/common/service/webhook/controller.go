@@ -26,7 +26,9 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/bitbucket"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/codehub"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitlab"
 	"github.com/koderover/zadig/pkg/setting"
@@ -131,6 +133,10 @@ func removeWebhook(t *task, logger *zap.Logger) {
 		}
 	case setting.SourceFromCodeHub:
 		cl = codehub.NewClient(t.ak, t.sk, t.region)
+	case setting.SourceFromGitee:
+		cl = gitee.NewClient(t.address, t.token)
+	case setting.SourceFromBitbucket:
+		cl = bitbucket.NewClient(t.address, t.token)
 	default:
 		t.err = fmt.Errorf("invaild source: %s", t.from)
 		t.doneCh <- struct{}{}
@@ -192,6 +198,10 @@ func addWebhook(t *task, logger *zap.Logger) {
 
 	case setting.SourceFromCodeHub:
 		cl = codehub.NewClient(t.ak, t.sk, t.region)
+	case setting.SourceFromGitee:
+		cl = gitee.NewClient(t.address, t.token)
+	case setting.SourceFromBitbucket:
+		cl = bitbucket.NewClient(t.address, t.token)
 	default:
 		t.err = fmt.Errorf("invaild source: %s", t.from)
 		t.doneCh <- struct{}{}
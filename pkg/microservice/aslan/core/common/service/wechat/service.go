@@ -292,6 +292,14 @@ func (w *Service) createNotifyBody(weChatNotification *wechatNotification) (cont
 		tmplSource += fmt.Sprintf("[%s](%s)\n", url, url)
 	}
 
+	rolledBack := base.GetRolledBackDeploys(weChatNotification.Task)
+	if len(rolledBack) != 0 {
+		tmplSource += "- 自动回滚：\n"
+	}
+	for _, d := range rolledBack {
+		tmplSource += fmt.Sprintf("%s/%s 已回滚至 %s\n", d.ServiceName, d.Container, d.Image)
+	}
+
 	if weChatNotification.WebHookType == dingDingType {
 		if len(weChatNotification.AtMobiles) > 0 && !weChatNotification.IsAtAll {
 			tmplSource = fmt.Sprintf("%s - 相关人员：@%s \n", tmplSource, strings.Join(weChatNotification.AtMobiles, "@"))
@@ -22,6 +22,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	cronservice "github.com/koderover/zadig/pkg/microservice/aslan/core/cron/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func CleanJobCronJob(c *gin.Context) {
@@ -38,6 +39,19 @@ func CleanConfigmapCronJob(c *gin.Context) {
 	cronservice.CleanConfigmapCronJob(ctx.Logger)
 }
 
+func PreviewCron(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(cronservice.CronPreviewArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = cronservice.PreviewCron(args, ctx.Logger)
+}
+
 // param type: cronjob的执行内容类型
 // param name: 当type为workflow的时候 代表workflow名称， 当type为test的时候，为test名称
 type DisableCronjobReq struct {
@@ -67,6 +81,7 @@ type cronjobResp struct {
 	Frequency    string                         `json:"frequency"`
 	Time         string                         `json:"time"`
 	Cron         string                         `json:"cron"`
+	TimeZone     string                         `json:"time_zone,omitempty"`
 	ProductName  string                         `json:"product_name,omitempty"`
 	MaxFailure   int                            `json:"max_failures,omitempty"`
 	TaskArgs     *commonmodels.TaskArgs         `json:"task_args,omitempty"`
@@ -91,6 +106,7 @@ func ListActiveCronjobFailsafe(c *gin.Context) {
 			Frequency:    cronjob.Frequency,
 			Time:         cronjob.Time,
 			Cron:         cronjob.Cron,
+			TimeZone:     cronjob.TimeZone,
 			ProductName:  cronjob.ProductName,
 			MaxFailure:   cronjob.MaxFailure,
 			TaskArgs:     cronjob.TaskArgs,
@@ -119,6 +135,7 @@ func ListActiveCronjob(c *gin.Context) {
 			Frequency:    cronjob.Frequency,
 			Time:         cronjob.Time,
 			Cron:         cronjob.Cron,
+			TimeZone:     cronjob.TimeZone,
 			ProductName:  cronjob.ProductName,
 			MaxFailure:   cronjob.MaxFailure,
 			TaskArgs:     cronjob.TaskArgs,
@@ -150,6 +167,7 @@ func ListCronjob(c *gin.Context) {
 			Frequency:    cronjob.Frequency,
 			Time:         cronjob.Time,
 			Cron:         cronjob.Cron,
+			TimeZone:     cronjob.TimeZone,
 			ProductName:  cronjob.ProductName,
 			MaxFailure:   cronjob.MaxFailure,
 			TaskArgs:     cronjob.TaskArgs,
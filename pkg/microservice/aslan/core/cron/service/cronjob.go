@@ -42,6 +42,7 @@ func DisableCronjob(name, ptype string, log *zap.SugaredLogger) error {
 			Frequency:    job.Frequency,
 			Time:         job.Time,
 			Cron:         job.Cron,
+			TimeZone:     job.TimeZone,
 			MaxFailure:   job.MaxFailure,
 			TaskArgs:     job.TaskArgs,
 			WorkflowArgs: job.WorkflowArgs,
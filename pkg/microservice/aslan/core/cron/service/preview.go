@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/tool/cron"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 50
+)
+
+type CronPreviewArgs struct {
+	Cron     string `json:"cron"`
+	TimeZone string `json:"time_zone"`
+	Count    int    `json:"count"`
+}
+
+type CronPreview struct {
+	Cron     string      `json:"cron"`
+	TimeZone string      `json:"time_zone"`
+	Next     []time.Time `json:"next"`
+}
+
+// PreviewCron 校验 cron 表达式，并返回接下来的若干次触发时间
+func PreviewCron(args *CronPreviewArgs, log *zap.SugaredLogger) (*CronPreview, error) {
+	schedule, err := cron.Parse(args.Cron, args.TimeZone)
+	if err != nil {
+		log.Warnf("invalid cron %q in time zone %q: %v", args.Cron, args.TimeZone, err)
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	count := args.Count
+	if count <= 0 {
+		count = defaultPreviewCount
+	}
+	if count > maxPreviewCount {
+		count = maxPreviewCount
+	}
+
+	return &CronPreview{
+		Cron:     schedule.Spec(),
+		TimeZone: schedule.Location().String(),
+		Next:     schedule.NextN(time.Now(), count),
+	}, nil
+}
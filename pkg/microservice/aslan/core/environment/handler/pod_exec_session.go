@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListPodExecSessions(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.ListPodExecSessionsArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	resp, count, err := service.ListPodExecSessions(args, ctx.Logger)
+	ctx.Resp = resp
+	ctx.Err = err
+	c.Writer.Header().Set("X-Total", strconv.Itoa(count))
+}
+
+// CreatePodExecSession podexec 服务在终端会话开始前上报会话信息，失败时不允许进入终端
+func CreatePodExecSession(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.PodExecSession)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid pod exec session args")
+		return
+	}
+	ctx.Resp, ctx.Err = service.CreatePodExecSession(args, ctx.Logger)
+}
+
+// UploadPodExecSessionRecording 请求体为 asciinema v2 格式的录像，会话的结束时间等信息通过查询参数传递
+func UploadPodExecSessionRecording(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.UploadPodExecSessionRecordingArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Err = service.UploadPodExecSessionRecording(c.Param("id"), args, c.Request.Body, ctx.Logger)
+}
+
+func GetPodExecSessionRecording(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+
+	content, err := service.GetPodExecSessionRecording(c.Param("id"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.cast"`, c.Param("id")))
+	c.Data(http.StatusOK, "application/x-asciicast", content)
+}
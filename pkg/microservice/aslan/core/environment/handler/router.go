@@ -130,6 +130,12 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.GET("/:productName/services/:serviceName/containers/:container/namespaces/:namespace", GetServiceContainer)
 
 		environments.GET("/estimated-renderchart", GetEstimatedRenderCharts)
+
+		environments.GET("/:productName/snapshots", ListEnvSnapshots)
+		environments.POST("/:productName/snapshots", gin2.UpdateOperationLogStatus, CreateEnvSnapshot)
+		environments.GET("/:productName/snapshots/:id", GetEnvSnapshot)
+		environments.DELETE("/:productName/snapshots/:id", gin2.UpdateOperationLogStatus, DeleteEnvSnapshot)
+		environments.POST("/:productName/snapshots/:id/restore", gin2.UpdateOperationLogStatus, RestoreEnvSnapshot)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -149,4 +155,15 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	{
 		revision.GET("/products", ListProductsRevision)
 	}
+
+	// ---------------------------------------------------------------------------------------
+	// 终端会话录像接口
+	// ---------------------------------------------------------------------------------------
+	podExec := router.Group("podexec")
+	{
+		podExec.GET("/sessions", ListPodExecSessions)
+		podExec.POST("/sessions", CreatePodExecSession)
+		podExec.GET("/sessions/:id/recording", GetPodExecSessionRecording)
+		podExec.PUT("/sessions/:id/recording", UploadPodExecSessionRecording)
+	}
 }
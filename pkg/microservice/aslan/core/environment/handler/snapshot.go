@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func ListEnvSnapshots(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListEnvSnapshots(c.Param("productName"), c.Query("envName"), ctx.Logger)
+}
+
+func GetEnvSnapshot(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetEnvSnapshot(c.Param("productName"), c.Query("envName"), c.Param("id"), ctx.Logger)
+}
+
+func CreateEnvSnapshot(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Query("envName")
+	productName := c.Param("productName")
+
+	args := new(service.EnvSnapshotArg)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("CreateEnvSnapshot c.GetRawData() err : %v", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("CreateEnvSnapshot json.Unmarshal err : %v", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, productName, "新增", "集成环境-快照", envName, string(data), ctx.Logger)
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.CreateEnvSnapshot(productName, envName, ctx.UserName, args, ctx.Logger)
+}
+
+func DeleteEnvSnapshot(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Query("envName")
+	productName := c.Param("productName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, productName, "删除", "集成环境-快照", envName+":"+c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.DeleteEnvSnapshot(productName, envName, c.Param("id"), ctx.Logger)
+}
+
+func RestoreEnvSnapshot(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Query("envName")
+	productName := c.Param("productName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, productName, "恢复", "集成环境-快照", envName+":"+c.Param("id"), "", ctx.Logger)
+	// restore product asynchronously
+	ctx.Err = service.RestoreEnvSnapshot(productName, envName, c.Param("id"), ctx.UserName, ctx.RequestID, ctx.Logger)
+}
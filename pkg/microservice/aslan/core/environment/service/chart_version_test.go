@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Testing chart repo versions", func() {
+
+	Describe("test newerChartVersion", func() {
+		versions := []string{"2.0.0-rc.1", "1.2.0", "1.1.0+build-1", "1.0.0"}
+
+		It("should offer the latest stable version", func() {
+			Expect(newerChartVersion("1.0.0", versions)).To(Equal("1.2.0"))
+			Expect(newerChartVersion("1.1.0+build-1", versions)).To(Equal("1.2.0"))
+		})
+
+		It("should offer prerelease versions to prerelease charts", func() {
+			Expect(newerChartVersion("2.0.0-beta.1", []string{"2.0.0-rc.1", "2.0.0-beta.1"})).To(Equal("2.0.0-rc.1"))
+		})
+
+		It("should not offer anything if the chart is up to date", func() {
+			Expect(newerChartVersion("1.2.0", versions)).To(BeEmpty())
+			Expect(newerChartVersion("2.0.0-rc.1", versions)).To(BeEmpty())
+		})
+
+		It("should not offer anything if the version is not published", func() {
+			Expect(newerChartVersion("0.9.0", versions)).To(BeEmpty())
+			Expect(newerChartVersion("1.0.0", nil)).To(BeEmpty())
+		})
+	})
+
+	Describe("test GetChartRepoSource", func() {
+
+		It("should decode the creation detail loaded from database", func() {
+			svc := &commonmodels.Service{
+				Source: setting.SourceFromChartRepo,
+				CreateFrom: map[string]interface{}{
+					"chart_repo_name": "bitnami",
+					"chart_name":      "nginx",
+					"chart_version":   "1.0.0",
+				},
+			}
+			source, err := svc.GetChartRepoSource()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source.ChartRepoName).To(Equal("bitnami"))
+			Expect(source.ChartName).To(Equal("nginx"))
+			Expect(source.ChartVersion).To(Equal("1.0.0"))
+		})
+	})
+})
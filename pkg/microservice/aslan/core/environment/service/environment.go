@@ -55,6 +55,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/shared/secretref"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -599,6 +600,11 @@ func UpdateProductV2(envName, productName, user, requestID string, force bool, k
 		// do nothing
 	}
 
+	// 更新前记录环境快照，便于恢复
+	if err := createAutoSnapshot(exitedProd, user, log); err != nil {
+		return e.ErrUpdateEnv.AddDesc(err.Error())
+	}
+
 	// 设置产品状态为更新中
 	if err := commonrepo.NewProductColl().UpdateStatus(envName, productName, setting.ProductStatusUpdating); err != nil {
 		log.Errorf("[%s][P:%s] Product.UpdateStatus error: %v", envName, productName, err)
@@ -783,6 +789,12 @@ func UpdateHelmProduct(productName, envName, updateType, username, requestID str
 		log.Errorf("[%s][P:%s] GetProductTemplate error: %v", envName, productName, err)
 		return e.ErrUpdateEnv.AddDesc(e.FindProductTmplErrMsg)
 	}
+
+	// 更新前记录环境快照，便于恢复
+	if err := createAutoSnapshot(productResp, username, log); err != nil {
+		return e.ErrUpdateEnv.AddDesc(err.Error())
+	}
+
 	productResp.Services = updateProd.Services
 
 	// 设置产品状态为更新中
@@ -1276,16 +1288,19 @@ func GetHelmChartVersions(productName, envName string, log *zap.SugaredLogger) (
 		return helmVersions, err
 	}
 
+	chartRepoVersions := make(map[string][]string)
 	for _, latestSvc := range latestServices {
+		availableVersion := availableChartVersion(latestSvc, chartRepoVersions, log)
 		if prodService, ok := prodServiceMap[latestSvc.ServiceName]; ok {
 			delete(prodServiceMap, latestSvc.ServiceName)
-			if latestSvc.Revision == prodService.Revision {
+			if latestSvc.Revision == prodService.Revision && availableVersion == "" {
 				continue
 			}
 			helmVersion := &commonmodels.HelmVersions{
 				ServiceName:      latestSvc.ServiceName,
 				LatestVersion:    latestSvc.HelmChart.Version,
 				LatestValuesYaml: latestSvc.HelmChart.ValuesYaml,
+				AvailableVersion: availableVersion,
 			}
 			if chartInfo, ok := chartInfoMap[latestSvc.ServiceName]; ok {
 				helmVersion.CurrentVersion = chartInfo.ChartVersion
@@ -1297,6 +1312,7 @@ func GetHelmChartVersions(productName, envName string, log *zap.SugaredLogger) (
 				ServiceName:      latestSvc.ServiceName,
 				LatestVersion:    latestSvc.HelmChart.Version,
 				LatestValuesYaml: latestSvc.HelmChart.ValuesYaml,
+				AvailableVersion: availableVersion,
 			}
 			helmVersions = append(helmVersions, helmVersion)
 		}
@@ -1317,6 +1333,53 @@ func GetHelmChartVersions(productName, envName string, log *zap.SugaredLogger) (
 	return helmVersions, nil
 }
 
+// availableChartVersion 对于从 chart 仓库创建的服务，返回仓库中比服务当前 chart 更新的版本，没有则返回空
+// chartRepoVersions 缓存同一仓库同一 chart 的版本列表，避免重复请求
+func availableChartVersion(svc *commonmodels.Service, chartRepoVersions map[string][]string, log *zap.SugaredLogger) string {
+	if svc.Source != setting.SourceFromChartRepo || svc.HelmChart == nil {
+		return ""
+	}
+	source, err := svc.GetChartRepoSource()
+	if err != nil {
+		log.Warnf("failed to get chart repo source of service %s: %s", svc.ServiceName, err)
+		return ""
+	}
+
+	key := source.ChartRepoName + "/" + source.ChartName
+	versions, ok := chartRepoVersions[key]
+	if !ok {
+		chartRepo, err := commonservice.NewChartRepoClient(source.ChartRepoName)
+		if err == nil {
+			versions, err = chartRepo.ListChartVersions(source.ChartName)
+		}
+		if err != nil {
+			log.Warnf("failed to list versions of chart %s: %s", key, err)
+		}
+		chartRepoVersions[key] = versions
+	}
+
+	return newerChartVersion(svc.HelmChart.Version, versions)
+}
+
+// newerChartVersion versions 按从新到旧排序，返回排在当前版本之前的最新版本
+// 当前版本不是预发布版本时忽略预发布版本，当前版本已不在仓库中时不提示升级
+func newerChartVersion(current string, versions []string) string {
+	newer := ""
+	for _, version := range versions {
+		if version == current {
+			return newer
+		}
+		if newer == "" && (isPrereleaseVersion(current) || !isPrereleaseVersion(version)) {
+			newer = version
+		}
+	}
+	return ""
+}
+
+func isPrereleaseVersion(version string) bool {
+	return strings.Contains(strings.SplitN(version, "+", 2)[0], "-")
+}
+
 func GetEstimatedRenderCharts(productName, envName, serviceNameListStr string, log *zap.SugaredLogger) ([]*commonservice.RenderChartArg, error) {
 
 	var serviceNameList []string
@@ -1471,8 +1534,16 @@ func upsertService(isUpdate bool, env *commonmodels.Product,
 	envName := env.EnvName
 	namespace := env.Namespace
 
+	// 变量中的 secret:// 引用只在部署时解析，解析后的值不保存到变量集中
+	resolvedRenderSet, err := resolveRenderSecretRefs(renderSet, namespace, kubeClient)
+	if err != nil {
+		log.Errorf("Failed to resolve secret references of service %s, error: %v", service.ServiceName, err)
+		errList = multierror.Append(errList, err)
+		return nil, errList
+	}
+
 	// 获取服务模板
-	parsedYaml, err := renderService(env, renderSet, service)
+	parsedYaml, err := renderService(env, resolvedRenderSet, service)
 
 	if err != nil {
 		log.Errorf("Failed to render service %s, error: %v", service.ServiceName, err)
@@ -1803,6 +1874,31 @@ func renderService(prod *commonmodels.Product, render *commonmodels.RenderSet, s
 	return &parsedYaml, nil
 }
 
+// resolveRenderSecretRefs 返回变量值中的 secret:// 引用被解析后的变量集副本
+func resolveRenderSecretRefs(renderSet *commonmodels.RenderSet, namespace string, kubeClient client.Client) (*commonmodels.RenderSet, error) {
+	if renderSet == nil {
+		return nil, nil
+	}
+
+	resolver := secretref.NewResolver(kubeClient, namespace, renderSet.ProductTmpl)
+	resolved := *renderSet
+	resolved.KVs = make([]*templatemodels.RenderKV, 0, len(renderSet.KVs))
+	for _, kv := range renderSet.KVs {
+		if kv == nil {
+			continue
+		}
+		value, err := resolver.Resolve(kv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve variable %s: %v", kv.Key, err)
+		}
+		resolvedKV := *kv
+		resolvedKV.Value = value
+		resolved.KVs = append(resolved.KVs, &resolvedKV)
+	}
+
+	return &resolved, nil
+}
+
 func replaceContainerImages(tmpl string, ori []*commonmodels.Container, replace []*commonmodels.Container) string {
 
 	replaceMap := make(map[string]string)
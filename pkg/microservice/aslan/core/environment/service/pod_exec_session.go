@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	s3service "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+type ListPodExecSessionsArgs struct {
+	Username    string `form:"username"`
+	ProductName string `form:"projectName"`
+	ClusterID   string `form:"clusterId"`
+	Namespace   string `form:"namespace"`
+	PodName     string `form:"podName"`
+	StartTime   int64  `form:"startTime"`
+	EndTime     int64  `form:"endTime"`
+	PerPage     int    `form:"perPage,default=20"`
+	Page        int    `form:"page,default=1"`
+}
+
+// UploadPodExecSessionRecordingArgs 会话结束时的信息，随录像一起上传
+type UploadPodExecSessionRecordingArgs struct {
+	EndTime   int64 `form:"endTime"`
+	Truncated bool  `form:"truncated"`
+}
+
+// CreatePodExecSession 在会话开始前记录会话信息，分配录像的存储位置并确认对象存储可用，
+// 录像在会话结束后通过 UploadPodExecSessionRecording 上传
+func CreatePodExecSession(session *commonmodels.PodExecSession, log *zap.SugaredLogger) (*commonmodels.PodExecSession, error) {
+	if session.Namespace == "" || session.PodName == "" {
+		return nil, e.ErrInvalidParam.AddDesc("namespace and pod name are required")
+	}
+
+	storage, err := s3service.FindDefaultS3()
+	if err != nil {
+		log.Errorf("Failed to find default s3, err: %s", err)
+		return nil, e.ErrCreatePodExecSession.AddErr(err)
+	}
+	client, err := newS3Client(storage)
+	if err != nil {
+		log.Errorf("Failed to create s3 client, err: %s", err)
+		return nil, e.ErrCreatePodExecSession.AddErr(err)
+	}
+	if err = client.ValidateBucket(storage.Bucket); err != nil {
+		log.Errorf("Failed to validate bucket %s, err: %s", storage.Bucket, err)
+		return nil, e.ErrCreatePodExecSession.AddErr(err)
+	}
+
+	session.ID = primitive.NewObjectID()
+	clusterID := session.ClusterID
+	if clusterID == "" {
+		clusterID = "local"
+	}
+	session.ObjectKey = storage.GetObjectPath(fmt.Sprintf("podexec/%s/%s/%s.cast", clusterID, session.Namespace, session.ID.Hex()))
+	if !storage.ID.IsZero() {
+		session.S3StorageID = storage.ID.Hex()
+	}
+	session.Size, session.EndTime, session.Truncated = 0, 0, false
+	if err = commonrepo.NewPodExecSessionColl().Create(session); err != nil {
+		log.Errorf("Failed to create pod exec session, err: %s", err)
+		return nil, e.ErrCreatePodExecSession.AddErr(err)
+	}
+	return session, nil
+}
+
+// UploadPodExecSessionRecording 把录像以流的方式转存到对象存储，不在内存中缓存完整的录像
+func UploadPodExecSessionRecording(id string, args *UploadPodExecSessionRecordingArgs, recording io.Reader, log *zap.SugaredLogger) error {
+	session, err := commonrepo.NewPodExecSessionColl().Find(id)
+	if err != nil {
+		log.Errorf("Failed to find pod exec session %s, err: %s", id, err)
+		return e.ErrCreatePodExecSession.AddErr(err)
+	}
+
+	storage, err := sessionStorage(session)
+	if err != nil {
+		log.Errorf("Failed to find s3 storage %s, err: %s", session.S3StorageID, err)
+		return e.ErrCreatePodExecSession.AddErr(err)
+	}
+	client, err := newS3Client(storage)
+	if err != nil {
+		log.Errorf("Failed to create s3 client, err: %s", err)
+		return e.ErrCreatePodExecSession.AddErr(err)
+	}
+
+	counter := &countingReader{Reader: recording}
+	if err = client.UploadStream(storage.Bucket, session.ObjectKey, counter); err != nil {
+		log.Errorf("Failed to upload recording to s3, err: %s", err)
+		return e.ErrCreatePodExecSession.AddErr(err)
+	}
+
+	if err = commonrepo.NewPodExecSessionColl().UpdateRecording(id, counter.n, args.EndTime, args.Truncated); err != nil {
+		log.Errorf("Failed to update recording of pod exec session %s, err: %s", id, err)
+		return e.ErrCreatePodExecSession.AddErr(err)
+	}
+	return nil
+}
+
+func ListPodExecSessions(args *ListPodExecSessionsArgs, log *zap.SugaredLogger) ([]*commonmodels.PodExecSession, int, error) {
+	sessions, count, err := commonrepo.NewPodExecSessionColl().List(&commonrepo.PodExecSessionListOption{
+		Username:    args.Username,
+		ProductName: args.ProductName,
+		ClusterID:   args.ClusterID,
+		Namespace:   args.Namespace,
+		PodName:     args.PodName,
+		StartTime:   args.StartTime,
+		EndTime:     args.EndTime,
+		PerPage:     args.PerPage,
+		Page:        args.Page,
+	})
+	if err != nil {
+		log.Errorf("Failed to list pod exec sessions, err: %s", err)
+		return nil, 0, e.ErrListPodExecSessions.AddErr(err)
+	}
+	return sessions, count, nil
+}
+
+// GetPodExecSessionRecording 返回会话的 asciinema 录像，用于回放
+func GetPodExecSessionRecording(id string, log *zap.SugaredLogger) ([]byte, error) {
+	session, err := commonrepo.NewPodExecSessionColl().Find(id)
+	if err != nil {
+		log.Errorf("Failed to find pod exec session %s, err: %s", id, err)
+		return nil, e.ErrGetPodExecSession.AddErr(err)
+	}
+
+	storage, err := sessionStorage(session)
+	if err != nil {
+		log.Errorf("Failed to find s3 storage %s, err: %s", session.S3StorageID, err)
+		return nil, e.ErrGetPodExecSession.AddErr(err)
+	}
+	client, err := newS3Client(storage)
+	if err != nil {
+		log.Errorf("Failed to create s3 client, err: %s", err)
+		return nil, e.ErrGetPodExecSession.AddErr(err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		log.Errorf("Failed to create temp dir, err: %s", err)
+		return nil, e.ErrGetPodExecSession.AddErr(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localPath := filepath.Join(tmpDir, filepath.Base(session.ObjectKey))
+	if err = client.Download(storage.Bucket, session.ObjectKey, localPath); err != nil {
+		log.Errorf("Failed to download recording %s, err: %s", session.ObjectKey, err)
+		return nil, e.ErrGetPodExecSession.AddErr(err)
+	}
+
+	content, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return nil, e.ErrGetPodExecSession.AddErr(err)
+	}
+	return content, nil
+}
+
+// sessionStorage S3StorageID 为空时录像保存在系统内置的对象存储中
+func sessionStorage(session *commonmodels.PodExecSession) (*s3service.S3, error) {
+	if session.S3StorageID == "" {
+		return s3service.FindInternalS3(), nil
+	}
+	s3Storage, err := commonrepo.NewS3StorageColl().Find(session.S3StorageID)
+	if err != nil {
+		return nil, err
+	}
+	return &s3service.S3{S3Storage: s3Storage}, nil
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func newS3Client(storage *s3service.S3) (*s3tool.Client, error) {
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	return s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Insecure, forcedPathStyle)
+}
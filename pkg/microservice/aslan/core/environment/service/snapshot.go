@@ -0,0 +1,377 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	helmclient "github.com/mittwald/go-helm-client"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// autoSnapshotRetention 每个环境保留的自动快照数量
+const autoSnapshotRetention = 20
+
+type EnvSnapshotArg struct {
+	Name string `json:"name"`
+}
+
+func ListEnvSnapshots(productName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvSnapshot, error) {
+	snapshots, err := commonrepo.NewEnvSnapshotColl().List(productName, envName)
+	if err != nil {
+		log.Errorf("[%s][P:%s] EnvSnapshot.List error: %v", envName, productName, err)
+		return nil, e.ErrListEnvSnapshots.AddErr(err)
+	}
+	return snapshots, nil
+}
+
+func GetEnvSnapshot(productName, envName, id string, log *zap.SugaredLogger) (*commonmodels.EnvSnapshot, error) {
+	snapshot, err := commonrepo.NewEnvSnapshotColl().Find(productName, envName, id)
+	if err != nil {
+		log.Errorf("[%s][P:%s] EnvSnapshot.Find %s error: %v", envName, productName, id, err)
+		return nil, e.ErrGetEnvSnapshot.AddErr(err)
+	}
+	return snapshot, nil
+}
+
+func DeleteEnvSnapshot(productName, envName, id string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewEnvSnapshotColl().Delete(productName, envName, id); err != nil {
+		log.Errorf("[%s][P:%s] EnvSnapshot.Delete %s error: %v", envName, productName, id, err)
+		return e.ErrDeleteEnvSnapshot.AddErr(err)
+	}
+	return nil
+}
+
+// CreateEnvSnapshot 手动为环境创建快照
+func CreateEnvSnapshot(productName, envName, user string, args *EnvSnapshotArg, log *zap.SugaredLogger) (*commonmodels.EnvSnapshot, error) {
+	prod, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		log.Errorf("[%s][P:%s] Product.Find error: %v", envName, productName, err)
+		return nil, e.ErrCreateEnvSnapshot.AddDesc(e.EnvNotFoundErrMsg)
+	}
+
+	snapshot := newEnvSnapshot(prod, args.Name, user, false)
+	if err := commonrepo.NewEnvSnapshotColl().Create(snapshot); err != nil {
+		log.Errorf("[%s][P:%s] EnvSnapshot.Create error: %v", envName, productName, err)
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+	return snapshot, nil
+}
+
+// createAutoSnapshot 更新环境前记录环境当前的状态，并清理过期的自动快照
+func createAutoSnapshot(prod *commonmodels.Product, user string, log *zap.SugaredLogger) error {
+	snapshot := newEnvSnapshot(prod, "", user, true)
+	if err := commonrepo.NewEnvSnapshotColl().Create(snapshot); err != nil {
+		log.Errorf("[%s][P:%s] EnvSnapshot.Create error: %v", prod.EnvName, prod.ProductName, err)
+		return err
+	}
+
+	if err := commonrepo.NewEnvSnapshotColl().PruneAuto(prod.ProductName, prod.EnvName, autoSnapshotRetention); err != nil {
+		log.Warnf("[%s][P:%s] EnvSnapshot.PruneAuto error: %v", prod.EnvName, prod.ProductName, err)
+	}
+	return nil
+}
+
+func newEnvSnapshot(prod *commonmodels.Product, name, user string, auto bool) *commonmodels.EnvSnapshot {
+	now := time.Now()
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", prod.EnvName, now.Format("20060102150405"))
+		if auto {
+			name = "auto-" + name
+		}
+	}
+
+	services := make([][]*commonmodels.ProductService, 0, len(prod.Services))
+	for _, group := range prod.Services {
+		svcs := make([]*commonmodels.ProductService, 0, len(group))
+		for _, svc := range group {
+			svcCopy := *svc
+			svcCopy.Containers = make([]*commonmodels.Container, 0, len(svc.Containers))
+			for _, c := range svc.Containers {
+				containerCopy := *c
+				svcCopy.Containers = append(svcCopy.Containers, &containerCopy)
+			}
+			svcs = append(svcs, &svcCopy)
+		}
+		services = append(services, svcs)
+	}
+
+	var render *commonmodels.RenderInfo
+	if prod.Render != nil {
+		renderCopy := *prod.Render
+		render = &renderCopy
+	}
+
+	return &commonmodels.EnvSnapshot{
+		Name:        name,
+		ProductName: prod.ProductName,
+		EnvName:     prod.EnvName,
+		Namespace:   prod.Namespace,
+		Source:      prod.Source,
+		Services:    services,
+		Render:      render,
+		Auto:        auto,
+		CreatedBy:   user,
+		CreateTime:  now.Unix(),
+	}
+}
+
+// RestoreEnvSnapshot 按快照中的服务版本、镜像和渲染配置集版本重新部署环境
+func RestoreEnvSnapshot(productName, envName, id, user, requestID string, log *zap.SugaredLogger) error {
+	snapshot, err := commonrepo.NewEnvSnapshotColl().Find(productName, envName, id)
+	if err != nil {
+		log.Errorf("[%s][P:%s] EnvSnapshot.Find %s error: %v", envName, productName, id, err)
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+
+	prod, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		log.Errorf("[%s][P:%s] Product.Find error: %v", envName, productName, err)
+		return e.ErrRestoreEnvSnapshot.AddDesc(e.EnvNotFoundErrMsg)
+	}
+
+	switch prod.Status {
+	case setting.ProductStatusCreating, setting.ProductStatusUpdating, setting.ProductStatusDeleting:
+		log.Errorf("[%s][P:%s] Product is not in valid status", envName, productName)
+		return e.ErrRestoreEnvSnapshot.AddDesc(e.EnvCantUpdatedMsg)
+	default:
+		// do nothing
+	}
+
+	if snapshot.Source != prod.Source {
+		return e.ErrRestoreEnvSnapshot.AddDesc(fmt.Sprintf("snapshot source %s does not match env source %s", snapshot.Source, prod.Source))
+	}
+
+	renderSet, err := findSnapshotRenderSet(snapshot.Render)
+	if err != nil {
+		log.Errorf("[%s][P:%s] failed to find renderset of snapshot %s: %v", envName, productName, id, err)
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+
+	// 恢复前同样记录一次当前状态，便于撤销本次恢复
+	if err := createAutoSnapshot(prod, user, log); err != nil {
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+
+	if err := commonrepo.NewProductColl().UpdateStatus(envName, productName, setting.ProductStatusUpdating); err != nil {
+		log.Errorf("[%s][P:%s] Product.UpdateStatus error: %v", envName, productName, err)
+		return e.ErrRestoreEnvSnapshot.AddDesc(e.UpdateEnvStatusErrMsg)
+	}
+
+	go func() {
+		var err error
+		if prod.Source == setting.SourceFromHelm {
+			err = restoreHelmProduct(prod, snapshot, renderSet, log)
+		} else {
+			err = restoreK8sProduct(prod, snapshot, renderSet, log)
+		}
+
+		if err != nil {
+			log.Errorf("[%s][P:%s] failed to restore snapshot %s: %v", envName, productName, snapshot.Name, err)
+			title := fmt.Sprintf("恢复 [%s] 的 [%s] 环境到快照 [%s] 失败", productName, envName, snapshot.Name)
+			commonservice.SendErrorMessage(user, title, requestID, err, log)
+
+			if err2 := commonrepo.NewProductColl().UpdateStatus(envName, productName, setting.ProductStatusFailed); err2 != nil {
+				log.Errorf("[%s][P:%s] Product.UpdateStatus error: %v", envName, productName, err2)
+				return
+			}
+			if err2 := commonrepo.NewProductColl().UpdateErrors(envName, productName, err.Error()); err2 != nil {
+				log.Errorf("[%s][P:%s] Product.UpdateErrors error: %v", envName, productName, err2)
+			}
+			return
+		}
+
+		if err = commonrepo.NewProductColl().UpdateStatus(envName, productName, setting.ProductStatusSuccess); err != nil {
+			log.Errorf("[%s][P:%s] Product.UpdateStatus error: %v", envName, productName, err)
+			return
+		}
+		if err = commonrepo.NewProductColl().UpdateErrors(envName, productName, ""); err != nil {
+			log.Errorf("[%s][P:%s] Product.UpdateErrors error: %v", envName, productName, err)
+		}
+	}()
+	return nil
+}
+
+func findSnapshotRenderSet(render *commonmodels.RenderInfo) (*commonmodels.RenderSet, error) {
+	// 兼容没有渲染配置集的历史环境
+	if render == nil || render.Name == "" {
+		return &commonmodels.RenderSet{}, nil
+	}
+	if render.Revision == 0 {
+		return nil, fmt.Errorf("renderset %s has no revision", render.Name)
+	}
+	return commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{Name: render.Name, Revision: render.Revision})
+}
+
+// removedServices 返回环境中存在但快照中不存在的服务
+func removedServices(current, snapshot [][]*commonmodels.ProductService) []*commonmodels.ProductService {
+	kept := make(map[string]bool)
+	for _, group := range snapshot {
+		for _, svc := range group {
+			kept[svc.ServiceName] = true
+		}
+	}
+
+	var resp []*commonmodels.ProductService
+	for _, group := range current {
+		for _, svc := range group {
+			if !kept[svc.ServiceName] {
+				resp = append(resp, svc)
+			}
+		}
+	}
+	return resp
+}
+
+func restoreK8sProduct(prod *commonmodels.Product, snapshot *commonmodels.EnvSnapshot, renderSet *commonmodels.RenderSet, log *zap.SugaredLogger) error {
+	productName, envName, namespace := prod.ProductName, prod.EnvName, prod.Namespace
+
+	kubeClient, err := kube.GetKubeClient(prod.ClusterID)
+	if err != nil {
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+
+	for _, svc := range removedServices(prod.Services, snapshot.Services) {
+		if svc.Type != setting.K8SDeployType {
+			continue
+		}
+		log.Infof("[%s][P:%s][S:%s] delete service which does not exist in snapshot", envName, productName, svc.ServiceName)
+		selector := labels.Set{setting.ProductLabel: productName, setting.ServiceLabel: svc.ServiceName}.AsSelector()
+		if err := commonservice.DeleteResourcesAsync(namespace, selector, kubeClient, log); err != nil {
+			log.Errorf("delete resource of service %s error:%v", svc.ServiceName, err)
+		}
+		clusterSelector := labels.Set{setting.ProductLabel: productName, setting.ServiceLabel: svc.ServiceName, setting.EnvNameLabel: envName}.AsSelector()
+		if err := commonservice.DeleteClusterResourceAsync(clusterSelector, kubeClient, log); err != nil {
+			log.Errorf("delete cluster resource of service %s error:%v", svc.ServiceName, err)
+		}
+	}
+
+	existedServices := prod.GetServiceMap()
+	prod.Services = snapshot.Services
+	prod.Render = snapshot.Render
+
+	// 单个服务更新后 render 版本可能与环境不同，按服务记录的 render 版本渲染
+	renderSets := map[int64]*commonmodels.RenderSet{}
+	if prod.Render != nil {
+		renderSets[prod.Render.Revision] = renderSet
+	}
+
+	for _, group := range snapshot.Services {
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		errList := &multierror.Error{
+			ErrorFormat: func(es []error) string {
+				points := make([]string, len(es))
+				for i, err := range es {
+					points[i] = fmt.Sprintf("%v", err)
+				}
+				return strings.Join(points, "\n")
+			},
+		}
+
+		for _, svc := range group {
+			if svc.Type != setting.K8SDeployType {
+				continue
+			}
+
+			svcRenderSet := renderSet
+			if svc.Render != nil && svc.Render.Name == renderSet.Name {
+				if rs, ok := renderSets[svc.Render.Revision]; ok {
+					svcRenderSet = rs
+				} else if rs, err := findSnapshotRenderSet(svc.Render); err == nil {
+					renderSets[svc.Render.Revision] = rs
+					svcRenderSet = rs
+				} else {
+					log.Warnf("[%s][P:%s][S:%s] renderset revision %d not found, use env renderset: %v", envName, productName, svc.ServiceName, svc.Render.Revision, err)
+				}
+			}
+
+			wg.Add(1)
+			go func(svc *commonmodels.ProductService, rs *commonmodels.RenderSet) {
+				defer wg.Done()
+
+				_, err := upsertService(existedServices[svc.ServiceName] != nil, prod, svc, existedServices[svc.ServiceName], rs, kubeClient, log)
+				if err != nil {
+					lock.Lock()
+					errList = multierror.Append(errList, err)
+					lock.Unlock()
+				}
+			}(svc, svcRenderSet)
+		}
+		wg.Wait()
+
+		if err := errList.ErrorOrNil(); err != nil {
+			return e.ErrRestoreEnvSnapshot.AddDesc(err.Error())
+		}
+	}
+
+	prod.Status = setting.ProductStatusUpdating
+	if err := commonrepo.NewProductColl().Update(prod); err != nil {
+		log.Errorf("[%s][P:%s] Product.Update error: %v", envName, productName, err)
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+	return nil
+}
+
+func restoreHelmProduct(prod *commonmodels.Product, snapshot *commonmodels.EnvSnapshot, renderSet *commonmodels.RenderSet, log *zap.SugaredLogger) error {
+	restConfig, err := kube.GetRESTConfig(prod.ClusterID)
+	if err != nil {
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+
+	helmClient, err := helmtool.NewClientFromRestConf(restConfig, prod.Namespace)
+	if err != nil {
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+
+	for _, svc := range removedServices(prod.Services, snapshot.Services) {
+		releaseName := util.GeneHelmReleaseName(prod.Namespace, svc.ServiceName)
+		log.Infof("ready to uninstall release:%s", releaseName)
+		if err := helmClient.UninstallRelease(&helmclient.ChartSpec{
+			ReleaseName: releaseName,
+			Namespace:   prod.Namespace,
+			Wait:        true,
+		}); err != nil {
+			log.Errorf("helm uninstall release %s err:%v", releaseName, err)
+		}
+	}
+
+	prod.Services = snapshot.Services
+	prod.Render = snapshot.Render
+	prod.ChartInfos = renderSet.ChartInfos
+	prod.Status = setting.ProductStatusUpdating
+
+	if err := updateProductVariable(prod.ProductName, prod.EnvName, prod, renderSet, log); err != nil {
+		return errors.Wrapf(err, "failed to restore helm releases")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+func testSnapshotProduct() *commonmodels.Product {
+	return &commonmodels.Product{
+		ProductName: "test_product",
+		EnvName:     "dev",
+		Namespace:   "test-product-env-dev",
+		Source:      setting.SourceFromZadig,
+		Render:      &commonmodels.RenderInfo{Name: "test-product-env-dev", Revision: 3},
+		Services: [][]*commonmodels.ProductService{
+			{
+				{ServiceName: "a", Type: setting.K8SDeployType, Revision: 2, Containers: []*commonmodels.Container{{Name: "a", Image: "a:v1"}}},
+			},
+			{
+				{ServiceName: "b", Type: setting.K8SDeployType, Revision: 5},
+			},
+		},
+	}
+}
+
+var _ = Describe("Testing env snapshot", func() {
+
+	Describe("test newEnvSnapshot", func() {
+
+		It("should keep service revisions, images and render revision", func() {
+			snapshot := newEnvSnapshot(testSnapshotProduct(), "before-release", "test_user", false)
+			Expect(snapshot.Name).To(Equal("before-release"))
+			Expect(snapshot.Render.Revision).To(Equal(int64(3)))
+			Expect(snapshot.Services).To(HaveLen(2))
+			Expect(snapshot.Services[0][0].Revision).To(Equal(int64(2)))
+			Expect(snapshot.Services[0][0].Containers[0].Image).To(Equal("a:v1"))
+			Expect(snapshot.Auto).To(BeFalse())
+		})
+
+		It("should not change when the env is updated later", func() {
+			prod := testSnapshotProduct()
+			snapshot := newEnvSnapshot(prod, "", "test_user", true)
+
+			prod.Render.Revision = 4
+			prod.Services[0][0].Revision = 3
+			prod.Services[0][0].Containers[0].Image = "a:v2"
+
+			Expect(snapshot.Render.Revision).To(Equal(int64(3)))
+			Expect(snapshot.Services[0][0].Revision).To(Equal(int64(2)))
+			Expect(snapshot.Services[0][0].Containers[0].Image).To(Equal("a:v1"))
+			Expect(strings.HasPrefix(snapshot.Name, "auto-dev-")).To(BeTrue())
+		})
+	})
+
+	Describe("test removedServices", func() {
+
+		It("should return services which are not in snapshot", func() {
+			current := testSnapshotProduct().Services
+			current = append(current, []*commonmodels.ProductService{{ServiceName: "c", Type: setting.K8SDeployType}})
+			snapshot := testSnapshotProduct().Services
+
+			svcs := removedServices(current, snapshot)
+			Expect(svcs).To(HaveLen(1))
+			Expect(svcs[0].ServiceName).To(Equal("c"))
+		})
+	})
+})
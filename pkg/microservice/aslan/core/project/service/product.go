@@ -577,6 +577,20 @@ func ensureProductTmpl(args *template.Product) error {
 		return fmt.Errorf("product name must match %s", config.ServiceNameRegexString)
 	}
 
+	if pe := args.PreviewEnv; pe != nil && pe.Enabled {
+		if pe.BaseEnv == "" || pe.Workflow == "" {
+			return errors.New("base env and workflow are required for preview env")
+		}
+		if pe.RepoOwner == "" || pe.RepoName == "" {
+			return errors.New("repo is required for preview env")
+		}
+		for _, svc := range pe.Services {
+			if svc.ServiceName == "" || len(svc.MatchFolders) == 0 {
+				return errors.New("service name and match folders are required for preview env services")
+			}
+		}
+	}
+
 	serviceNames := sets.NewString()
 	for _, sg := range args.Services {
 		for _, s := range sg {
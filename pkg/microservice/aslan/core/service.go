@@ -198,9 +198,15 @@ func initDatabase() {
 		commonrepo.NewWebHookUserColl(),
 		commonrepo.NewWorkflowColl(),
 		commonrepo.NewWorkflowStatColl(),
+		commonrepo.NewWorkflowTaskApprovalColl(),
+		commonrepo.NewTestCaseResultColl(),
+		commonrepo.NewTestCoverageColl(),
 		commonrepo.NewWorkLoadsStatColl(),
 		commonrepo.NewServicesInExternalEnvColl(),
 		commonrepo.NewExternalLinkColl(),
+		commonrepo.NewEnvSnapshotColl(),
+		commonrepo.NewPreviewEnvColl(),
+		commonrepo.NewPodExecSessionColl(),
 
 		templaterepo.NewChartColl(),
 		templaterepo.NewDockerfileTemplateColl(),
@@ -210,6 +216,7 @@ func initDatabase() {
 
 		systemrepo.NewAnnouncementColl(),
 		systemrepo.NewOperationLogColl(),
+		systemrepo.NewAuditLogColl(),
 	} {
 		wg.Add(1)
 		go func(r indexer) {
@@ -99,6 +99,7 @@ type helmServiceCreationArgs struct {
 	RepoLink         string
 	Source           string
 	HelmTemplateName string
+	ChartRepoName    string
 	ValuePaths       []string
 	ValuesYaml       string
 	Variables        []*Variable
@@ -247,6 +248,8 @@ func CreateOrUpdateHelmService(projectName string, args *HelmServiceCreationArgs
 		return CreateOrUpdateHelmServiceFromGitRepo(projectName, args, logger)
 	case LoadFromChartTemplate:
 		return CreateOrUpdateHelmServiceFromChartTemplate(projectName, args, logger)
+	case LoadFromChartRepo:
+		return CreateOrUpdateHelmServiceFromChartRepo(projectName, args, logger)
 	default:
 		return nil, fmt.Errorf("invalid source")
 	}
@@ -345,6 +348,92 @@ func CreateOrUpdateHelmServiceFromChartTemplate(projectName string, args *HelmSe
 	}, nil
 }
 
+func CreateOrUpdateHelmServiceFromChartRepo(projectName string, args *HelmServiceCreationArgs, logger *zap.SugaredLogger) (*BulkHelmServiceCreationResponse, error) {
+	repoArgs, ok := args.CreateFrom.(*CreateFromChartRepo)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument")
+	}
+	if repoArgs.ChartRepoName == "" || repoArgs.ChartName == "" {
+		return nil, fmt.Errorf("invalid argument, missing chart repo or chart name")
+	}
+
+	serviceName := args.Name
+	if serviceName == "" {
+		serviceName = repoArgs.ChartName
+	}
+
+	// pull chart to service path, the latest version is used if no version is given
+	to := filepath.Join(config.LocalServicePath(projectName, serviceName), serviceName)
+	if err := commonservice.PullChartFromRepo(repoArgs.ChartRepoName, repoArgs.ChartName, repoArgs.ChartVersion, to); err != nil {
+		logger.Errorf("Failed to pull chart %s:%s from repo %s, err: %s", repoArgs.ChartName, repoArgs.ChartVersion, repoArgs.ChartRepoName, err)
+		return nil, e.ErrCreateTemplate.AddErr(err)
+	}
+
+	values := [][]byte{}
+	defaultValues, err := os.ReadFile(filepath.Join(to, setting.ValuesYaml))
+	if err == nil && len(defaultValues) > 0 {
+		values = append(values, defaultValues)
+	}
+	if len(repoArgs.ValuesYAML) > 0 {
+		values = append(values, []byte(repoArgs.ValuesYAML))
+	}
+
+	merged, err := yamlutil.Merge(values)
+	if err != nil {
+		logger.Errorf("Failed to merge values, err: %s", err)
+		return nil, err
+	}
+
+	if err = os.WriteFile(filepath.Join(to, setting.ValuesYaml), merged, 0644); err != nil {
+		logger.Errorf("Failed to write values, err: %s", err)
+		return nil, err
+	}
+
+	fsTree := os.DirFS(config.LocalServicePath(projectName, serviceName))
+	ServiceS3Base := config.ObjectStorageServicePath(projectName, serviceName)
+	if err = fsservice.ArchiveAndUploadFilesToS3(fsTree, serviceName, ServiceS3Base, logger); err != nil {
+		logger.Errorf("Failed to upload files for service %s in project %s, err: %s", serviceName, projectName, err)
+		return nil, err
+	}
+
+	chartName, chartVersion, err := readChartYAML(fsTree, serviceName, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := createOrUpdateHelmService(
+		fsTree,
+		&helmServiceCreationArgs{
+			ChartName:     chartName,
+			ChartVersion:  chartVersion,
+			MergedValues:  string(merged),
+			ServiceName:   serviceName,
+			FilePath:      to,
+			ProductName:   projectName,
+			CreateBy:      args.CreatedBy,
+			Source:        setting.SourceFromChartRepo,
+			ChartRepoName: repoArgs.ChartRepoName,
+			ValuesYaml:    repoArgs.ValuesYAML,
+		},
+		logger,
+	)
+	if err != nil {
+		logger.Errorf("Failed to create service %s in project %s, error: %s", serviceName, projectName, err)
+		return nil, err
+	}
+
+	compareHelmVariable([]*templatemodels.RenderChart{
+		{ServiceName: serviceName,
+			ChartVersion: svc.HelmChart.Version,
+			ValuesYaml:   svc.HelmChart.ValuesYaml,
+		},
+	}, projectName, args.CreatedBy, logger)
+
+	return &BulkHelmServiceCreationResponse{
+		SuccessServices: []string{serviceName},
+	}, nil
+}
+
 func getCodehostType(repoArgs *CreateFromRepo, repoLink string) (string, *systemconfig.CodeHost, error) {
 	if repoLink != "" {
 		return setting.SourceFromPublicRepo, nil, nil
@@ -667,7 +756,9 @@ func geneCreationDetail(args *helmServiceCreationArgs) interface{} {
 	case setting.SourceFromGitlab,
 		setting.SourceFromGithub,
 		setting.SourceFromGerrit,
-		setting.SourceFromCodeHub:
+		setting.SourceFromCodeHub,
+		setting.SourceFromGitee,
+		setting.SourceFromBitbucket:
 		return &models.CreateFromRepo{
 			GitRepoConfig: &templatemodels.GitRepoConfig{
 				CodehostID: args.CodehostID,
@@ -699,6 +790,13 @@ func geneCreationDetail(args *helmServiceCreationArgs) interface{} {
 			ServiceName:  args.ServiceName,
 			Variables:    variables,
 		}
+	case setting.SourceFromChartRepo:
+		return &models.CreateFromChartRepo{
+			ChartRepoName: args.ChartRepoName,
+			ChartName:     args.ChartName,
+			ChartVersion:  args.ChartVersion,
+			ValuesYAML:    args.ValuesYaml,
+		}
 	}
 	return nil
 }
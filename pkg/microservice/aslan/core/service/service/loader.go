@@ -58,7 +58,7 @@ func PreloadServiceFromCodeHost(codehostID int, repoOwner, repoName, repoUUID, b
 		return nil, e.ErrPreloadServiceTemplate.AddDesc(err.Error())
 	}
 	switch ch.Type {
-	case setting.SourceFromGithub, setting.SourceFromGitlab:
+	case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromBitbucket:
 		ret, err = preloadService(ch, repoOwner, repoName, branchName, path, isDir, log)
 	case setting.SourceFromGerrit:
 		ret, err = preloadGerritService(ch, repoName, branchName, remoteName, path, isDir)
@@ -79,7 +79,7 @@ func LoadServiceFromCodeHost(username string, codehostID int, repoOwner, repoNam
 		return e.ErrLoadServiceTemplate.AddDesc(err.Error())
 	}
 	switch ch.Type {
-	case setting.SourceFromGithub, setting.SourceFromGitlab:
+	case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromBitbucket:
 		return loadService(username, ch, repoOwner, repoName, branchName, args, log)
 	case setting.SourceFromGerrit:
 		return loadGerritService(username, ch, repoOwner, repoName, branchName, remoteName, args, log)
@@ -106,6 +106,8 @@ func ValidateServiceUpdate(codehostID int, serviceName, repoOwner, repoName, rep
 		return validateServiceUpdateGerrit(detail, serviceName, repoName, branchName, remoteName, path, isDir)
 	case setting.SourceFromCodeHub:
 		return validateServiceUpdateCodehub(detail, serviceName, repoName, repoUUID, branchName, path, isDir)
+	case setting.SourceFromGitee, setting.SourceFromBitbucket:
+		return validateServiceUpdate(detail, serviceName, repoOwner, repoName, branchName, path, isDir)
 	default:
 		return e.ErrValidateServiceUpdate.AddDesc("Not supported code source")
 	}
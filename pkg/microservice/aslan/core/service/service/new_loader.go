@@ -26,7 +26,9 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	bitbucketservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/bitbucket"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	giteeservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
 	githubservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	gitlabservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitlab"
 	"github.com/koderover/zadig/pkg/setting"
@@ -207,6 +209,43 @@ func loadService(username string, ch *systemconfig.CodeHost, owner, repo, branch
 	return nil
 }
 
+// validateServiceUpdate 确认加载路径对应的服务名与要更新的服务一致
+func validateServiceUpdate(ch *systemconfig.CodeHost, serviceName, owner, repo, branch, path string, isDir bool) error {
+	if !isDir {
+		if !isYaml(path) {
+			return e.ErrValidateServiceUpdate.AddDesc("File is not of type yaml or yml, select again")
+		}
+		if getFileName(path) != serviceName {
+			log.Errorf("The loaded file name [%s] is not the same as the service to be updated: [%s]", path, serviceName)
+			return e.ErrValidateServiceUpdate.AddDesc("文件名称和服务名称不一致")
+		}
+		return nil
+	}
+
+	loader, err := getLoader(ch)
+	if err != nil {
+		return e.ErrValidateServiceUpdate.AddDesc(err.Error())
+	}
+	treeNodes, err := loader.GetTree(owner, repo, path, branch)
+	if err != nil {
+		log.Errorf("Failed to get tree under path %s, err: %s", path, err)
+		return e.ErrValidateServiceUpdate.AddDesc(err.Error())
+	}
+	if !hasYAMLFiles(treeNodes) {
+		return e.ErrValidateServiceUpdate.AddDesc("所选路径中没有yaml，请重新选择")
+	}
+
+	svcName := path
+	if path == "" {
+		svcName = repo
+	}
+	if getFileName(svcName) != serviceName {
+		log.Errorf("The loaded folder name [%s] is not the same as the service to be updated: [%s]", svcName, serviceName)
+		return e.ErrValidateServiceUpdate.AddDesc("文件夹名称和服务名称不一致")
+	}
+	return nil
+}
+
 func getFoldersAndYAMLFiles(treeNodes []*git.TreeNode) ([]*git.TreeNode, []*git.TreeNode) {
 	var folders, files []*git.TreeNode
 	for _, tn := range treeNodes {
@@ -242,6 +281,10 @@ func getLoader(ch *systemconfig.CodeHost) (yamlLoader, error) {
 		return githubservice.NewClient(ch.AccessToken, config.ProxyHTTPSAddr()), nil
 	case setting.SourceFromGitlab:
 		return gitlabservice.NewClient(ch.Address, ch.AccessToken)
+	case setting.SourceFromGitee:
+		return giteeservice.NewClient(ch.Address, ch.AccessToken), nil
+	case setting.SourceFromBitbucket:
+		return bitbucketservice.NewClient(ch.Address, ch.AccessToken), nil
 	default:
 		// should not have happened here
 		log.DPanicf("invalid source: %s", ch.Type)
@@ -249,7 +249,8 @@ func GetServiceOption(args *commonmodels.Service, log *zap.SugaredLogger) (*Serv
 	}
 
 	if args.Source == setting.SourceFromGitlab || args.Source == setting.SourceFromGithub ||
-		args.Source == setting.SourceFromGerrit || args.Source == setting.SourceFromCodeHub {
+		args.Source == setting.SourceFromGerrit || args.Source == setting.SourceFromCodeHub ||
+		args.Source == setting.SourceFromGitee || args.Source == setting.SourceFromBitbucket {
 		serviceOption.Yaml = args.Yaml
 	}
 	return serviceOption, nil
@@ -663,7 +664,8 @@ func CreateServiceTemplate(userName string, args *commonmodels.Service, log *zap
 				}
 			}
 			// 配置来源为Gitlab，对比配置的ChangeLog是否变化
-			if args.Source == setting.SourceFromGitlab || args.Source == setting.SourceFromGithub || args.Source == setting.SourceFromCodeHub {
+			if args.Source == setting.SourceFromGitlab || args.Source == setting.SourceFromGithub || args.Source == setting.SourceFromCodeHub ||
+				args.Source == setting.SourceFromGitee || args.Source == setting.SourceFromBitbucket {
 				if args.Commit != nil && serviceTmpl.Commit != nil && args.Commit.SHA == serviceTmpl.Commit.SHA {
 					log.Infof("%s change log remains the same, quit creation", args.Source)
 					return GetServiceOption(serviceTmpl, log)
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListAuditLogs(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.AuditLogArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.PerPage == 0 {
+		args.PerPage = 50
+	}
+	if args.Page == 0 {
+		args.Page = 1
+	}
+
+	resp, count, err := service.ListAuditLogs(args, ctx.Logger)
+	ctx.Resp = resp
+	ctx.Err = err
+	c.Writer.Header().Set("X-Total", strconv.Itoa(count))
+}
+
+func AddAuditLog(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.AuditLog)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid audit log args")
+		return
+	}
+	ctx.Err = service.InsertAuditLog(args, ctx.Logger)
+}
+
+func ExportAuditLogs(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.AuditLogArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	format := c.DefaultQuery("format", service.AuditLogExportCSV)
+	buf := &bytes.Buffer{}
+	if err := service.ExportAuditLogs(c.Request.Context(), args, format, buf, ctx.Logger); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	contentType := "text/csv"
+	if format == service.AuditLogExportJSON {
+		contentType = "application/json"
+	}
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log-%s.%s"`, time.Now().Format("20060102150405"), format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
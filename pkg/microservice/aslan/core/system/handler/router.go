@@ -186,6 +186,13 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		operation.PUT("/:id", UpdateOperationLog)
 	}
 
+	audit := router.Group("audit")
+	{
+		audit.GET("", ListAuditLogs)
+		audit.POST("", AddAuditLog)
+		audit.GET("/export", ExportAuditLogs)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// system external link
 	// ---------------------------------------------------------------------------------------
@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AuditLog 由中间件自动记录的变更类请求
+type AuditLog struct {
+	ID primitive.ObjectID `bson:"_id,omitempty"               json:"id,omitempty"`
+	// Service 请求所属的服务，如 aslan、user、policy、systemconfig
+	Service     string `bson:"service"                     json:"service"`
+	UserID      string `bson:"user_id"                     json:"user_id"`
+	Username    string `bson:"username"                    json:"username"`
+	ProjectName string `bson:"project_name"                json:"project_name"`
+	Method      string `bson:"method"                      json:"method"`
+	Path        string `bson:"path"                        json:"path"`
+	// Resource 请求匹配的路由，如 /api/workflow/workflow/:name
+	Resource    string `bson:"resource"                    json:"resource"`
+	RequestBody string `bson:"request_body"                json:"request_body"`
+	Status      int    `bson:"status"                      json:"status"`
+	RequestID   string `bson:"request_id"                  json:"request_id"`
+	ClientIP    string `bson:"client_ip"                   json:"client_ip"`
+	// Latency 请求耗时，单位毫秒
+	Latency   int64 `bson:"latency"                     json:"latency"`
+	CreatedAt int64 `bson:"created_at"                  json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type AuditLogArgs struct {
+	Service     string
+	Username    string
+	ProjectName string
+	Method      string
+	Resource    string
+	Status      int
+	StartTime   int64
+	EndTime     int64
+	PerPage     int
+	Page        int
+}
+
+type AuditLogColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewAuditLogColl() *AuditLogColl {
+	name := models.AuditLog{}.TableName()
+	return &AuditLogColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *AuditLogColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *AuditLogColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"created_at": -1},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "username", Value: 1},
+				bson.E{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *AuditLogColl) Insert(args *models.AuditLog) error {
+	if args == nil {
+		return errors.New("nil audit_log args")
+	}
+
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil || res == nil {
+		return err
+	}
+
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = oid
+	}
+
+	return nil
+}
+
+func (c *AuditLogColl) List(args *AuditLogArgs) ([]*models.AuditLog, int, error) {
+	res := make([]*models.AuditLog, 0)
+	query := buildAuditLogQuery(args)
+
+	opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+	if args.Page > 0 && args.PerPage > 0 {
+		opts.SetSkip(int64(args.PerPage * (args.Page - 1))).SetLimit(int64(args.PerPage))
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res, int(count), nil
+}
+
+// Cursor 按创建时间倒序遍历满足条件的审计日志，用于导出大量数据，调用方负责关闭
+func (c *AuditLogColl) Cursor(ctx context.Context, args *AuditLogArgs) (*mongo.Cursor, error) {
+	opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+	return c.Collection.Find(ctx, buildAuditLogQuery(args), opts)
+}
+
+// DeleteBefore 删除指定时间之前的审计日志
+func (c *AuditLogColl) DeleteBefore(createdAt int64) (int64, error) {
+	res, err := c.DeleteMany(context.TODO(), bson.M{"created_at": bson.M{"$lt": createdAt}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteExceeding 只保留最近 maxItems 条审计日志
+func (c *AuditLogColl) DeleteExceeding(maxItems int64) (int64, error) {
+	opts := options.FindOne().SetSort(bson.D{{"created_at", -1}}).SetSkip(maxItems).SetProjection(bson.M{"created_at": 1})
+	last := new(models.AuditLog)
+	err := c.FindOne(context.TODO(), bson.M{}, opts).Decode(last)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := c.DeleteMany(context.TODO(), bson.M{"created_at": bson.M{"$lte": last.CreatedAt}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func buildAuditLogQuery(args *AuditLogArgs) bson.M {
+	query := bson.M{}
+	if args.Service != "" {
+		query["service"] = args.Service
+	}
+	if args.Username != "" {
+		query["username"] = bson.M{"$regex": args.Username}
+	}
+	if args.ProjectName != "" {
+		query["project_name"] = args.ProjectName
+	}
+	if args.Method != "" {
+		query["method"] = args.Method
+	}
+	if args.Resource != "" {
+		query["resource"] = bson.M{"$regex": args.Resource}
+	}
+	if args.Status != 0 {
+		query["status"] = args.Status
+	}
+
+	createdAt := bson.M{}
+	if args.StartTime > 0 {
+		createdAt["$gte"] = args.StartTime
+	}
+	if args.EndTime > 0 {
+		createdAt["$lte"] = args.EndTime
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	return query
+}
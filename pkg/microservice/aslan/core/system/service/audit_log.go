@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	AuditLogExportCSV  = "csv"
+	AuditLogExportJSON = "json"
+)
+
+var auditLogCSVHeader = []string{"created_at", "service", "username", "user_id", "project_name", "method", "path", "resource", "status", "request_id", "client_ip", "latency", "request_body"}
+
+type AuditLogArgs struct {
+	Service     string `form:"service"`
+	Username    string `form:"username"`
+	ProjectName string `form:"projectName"`
+	Method      string `form:"method"`
+	Resource    string `form:"resource"`
+	Status      int    `form:"status"`
+	StartTime   int64  `form:"startTime"`
+	EndTime     int64  `form:"endTime"`
+	PerPage     int    `form:"perPage"`
+	Page        int    `form:"page"`
+}
+
+func (args *AuditLogArgs) toFindOption() *mongodb.AuditLogArgs {
+	return &mongodb.AuditLogArgs{
+		Service:     args.Service,
+		Username:    args.Username,
+		ProjectName: args.ProjectName,
+		Method:      args.Method,
+		Resource:    args.Resource,
+		Status:      args.Status,
+		StartTime:   args.StartTime,
+		EndTime:     args.EndTime,
+		PerPage:     args.PerPage,
+		Page:        args.Page,
+	}
+}
+
+func ListAuditLogs(args *AuditLogArgs, log *zap.SugaredLogger) ([]*models.AuditLog, int, error) {
+	resp, count, err := mongodb.NewAuditLogColl().List(args.toFindOption())
+	if err != nil {
+		log.Errorf("list audit log error: %v", err)
+		return nil, 0, e.ErrListAuditLog.AddErr(err)
+	}
+	return resp, count, nil
+}
+
+func InsertAuditLog(args *models.AuditLog, log *zap.SugaredLogger) error {
+	if args.CreatedAt == 0 {
+		args.CreatedAt = time.Now().Unix()
+	}
+	if err := mongodb.NewAuditLogColl().Insert(args); err != nil {
+		log.Errorf("insert audit log error: %v", err)
+		return e.ErrCreateAuditLog.AddErr(err)
+	}
+	return nil
+}
+
+// ExportAuditLogs 把满足条件的审计日志以 csv 或 json 格式写入 w，忽略分页参数
+func ExportAuditLogs(ctx context.Context, args *AuditLogArgs, format string, w io.Writer, log *zap.SugaredLogger) error {
+	opt := args.toFindOption()
+	opt.Page, opt.PerPage = 0, 0
+
+	cursor, err := mongodb.NewAuditLogColl().Cursor(ctx, opt)
+	if err != nil {
+		log.Errorf("export audit log error: %v", err)
+		return e.ErrExportAuditLog.AddErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var exporter auditLogExporter
+	switch format {
+	case AuditLogExportCSV:
+		exporter = &auditLogCSVExporter{w: csv.NewWriter(w)}
+	case AuditLogExportJSON:
+		exporter = &auditLogJSONExporter{w: w}
+	default:
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported export format %q", format))
+	}
+
+	if err = exporter.begin(); err != nil {
+		return e.ErrExportAuditLog.AddErr(err)
+	}
+	for cursor.Next(ctx) {
+		auditLog := new(models.AuditLog)
+		if err = cursor.Decode(auditLog); err != nil {
+			log.Errorf("decode audit log error: %v", err)
+			return e.ErrExportAuditLog.AddErr(err)
+		}
+		if err = exporter.write(auditLog); err != nil {
+			return e.ErrExportAuditLog.AddErr(err)
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		log.Errorf("iterate audit log error: %v", err)
+		return e.ErrExportAuditLog.AddErr(err)
+	}
+	if err = exporter.end(); err != nil {
+		return e.ErrExportAuditLog.AddErr(err)
+	}
+	return nil
+}
+
+type auditLogExporter interface {
+	begin() error
+	write(auditLog *models.AuditLog) error
+	end() error
+}
+
+type auditLogCSVExporter struct {
+	w *csv.Writer
+}
+
+func (c *auditLogCSVExporter) begin() error {
+	return c.w.Write(auditLogCSVHeader)
+}
+
+func (c *auditLogCSVExporter) write(l *models.AuditLog) error {
+	return c.w.Write([]string{
+		time.Unix(l.CreatedAt, 0).Format(time.RFC3339),
+		l.Service,
+		l.Username,
+		l.UserID,
+		l.ProjectName,
+		l.Method,
+		l.Path,
+		l.Resource,
+		strconv.Itoa(l.Status),
+		l.RequestID,
+		l.ClientIP,
+		strconv.FormatInt(l.Latency, 10),
+		l.RequestBody,
+	})
+}
+
+func (c *auditLogCSVExporter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// auditLogJSONExporter 逐条写入 json 数组，避免把所有日志加载到内存
+type auditLogJSONExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *auditLogJSONExporter) begin() error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *auditLogJSONExporter) write(l *models.AuditLog) error {
+	content, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		if _, err = io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.count++
+	_, err = j.w.Write(content)
+	return err
+}
+
+func (j *auditLogJSONExporter) end() error {
+	_, err := io.WriteString(j.w, "]")
+	return err
+}
+
+func handleAuditLogRetention(strategy *commonmodels.CapacityStrategy, dryRun bool) error {
+	retention := strategy.Retention
+	coll := mongodb.NewAuditLogColl()
+
+	var (
+		count int64
+		err   error
+	)
+	switch {
+	case dryRun:
+		log.Infof("audit logs will be cleaned up, max days: %d, max items: %d", retention.MaxDays, retention.MaxItems)
+		return nil
+	case retention.MaxDays > 0:
+		count, err = coll.DeleteBefore(time.Now().AddDate(0, 0, -retention.MaxDays).Unix())
+	case retention.MaxItems > 0:
+		count, err = coll.DeleteExceeding(int64(retention.MaxItems))
+	default:
+		return errors.New("no valid strategy for audit log retention")
+	}
+	if err != nil {
+		log.Errorf("failed to clean up audit logs, err: %v", err)
+		return err
+	}
+
+	log.Infof("%d audit logs are cleaned up", count)
+	return nil
+}
@@ -34,6 +34,7 @@ import (
 
 const (
 	defaultWorkflowMaxDays int = 365
+	defaultAuditLogMaxDays int = 180
 	//logTag                     = "SysCap"
 )
 
@@ -44,6 +45,13 @@ var defaultWorkflowTaskRetention = &commonmodels.CapacityStrategy{
 	},
 }
 
+var defaultAuditLogRetention = &commonmodels.CapacityStrategy{
+	Target: commonmodels.AuditLogRetention,
+	Retention: &commonmodels.RetentionConfig{
+		MaxDays: defaultAuditLogMaxDays,
+	},
+}
+
 func UpdateSysCapStrategy(strategy *commonmodels.CapacityStrategy) error {
 	if err := validateStrategy(strategy); err != nil {
 		return err
@@ -55,6 +63,10 @@ func UpdateSysCapStrategy(strategy *commonmodels.CapacityStrategy) error {
 	}
 
 	// 更新成功后，立即按照新的配置清理数据
+	if strategy.Target == commonmodels.AuditLogRetention {
+		go handleAuditLogRetention(strategy, false)
+		return nil
+	}
 	go handleWorkflowTaskRetentionCenter(strategy, false)
 
 	return nil
@@ -65,10 +77,24 @@ func GetCapacityStrategy(target commonmodels.CapacityTarget) (*commonmodels.Capa
 	if err != nil && target == commonmodels.WorkflowTaskRetention {
 		return defaultWorkflowTaskRetention, nil // Return default setup
 	}
+	if err != nil && target == commonmodels.AuditLogRetention {
+		return defaultAuditLogRetention, nil
+	}
 	return result, err
 }
 
 func HandleSystemGC(dryRun bool) error {
+	// 审计日志的清理与工作流任务相互独立
+	auditStrategy, err := commonrepo.NewStrategyColl().GetByTarget(commonmodels.AuditLogRetention)
+	if err != nil {
+		auditStrategy = defaultAuditLogRetention
+	}
+	if err = validateStrategy(auditStrategy); err != nil {
+		log.Errorf("invalid audit log retention strategy: %v", err)
+	} else if err = handleAuditLogRetention(auditStrategy, dryRun); err != nil {
+		log.Errorf("failed to handle audit log retention: %v", err)
+	}
+
 	// Find the strategy
 	strategy, err := commonrepo.NewStrategyColl().GetByTarget(commonmodels.WorkflowTaskRetention)
 	if err != nil {
@@ -325,10 +351,10 @@ func cleanStaleTasks(tasks []*task.Task, s3Server *s3.S3, dryRun bool) []string
 }
 
 func validateStrategy(strategy *commonmodels.CapacityStrategy) error {
-	if strategy.Target == commonmodels.WorkflowTaskRetention {
+	if strategy.Target == commonmodels.WorkflowTaskRetention || strategy.Target == commonmodels.AuditLogRetention {
 		retention := strategy.Retention
 		if retention == nil {
-			return errors.New("SysCap strategy: nil retention config for WorkflowTaskRetention")
+			return fmt.Errorf("SysCap strategy: nil retention config for %s", strategy.Target)
 		}
 		if !(retention.MaxDays > 0 && retention.MaxItems == 0) &&
 			!(retention.MaxDays == 0 && retention.MaxItems > 0) {
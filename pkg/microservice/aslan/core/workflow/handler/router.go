@@ -127,6 +127,17 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		workflowtask.GET("/id/:id/pipelines/:name", GetWorkflowTask)
 		workflowtask.POST("/id/:id/pipelines/:name/restart", GetWorkflowTaskProductNameByTask, gin2.UpdateOperationLogStatus, RestartWorkflowTask)
 		workflowtask.DELETE("/id/:id/pipelines/:name", GetWorkflowTaskProductNameByTask, gin2.UpdateOperationLogStatus, CancelWorkflowTaskV2)
+		workflowtask.GET("/id/:id/pipelines/:name/approval", GetWorkflowTaskApproval)
+		workflowtask.POST("/id/:id/pipelines/:name/approval", GetWorkflowTaskProductNameByTask, gin2.UpdateOperationLogStatus, ApproveWorkflowTask)
+		workflowtask.GET("/id/:id/pipelines/:name/security", GetWorkflowTaskSecurityReport)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// 定时任务管理接口
+	// ---------------------------------------------------------------------------------------
+	cron := router.Group("cron")
+	{
+		cron.GET("/approvaltimeout", TimeoutWaitForApproveTasks)
 	}
 
 	serviceTask := router.Group("servicetask")
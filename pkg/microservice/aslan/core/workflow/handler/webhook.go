@@ -27,7 +27,9 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/webhook"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/pkg/tool/codehub"
+	"github.com/koderover/zadig/pkg/tool/gitee"
 )
 
 // @Router /workflow/webhook [POST]
@@ -50,6 +52,10 @@ func ProcessWebHook(c *gin.Context) {
 		ctx.Err = webhook.ProcessGitlabHook(payload, c.Request, ctx.RequestID, ctx.Logger)
 	} else if codehub.HookEventType(c.Request) != "" {
 		ctx.Err = webhook.ProcessCodehubHook(payload, c.Request, ctx.RequestID, ctx.Logger)
+	} else if gitee.HookEventType(c.Request) != "" {
+		ctx.Err = webhook.ProcessGiteeHook(payload, c.Request, ctx.RequestID, ctx.Logger)
+	} else if bitbucket.HookEventType(c.Request) != "" {
+		ctx.Err = webhook.ProcessBitbucketHook(payload, c.Request, ctx.RequestID, ctx.Logger)
 	} else {
 		ctx.Err = webhook.ProcessGerritHook(payload, c.Request, ctx.RequestID, ctx.Logger)
 	}
@@ -211,3 +211,57 @@ func CancelWorkflowTaskV2(c *gin.Context) {
 	}
 	ctx.Err = commonservice.CancelTaskV2(ctx.UserName, c.Param("name"), taskID, config.WorkflowType, ctx.RequestID, ctx.Logger)
 }
+
+func ApproveWorkflowTask(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.GetString("productName"), "审批", "工作流-task", c.Param("name"), "", ctx.Logger)
+
+	taskID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	args := new(workflow.ApprovalArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	ctx.Err = workflow.ApproveWorkflowTask(ctx.UserID, ctx.UserName, c.Param("name"), taskID, args, ctx.Logger)
+}
+
+func GetWorkflowTaskApproval(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowTaskApproval(c.Param("name"), taskID, ctx.Logger)
+}
+
+// TimeoutWaitForApproveTasks 由 cron 服务定时调用
+func TimeoutWaitForApproveTasks(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	workflow.TimeoutWaitForApproveTasks(ctx.Logger)
+}
+
+func GetWorkflowTaskSecurityReport(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowTaskSecurityReport(c.Param("name"), taskID, c.Query("severity"), ctx.Logger)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
+)
+
+func ProcessBitbucketHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	if err := bitbucket.ValidateSignature(req, payload, gitservice.GetHookSecret()); err != nil {
+		return err
+	}
+
+	eventType := bitbucket.HookEventType(req)
+	event, err := bitbucket.ParseHook(eventType, payload)
+	if err != nil {
+		log.Warnf("unexpected event type: %s", eventType)
+		return nil
+	}
+
+	forwardedProto := req.Header.Get("X-Forwarded-Proto")
+	forwardedHost := req.Header.Get("X-Forwarded-Host")
+	baseURI := fmt.Sprintf("%s://%s", forwardedProto, forwardedHost)
+
+	// 一次推送可能包含多个分支的变更，拆分为单个分支的事件分别触发
+	var events []interface{}
+	switch ev := event.(type) {
+	case *bitbucket.RefsChangedEvent:
+		if ev.Actor != nil {
+			webhookUser := &commonmodels.WebHookUser{
+				Domain:    req.Header.Get("X-Forwarded-Host"),
+				UserName:  ev.Actor.Name,
+				Email:     ev.Actor.EmailAddress,
+				Source:    setting.SourceFromBitbucket,
+				CreatedAt: time.Now().Unix(),
+			}
+			commonrepo.NewWebHookUserColl().Upsert(webhookUser)
+		}
+
+		for _, change := range ev.Changes {
+			if change.Ref == nil || change.Ref.Type != bitbucket.RefTypeBranch || change.Type == bitbucket.ChangeTypeDelete {
+				continue
+			}
+			events = append(events, &bitbucket.RefsChangedEvent{
+				EventKey:   ev.EventKey,
+				Date:       ev.Date,
+				Actor:      ev.Actor,
+				Repository: ev.Repository,
+				Changes:    []*bitbucket.RefChange{change},
+			})
+		}
+	default:
+		events = append(events, event)
+	}
+
+	var errorList = &multierror.Error{}
+	for _, ev := range events {
+		//产品工作流webhook
+		if err = TriggerWorkflowByBitbucketEvent(ev, baseURI, requestID, log); err != nil {
+			errorList = multierror.Append(errorList, err)
+		}
+
+		//测试管理webhook
+		if err = TriggerTestByBitbucketEvent(ev, baseURI, requestID, log); err != nil {
+			errorList = multierror.Append(errorList, err)
+		}
+	}
+
+	return errorList.ErrorOrNil()
+}
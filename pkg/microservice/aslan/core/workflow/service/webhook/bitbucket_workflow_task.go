@@ -0,0 +1,313 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// matchBitbucketBranch 判断分支是否命中触发器，命中时把触发器的分支设置为该分支
+func matchBitbucketBranch(hookRepo *commonmodels.MainHookRepo, repo *bitbucket.Repository, branch string, event config.HookEventType) bool {
+	// project key 不区分大小写
+	if repo == nil || !strings.EqualFold(hookRepo.RepoOwner+"/"+hookRepo.RepoName, repo.FullName()) {
+		return false
+	}
+	if !EventConfigured(hookRepo, event) {
+		return false
+	}
+
+	if !hookRepo.IsRegular && hookRepo.Branch != branch {
+		return false
+	}
+	if hookRepo.IsRegular {
+		if matched, _ := regexp.MatchString(hookRepo.Branch, branch); !matched {
+			return false
+		}
+	}
+	hookRepo.Branch = branch
+
+	return true
+}
+
+func matchBitbucketRefsChangedEvent(hookRepo *commonmodels.MainHookRepo, ev *bitbucket.RefsChangedEvent) (bool, error) {
+	if len(ev.Changes) == 0 {
+		return false, nil
+	}
+	change := ev.Changes[0]
+	if !matchBitbucketBranch(hookRepo, ev.Repository, change.Ref.DisplayID, config.HookEventPush) {
+		return false, nil
+	}
+
+	client, err := getBitbucketClient(hookRepo.CodehostID)
+	if err != nil {
+		return false, err
+	}
+
+	// 新建分支时只比较最新的提交
+	to := change.FromHash
+	if change.IsCreate() {
+		to = ""
+	}
+	changedFiles, err := client.ListChangedFiles(ev.Repository.Project.Key, ev.Repository.Slug, change.ToHash, to)
+	if err != nil {
+		return false, err
+	}
+
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func matchBitbucketPullRequestEvent(hookRepo *commonmodels.MainHookRepo, ev *bitbucket.PullRequestEvent) (bool, error) {
+	pr := ev.PullRequest
+	if pr == nil || pr.ToRef == nil || pr.FromRef == nil {
+		return false, nil
+	}
+	if !matchBitbucketBranch(hookRepo, pr.ToRef.Repository, pr.ToRef.DisplayID, config.HookEventPr) {
+		return false, nil
+	}
+
+	client, err := getBitbucketClient(hookRepo.CodehostID)
+	if err != nil {
+		return false, err
+	}
+	changedFiles, err := client.ListPullRequestFiles(pr.ToRef.Repository.Project.Key, pr.ToRef.Repository.Slug, pr.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func getBitbucketClient(codehostID int) (*bitbucket.Client, error) {
+	detail, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find codehost %d: %v", codehostID, err)
+	}
+
+	return bitbucket.NewClient(detail.Address, detail.AccessToken), nil
+}
+
+type bitbucketPullRequestEventMatcher struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.Workflow
+	event    *bitbucket.PullRequestEvent
+}
+
+func (bpem *bitbucketPullRequestEventMatcher) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	matched, err := matchBitbucketPullRequestEvent(hookRepo, bpem.event)
+	if err != nil {
+		bpem.log.Warnf("failed to get changes of event %v, err:%s", bpem.event, err)
+	}
+	return matched, err
+}
+
+func (bpem *bitbucketPullRequestEventMatcher) UpdateTaskArgs(
+	product *commonmodels.Product, args *commonmodels.WorkflowTaskArgs, hookRepo *commonmodels.MainHookRepo, requestID string,
+) *commonmodels.WorkflowTaskArgs {
+	factory := &workflowArgsFactory{
+		workflow: bpem.workflow,
+		reqID:    requestID,
+	}
+
+	args = factory.Update(product, args, &types.Repository{
+		CodehostID: hookRepo.CodehostID,
+		RepoName:   hookRepo.RepoName,
+		RepoOwner:  hookRepo.RepoOwner,
+		Branch:     hookRepo.Branch,
+		PR:         bpem.event.PullRequest.ID,
+	})
+
+	return args
+}
+
+type bitbucketRefsChangedEventMatcher struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.Workflow
+	event    *bitbucket.RefsChangedEvent
+}
+
+func (brem *bitbucketRefsChangedEventMatcher) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	matched, err := matchBitbucketRefsChangedEvent(hookRepo, brem.event)
+	if err != nil {
+		brem.log.Errorf("Failed to get push event diffs, error: %s", err)
+	}
+	return matched, err
+}
+
+func (brem *bitbucketRefsChangedEventMatcher) UpdateTaskArgs(
+	product *commonmodels.Product, args *commonmodels.WorkflowTaskArgs, hookRepo *commonmodels.MainHookRepo, requestID string,
+) *commonmodels.WorkflowTaskArgs {
+	factory := &workflowArgsFactory{
+		workflow: brem.workflow,
+		reqID:    requestID,
+	}
+
+	factory.Update(product, args, &types.Repository{
+		CodehostID: hookRepo.CodehostID,
+		RepoName:   hookRepo.RepoName,
+		RepoOwner:  hookRepo.RepoOwner,
+		Branch:     hookRepo.Branch,
+	})
+
+	return args
+}
+
+func createBitbucketEventMatcher(
+	event interface{}, workflow *commonmodels.Workflow, log *zap.SugaredLogger,
+) gitEventMatcher {
+	switch evt := event.(type) {
+	case *bitbucket.RefsChangedEvent:
+		return &bitbucketRefsChangedEventMatcher{
+			workflow: workflow,
+			log:      log,
+			event:    evt,
+		}
+	case *bitbucket.PullRequestEvent:
+		return &bitbucketPullRequestEventMatcher{
+			log:      log,
+			event:    evt,
+			workflow: workflow,
+		}
+	}
+
+	return nil
+}
+
+func TriggerWorkflowByBitbucketEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	// 1. find configured workflow
+	workflowList, err := commonrepo.NewWorkflowColl().List(&commonrepo.ListWorkflowOption{})
+	if err != nil {
+		log.Errorf("failed to list workflow %v", err)
+		return err
+	}
+
+	mErr := &multierror.Error{}
+	var notification *commonmodels.Notification
+
+	for _, workflow := range workflowList {
+		if workflow.HookCtl == nil || !workflow.HookCtl.Enabled {
+			continue
+		}
+
+		log.Debugf("find %d hooks in workflow %s", len(workflow.HookCtl.Items), workflow.Name)
+		for _, item := range workflow.HookCtl.Items {
+			if item.WorkflowArgs == nil {
+				continue
+			}
+
+			// 2. match webhook
+			matcher := createBitbucketEventMatcher(event, workflow, log)
+			if matcher == nil {
+				continue
+			}
+
+			matches, err := matcher.Match(item.MainRepo)
+			if err != nil {
+				mErr = multierror.Append(mErr, err)
+				continue
+			}
+
+			if !matches {
+				log.Debugf("event not matches %v", item.MainRepo)
+				continue
+			}
+
+			log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
+			namespace := strings.Split(item.WorkflowArgs.Namespace, ",")[0]
+			opt := &commonrepo.ProductFindOptions{Name: workflow.ProductTmplName, EnvName: namespace}
+			var prod *commonmodels.Product
+			if prod, err = commonrepo.NewProductColl().Find(opt); err != nil {
+				log.Warnf("can't find environment %s-%s", item.WorkflowArgs.Namespace, workflow.ProductTmplName)
+				continue
+			}
+
+			prID := 0
+			var mergeRequestID, commitID string
+			if ev, isPr := event.(*bitbucket.PullRequestEvent); isPr {
+				prID = ev.PullRequest.ID
+				// 如果是merge request，且该webhook触发器配置了自动取消，
+				// 则需要确认该merge request在本次commit之前的commit触发的任务是否处理完，没有处理完则取消掉。
+				mergeRequestID = strconv.Itoa(ev.PullRequest.ID)
+				commitID = ev.PullRequest.FromRef.LatestCommit
+				autoCancelOpt := &AutoCancelOpt{
+					MergeRequestID: mergeRequestID,
+					CommitID:       commitID,
+					TaskType:       config.WorkflowType,
+					MainRepo:       item.MainRepo,
+					WorkflowArgs:   item.WorkflowArgs,
+				}
+				err := AutoCancelTask(autoCancelOpt, log)
+				if err != nil {
+					log.Errorf("failed to auto cancel workflow task when receive event %v due to %v ", event, err)
+					mErr = multierror.Append(mErr, err)
+				}
+
+				if notification == nil {
+					item.MainRepo.Revision = commitID
+					notification, _ = scmnotify.NewService().SendInitWebhookComment(
+						item.MainRepo, prID, baseURI, false, false, log,
+					)
+				}
+			}
+
+			if notification != nil {
+				item.WorkflowArgs.NotificationID = notification.ID.Hex()
+			}
+
+			args := matcher.UpdateTaskArgs(prod, item.WorkflowArgs, item.MainRepo, requestID)
+			args.MergeRequestID = mergeRequestID
+			args.CommitID = commitID
+			args.Source = setting.SourceFromBitbucket
+			args.CodehostID = item.MainRepo.CodehostID
+			args.RepoOwner = item.MainRepo.RepoOwner
+			args.RepoName = item.MainRepo.RepoName
+			// 3. create task with args
+			if resp, err := workflowservice.CreateWorkflowTask(args, setting.WebhookTaskCreator, log); err != nil {
+				log.Errorf("failed to create workflow task when receive event %v due to %v ", event, err)
+				mErr = multierror.Append(mErr, err)
+				if prID != 0 {
+					// 单独创建一条通知，展示任务创建失败的错误信息
+					_, err2 := scmnotify.NewService().SendErrWebhookComment(
+						item.MainRepo, workflow, err, prID, baseURI, false, false, log,
+					)
+					if err2 != nil {
+						log.Errorf("SendErrWebhookComment failed, product:%s, workflow:%s, err:%v", workflow.ProductTmplName, workflow.Name, err2)
+					}
+				}
+			} else {
+				log.Infof("succeed to create task %v", resp)
+			}
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+)
+
+func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	if err := gitee.ValidateToken(req, gitservice.GetHookSecret()); err != nil {
+		return err
+	}
+
+	eventType := gitee.HookEventType(req)
+	event, err := gitee.ParseHook(eventType, payload)
+	if err != nil {
+		log.Warnf("unexpected event type: %s", eventType)
+		return nil
+	}
+
+	forwardedProto := req.Header.Get("X-Forwarded-Proto")
+	forwardedHost := req.Header.Get("X-Forwarded-Host")
+	baseURI := fmt.Sprintf("%s://%s", forwardedProto, forwardedHost)
+
+	var errorList = &multierror.Error{}
+	if pushEvent, ok := event.(*gitee.PushEvent); ok && len(pushEvent.Commits) > 0 && pushEvent.Commits[0].Author != nil {
+		webhookUser := &commonmodels.WebHookUser{
+			Domain:    req.Header.Get("X-Forwarded-Host"),
+			UserName:  pushEvent.Commits[0].Author.Name,
+			Email:     pushEvent.Commits[0].Author.Email,
+			Source:    setting.SourceFromGitee,
+			CreatedAt: time.Now().Unix(),
+		}
+		commonrepo.NewWebHookUserColl().Upsert(webhookUser)
+	}
+
+	//产品工作流webhook
+	if err = TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
+		errorList = multierror.Append(errorList, err)
+	}
+
+	//测试管理webhook
+	if err = TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
+		errorList = multierror.Append(errorList, err)
+	}
+
+	return errorList.ErrorOrNil()
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+)
+
+var _ = Describe("Testing gitee webhook", func() {
+
+	Describe("test matchGiteePushEvent", func() {
+		var hookRepo *commonmodels.MainHookRepo
+		var ev *gitee.PushEvent
+
+		BeforeEach(func() {
+			hookRepo = &commonmodels.MainHookRepo{
+				RepoOwner:    "koderover",
+				RepoName:     "zadig",
+				Branch:       "main",
+				MatchFolders: []string{"/"},
+				Events:       []config.HookEventType{config.HookEventPush},
+			}
+			ev = &gitee.PushEvent{
+				Ref:        "refs/heads/main",
+				Repository: &gitee.WebhookRepository{PathWithNamespace: "koderover/zadig"},
+				Commits:    []*gitee.PushEventCommit{{Modified: []string{"pkg/main.go"}}},
+			}
+		})
+
+		It("should match push to the configured branch", func() {
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeTrue())
+			Expect(hookRepo.Branch).To(Equal("main"))
+		})
+
+		It("should match branch by regular expression and set the pushed branch", func() {
+			hookRepo.IsRegular = true
+			hookRepo.Branch = "release-.*"
+			ev.Ref = "refs/heads/release-1.0"
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeTrue())
+			Expect(hookRepo.Branch).To(Equal("release-1.0"))
+		})
+
+		It("should not match tag push", func() {
+			ev.Ref = "refs/tags/v1.0.0"
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+
+			hookRepo.IsRegular = true
+			hookRepo.Branch = ".*"
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+			Expect(hookRepo.Branch).To(Equal(".*"))
+		})
+
+		It("should not match other branch, repo, deleted branch or unconfigured event", func() {
+			ev.Ref = "refs/heads/dev"
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+
+			ev.Ref = "refs/heads/main"
+			ev.Repository.PathWithNamespace = "koderover/other"
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+
+			ev.Repository.PathWithNamespace = "koderover/zadig"
+			ev.Deleted = true
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+
+			ev.Deleted = false
+			hookRepo.Events = []config.HookEventType{config.HookEventPr}
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+		})
+
+		It("should not match when no changed file is in the match folders", func() {
+			hookRepo.MatchFolders = []string{"docs/"}
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+
+			hookRepo.MatchFolders = []string{"/", "!.go"}
+			Expect(matchGiteePushEvent(hookRepo, ev)).To(BeFalse())
+		})
+	})
+
+	Describe("test matchGiteeMergeEvent", func() {
+		var hookRepo *commonmodels.MainHookRepo
+		var ev *gitee.MergeEvent
+
+		BeforeEach(func() {
+			hookRepo = &commonmodels.MainHookRepo{
+				RepoOwner: "koderover",
+				RepoName:  "zadig",
+				Branch:    "main",
+				Events:    []config.HookEventType{config.HookEventPr},
+			}
+			ev = &gitee.MergeEvent{
+				Action:       gitee.MergeRequestActionOpen,
+				PullRequest:  &gitee.MergeEventPullRequest{Number: 1},
+				Repository:   &gitee.WebhookRepository{PathWithNamespace: "koderover/zadig"},
+				TargetBranch: "dev",
+			}
+		})
+
+		It("should not match pr of other action, target branch, repo or unconfigured event", func() {
+			matched, err := matchGiteeMergeEvent(hookRepo, ev)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(BeFalse())
+
+			ev.TargetBranch = "main"
+			ev.Action = "merge"
+			matched, err = matchGiteeMergeEvent(hookRepo, ev)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(BeFalse())
+
+			ev.Action = gitee.MergeRequestActionUpdate
+			ev.Repository.PathWithNamespace = "koderover/other"
+			matched, err = matchGiteeMergeEvent(hookRepo, ev)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(BeFalse())
+
+			ev.Repository.PathWithNamespace = "koderover/zadig"
+			hookRepo.Events = []config.HookEventType{config.HookEventPush}
+			matched, err = matchGiteeMergeEvent(hookRepo, ev)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(BeFalse())
+		})
+	})
+})
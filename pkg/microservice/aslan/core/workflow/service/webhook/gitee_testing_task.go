@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	testingservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+)
+
+type giteePushEventMatcherForTesting struct {
+	log     *zap.SugaredLogger
+	testing *commonmodels.Testing
+	event   *gitee.PushEvent
+}
+
+func (gpem *giteePushEventMatcherForTesting) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	return matchGiteePushEvent(hookRepo, gpem.event), nil
+}
+
+func (gpem *giteePushEventMatcherForTesting) UpdateTaskArgs(args *commonmodels.TestTaskArgs, requestID string) *commonmodels.TestTaskArgs {
+	factory := &testArgsFactory{
+		testing: gpem.testing,
+		reqID:   requestID,
+	}
+
+	return factory.Update(args)
+}
+
+type giteeMergeEventMatcherForTesting struct {
+	log     *zap.SugaredLogger
+	testing *commonmodels.Testing
+	event   *gitee.MergeEvent
+}
+
+func (gmem *giteeMergeEventMatcherForTesting) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	matched, err := matchGiteeMergeEvent(hookRepo, gmem.event)
+	if err != nil {
+		gmem.log.Warnf("failed to get changes of event %v, err:%s", gmem.event, err)
+	}
+	return matched, err
+}
+
+func (gmem *giteeMergeEventMatcherForTesting) UpdateTaskArgs(args *commonmodels.TestTaskArgs, requestID string) *commonmodels.TestTaskArgs {
+	factory := &testArgsFactory{
+		testing: gmem.testing,
+		reqID:   requestID,
+	}
+
+	return factory.Update(args)
+}
+
+func createGiteeEventMatcherForTesting(
+	event interface{}, testing *commonmodels.Testing, log *zap.SugaredLogger,
+) gitEventMatcherForTesting {
+	switch evt := event.(type) {
+	case *gitee.PushEvent:
+		return &giteePushEventMatcherForTesting{
+			testing: testing,
+			log:     log,
+			event:   evt,
+		}
+	case *gitee.MergeEvent:
+		return &giteeMergeEventMatcherForTesting{
+			log:     log,
+			event:   evt,
+			testing: testing,
+		}
+	}
+
+	return nil
+}
+
+// TriggerTestByGiteeEvent 测试管理模块的触发器任务
+func TriggerTestByGiteeEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	// 1. find configured testing
+	testingList, err := commonrepo.NewTestingColl().List(&commonrepo.ListTestOption{})
+	if err != nil {
+		log.Errorf("failed to list testing %v", err)
+		return err
+	}
+
+	mErr := &multierror.Error{}
+	var notification *commonmodels.Notification
+
+	for _, testing := range testingList {
+		if testing.HookCtl == nil || !testing.HookCtl.Enabled {
+			continue
+		}
+
+		log.Infof("find %d hooks in testing %s", len(testing.HookCtl.Items), testing.Name)
+		for _, item := range testing.HookCtl.Items {
+			if item.TestArgs == nil {
+				continue
+			}
+
+			// 2. match webhook
+			matcher := createGiteeEventMatcherForTesting(event, testing, log)
+			if matcher == nil {
+				continue
+			}
+
+			matches, err := matcher.Match(item.MainRepo)
+			if err != nil {
+				mErr = multierror.Append(mErr, err)
+				continue
+			}
+			if !matches {
+				log.Debugf("event not matches %v", item.MainRepo)
+				continue
+			}
+
+			log.Infof("event match hook %v of %s", item.MainRepo, testing.Name)
+			var mergeRequestID, commitID string
+			if ev, isPr := event.(*gitee.MergeEvent); isPr {
+				// 如果是merge request，且该webhook触发器配置了自动取消，
+				// 则需要确认该merge request在本次commit之前的commit触发的任务是否处理完，没有处理完则取消掉。
+				mergeRequestID = strconv.Itoa(ev.PullRequest.Number)
+				if ev.PullRequest.Head != nil {
+					commitID = ev.PullRequest.Head.SHA
+				}
+				autoCancelOpt := &AutoCancelOpt{
+					MergeRequestID: mergeRequestID,
+					CommitID:       commitID,
+					TaskType:       config.TestType,
+					MainRepo:       item.MainRepo,
+					TestArgs:       item.TestArgs,
+				}
+				err := AutoCancelTask(autoCancelOpt, log)
+				if err != nil {
+					log.Errorf("failed to auto cancel testing task when receive event %v due to %v ", event, err)
+					mErr = multierror.Append(mErr, err)
+				}
+				// 发送本次commit的通知
+				if notification == nil {
+					item.MainRepo.Revision = commitID
+					notification, _ = scmnotify.NewService().SendInitWebhookComment(
+						item.MainRepo, ev.PullRequest.Number, baseURI, false, true, log,
+					)
+				}
+			}
+
+			if notification != nil {
+				item.TestArgs.NotificationID = notification.ID.Hex()
+			}
+
+			args := matcher.UpdateTaskArgs(item.TestArgs, requestID)
+			args.MergeRequestID = mergeRequestID
+			args.CommitID = commitID
+			args.Source = setting.SourceFromGitee
+			args.CodehostID = item.MainRepo.CodehostID
+			args.RepoOwner = item.MainRepo.RepoOwner
+			args.RepoName = item.MainRepo.RepoName
+
+			// 3. create task with args
+			if resp, err := testingservice.CreateTestTask(args, log); err != nil {
+				log.Errorf("failed to create testing task when receive event %v due to %v ", event, err)
+				mErr = multierror.Append(mErr, err)
+			} else {
+				log.Infof("succeed to create task %v", resp)
+			}
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const giteeTagRefPrefix = "refs/tags/"
+
+// matchGiteePushEvent 判断推送事件是否命中触发器，命中时把触发器的分支设置为推送的分支
+func matchGiteePushEvent(hookRepo *commonmodels.MainHookRepo, ev *gitee.PushEvent) bool {
+	if ev.Repository == nil || (hookRepo.RepoOwner+"/"+hookRepo.RepoName) != ev.Repository.PathWithNamespace {
+		return false
+	}
+	if ev.Deleted || !EventConfigured(hookRepo, config.HookEventPush) {
+		return false
+	}
+	// Gitee 的 tag 推送也以推送事件上报，tag 不是分支，不触发工作流和测试
+	if strings.HasPrefix(ev.Ref, giteeTagRefPrefix) {
+		return false
+	}
+
+	branch := getBranchFromRef(ev.Ref)
+	if !hookRepo.IsRegular && hookRepo.Branch != branch {
+		return false
+	}
+	if hookRepo.IsRegular {
+		if matched, _ := regexp.MatchString(hookRepo.Branch, branch); !matched {
+			return false
+		}
+	}
+	hookRepo.Branch = branch
+
+	var changedFiles []string
+	for _, commit := range ev.Commits {
+		changedFiles = append(changedFiles, commit.Added...)
+		changedFiles = append(changedFiles, commit.Removed...)
+		changedFiles = append(changedFiles, commit.Modified...)
+	}
+
+	return MatchChanges(hookRepo, changedFiles)
+}
+
+// matchGiteeMergeEvent 判断 PR 事件是否命中触发器，只处理 PR 的创建和更新
+func matchGiteeMergeEvent(hookRepo *commonmodels.MainHookRepo, ev *gitee.MergeEvent) (bool, error) {
+	if ev.Repository == nil || ev.PullRequest == nil || (hookRepo.RepoOwner+"/"+hookRepo.RepoName) != ev.Repository.PathWithNamespace {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPr) {
+		return false, nil
+	}
+	if ev.Action != gitee.MergeRequestActionOpen && ev.Action != gitee.MergeRequestActionUpdate {
+		return false, nil
+	}
+
+	if !hookRepo.IsRegular && hookRepo.Branch != ev.TargetBranch {
+		return false, nil
+	}
+	if hookRepo.IsRegular {
+		if matched, _ := regexp.MatchString(hookRepo.Branch, ev.TargetBranch); !matched {
+			return false, nil
+		}
+	}
+	hookRepo.Branch = ev.TargetBranch
+
+	changedFiles, err := findChangedFilesOfGiteePullRequest(ev, hookRepo.CodehostID)
+	if err != nil {
+		return false, err
+	}
+
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func findChangedFilesOfGiteePullRequest(ev *gitee.MergeEvent, codehostID int) ([]string, error) {
+	detail, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find codehost %d: %v", codehostID, err)
+	}
+
+	owner, repo := splitPathWithNamespace(ev.Repository.PathWithNamespace)
+	files, err := gitee.NewClient(detail.Address, detail.AccessToken).ListPullRequestFiles(owner, repo, ev.PullRequest.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	var changedFiles []string
+	for _, f := range files {
+		changedFiles = append(changedFiles, f.Filename)
+	}
+	return changedFiles, nil
+}
+
+func splitPathWithNamespace(pathWithNamespace string) (string, string) {
+	idx := strings.LastIndex(pathWithNamespace, "/")
+	if idx < 0 {
+		return "", pathWithNamespace
+	}
+	return pathWithNamespace[:idx], pathWithNamespace[idx+1:]
+}
+
+type giteeMergeEventMatcher struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.Workflow
+	event    *gitee.MergeEvent
+}
+
+func (gmem *giteeMergeEventMatcher) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	matched, err := matchGiteeMergeEvent(hookRepo, gmem.event)
+	if err != nil {
+		gmem.log.Warnf("failed to get changes of event %v, err:%s", gmem.event, err)
+	}
+	return matched, err
+}
+
+func (gmem *giteeMergeEventMatcher) UpdateTaskArgs(
+	product *commonmodels.Product, args *commonmodels.WorkflowTaskArgs, hookRepo *commonmodels.MainHookRepo, requestID string,
+) *commonmodels.WorkflowTaskArgs {
+	factory := &workflowArgsFactory{
+		workflow: gmem.workflow,
+		reqID:    requestID,
+	}
+
+	args = factory.Update(product, args, &types.Repository{
+		CodehostID: hookRepo.CodehostID,
+		RepoName:   hookRepo.RepoName,
+		RepoOwner:  hookRepo.RepoOwner,
+		Branch:     hookRepo.Branch,
+		PR:         gmem.event.PullRequest.Number,
+	})
+
+	return args
+}
+
+type giteePushEventMatcher struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.Workflow
+	event    *gitee.PushEvent
+}
+
+func (gpem *giteePushEventMatcher) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	return matchGiteePushEvent(hookRepo, gpem.event), nil
+}
+
+func (gpem *giteePushEventMatcher) UpdateTaskArgs(
+	product *commonmodels.Product, args *commonmodels.WorkflowTaskArgs, hookRepo *commonmodels.MainHookRepo, requestID string,
+) *commonmodels.WorkflowTaskArgs {
+	factory := &workflowArgsFactory{
+		workflow: gpem.workflow,
+		reqID:    requestID,
+	}
+
+	factory.Update(product, args, &types.Repository{
+		CodehostID: hookRepo.CodehostID,
+		RepoName:   hookRepo.RepoName,
+		RepoOwner:  hookRepo.RepoOwner,
+		Branch:     hookRepo.Branch,
+	})
+
+	return args
+}
+
+func createGiteeEventMatcher(
+	event interface{}, workflow *commonmodels.Workflow, log *zap.SugaredLogger,
+) gitEventMatcher {
+	switch evt := event.(type) {
+	case *gitee.PushEvent:
+		return &giteePushEventMatcher{
+			workflow: workflow,
+			log:      log,
+			event:    evt,
+		}
+	case *gitee.MergeEvent:
+		return &giteeMergeEventMatcher{
+			log:      log,
+			event:    evt,
+			workflow: workflow,
+		}
+	}
+
+	return nil
+}
+
+func TriggerWorkflowByGiteeEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	// 1. find configured workflow
+	workflowList, err := commonrepo.NewWorkflowColl().List(&commonrepo.ListWorkflowOption{})
+	if err != nil {
+		log.Errorf("failed to list workflow %v", err)
+		return err
+	}
+
+	mErr := &multierror.Error{}
+	var notification *commonmodels.Notification
+
+	for _, workflow := range workflowList {
+		if workflow.HookCtl == nil || !workflow.HookCtl.Enabled {
+			continue
+		}
+
+		log.Debugf("find %d hooks in workflow %s", len(workflow.HookCtl.Items), workflow.Name)
+		for _, item := range workflow.HookCtl.Items {
+			if item.WorkflowArgs == nil {
+				continue
+			}
+
+			// 2. match webhook
+			matcher := createGiteeEventMatcher(event, workflow, log)
+			if matcher == nil {
+				continue
+			}
+
+			matches, err := matcher.Match(item.MainRepo)
+			if err != nil {
+				mErr = multierror.Append(mErr, err)
+				continue
+			}
+
+			if !matches {
+				log.Debugf("event not matches %v", item.MainRepo)
+				continue
+			}
+
+			log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
+			namespace := strings.Split(item.WorkflowArgs.Namespace, ",")[0]
+			opt := &commonrepo.ProductFindOptions{Name: workflow.ProductTmplName, EnvName: namespace}
+			var prod *commonmodels.Product
+			if prod, err = commonrepo.NewProductColl().Find(opt); err != nil {
+				log.Warnf("can't find environment %s-%s", item.WorkflowArgs.Namespace, workflow.ProductTmplName)
+				continue
+			}
+
+			prID := 0
+			var mergeRequestID, commitID string
+			if ev, isPr := event.(*gitee.MergeEvent); isPr {
+				prID = ev.PullRequest.Number
+				// 如果是merge request，且该webhook触发器配置了自动取消，
+				// 则需要确认该merge request在本次commit之前的commit触发的任务是否处理完，没有处理完则取消掉。
+				mergeRequestID = strconv.Itoa(ev.PullRequest.Number)
+				if ev.PullRequest.Head != nil {
+					commitID = ev.PullRequest.Head.SHA
+				}
+				autoCancelOpt := &AutoCancelOpt{
+					MergeRequestID: mergeRequestID,
+					CommitID:       commitID,
+					TaskType:       config.WorkflowType,
+					MainRepo:       item.MainRepo,
+					WorkflowArgs:   item.WorkflowArgs,
+				}
+				err := AutoCancelTask(autoCancelOpt, log)
+				if err != nil {
+					log.Errorf("failed to auto cancel workflow task when receive event %v due to %v ", event, err)
+					mErr = multierror.Append(mErr, err)
+				}
+
+				if notification == nil {
+					item.MainRepo.Revision = commitID
+					notification, _ = scmnotify.NewService().SendInitWebhookComment(
+						item.MainRepo, prID, baseURI, false, false, log,
+					)
+				}
+			}
+
+			if notification != nil {
+				item.WorkflowArgs.NotificationID = notification.ID.Hex()
+			}
+
+			args := matcher.UpdateTaskArgs(prod, item.WorkflowArgs, item.MainRepo, requestID)
+			args.MergeRequestID = mergeRequestID
+			args.CommitID = commitID
+			args.Source = setting.SourceFromGitee
+			args.CodehostID = item.MainRepo.CodehostID
+			args.RepoOwner = item.MainRepo.RepoOwner
+			args.RepoName = item.MainRepo.RepoName
+			// 3. create task with args
+			if resp, err := workflowservice.CreateWorkflowTask(args, setting.WebhookTaskCreator, log); err != nil {
+				log.Errorf("failed to create workflow task when receive event %v due to %v ", event, err)
+				mErr = multierror.Append(mErr, err)
+				if prID != 0 {
+					// 单独创建一条通知，展示任务创建失败的错误信息
+					_, err2 := scmnotify.NewService().SendErrWebhookComment(
+						item.MainRepo, workflow, err, prID, baseURI, false, false, log,
+					)
+					if err2 != nil {
+						log.Errorf("SendErrWebhookComment failed, product:%s, workflow:%s, err:%v", workflow.ProductTmplName, workflow.Name, err2)
+					}
+				}
+			} else {
+				log.Infof("succeed to create task %v", resp)
+			}
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
@@ -408,6 +408,12 @@ func ProcessGithubWebHook(payload []byte, req *http.Request, requestID string, l
 
 	switch et := event.(type) {
 	case *github.PullRequestEvent:
+		if ev := githubPreviewEnvEvent(et); ev != nil {
+			if err = HandlePreviewEnvEvent(ev, baseURI, requestID, log); err != nil {
+				log.Errorf("HandlePreviewEnvEvent error: %v", err)
+			}
+		}
+
 		if *et.Action != "opened" && *et.Action != "synchronize" {
 			return nil
 		}
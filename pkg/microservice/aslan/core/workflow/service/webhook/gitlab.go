@@ -178,6 +178,17 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 				errorList = multierror.Append(errorList, err)
 			}
 		}()
+
+		//PR 预览环境
+		if ev := gitlabPreviewEnvEvent(mergeEvent); ev != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err = HandlePreviewEnvEvent(ev, baseURI, requestID, log); err != nil {
+					errorList = multierror.Append(errorList, err)
+				}
+			}()
+		}
 	}
 
 	wg.Wait()
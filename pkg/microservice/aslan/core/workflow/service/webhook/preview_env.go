@@ -0,0 +1,513 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xanzy/go-gitlab"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	githubtool "github.com/koderover/zadig/pkg/tool/git/github"
+	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// previewEnvEvent GitHub PR 和 GitLab MR 事件中预览环境需要的信息
+type previewEnvEvent struct {
+	source   string
+	owner    string
+	repo     string
+	pr       int
+	branch   string
+	commitID string
+	closed   bool
+	// changedFiles 获取 PR 变更的文件列表
+	changedFiles func(codehostID int) ([]string, error)
+}
+
+// keyedMutex 按 key 加锁，不同 PR 之间互不阻塞
+type keyedMutex struct {
+	locks sync.Map
+}
+
+func (m *keyedMutex) Lock(key string) (unlock func()) {
+	v, _ := m.locks.LoadOrStore(key, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+var (
+	// previewEnvRecordLocks 串行化同一个 PR 预览环境记录的创建、提交更新和删除
+	previewEnvRecordLocks = &keyedMutex{}
+	// previewEnvDeployLocks 串行化同一个 PR 预览环境的部署和评论，避免并发的部署互相覆盖 CommitID 和 CommentID
+	previewEnvDeployLocks = &keyedMutex{}
+)
+
+func previewEnvKey(productName string, codehostID int, ev *previewEnvEvent) string {
+	return fmt.Sprintf("%s/%d/%s/%s/%d", productName, codehostID, ev.owner, ev.repo, ev.pr)
+}
+
+func findPreviewEnv(productName string, codehostID int, ev *previewEnvEvent) (*commonmodels.PreviewEnv, error) {
+	return commonrepo.NewPreviewEnvColl().Find(&commonrepo.PreviewEnvFindOption{
+		ProductName: productName,
+		CodehostID:  codehostID,
+		RepoOwner:   ev.owner,
+		RepoName:    ev.repo,
+		PR:          ev.pr,
+	})
+}
+
+func githubPreviewEnvEvent(ev *github.PullRequestEvent) *previewEnvEvent {
+	switch ev.GetAction() {
+	case "opened", "reopened", "synchronize", "closed":
+	default:
+		return nil
+	}
+
+	pr := ev.GetPullRequest()
+	return &previewEnvEvent{
+		source:   setting.SourceFromGithub,
+		owner:    ev.GetRepo().GetOwner().GetLogin(),
+		repo:     ev.GetRepo().GetName(),
+		pr:       pr.GetNumber(),
+		branch:   pr.GetBase().GetRef(),
+		commitID: pr.GetHead().GetSHA(),
+		closed:   ev.GetAction() == "closed",
+		changedFiles: func(codehostID int) ([]string, error) {
+			return findChangedFilesOfPullRequest(ev, codehostID)
+		},
+	}
+}
+
+func gitlabPreviewEnvEvent(ev *gitlab.MergeEvent) *previewEnvEvent {
+	attrs := ev.ObjectAttributes
+	switch attrs.Action {
+	case "open", "reopen", "update", "close", "merge":
+	default:
+		return nil
+	}
+
+	var owner, repo string
+	if attrs.Target != nil {
+		path := attrs.Target.PathWithNamespace
+		if idx := strings.LastIndex(path, "/"); idx >= 0 {
+			owner, repo = path[:idx], path[idx+1:]
+		}
+	}
+	return &previewEnvEvent{
+		source:   setting.SourceFromGitlab,
+		owner:    owner,
+		repo:     repo,
+		pr:       attrs.IID,
+		branch:   attrs.TargetBranch,
+		commitID: attrs.LastCommit.ID,
+		closed:   attrs.Action == "close" || attrs.Action == "merge",
+		changedFiles: func(codehostID int) ([]string, error) {
+			return findChangedFilesOfMergeRequest(ev, codehostID)
+		},
+	}
+}
+
+// HandlePreviewEnvEvent PR 打开或更新时创建/更新预览环境，PR 合并或关闭时删除预览环境
+func HandlePreviewEnvEvent(ev *previewEnvEvent, baseURI, requestID string, log *zap.SugaredLogger) error {
+	projects, err := templaterepo.NewProductColl().List()
+	if err != nil {
+		log.Errorf("failed to list projects: %v", err)
+		return err
+	}
+
+	mErr := &multierror.Error{}
+	for _, project := range projects {
+		if !matchPreviewEnv(project.PreviewEnv, ev) {
+			continue
+		}
+
+		if ev.closed {
+			err = deletePreviewEnv(project, ev, requestID, log)
+		} else {
+			err = upsertPreviewEnv(project, ev, baseURI, requestID, log)
+		}
+		if err != nil {
+			log.Errorf("failed to handle preview env of %s for %s/%s#%d: %v", project.ProductName, ev.owner, ev.repo, ev.pr, err)
+			mErr = multierror.Append(mErr, err)
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+func matchPreviewEnv(cfg *template.PreviewEnv, ev *previewEnvEvent) bool {
+	if cfg == nil || !cfg.Enabled || cfg.BaseEnv == "" || cfg.Workflow == "" {
+		return false
+	}
+	if cfg.RepoOwner != ev.owner || cfg.RepoName != ev.repo {
+		return false
+	}
+	if cfg.Branch == "" {
+		return true
+	}
+	// Do not use regexp.MustCompile to avoid panic
+	matched, _ := regexp.MatchString("^"+cfg.Branch+"$", ev.branch)
+	return matched
+}
+
+func upsertPreviewEnv(project *template.Product, ev *previewEnvEvent, baseURI, requestID string, log *zap.SugaredLogger) error {
+	cfg := project.PreviewEnv
+
+	unlock := previewEnvRecordLocks.Lock(previewEnvKey(project.ProductName, cfg.CodehostID, ev))
+	defer unlock()
+
+	previewEnv, err := findPreviewEnv(project.ProductName, cfg.CodehostID, ev)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		previewEnv, err = createPreviewEnv(project, ev, requestID, log)
+	} else if err != nil {
+		return fmt.Errorf("failed to find preview env: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if previewEnv.CommitID == ev.commitID {
+		return nil
+	}
+	if err = commonrepo.NewPreviewEnvColl().UpdateCommit(previewEnv.ProductName, previewEnv.EnvName, ev.commitID); err != nil {
+		return fmt.Errorf("failed to update commit of preview env %s: %v", previewEnv.EnvName, err)
+	}
+
+	go deployPreviewEnv(project, ev, baseURI, requestID, log)
+	return nil
+}
+
+// createPreviewEnv 复制基准环境创建预览环境
+func createPreviewEnv(project *template.Product, ev *previewEnvEvent, requestID string, log *zap.SugaredLogger) (*commonmodels.PreviewEnv, error) {
+	cfg := project.PreviewEnv
+	baseProduct, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: project.ProductName, EnvName: cfg.BaseEnv})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find base env %s: %v", cfg.BaseEnv, err)
+	}
+	if baseProduct.Render != nil {
+		if renderSet, _ := commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{Name: baseProduct.Render.Name, Revision: baseProduct.Render.Revision}); renderSet != nil {
+			baseProduct.Vars = renderSet.KVs
+		}
+	}
+
+	envName := fmt.Sprintf("pr-%d-%s%s", ev.pr, util.GetRandomNumString(3), util.GetRandomString(3))
+	util.Clear(&baseProduct.ID)
+	baseProduct.Namespace = commonservice.GetProductEnvNamespace(envName, project.ProductName, "")
+	baseProduct.UpdateBy = setting.SystemUser
+	baseProduct.EnvName = envName
+	if err = environmentservice.CreateProduct(setting.SystemUser, requestID, baseProduct, log); err != nil {
+		return nil, fmt.Errorf("failed to create preview env %s: %v", envName, err)
+	}
+
+	previewEnv := &commonmodels.PreviewEnv{
+		ProductName: project.ProductName,
+		EnvName:     envName,
+		BaseEnv:     cfg.BaseEnv,
+		Source:      ev.source,
+		CodehostID:  cfg.CodehostID,
+		RepoOwner:   ev.owner,
+		RepoName:    ev.repo,
+		PR:          ev.pr,
+		Branch:      ev.branch,
+	}
+	if err = commonrepo.NewPreviewEnvColl().Create(previewEnv); err != nil {
+		return nil, fmt.Errorf("failed to save preview env %s: %v", envName, err)
+	}
+	log.Infof("preview env %s of %s created for %s/%s#%d", envName, project.ProductName, ev.owner, ev.repo, ev.pr)
+	return previewEnv, nil
+}
+
+// deployPreviewEnv 等待预览环境就绪后，用工作流构建并部署 PR 变更的服务，然后把访问地址评论到 PR
+// 同一个 PR 的部署串行执行，拿到锁时如果已经有更新的提交，交给新提交的部署处理
+func deployPreviewEnv(project *template.Product, ev *previewEnvEvent, baseURI, requestID string, log *zap.SugaredLogger) {
+	cfg := project.PreviewEnv
+	unlock := previewEnvDeployLocks.Lock(previewEnvKey(project.ProductName, cfg.CodehostID, ev))
+	defer unlock()
+
+	previewEnv, err := findPreviewEnv(project.ProductName, cfg.CodehostID, ev)
+	if err != nil {
+		log.Errorf("failed to find preview env of %s/%s#%d: %v", ev.owner, ev.repo, ev.pr, err)
+		return
+	}
+	if previewEnv.CommitID != ev.commitID {
+		log.Infof("skip deploying preview env %s for outdated commit %s", previewEnv.EnvName, ev.commitID)
+		return
+	}
+	productName, envName := previewEnv.ProductName, previewEnv.EnvName
+
+	prod, err := waitPreviewEnvReady(productName, envName, config.ServiceStartTimeout(), log)
+	if err != nil {
+		log.Errorf("preview env %s is not ready: %v", envName, err)
+		commentPreviewEnv(previewEnv, fmt.Sprintf("预览环境 %s 创建失败：%v", envName, err), log)
+		return
+	}
+
+	workflow, err := commonrepo.NewWorkflowColl().Find(cfg.Workflow)
+	if err != nil {
+		log.Errorf("failed to find workflow %s: %v", cfg.Workflow, err)
+		return
+	}
+
+	builds, err := commonrepo.NewBuildColl().List(&commonrepo.BuildListOption{ProductName: productName})
+	if err != nil {
+		log.Errorf("failed to list builds of %s: %v", productName, err)
+		return
+	}
+
+	files, err := ev.changedFiles(previewEnv.CodehostID)
+	if err != nil {
+		log.Errorf("failed to get changed files of %s/%s#%d: %v", ev.owner, ev.repo, ev.pr, err)
+		commentPreviewEnv(previewEnv, fmt.Sprintf("预览环境 %s 部署失败：%v", envName, err), log)
+		return
+	}
+
+	var taskURL string
+	targets := previewTargets(builds, previewEnv, prod, changedPreviewServices(cfg.Services, files))
+	if len(targets) > 0 {
+		args := &commonmodels.WorkflowTaskArgs{
+			Namespace:      envName,
+			Target:         targets,
+			MergeRequestID: strconv.Itoa(previewEnv.PR),
+			CommitID:       previewEnv.CommitID,
+			Source:         previewEnv.Source,
+			CodehostID:     previewEnv.CodehostID,
+			RepoOwner:      previewEnv.RepoOwner,
+			RepoName:       previewEnv.RepoName,
+		}
+		factory := &workflowArgsFactory{workflow: workflow, reqID: requestID}
+		args = factory.Update(prod, args, &types.Repository{
+			CodehostID: previewEnv.CodehostID,
+			RepoName:   previewEnv.RepoName,
+			RepoOwner:  previewEnv.RepoOwner,
+			Branch:     previewEnv.Branch,
+			PR:         previewEnv.PR,
+		})
+
+		resp, err := workflowservice.CreateWorkflowTask(args, setting.WebhookTaskCreator, log)
+		if err != nil {
+			log.Errorf("failed to create workflow task for preview env %s: %v", envName, err)
+			commentPreviewEnv(previewEnv, fmt.Sprintf("预览环境 %s 部署失败：%v", envName, err), log)
+			return
+		}
+		taskURL = fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/multi/%s/%d", baseURI, productName, resp.PipelineName, resp.TaskID)
+	}
+
+	var hosts []string
+	ingresses, err := environmentservice.GetProductIngress(setting.SystemUser, productName, log)
+	if err != nil {
+		log.Warnf("failed to get ingress of preview env %s: %v", envName, err)
+	}
+	for _, ingress := range ingresses {
+		if ingress.EnvName != envName {
+			continue
+		}
+		for _, info := range ingress.IngressInfos {
+			for _, host := range info.HostInfo {
+				hosts = append(hosts, host.Host)
+			}
+		}
+	}
+
+	commentPreviewEnv(previewEnv, previewEnvComment(baseURI, previewEnv, hosts, taskURL), log)
+}
+
+// changedPreviewServices 返回代码目录命中 PR 变更文件的服务
+func changedPreviewServices(services []*template.PreviewEnvService, files []string) sets.String {
+	changed := sets.NewString()
+	for _, svc := range services {
+		folders := MatchFolders(svc.MatchFolders)
+		for _, file := range files {
+			if folders.ContainsFile(file) {
+				changed.Insert(svc.ServiceName)
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// previewTargets 返回 PR 变更的服务中，构建代码库包含 PR 所在代码库，且在预览环境中存在的服务
+func previewTargets(builds []*commonmodels.Build, previewEnv *commonmodels.PreviewEnv, prod *commonmodels.Product, changed sets.String) []*commonmodels.TargetArgs {
+	services := prod.GetServiceMap()
+	seen := make(map[string]bool)
+
+	var targets []*commonmodels.TargetArgs
+	for _, build := range builds {
+		var matched bool
+		for _, repo := range build.Repos {
+			if repo.CodehostID == previewEnv.CodehostID && repo.RepoOwner == previewEnv.RepoOwner && repo.RepoName == previewEnv.RepoName {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		for _, target := range build.Targets {
+			key := target.ServiceName + SplitSymbol + target.ServiceModule
+			if _, ok := services[target.ServiceName]; !ok || !changed.Has(target.ServiceName) || seen[key] {
+				continue
+			}
+			seen[key] = true
+			targets = append(targets, &commonmodels.TargetArgs{
+				Name:        target.ServiceModule,
+				ServiceName: target.ServiceName,
+				ProductName: prod.ProductName,
+			})
+		}
+	}
+	return targets
+}
+
+func previewEnvComment(baseURI string, previewEnv *commonmodels.PreviewEnv, hosts []string, taskURL string) string {
+	body := fmt.Sprintf("预览环境：[%s](%s/v1/projects/detail/%s/envs/detail?envName=%s) 基准环境：%s 提交：%s \n\n",
+		previewEnv.EnvName, baseURI, previewEnv.ProductName, previewEnv.EnvName, previewEnv.BaseEnv, previewEnv.CommitID)
+	if taskURL != "" {
+		body += fmt.Sprintf("部署任务：%s \n\n", taskURL)
+	}
+	if len(hosts) > 0 {
+		body += "访问地址：\n"
+		for _, host := range hosts {
+			body += fmt.Sprintf("- http://%s \n", host)
+		}
+	}
+	return body
+}
+
+func waitPreviewEnvReady(productName, envName string, timeoutSeconds int, log *zap.SugaredLogger) (*commonmodels.Product, error) {
+	timeout := time.After(time.Duration(timeoutSeconds) * time.Second)
+	for {
+		select {
+		case <-timeout:
+			return nil, fmt.Errorf("wait env %s timeout in %d seconds", envName, timeoutSeconds)
+		default:
+		}
+
+		prod, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+		if err != nil {
+			log.Errorf("failed to find preview env %s: %v", envName, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch prod.Status {
+		case setting.ProductStatusSuccess:
+			return prod, nil
+		case setting.ProductStatusFailed:
+			return nil, fmt.Errorf("%s", prod.Error)
+		default:
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func deletePreviewEnv(project *template.Product, ev *previewEnvEvent, requestID string, log *zap.SugaredLogger) error {
+	unlock := previewEnvRecordLocks.Lock(previewEnvKey(project.ProductName, project.PreviewEnv.CodehostID, ev))
+	defer unlock()
+
+	previewEnv, err := findPreviewEnv(project.ProductName, project.PreviewEnv.CodehostID, ev)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// PR 没有对应的预览环境
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to find preview env: %v", err)
+	}
+
+	if err = commonservice.DeleteProduct(setting.SystemUser, previewEnv.EnvName, previewEnv.ProductName, requestID, log); err != nil {
+		return fmt.Errorf("failed to delete preview env %s: %v", previewEnv.EnvName, err)
+	}
+	commentPreviewEnv(previewEnv, fmt.Sprintf("PR 已关闭，预览环境 %s 已删除", previewEnv.EnvName), log)
+
+	if err = commonrepo.NewPreviewEnvColl().Delete(previewEnv.ProductName, previewEnv.EnvName); err != nil {
+		log.Errorf("PreviewEnv.Delete error: %v", err)
+	}
+	return nil
+}
+
+// commentPreviewEnv 创建或更新 PR 中预览环境的评论
+func commentPreviewEnv(previewEnv *commonmodels.PreviewEnv, body string, log *zap.SugaredLogger) {
+	detail, err := systemconfig.New().GetCodeHost(previewEnv.CodehostID)
+	if err != nil {
+		log.Errorf("failed to find codehost %d: %v", previewEnv.CodehostID, err)
+		return
+	}
+
+	switch previewEnv.Source {
+	case setting.SourceFromGithub:
+		cli := githubtool.NewClient(&githubtool.Config{AccessToken: detail.AccessToken, Proxy: config.ProxyHTTPSAddr()})
+		if previewEnv.CommentID != "" {
+			commentID, _ := strconv.ParseInt(previewEnv.CommentID, 10, 64)
+			err = cli.EditIssueComment(context.Background(), previewEnv.RepoOwner, previewEnv.RepoName, commentID, body)
+			break
+		}
+		comment, err1 := cli.CreateIssueComment(context.Background(), previewEnv.RepoOwner, previewEnv.RepoName, previewEnv.PR, body)
+		if err = err1; err == nil {
+			previewEnv.CommentID = strconv.FormatInt(comment.GetID(), 10)
+		}
+	case setting.SourceFromGitlab:
+		cli, err1 := gitlabtool.NewClient(detail.Address, detail.AccessToken)
+		if err1 != nil {
+			log.Errorf("failed to create gitlab client: %v", err1)
+			return
+		}
+		if previewEnv.CommentID != "" {
+			noteID, _ := strconv.Atoi(previewEnv.CommentID)
+			err = cli.UpdateMergeRequestNote(previewEnv.RepoOwner, previewEnv.RepoName, previewEnv.PR, noteID, body)
+			break
+		}
+		note, err1 := cli.CreateMergeRequestNote(previewEnv.RepoOwner, previewEnv.RepoName, previewEnv.PR, body)
+		if err = err1; err == nil {
+			previewEnv.CommentID = strconv.Itoa(note.ID)
+		}
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Errorf("failed to comment preview env %s on %s/%s#%d: %v", previewEnv.EnvName, previewEnv.RepoOwner, previewEnv.RepoName, previewEnv.PR, err)
+		return
+	}
+	if err = commonrepo.NewPreviewEnvColl().UpdateComment(previewEnv.ProductName, previewEnv.EnvName, previewEnv.CommentID); err != nil {
+		log.Errorf("PreviewEnv.UpdateComment error: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+var _ = Describe("Testing preview env", func() {
+
+	Describe("test matchPreviewEnv", func() {
+		ev := &previewEnvEvent{owner: "koderover", repo: "zadig", branch: "release-1.0"}
+
+		It("should match enabled config of the same repo", func() {
+			cfg := &template.PreviewEnv{Enabled: true, BaseEnv: "dev", Workflow: "wf", RepoOwner: "koderover", RepoName: "zadig"}
+			Expect(matchPreviewEnv(cfg, ev)).To(BeTrue())
+
+			cfg.Branch = "release-.*"
+			Expect(matchPreviewEnv(cfg, ev)).To(BeTrue())
+		})
+
+		It("should not match disabled config, other repo or branch", func() {
+			Expect(matchPreviewEnv(nil, ev)).To(BeFalse())
+			Expect(matchPreviewEnv(&template.PreviewEnv{BaseEnv: "dev", Workflow: "wf", RepoOwner: "koderover", RepoName: "zadig"}, ev)).To(BeFalse())
+			Expect(matchPreviewEnv(&template.PreviewEnv{Enabled: true, BaseEnv: "dev", Workflow: "wf", RepoOwner: "koderover", RepoName: "other"}, ev)).To(BeFalse())
+			Expect(matchPreviewEnv(&template.PreviewEnv{Enabled: true, BaseEnv: "dev", Workflow: "wf", RepoOwner: "koderover", RepoName: "zadig", Branch: "main"}, ev)).To(BeFalse())
+		})
+	})
+
+	Describe("test previewTargets", func() {
+
+		It("should only return changed services built from the pr repo and existing in env", func() {
+			previewEnv := &commonmodels.PreviewEnv{CodehostID: 1, RepoOwner: "koderover", RepoName: "zadig"}
+			prod := &commonmodels.Product{
+				ProductName: "test_product",
+				Services: [][]*commonmodels.ProductService{
+					{{ServiceName: "aslan", Type: setting.K8SDeployType}, {ServiceName: "warpdrive", Type: setting.K8SDeployType}, {ServiceName: "cron", Type: setting.K8SDeployType}},
+				},
+			}
+			builds := []*commonmodels.Build{
+				{
+					Repos:   []*types.Repository{{CodehostID: 1, RepoOwner: "koderover", RepoName: "zadig"}},
+					Targets: []*commonmodels.ServiceModuleTarget{{ServiceName: "aslan", ServiceModule: "aslan"}, {ServiceName: "cron", ServiceModule: "cron"}, {ServiceName: "removed", ServiceModule: "removed"}},
+				},
+				{
+					Repos:   []*types.Repository{{CodehostID: 1, RepoOwner: "koderover", RepoName: "other"}},
+					Targets: []*commonmodels.ServiceModuleTarget{{ServiceName: "warpdrive", ServiceModule: "warpdrive"}},
+				},
+			}
+
+			targets := previewTargets(builds, previewEnv, prod, sets.NewString("aslan", "warpdrive", "removed"))
+			Expect(targets).To(HaveLen(1))
+			Expect(targets[0].ServiceName).To(Equal("aslan"))
+			Expect(targets[0].Name).To(Equal("aslan"))
+			Expect(targets[0].ProductName).To(Equal("test_product"))
+		})
+	})
+
+	Describe("test changedPreviewServices", func() {
+
+		It("should return services whose folders match the changed files", func() {
+			services := []*template.PreviewEnvService{
+				{ServiceName: "aslan", MatchFolders: []string{"pkg/microservice/aslan", "!.md"}},
+				{ServiceName: "warpdrive", MatchFolders: []string{"pkg/microservice/warpdrive"}},
+				{ServiceName: "cron", MatchFolders: []string{"pkg/microservice/cron"}},
+			}
+			files := []string{"pkg/microservice/aslan/README.md", "pkg/microservice/warpdrive/main.go", "docs/index.md"}
+
+			Expect(changedPreviewServices(services, files).List()).To(Equal([]string{"warpdrive"}))
+			Expect(changedPreviewServices(services, nil).Len()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	// approvalTarget 审批 subtask 在 stage 中的名称，一个任务只有一个审批
+	approvalTarget = "approval"
+	// defaultApprovalTimeout 未配置超时时间时的默认值，单位分钟
+	defaultApprovalTimeout = 60
+)
+
+type ApprovalArgs struct {
+	Approve bool   `json:"approve"`
+	Comment string `json:"comment"`
+}
+
+// approvalToSubTask 生成人工审批的 subtask，任务中没有部署时不需要审批，ProductionOnly 时只在环境位于生产集群时生成
+func approvalToSubTask(workflow *commonmodels.Workflow, env *commonmodels.Product, stages []*commonmodels.Stage) (map[string]interface{}, error) {
+	stage := workflow.ApprovalStage
+	if stage == nil || !stage.Enabled || !hasDeployStage(stages) {
+		return nil, nil
+	}
+
+	if stage.ProductionOnly {
+		production, err := isProductionEnv(env)
+		if err != nil {
+			return nil, err
+		}
+		if !production {
+			return nil, nil
+		}
+	}
+
+	approvalTask := task.Approval{
+		TaskType:  config.TaskApproval,
+		Enabled:   true,
+		Approvers: stage.Approvers,
+		Timeout:   stage.Timeout,
+	}
+	return approvalTask.ToSubTask()
+}
+
+func hasDeployStage(stages []*commonmodels.Stage) bool {
+	for _, stage := range stages {
+		if stage.TaskType == config.TaskDeploy && len(stage.SubTasks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isProductionEnv(env *commonmodels.Product) (bool, error) {
+	if env == nil || env.ClusterID == "" {
+		return false, nil
+	}
+
+	cluster, err := commonrepo.NewK8SClusterColl().Get(env.ClusterID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find cluster %s: %v", env.ClusterID, err)
+	}
+	return cluster.Production, nil
+}
+
+// ApproveWorkflowTask 审批人通过或拒绝在审批处暂停的工作流任务，每个任务只接受第一次审批，
+// 审批后任务重新进入队列，warpdrive 根据审批结果继续执行或失败
+func ApproveWorkflowTask(uid, userName, pipelineName string, taskID int64, args *ApprovalArgs, log *zap.SugaredLogger) error {
+	t, err := commonrepo.NewTaskColl().Find(taskID, pipelineName, config.WorkflowType)
+	if err != nil {
+		log.Errorf("[%d:%s] find workflow task error: %v", taskID, pipelineName, err)
+		return e.ErrApproveTask.AddDesc(e.FindPipelineTaskErrMsg)
+	}
+
+	approval, _ := findApprovalSubTask(t)
+	if approval == nil {
+		return e.ErrApproveTask.AddDesc("该任务不需要审批")
+	}
+	if t.Status != config.StatusWaitForApprove {
+		return e.ErrApproveTask.AddDesc("该任务当前不在等待审批状态")
+	}
+	if approvalExpired(approval, time.Now()) {
+		return e.ErrApproveTask.AddDesc("审批已超时")
+	}
+	if !isApprover(approval.Approvers, uid) {
+		return e.ErrForbidden.AddDesc("当前用户不是该任务的审批人")
+	}
+
+	existing, err := commonrepo.NewWorkflowTaskApprovalColl().Find(pipelineName, taskID)
+	if err != nil {
+		log.Errorf("[%d:%s] find approval error: %v", taskID, pipelineName, err)
+		return e.ErrApproveTask.AddErr(err)
+	}
+	if existing != nil {
+		return e.ErrApproveTask.AddDesc(fmt.Sprintf("该任务已被 %s 审批", existing.UserName))
+	}
+
+	status := config.ApprovalRejected
+	if args.Approve {
+		status = config.ApprovalApproved
+	}
+	err = commonrepo.NewWorkflowTaskApprovalColl().Create(&commonmodels.WorkflowTaskApproval{
+		PipelineName: pipelineName,
+		TaskID:       taskID,
+		Status:       status,
+		UID:          uid,
+		UserName:     userName,
+		Comment:      args.Comment,
+	})
+	if err != nil {
+		log.Errorf("[%d:%s] create approval error: %v", taskID, pipelineName, err)
+		return e.ErrApproveTask.AddErr(err)
+	}
+
+	t.Status = config.StatusCreated
+	if err := UpdateTask(t); err != nil {
+		log.Errorf("[%d:%s] requeue approved task error: %v", taskID, pipelineName, err)
+		return e.ErrApproveTask.AddErr(err)
+	}
+
+	return nil
+}
+
+// TimeoutWaitForApproveTasks 将审批超时的暂停任务置为超时，由 cron 服务定时触发
+func TimeoutWaitForApproveTasks(log *zap.SugaredLogger) {
+	tasks, err := commonrepo.NewTaskColl().ListWaitForApproveTasks()
+	if err != nil {
+		log.Errorf("list tasks waiting for approval error: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, t := range tasks {
+		approval, stage := findApprovalSubTask(t)
+		if approval == nil || !approvalExpired(approval, now) {
+			continue
+		}
+
+		approval.TaskStatus = config.StatusTimeout
+		approval.Error = "approval is timeout"
+		approval.EndTime = now.Unix()
+		subTask, err := approval.ToSubTask()
+		if err != nil {
+			log.Errorf("[%d:%s] convert approval error: %v", t.TaskID, t.PipelineName, err)
+			continue
+		}
+		stage.SubTasks[approvalTarget] = subTask
+		stage.Status = config.StatusTimeout
+		t.Status = config.StatusTimeout
+		t.EndTime = now.Unix()
+
+		if err := commonrepo.NewTaskColl().Update(t); err != nil {
+			log.Errorf("[%d:%s] update approval timeout task error: %v", t.TaskID, t.PipelineName, err)
+			continue
+		}
+
+		notifyCli := scmnotify.NewService()
+		_ = notifyCli.UpdateWebhookComment(t, log)
+		_ = notifyCli.UpdateDiffNote(t, log)
+	}
+}
+
+func approvalExpired(approval *task.Approval, now time.Time) bool {
+	timeout := approval.Timeout
+	if timeout == 0 {
+		timeout = defaultApprovalTimeout
+	}
+	return approval.StartTime > 0 && now.Unix() > approval.StartTime+int64(timeout*60)
+}
+
+// GetWorkflowTaskApproval 返回任务的审批结果，还没有人审批时状态为 waiting
+func GetWorkflowTaskApproval(pipelineName string, taskID int64, log *zap.SugaredLogger) (*commonmodels.WorkflowTaskApproval, error) {
+	approval, err := commonrepo.NewWorkflowTaskApprovalColl().Find(pipelineName, taskID)
+	if err != nil {
+		log.Errorf("[%d:%s] find approval error: %v", taskID, pipelineName, err)
+		return nil, e.ErrGetTaskApproval.AddErr(err)
+	}
+
+	if approval == nil {
+		return &commonmodels.WorkflowTaskApproval{
+			PipelineName: pipelineName,
+			TaskID:       taskID,
+			Status:       config.ApprovalWaiting,
+		}, nil
+	}
+	return approval, nil
+}
+
+// resetApproval 重试任务时清除上一次的审批结果，审批 subtask 需要重新执行
+func resetApproval(t *task.Task, log *zap.SugaredLogger) {
+	if err := commonrepo.NewWorkflowTaskApprovalColl().Delete(t.PipelineName, t.TaskID); err != nil {
+		log.Errorf("[%d:%s] delete approval error: %v", t.TaskID, t.PipelineName, err)
+	}
+
+	approval, stage := findApprovalSubTask(t)
+	if approval == nil {
+		return
+	}
+	approval.TaskStatus = config.StatusCreated
+	approval.Decision = ""
+	approval.DecidedBy = ""
+	approval.Comment = ""
+	approval.Error = ""
+	if subTask, err := approval.ToSubTask(); err == nil {
+		stage.SubTasks[approvalTarget] = subTask
+	}
+}
+
+func findApprovalSubTask(t *task.Task) (*task.Approval, *commonmodels.Stage) {
+	for _, stage := range t.Stages {
+		if stage.TaskType != config.TaskApproval {
+			continue
+		}
+		approval, err := base.ToApprovalTask(stage.SubTasks[approvalTarget])
+		if err != nil || approval == nil {
+			return nil, nil
+		}
+		return approval, stage
+	}
+	return nil, nil
+}
+
+func isApprover(approvers []*commonmodels.Approver, uid string) bool {
+	for _, approver := range approvers {
+		if approver.UID == uid {
+			return true
+		}
+	}
+	return false
+}
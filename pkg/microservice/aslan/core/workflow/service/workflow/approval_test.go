@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package workflow
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+)
+
+var _ = Describe("Testing approval", func() {
+
+	It("requires approval only when the task deploys services", func() {
+		workflow := &commonmodels.Workflow{ApprovalStage: &commonmodels.ApprovalStage{Enabled: true}}
+		build := &commonmodels.Stage{TaskType: config.TaskBuild, SubTasks: map[string]map[string]interface{}{"svc": {}}}
+		deploy := &commonmodels.Stage{TaskType: config.TaskDeploy, SubTasks: map[string]map[string]interface{}{"svc": {}}}
+
+		subTask, err := approvalToSubTask(workflow, nil, []*commonmodels.Stage{build})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(subTask).To(BeNil())
+
+		subTask, err = approvalToSubTask(workflow, nil, []*commonmodels.Stage{build, deploy})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(subTask).To(HaveKeyWithValue("type", string(config.TaskApproval)))
+	})
+
+	It("expires after the timeout since the task paused", func() {
+		start := time.Date(2021, 9, 3, 9, 0, 0, 0, time.UTC)
+		approval := &task.Approval{StartTime: start.Unix()}
+
+		Expect(approvalExpired(approval, start.Add(59*time.Minute))).To(BeFalse())
+		Expect(approvalExpired(approval, start.Add(61*time.Minute))).To(BeTrue())
+
+		approval.Timeout = 120
+		Expect(approvalExpired(approval, start.Add(61*time.Minute))).To(BeFalse())
+
+		Expect(approvalExpired(&task.Approval{}, start)).To(BeFalse())
+	})
+
+	It("finds the approval subtask and its stage", func() {
+		approval := &task.Approval{TaskType: config.TaskApproval, Enabled: true, TaskStatus: config.StatusWaitForApprove}
+		subTask, err := approval.ToSubTask()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		stage := &commonmodels.Stage{TaskType: config.TaskApproval, SubTasks: map[string]map[string]interface{}{approvalTarget: subTask}}
+		found, foundStage := findApprovalSubTask(&task.Task{Stages: []*commonmodels.Stage{stage}})
+		Expect(found.TaskStatus).To(Equal(config.StatusWaitForApprove))
+		Expect(foundStage).To(Equal(stage))
+
+		found, _ = findApprovalSubTask(&task.Task{})
+		Expect(found).To(BeNil())
+	})
+})
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucket"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// setBitbucketBuildInfo 根据 tag、分支或 PR 获取最新的提交信息
+func setBitbucketBuildInfo(ch *systemconfig.CodeHost, build *types.Repository, log *zap.SugaredLogger) {
+	cli := bitbucket.NewClient(ch.Address, ch.AccessToken)
+
+	var commitID string
+	if build.PR > 0 {
+		pr, err := cli.GetPullRequest(build.RepoOwner, build.RepoName, build.PR)
+		if err != nil || pr.FromRef == nil {
+			log.Warnf("bitbucket setBuildInfo failed, use build %+v %v", build, err)
+			return
+		}
+		build.CommitID = pr.FromRef.LatestCommit
+		build.CommitMessage = pr.Title
+		build.AuthorName = pr.AuthorName()
+		return
+	} else if build.Tag != "" {
+		tags, err := cli.ListTags(build.RepoOwner, build.RepoName)
+		if err != nil {
+			log.Warnf("bitbucket setBuildInfo failed, use build %+v %v", build, err)
+			return
+		}
+		for _, tag := range tags {
+			if tag.DisplayID == build.Tag {
+				commitID = tag.LatestCommit
+				break
+			}
+		}
+	} else if build.Branch != "" {
+		branch, err := cli.GetBranch(build.RepoOwner, build.RepoName, build.Branch)
+		if err != nil {
+			log.Warnf("bitbucket setBuildInfo failed, use build %+v %v", build, err)
+			return
+		}
+		commitID = branch.LatestCommit
+	}
+
+	if commitID == "" {
+		return
+	}
+	commit, err := cli.GetCommit(build.RepoOwner, build.RepoName, commitID)
+	if err != nil {
+		log.Warnf("bitbucket setBuildInfo failed, use build %+v %v", build, err)
+		return
+	}
+	build.CommitID = commit.ID
+	build.CommitMessage = commit.Message
+	build.AuthorName = commit.AuthorName()
+}
@@ -65,6 +65,17 @@ func HandleCronjob(workflow *commonmodels.Workflow, log *zap.SugaredLogger) erro
 }
 
 func UpdateCronjob(parentName, parentType, productName string, schedule *commonmodels.ScheduleCtrl, log *zap.SugaredLogger) (deleteList []string, err error) {
+	// 已有的定时和间隔任务沿用原来的校验逻辑，这里只校验 cron 表达式及其时区
+	for _, item := range schedule.Items {
+		if item.Type != config.CrontabSchedule {
+			continue
+		}
+		if err := item.Validate(); err != nil {
+			log.Errorf("invalid schedule of %s %s: %v", parentType, parentName, err)
+			return nil, err
+		}
+	}
+
 	idMap := make(map[string]bool)
 	deleteList = make([]string, 0)
 	jobList, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
@@ -89,6 +100,7 @@ func UpdateCronjob(parentName, parentType, productName string, schedule *commonm
 			Frequency:    tasks.Frequency,
 			Time:         tasks.Time,
 			Cron:         tasks.Cron,
+			TimeZone:     tasks.TimeZone,
 			MaxFailure:   tasks.MaxFailures,
 			TaskArgs:     tasks.TaskArgs,
 			WorkflowArgs: tasks.WorkflowArgs,
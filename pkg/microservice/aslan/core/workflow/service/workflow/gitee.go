@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// setGiteeBuildInfo 根据 tag、分支或 PR 获取最新的提交信息
+func setGiteeBuildInfo(ch *systemconfig.CodeHost, build *types.Repository, log *zap.SugaredLogger) {
+	cli := gitee.NewClient(ch.Address, ch.AccessToken)
+
+	var commit *gitee.Commit
+	if build.PR > 0 {
+		pr, err := cli.GetPullRequest(build.RepoOwner, build.RepoName, build.PR)
+		if err != nil || pr.Head == nil {
+			log.Warnf("gitee setBuildInfo failed, use build %+v %v", build, err)
+			return
+		}
+		build.CommitID = pr.Head.SHA
+		build.CommitMessage = pr.Title
+		if pr.User != nil {
+			build.AuthorName = pr.User.Login
+		}
+		return
+	} else if build.Tag != "" {
+		tags, err := cli.ListTags(build.RepoOwner, build.RepoName)
+		if err != nil {
+			log.Warnf("gitee setBuildInfo failed, use build %+v %v", build, err)
+			return
+		}
+		for _, tag := range tags {
+			if tag.Name == build.Tag {
+				commit = tag.Commit
+				break
+			}
+		}
+	} else if build.Branch != "" {
+		branch, err := cli.GetBranch(build.RepoOwner, build.RepoName, build.Branch)
+		if err != nil {
+			log.Warnf("gitee setBuildInfo failed, use build %+v %v", build, err)
+			return
+		}
+		commit = branch.Commit
+	}
+
+	if commit == nil {
+		return
+	}
+	build.CommitID = commit.SHA
+	build.CommitMessage = commit.Message()
+	build.AuthorName = commit.AuthorName()
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const (
+	// matrixImageKey Exclude 和 AllowFailures 中匹配镜像维度使用的 key
+	matrixImageKey = "IMAGE"
+	// maxMatrixCombinations 单个构建或测试模块最多展开的组合数
+	maxMatrixCombinations = 32
+)
+
+// expandMatrix 把矩阵定义展开为组合列表，矩阵未定义镜像维度时使用模块本身的镜像 base
+// 允许失败的组合排在后面，保证第一个组合（即任务本身，负责产出构建物）失败时任务失败
+func expandMatrix(matrix *commonmodels.BuildMatrix, base *commonmodels.MatrixImage) ([]*task.MatrixCombination, error) {
+	images := matrix.Images
+	if len(images) == 0 {
+		images = []*commonmodels.MatrixImage{base}
+	}
+
+	type combination struct {
+		image  *commonmodels.MatrixImage
+		values map[string]string
+		envs   []*commonmodels.KeyVal
+	}
+
+	combinations := make([]*combination, 0, len(images))
+	for _, image := range images {
+		if image.BuildOS == "" {
+			return nil, fmt.Errorf("image of matrix must not be empty")
+		}
+		values := make(map[string]string)
+		if len(matrix.Images) > 0 {
+			values[matrixImageKey] = matrixImageName(image)
+		}
+		combinations = append(combinations, &combination{image: image, values: values})
+	}
+
+	keys := make(map[string]bool)
+	for _, env := range matrix.Envs {
+		if env.Key == "" || env.Key == matrixImageKey || keys[env.Key] {
+			return nil, fmt.Errorf("invalid or duplicated matrix env key %q", env.Key)
+		}
+		if len(env.Values) == 0 {
+			return nil, fmt.Errorf("matrix env %s has no values", env.Key)
+		}
+		keys[env.Key] = true
+
+		expanded := make([]*combination, 0, len(combinations)*len(env.Values))
+		for _, c := range combinations {
+			for _, value := range env.Values {
+				values := make(map[string]string, len(c.values)+1)
+				for k, v := range c.values {
+					values[k] = v
+				}
+				values[env.Key] = value
+
+				envs := make([]*commonmodels.KeyVal, 0, len(c.envs)+1)
+				envs = append(envs, c.envs...)
+				envs = append(envs, &commonmodels.KeyVal{Key: env.Key, Value: value})
+
+				expanded = append(expanded, &combination{image: c.image, values: values, envs: envs})
+			}
+		}
+		combinations = expanded
+	}
+
+	resp := make([]*task.MatrixCombination, 0, len(combinations))
+	for _, c := range combinations {
+		if matchMatrix(matrix.Exclude, c.values) {
+			continue
+		}
+
+		names := make([]string, 0, len(c.values))
+		if name, ok := c.values[matrixImageKey]; ok {
+			names = append(names, fmt.Sprintf("%s=%s", matrixImageKey, name))
+		}
+		for _, env := range c.envs {
+			names = append(names, fmt.Sprintf("%s=%s", env.Key, env.Value))
+		}
+
+		resp = append(resp, &task.MatrixCombination{
+			Name:         strings.Join(names, ", "),
+			BuildOS:      c.image.BuildOS,
+			ImageFrom:    c.image.ImageFrom,
+			ImageID:      c.image.ImageID,
+			Envs:         c.envs,
+			AllowFailure: matchMatrix(matrix.AllowFailures, c.values),
+		})
+	}
+
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("all combinations of matrix are excluded")
+	}
+	if len(resp) > maxMatrixCombinations {
+		return nil, fmt.Errorf("matrix has %d combinations, exceeds the limit %d", len(resp), maxMatrixCombinations)
+	}
+
+	sort.SliceStable(resp, func(i, j int) bool {
+		return !resp[i].AllowFailure && resp[j].AllowFailure
+	})
+	return resp, nil
+}
+
+func matrixImageName(image *commonmodels.MatrixImage) string {
+	if image.Name != "" {
+		return image.Name
+	}
+	return image.BuildOS
+}
+
+// matchMatrix 组合匹配任一规则中的所有 key 时返回 true，空规则不匹配任何组合
+func matchMatrix(rules []map[string]string, values map[string]string) bool {
+	for _, rule := range rules {
+		if len(rule) == 0 {
+			continue
+		}
+		matched := true
+		for k, v := range rule {
+			if values[k] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMatrix 展开矩阵，并把第一个组合的镜像和变量应用到任务本身
+// 自定义基础镜像的镜像名称可能会被更新，需要使用ID获取最新的镜像名称
+func applyMatrix(matrix *commonmodels.BuildMatrix, imageID, buildOS, imageFrom *string, envs *[]*commonmodels.KeyVal, log *zap.SugaredLogger) ([]*task.MatrixCombination, error) {
+	if matrix == nil {
+		return nil, nil
+	}
+
+	for _, image := range matrix.Images {
+		if image.ImageID == "" {
+			continue
+		}
+		basicImage, err := commonrepo.NewBasicImageColl().Find(image.ImageID)
+		if err != nil {
+			log.Errorf("BasicImage.Find failed, id:%s, err:%v", image.ImageID, err)
+			continue
+		}
+		image.BuildOS = basicImage.Value
+	}
+
+	combinations, err := expandMatrix(matrix, &commonmodels.MatrixImage{ImageID: *imageID, BuildOS: *buildOS, ImageFrom: *imageFrom})
+	if err != nil {
+		return nil, err
+	}
+
+	primary := combinations[0]
+	*imageID, *buildOS, *imageFrom = primary.ImageID, primary.BuildOS, primary.ImageFrom
+	*envs = mergeMatrixEnvs(*envs, primary.Envs)
+	return combinations, nil
+}
+
+// mergeMatrixEnvs 使用组合的变量覆盖同名变量，返回新的列表
+func mergeMatrixEnvs(envs, overrides []*commonmodels.KeyVal) []*commonmodels.KeyVal {
+	merged := make([]*commonmodels.KeyVal, 0, len(envs)+len(overrides))
+	values := make(map[string]*commonmodels.KeyVal, len(overrides))
+	for _, kv := range overrides {
+		values[kv.Key] = kv
+	}
+	for _, kv := range envs {
+		if override, ok := values[kv.Key]; ok {
+			merged = append(merged, &commonmodels.KeyVal{Key: kv.Key, Value: override.Value, IsCredential: kv.IsCredential})
+			delete(values, kv.Key)
+			continue
+		}
+		merged = append(merged, kv)
+	}
+	for _, kv := range overrides {
+		if _, ok := values[kv.Key]; ok {
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing matrix", func() {
+	var base *commonmodels.MatrixImage
+
+	BeforeEach(func() {
+		base = &commonmodels.MatrixImage{BuildOS: "focal", ImageFrom: commonmodels.ImageFromKoderover}
+	})
+
+	Context("expandMatrix", func() {
+		It("expands images and envs", func() {
+			combinations, err := expandMatrix(&commonmodels.BuildMatrix{
+				Images: []*commonmodels.MatrixImage{{Name: "bionic", BuildOS: "bionic"}, {BuildOS: "focal"}},
+				Envs:   []*commonmodels.MatrixEnv{{Key: "GO", Values: []string{"1.16", "1.17"}}},
+			}, base)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(combinations).To(HaveLen(4))
+			Expect(combinations[0].Name).To(Equal("IMAGE=bionic, GO=1.16"))
+			Expect(combinations[0].BuildOS).To(Equal("bionic"))
+			Expect(combinations[3].Name).To(Equal("IMAGE=focal, GO=1.17"))
+			Expect(combinations[3].Envs).To(Equal([]*commonmodels.KeyVal{{Key: "GO", Value: "1.17"}}))
+		})
+
+		It("uses the module image without image dimension", func() {
+			combinations, err := expandMatrix(&commonmodels.BuildMatrix{
+				Envs: []*commonmodels.MatrixEnv{{Key: "ARCH", Values: []string{"amd64", "arm64"}}},
+			}, base)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(combinations).To(HaveLen(2))
+			Expect(combinations[1].Name).To(Equal("ARCH=arm64"))
+			Expect(combinations[1].BuildOS).To(Equal("focal"))
+		})
+
+		It("excludes combinations and orders allowed failures last", func() {
+			combinations, err := expandMatrix(&commonmodels.BuildMatrix{
+				Envs: []*commonmodels.MatrixEnv{
+					{Key: "GO", Values: []string{"tip", "1.16", "1.17"}},
+					{Key: "ARCH", Values: []string{"amd64", "arm64"}},
+				},
+				Exclude:       []map[string]string{{"GO": "1.16", "ARCH": "arm64"}},
+				AllowFailures: []map[string]string{{"GO": "tip"}},
+			}, base)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(combinations).To(HaveLen(5))
+			Expect(combinations[0].Name).To(Equal("GO=1.16, ARCH=amd64"))
+			Expect(combinations[0].AllowFailure).To(BeFalse())
+			Expect(combinations[3].AllowFailure).To(BeTrue())
+			Expect(combinations[4].Name).To(Equal("GO=tip, ARCH=arm64"))
+		})
+
+		It("should raise error for invalid matrix", func() {
+			_, err := expandMatrix(&commonmodels.BuildMatrix{
+				Envs: []*commonmodels.MatrixEnv{{Key: "GO", Values: []string{"1.16"}}, {Key: "GO", Values: []string{"1.17"}}},
+			}, base)
+			Expect(err).Should(HaveOccurred())
+
+			_, err = expandMatrix(&commonmodels.BuildMatrix{
+				Envs:    []*commonmodels.MatrixEnv{{Key: "GO", Values: []string{"1.16"}}},
+				Exclude: []map[string]string{{"GO": "1.16"}},
+			}, base)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("mergeMatrixEnvs", func() {
+		It("overrides envs of the same key", func() {
+			envs := []*commonmodels.KeyVal{{Key: "GO", Value: "1.15"}, {Key: "TOKEN", Value: "x", IsCredential: true}}
+			merged := mergeMatrixEnvs(envs, []*commonmodels.KeyVal{{Key: "GO", Value: "1.16"}, {Key: "ARCH", Value: "amd64"}})
+			Expect(merged).To(Equal([]*commonmodels.KeyVal{
+				{Key: "GO", Value: "1.16"},
+				{Key: "TOKEN", Value: "x", IsCredential: true},
+				{Key: "ARCH", Value: "amd64"},
+			}))
+			Expect(envs[0].Value).To(Equal("1.15"))
+		})
+	})
+})
@@ -46,6 +46,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/git/gitlab"
 	"github.com/koderover/zadig/pkg/tool/log"
 	s3tool "github.com/koderover/zadig/pkg/tool/s3"
@@ -162,6 +163,8 @@ func (h *TaskAckHandler) handle(message *nsq.Message) error {
 	if pt.Status == config.StatusPassed || pt.Status == config.StatusFailed || pt.Status == config.StatusTimeout {
 		h.log.Infof("%s:%d:%v task done", pt.PipelineName, pt.TaskID, pt.Status)
 		h.queue.Remove(pt)
+		metrics.TaskDuration.WithLabelValues(pt.ProductName, pt.PipelineName, string(pt.Type), string(pt.Status)).
+			Observe(float64(pt.EndTime - pt.StartTime))
 		go func() {
 			if err = h.uploadTaskData(pt); err != nil {
 				h.log.Errorf("uploadTaskData err: %v", err)
@@ -181,6 +184,12 @@ func (h *TaskAckHandler) handle(message *nsq.Message) error {
 		}()
 	}
 
+	// 等待审批的任务移出队列，释放 warpdrive，审批后重新进入队列
+	if pt.Status == config.StatusWaitForApprove {
+		h.log.Infof("%s:%d task is waiting for approval", pt.PipelineName, pt.TaskID)
+		h.queue.Remove(pt)
+	}
+
 	// 更新数据库 product
 	var deploys []*task.Deploy
 
@@ -453,6 +462,13 @@ func (h *TaskAckHandler) uploadTaskData(pt *task.Task) error {
 							continue
 						}
 
+						// 覆盖率与测试结果相互独立，不区分测试类型
+						if testInfo.Coverage != nil {
+							if err := recordTestCoverage(pt, testInfo); err != nil {
+								h.log.Errorf("uploadTaskData recordTestCoverage err:%v", err)
+							}
+						}
+
 						if testInfo.JobCtx.TestType == setting.FunctionTestType {
 							testTaskStat, _ = h.TestTaskStatColl.FindTestTaskStat(&commonrepo.TestTaskStatOption{Name: testInfo.TestModuleName})
 							if testTaskStat == nil {
@@ -499,6 +515,8 @@ func (h *TaskAckHandler) uploadTaskData(pt *task.Task) error {
 							if err := xml.Unmarshal(b, &testReport); err != nil {
 								msg := fmt.Sprintf("uploadTaskData testSuite unmarshal it report xml error: %v", err)
 								h.log.Error(msg)
+							} else if err := recordTestCaseResults(pt, testInfo, testReport); err != nil {
+								h.log.Errorf("uploadTaskData recordTestCaseResults err:%v", err)
 							}
 							totalCaseNum := testReport.Tests
 							if totalCaseNum != 0 {
@@ -38,6 +38,8 @@ func ListPipelines(log *zap.SugaredLogger) ([]*commonmodels.Pipeline, error) {
 	}
 	for i := range resp {
 		EnsureSubTasksResp(resp[i].SubTasks)
+		resp[i].NotifyCtl.MaskSecrets()
+		resp[i].Slack.MaskSecrets()
 	}
 	return resp, nil
 }
@@ -50,6 +52,8 @@ func GetPipeline(userID, pipelineName string, log *zap.SugaredLogger) (*commonmo
 	}
 
 	EnsureSubTasksResp(resp.SubTasks)
+	resp.NotifyCtl.MaskSecrets()
+	resp.Slack.MaskSecrets()
 
 	fPipe, err := commonrepo.NewFavoriteColl().Find(userID, pipelineName, string(config.SingleType))
 	if err == nil && fPipe != nil && fPipe.Name == pipelineName {
@@ -71,13 +75,17 @@ func UpsertPipeline(args *commonmodels.Pipeline, log *zap.SugaredLogger) error {
 		return e.ErrCreatePipeline.AddDesc(err.Error())
 	}
 
-	if err := ensurePipeline(args, log); err != nil {
+	currentPipeline, err := commonrepo.NewPipelineColl().Find(&commonrepo.PipelineFindOption{Name: args.Name})
+	if err != nil {
+		currentPipeline = nil
+	}
+
+	if err := ensurePipeline(args, currentPipeline, log); err != nil {
 		return e.ErrCreatePipeline.AddDesc(err.Error())
 	}
 
 	var currentHooks, updatedHooks []commonmodels.GitHook
-	currentPipeline, err := commonrepo.NewPipelineColl().Find(&commonrepo.PipelineFindOption{Name: args.Name})
-	if err == nil && currentPipeline != nil && currentPipeline.Hook != nil {
+	if currentPipeline != nil && currentPipeline.Hook != nil {
 		currentHooks = currentPipeline.Hook.GitHooks
 	}
 	if args.Hook != nil {
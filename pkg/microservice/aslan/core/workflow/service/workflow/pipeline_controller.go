@@ -32,6 +32,7 @@ import (
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	nsqservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/nsq"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -290,6 +291,8 @@ func PipelineTaskSender() {
 	for {
 		time.Sleep(time.Second * 3)
 
+		updateQueueMetrics()
+
 		//c.checkAgents()
 		if hasAgentAvaiable() {
 			t, err := NextWaitingTask()
@@ -331,6 +334,15 @@ func PipelineTaskSender() {
 	}
 }
 
+// updateQueueMetrics 按项目、工作流类型和状态统计队列中的任务数
+func updateQueueMetrics() {
+	tasks := ListTasks()
+	metrics.QueueTasks.Reset()
+	for _, t := range tasks {
+		metrics.QueueTasks.WithLabelValues(t.ProductName, string(t.Type), string(t.Status)).Inc()
+	}
+}
+
 func hasAgentAvaiable() bool {
 	kubeClient := krkubeclient.Client()
 	deployment, _, err := getter.GetDeployment(config.Namespace(), configbase.WarpDriveServiceName(), kubeClient)
@@ -358,8 +358,8 @@ func RestartPipelineTaskV2(userName string, taskID int64, pipelineName string, t
 		return e.ErrRestartTask.AddDesc(e.FindPipelineTaskErrMsg)
 	}
 
-	// 不重试已经成功的pipelie task
-	if t.Status == config.StatusRunning || t.Status == config.StatusPassed {
+	// 不重试已经成功的pipelie task，等待审批的任务需要先审批或取消
+	if t.Status == config.StatusRunning || t.Status == config.StatusPassed || t.Status == config.StatusWaitForApprove {
 		log.Errorf("cannot restart running or passed task. Status: %v", t.Status)
 		return e.ErrRestartTask.AddDesc(e.RestartPassedTaskErrMsg)
 	}
@@ -378,6 +378,8 @@ func RestartPipelineTaskV2(userName string, taskID int64, pipelineName string, t
 			}
 		}
 	} else if t.Type == config.WorkflowType {
+		resetApproval(t, log)
+
 		stageArray := t.Stages
 		for _, subStage := range stageArray {
 			taskType := subStage.TaskType
@@ -430,6 +432,8 @@ func RestartPipelineTaskV2(userName string, taskID int64, pipelineName string, t
 								}
 							}
 							buildInfo.JobCtx.Caches = newBuildInfo.Caches
+							buildInfo.JobCtx.CacheKeyFiles = newBuildInfo.CacheKeyFiles
+							buildInfo.JobCtx.CacheSizeLimit = buildCacheLimit(t.ProductName)
 							// 设置 build 安装脚本
 							buildInfo.InstallCtx, err = buildInstallCtx(buildInfo.InstallItems)
 							if err != nil {
@@ -455,6 +459,8 @@ func RestartPipelineTaskV2(userName string, taskID int64, pipelineName string, t
 							}
 
 							testInfo.JobCtx.TestResultPath = newTestInfo.TestResultPath
+							testInfo.JobCtx.CoverageReportPath = newTestInfo.CoverageReportPath
+							testInfo.CoverageGate = newTestInfo.CoverageGate
 							testInfo.JobCtx.Caches = newTestInfo.Caches
 							testInfo.JobCtx.ArtifactPaths = newTestInfo.ArtifactPaths
 
@@ -604,6 +610,12 @@ func TestArgsToTestSubtask(args *commonmodels.TestTaskArgs, pt *task.Task, log *
 		testTask.BuildOS = testModule.PreTest.BuildOS
 		testTask.ImageFrom = testModule.PreTest.ImageFrom
 		testTask.ResReq = testModule.PreTest.ResReq
+
+		testTask.Matrix, err = applyMatrix(testModule.Matrix, &testTask.ImageID, &testTask.BuildOS, &testTask.ImageFrom, &testTask.JobCtx.EnvVars, log)
+		if err != nil {
+			log.Errorf("[%s]expand test matrix error: %v", testModule.Name, err)
+			return resp, err
+		}
 	}
 	// 设置 build 安装脚本
 	testTask.InstallCtx, err = buildInstallCtx(testTask.InstallItems)
@@ -614,6 +626,7 @@ func TestArgsToTestSubtask(args *commonmodels.TestTaskArgs, pt *task.Task, log *
 	// Iterate test jobctx builds, and replace it if params specified from task.
 	// 外部触发的pipeline
 	_ = setManunalBuilds(testTask.JobCtx.Builds, testArg.Builds, log)
+	setTestCoverage(testTask, testModule, log)
 	return testTask, nil
 }
 
@@ -676,6 +689,7 @@ func GetTesting(name, productName string, log *zap.SugaredLogger) (*commonmodels
 				TestArgs:     v.TestArgs,
 				Type:         config.ScheduleType(v.JobType),
 				Cron:         v.Cron,
+				TimeZone:     v.TimeZone,
 				Enabled:      v.Enabled,
 			})
 		}
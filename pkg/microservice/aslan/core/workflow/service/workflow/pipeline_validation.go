@@ -77,7 +77,8 @@ func validatePipelineHookNames(p *commonmodels.Pipeline) error {
 	return validateHookNames(names)
 }
 
-func ensurePipeline(args *commonmodels.Pipeline, log *zap.SugaredLogger) error {
+// ensurePipeline current 为更新前的 pipeline，新建时为 nil
+func ensurePipeline(args, current *commonmodels.Pipeline, log *zap.SugaredLogger) error {
 	if !defaultNameRegex.MatchString(args.Name) {
 		log.Errorf("pipeline name must match %s", defaultNameRegexString)
 		return fmt.Errorf("%s %s", e.InvalidFormatErrMsg, defaultNameRegexString)
@@ -89,6 +90,18 @@ func ensurePipeline(args *commonmodels.Pipeline, log *zap.SugaredLogger) error {
 		}
 	}
 
+	if current != nil {
+		args.NotifyCtl.EnsureSecrets(current.NotifyCtl)
+		args.Slack.EnsureSecrets(current.Slack)
+	}
+	if err := args.NotifyCtl.Validate(); err != nil {
+		return err
+	}
+
+	if err := args.Slack.Validate(); err != nil {
+		return err
+	}
+
 	if err := validateSubTaskSetting(args.Name, args.SubTasks); err != nil {
 		log.Errorf("validateSubTaskSetting: %+v", err)
 		return err
@@ -355,6 +368,14 @@ func setBuildInfo(build *types.Repository, log *zap.SugaredLogger) {
 				}
 			}
 		}
+	} else if codeHostInfo.Type == systemconfig.GiteeProvider {
+		if build.CommitID == "" {
+			setGiteeBuildInfo(codeHostInfo, build, log)
+		}
+	} else if codeHostInfo.Type == systemconfig.BitbucketProvider {
+		if build.CommitID == "" {
+			setBitbucketBuildInfo(codeHostInfo, build, log)
+		}
 	} else {
 		gitCli := git.NewClient(codeHostInfo.AccessToken, config.ProxyHTTPSAddr())
 		//// 需要后端自动获取Branch当前Commit，并填写到build中
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Testing release chart", func() {
+	env := &commonmodels.Product{ProductName: "demo", EnvName: "dev"}
+	distributeStage := &commonmodels.DistributeStage{
+		Enabled:       true,
+		ChartRepoName: "charts",
+		Distributes:   []*commonmodels.ProductDistribute{{ChartDistribute: true}},
+	}
+
+	It("creates one release per service with the images of all its containers", func() {
+		releaseCharts := make(map[string]*task.ReleaseChart)
+		Expect(addReleaseCharts(releaseCharts, []commonmodels.DeployEnv{
+			{Env: "web/web", Type: setting.HelmDeployType},
+			{Env: "api/api", Type: setting.K8SDeployType},
+		}, env, distributeStage, nil, "web:v2")).To(Succeed())
+		Expect(addReleaseCharts(releaseCharts, []commonmodels.DeployEnv{
+			{Env: "web/nginx", Type: setting.HelmDeployType},
+		}, env, distributeStage, nil, "nginx:v2")).To(Succeed())
+
+		Expect(releaseCharts).To(HaveLen(1))
+		Expect(releaseCharts["web"].Images).To(Equal([]*task.ChartImage{
+			{ContainerName: "web", Image: "web:v2"},
+			{ContainerName: "nginx", Image: "nginx:v2"},
+		}))
+
+		stages := make([]*commonmodels.Stage, 0)
+		Expect(addReleaseChartsToStage(&stages, releaseCharts)).To(Succeed())
+		Expect(stages).To(HaveLen(1))
+		Expect(stages[0].TaskType).To(Equal(config.TaskReleaseChart))
+		Expect(stages[0].SubTasks).To(HaveKey("web"))
+	})
+
+	It("requires a SemVer version for charts", func() {
+		workflow := &commonmodels.Workflow{DistributeStage: distributeStage}
+		Expect(validateChartVersion(workflow, &commonmodels.VersionArgs{Enabled: true, Version: "1.2.0"})).To(Succeed())
+		Expect(validateChartVersion(workflow, &commonmodels.VersionArgs{Enabled: true, Version: "v1.0"})).NotTo(Succeed())
+		Expect(validateChartVersion(workflow, &commonmodels.VersionArgs{Enabled: false, Version: "v1.0"})).To(Succeed())
+		Expect(validateChartVersion(&commonmodels.Workflow{}, &commonmodels.VersionArgs{Enabled: true, Version: "v1.0"})).To(Succeed())
+	})
+})
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// SecurityReport 工作流任务中单个镜像的安全扫描报告
+type SecurityReport struct {
+	ImageName       string                           `json:"image_name"`
+	ImageID         string                           `json:"image_id"`
+	Status          config.Status                    `json:"status"`
+	Error           string                           `json:"error,omitempty"`
+	Summary         map[string]int                   `json:"summary"`
+	Thresholds      map[string]int                   `json:"thresholds,omitempty"`
+	Vulnerabilities []*commonmodels.DeliverySecurity `json:"vulnerabilities"`
+}
+
+// GetWorkflowTaskSecurityReport 返回工作流任务中所有安全扫描的完整结果，severity 不为空时只返回该等级的漏洞
+func GetWorkflowTaskSecurityReport(pipelineName string, taskID int64, severity string, log *zap.SugaredLogger) ([]*SecurityReport, error) {
+	t, err := commonrepo.NewTaskColl().Find(taskID, pipelineName, config.WorkflowType)
+	if err != nil {
+		log.Errorf("[%d:%s] find workflow task error: %v", taskID, pipelineName, err)
+		return nil, e.ErrGetTaskSecurityReport.AddDesc(e.FindPipelineTaskErrMsg)
+	}
+
+	reports := make([]*SecurityReport, 0)
+	for _, stage := range t.Stages {
+		if stage.TaskType != config.TaskSecurity {
+			continue
+		}
+		for _, subTask := range stage.SubTasks {
+			security, err := base.ToSecurityTask(subTask)
+			if err != nil {
+				log.Errorf("[%d:%s] convert security task error: %v", taskID, pipelineName, err)
+				return nil, e.ErrGetTaskSecurityReport.AddErr(err)
+			}
+
+			report := &SecurityReport{
+				ImageName:       security.ImageName,
+				ImageID:         security.ImageID,
+				Status:          security.TaskStatus,
+				Error:           security.Error,
+				Summary:         security.Summary,
+				Thresholds:      security.Thresholds,
+				Vulnerabilities: make([]*commonmodels.DeliverySecurity, 0),
+			}
+			// 扫描还没有结果
+			if security.ImageID == "" {
+				reports = append(reports, report)
+				continue
+			}
+
+			vulnerabilities, err := commonrepo.NewDeliverySecurityColl().Find(&commonrepo.DeliverySecurityArgs{
+				ImageID:  security.ImageID,
+				Severity: severity,
+			})
+			if err != nil {
+				log.Errorf("[%d:%s] find vulnerabilities of %s error: %v", taskID, pipelineName, security.ImageID, err)
+				return nil, e.ErrGetTaskSecurityReport.AddErr(err)
+			}
+			report.Vulnerabilities = vulnerabilities
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
@@ -40,12 +40,14 @@ var SubtaskOrder = map[config.TaskType]int{
 	config.TaskType("docker_build"):    5,
 	config.TaskType("archive"):         6,
 	config.TaskType("artifact"):        7,
-	config.TaskType("deploy"):          8,
-	config.TaskType("testingv2"):       9,
-	config.TaskType("security"):        10,
-	config.TaskType("distribute2kodo"): 11,
-	config.TaskType("release_image"):   12,
-	config.TaskType("reset_image"):     13,
+	config.TaskType("approval"):        8,
+	config.TaskType("deploy"):          9,
+	config.TaskType("testingv2"):       10,
+	config.TaskType("security"):        11,
+	config.TaskType("distribute2kodo"): 12,
+	config.TaskType("release_image"):   13,
+	config.TaskType("release_chart"):   14,
+	config.TaskType("reset_image"):     15,
 }
 
 type ByStageKind []*commonmodels.Stage
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	// flakyWindow 判断用例是否不稳定时只看最近的执行结果
+	flakyWindow = 20
+	// flakyFlipThreshold 窗口内结果在成功和失败之间切换的次数达到该值即认为用例不稳定
+	// 只切换一次通常是用例被修复或引入了回归
+	flakyFlipThreshold = 2
+	// testCaseResultLimit 读取用例历史时的最大记录数
+	testCaseResultLimit = 5000
+)
+
+// TestCaseHistory 单个用例最近的执行结果，按时间倒序排列
+type TestCaseHistory struct {
+	CaseName string                         `json:"case_name"`
+	Flaky    bool                           `json:"flaky"`
+	Flips    int                            `json:"flips"`
+	Failures int                            `json:"failures"`
+	Results  []*commonmodels.TestCaseResult `json:"results"`
+}
+
+// recordTestCaseResults 记录测试任务中每个用例的执行结果
+func recordTestCaseResults(pt *task.Task, testInfo *task.Testing, suite *commonmodels.TestSuite) error {
+	quarantined := sets.NewString(testInfo.QuarantinedCases...)
+	now := time.Now().Unix()
+
+	results := make([]*commonmodels.TestCaseResult, 0, len(suite.TestCases))
+	for _, tc := range suite.TestCases {
+		status := config.StatusPassed
+		if tc.Failed() {
+			status = config.StatusFailed
+		} else if tc.Skipped != nil {
+			status = config.StatusSkipped
+		}
+
+		results = append(results, &commonmodels.TestCaseResult{
+			TestName:     testInfo.TestModuleName,
+			CaseName:     tc.FullName(),
+			PipelineName: pt.PipelineName,
+			TaskID:       pt.TaskID,
+			Status:       status,
+			Quarantined:  quarantined.Has(tc.FullName()),
+			Duration:     tc.Time,
+			CreateTime:   now,
+		})
+	}
+
+	return commonrepo.NewTestCaseResultColl().Replace(pt.PipelineName, pt.TaskID, testInfo.TestModuleName, results)
+}
+
+// ListTestCaseHistory 返回测试中每个用例最近的执行结果，并标记在成功和失败之间反复切换的用例
+func ListTestCaseHistory(testName string, log *zap.SugaredLogger) ([]*TestCaseHistory, error) {
+	results, err := commonrepo.NewTestCaseResultColl().List(testName, testCaseResultLimit)
+	if err != nil {
+		log.Errorf("[%s] list test case results error: %v", testName, err)
+		return nil, e.ErrListTestCaseHistory.AddErr(err)
+	}
+
+	return buildTestCaseHistory(results), nil
+}
+
+// flakyTestCases 返回测试中被识别为不稳定的用例
+func flakyTestCases(testName string, log *zap.SugaredLogger) []string {
+	histories, err := ListTestCaseHistory(testName, log)
+	if err != nil {
+		return nil
+	}
+
+	var flaky []string
+	for _, history := range histories {
+		if history.Flaky {
+			flaky = append(flaky, history.CaseName)
+		}
+	}
+	return flaky
+}
+
+// quarantinedTestCases 合并测试手动隔离的用例，以及开启 QuarantineFlaky 时自动识别的不稳定用例
+func quarantinedTestCases(stage *commonmodels.TestStage, testName string, log *zap.SugaredLogger) []string {
+	if stage == nil {
+		return nil
+	}
+
+	cases := sets.NewString()
+	for _, test := range stage.Tests {
+		if test.Name == testName {
+			cases.Insert(test.QuarantinedCases...)
+		}
+	}
+	if stage.QuarantineFlaky {
+		cases.Insert(flakyTestCases(testName, log)...)
+	}
+	return cases.List()
+}
+
+// buildTestCaseHistory results 需要按时间倒序排列
+func buildTestCaseHistory(results []*commonmodels.TestCaseResult) []*TestCaseHistory {
+	historyMap := make(map[string]*TestCaseHistory)
+	for _, result := range results {
+		history, ok := historyMap[result.CaseName]
+		if !ok {
+			history = &TestCaseHistory{CaseName: result.CaseName}
+			historyMap[result.CaseName] = history
+		}
+		if len(history.Results) >= flakyWindow {
+			continue
+		}
+		history.Results = append(history.Results, result)
+	}
+
+	histories := make([]*TestCaseHistory, 0, len(historyMap))
+	for _, history := range historyMap {
+		history.Flips = countFlips(history.Results)
+		history.Flaky = history.Flips >= flakyFlipThreshold
+		for _, result := range history.Results {
+			if result.Status == config.StatusFailed {
+				history.Failures++
+			}
+		}
+		histories = append(histories, history)
+	}
+
+	sort.Slice(histories, func(i, j int) bool {
+		if histories[i].Flaky != histories[j].Flaky {
+			return histories[i].Flaky
+		}
+		return histories[i].CaseName < histories[j].CaseName
+	})
+	return histories
+}
+
+// countFlips 统计结果在成功和失败之间切换的次数，跳过的用例不参与统计
+func countFlips(results []*commonmodels.TestCaseResult) int {
+	flips := 0
+	var last config.Status
+	for _, result := range results {
+		if result.Status != config.StatusPassed && result.Status != config.StatusFailed {
+			continue
+		}
+		if last != "" && result.Status != last {
+			flips++
+		}
+		last = result.Status
+	}
+	return flips
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func caseResults(caseName string, statuses ...config.Status) []*commonmodels.TestCaseResult {
+	results := make([]*commonmodels.TestCaseResult, 0, len(statuses))
+	for _, status := range statuses {
+		results = append(results, &commonmodels.TestCaseResult{CaseName: caseName, Status: status})
+	}
+	return results
+}
+
+var _ = Describe("Testing test case history", func() {
+
+	Context("countFlips", func() {
+		It("should ignore skipped results", func() {
+			results := caseResults("a", config.StatusPassed, config.StatusSkipped, config.StatusPassed, config.StatusFailed)
+			Expect(countFlips(results)).To(Equal(1))
+		})
+		It("should count every change between passed and failed", func() {
+			results := caseResults("a", config.StatusPassed, config.StatusFailed, config.StatusPassed, config.StatusFailed)
+			Expect(countFlips(results)).To(Equal(3))
+		})
+	})
+
+	Context("buildTestCaseHistory", func() {
+		It("should not flag a regression as flaky", func() {
+			histories := buildTestCaseHistory(caseResults("a", config.StatusFailed, config.StatusFailed, config.StatusPassed, config.StatusPassed))
+			Expect(histories).To(HaveLen(1))
+			Expect(histories[0].Flaky).To(BeFalse())
+			Expect(histories[0].Failures).To(Equal(2))
+		})
+		It("should flag cases that flip between passed and failed and list them first", func() {
+			results := append(caseResults("a", config.StatusPassed, config.StatusPassed),
+				caseResults("b", config.StatusFailed, config.StatusPassed, config.StatusFailed)...)
+			histories := buildTestCaseHistory(results)
+			Expect(histories).To(HaveLen(2))
+			Expect(histories[0].CaseName).To(Equal("b"))
+			Expect(histories[0].Flaky).To(BeTrue())
+			Expect(histories[1].Flaky).To(BeFalse())
+		})
+		It("should only keep the latest results of each case", func() {
+			statuses := make([]config.Status, 0, flakyWindow+5)
+			for i := 0; i < flakyWindow; i++ {
+				statuses = append(statuses, config.StatusPassed)
+			}
+			statuses = append(statuses, config.StatusFailed, config.StatusPassed, config.StatusFailed)
+			histories := buildTestCaseHistory(caseResults("a", statuses...))
+			Expect(histories[0].Results).To(HaveLen(flakyWindow))
+			Expect(histories[0].Flaky).To(BeFalse())
+		})
+	})
+})
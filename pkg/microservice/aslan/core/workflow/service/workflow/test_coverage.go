@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// testCoverageLimit 读取覆盖率历史时的最大记录数
+const testCoverageLimit = 100
+
+// setTestCoverage 设置覆盖率报告路径和门禁，PR 触发时带上目标分支最近一次的覆盖率用于对比
+func setTestCoverage(testTask *task.Testing, testModule *commonmodels.Testing, log *zap.SugaredLogger) {
+	testTask.JobCtx.CoverageReportPath = testModule.CoverageReportPath
+	testTask.CoverageGate = testModule.CoverageGate
+	if testModule.CoverageReportPath == "" {
+		return
+	}
+
+	repo := coverageRepo(testTask.JobCtx.Builds)
+	if repo == nil || repo.PR == 0 {
+		return
+	}
+
+	base, err := commonrepo.NewTestCoverageColl().FindLatestBase(testModule.Name, repo.RepoOwner, repo.RepoName, repo.Branch)
+	if err != nil {
+		log.Infof("[%s] no base coverage found on %s/%s:%s: %v", testModule.Name, repo.RepoOwner, repo.RepoName, repo.Branch, err)
+		return
+	}
+	testTask.BaseCoverage = base.Coverage
+}
+
+// recordTestCoverage 记录测试任务的覆盖率
+func recordTestCoverage(pt *task.Task, testInfo *task.Testing) error {
+	coverage := &commonmodels.TestCoverage{
+		TestName:     testInfo.TestModuleName,
+		PipelineName: pt.PipelineName,
+		TaskID:       pt.TaskID,
+		Coverage:     testInfo.Coverage,
+		CreateTime:   time.Now().Unix(),
+	}
+	if repo := coverageRepo(testInfo.JobCtx.Builds); repo != nil {
+		coverage.RepoOwner = repo.RepoOwner
+		coverage.RepoName = repo.RepoName
+		coverage.Branch = repo.Branch
+		coverage.PR = repo.PR
+	}
+
+	return commonrepo.NewTestCoverageColl().Upsert(coverage)
+}
+
+// ListTestCoverage 按时间倒序返回测试最近的覆盖率
+func ListTestCoverage(testName string, log *zap.SugaredLogger) ([]*commonmodels.TestCoverage, error) {
+	coverages, err := commonrepo.NewTestCoverageColl().List(testName, testCoverageLimit)
+	if err != nil {
+		log.Errorf("[%s] list test coverage error: %v", testName, err)
+		return nil, e.ErrListTestCoverage.AddErr(err)
+	}
+	return coverages, nil
+}
+
+// coverageRepo 覆盖率以测试的第一个代码库作为主库进行对比
+func coverageRepo(builds []*types.Repository) *types.Repository {
+	if len(builds) == 0 {
+		return nil
+	}
+	return builds[0]
+}
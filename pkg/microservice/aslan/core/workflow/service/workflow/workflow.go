@@ -249,6 +249,7 @@ func FindWorkflow(workflowName string, log *zap.SugaredLogger) (*commonmodels.Wo
 				TestArgs:     v.TestArgs,
 				Type:         config.ScheduleType(v.JobType),
 				Cron:         v.Cron,
+				TimeZone:     v.TimeZone,
 				Enabled:      v.Enabled,
 			})
 		}
@@ -258,6 +259,9 @@ func FindWorkflow(workflowName string, log *zap.SugaredLogger) (*commonmodels.Wo
 		}
 		resp.Schedules = &schedule
 	}
+	resp.NotifyCtl.MaskSecrets()
+	resp.Slack.MaskSecrets()
+
 	return resp, nil
 }
 
@@ -364,6 +368,26 @@ func CreateWorkflow(workflow *commonmodels.Workflow, log *zap.SugaredLogger) err
 		return e.ErrUpsertWorkflow.AddDesc(err.Error())
 	}
 
+	if err := workflow.RolloutStrategy.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.ApprovalStage.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.SecurityStage.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.NotifyCtl.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.Slack.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
 	err = commonservice.ProcessWebhook(workflow.HookCtl.Items, nil, webhook.WorkflowPrefix+workflow.Name, log)
 	if err != nil {
 		log.Errorf("Failed to process webhook, err: %s", err)
@@ -407,6 +431,9 @@ func checkWorkflowSubModule(workflow *commonmodels.Workflow) bool {
 	if workflow.DistributeStage != nil && workflow.DistributeStage.Enabled {
 		return true
 	}
+	if workflow.ApprovalStage != nil && workflow.ApprovalStage.Enabled {
+		return true
+	}
 	if workflow.NotifyCtl != nil && workflow.NotifyCtl.Enabled {
 		return true
 	}
@@ -432,6 +459,28 @@ func UpdateWorkflow(workflow *commonmodels.Workflow, log *zap.SugaredLogger) err
 		return e.ErrUpsertWorkflow.AddDesc(err.Error())
 	}
 
+	if err := workflow.RolloutStrategy.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.ApprovalStage.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.SecurityStage.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	workflow.NotifyCtl.EnsureSecrets(currentWorkflow.NotifyCtl)
+	workflow.Slack.EnsureSecrets(currentWorkflow.Slack)
+	if err := workflow.NotifyCtl.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
+	if err := workflow.Slack.Validate(); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+
 	err = commonservice.ProcessWebhook(workflow.HookCtl.Items, currentWorkflow.HookCtl.Items, webhook.WorkflowPrefix+workflow.Name, log)
 	if err != nil {
 		log.Errorf("Failed to process webhook, err: %s", err)
@@ -496,6 +545,8 @@ func ListWorkflows(queryType string, productName string, userID string, log *zap
 	}
 
 	for _, workflow := range workflows {
+		workflow.NotifyCtl.MaskSecrets()
+		workflow.Slack.MaskSecrets()
 		if queryType == "artifact" {
 			if workflow.ArtifactStage == nil || !workflow.ArtifactStage.Enabled {
 				continue
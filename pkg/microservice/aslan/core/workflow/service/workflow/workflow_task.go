@@ -26,6 +26,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/blang/semver/v4"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -518,9 +519,15 @@ func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string,
 		return nil, e.ErrCreateTask.AddErr(err)
 	}
 
+	if err := validateChartVersion(workflow, args.VersionArgs); err != nil {
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+
 	stages := make([]*commonmodels.Stage, 0)
+	releaseCharts := make(map[string]*task.ReleaseChart)
 	for _, target := range args.Target {
 		var subTasks []map[string]interface{}
+		var chartDeploys []commonmodels.DeployEnv
 		var err error
 		if target.JenkinsBuildArgs == nil {
 			buildModuleArgs := &commonmodels.BuildModuleArgs{
@@ -550,7 +557,7 @@ func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string,
 				if deployEnv.Type == setting.PMDeployType {
 					continue
 				}
-				deployTask, err := deployEnvToSubTasks(deployEnv, env, productTempl.Timeout)
+				deployTask, err := deployEnvToSubTasks(deployEnv, env, productTempl.Timeout, workflow.RolloutStrategy, workflow.AutoRollback)
 				if err != nil {
 					log.Errorf("deploy env to subtask error: %v", err)
 					return nil, e.ErrCreateTask.AddErr(err)
@@ -597,6 +604,9 @@ func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string,
 						log.Errorf("distrbiute stages to subtasks error: %v", err)
 						return nil, e.ErrCreateTask.AddErr(err)
 					}
+					if distribute.ChartDistribute && env != nil {
+						chartDeploys = target.Deploy
+					}
 				}
 			}
 			subTasks = append(subTasks, distributeTasks...)
@@ -613,7 +623,7 @@ func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string,
 		}
 
 		if workflow.SecurityStage != nil && workflow.SecurityStage.Enabled {
-			securityTask, err := addSecurityToSubTasks()
+			securityTask, err := addSecurityToSubTasks(workflow.SecurityStage)
 			if err != nil {
 				log.Errorf("add security task error: %v", err)
 				return nil, e.ErrCreateTask.AddErr(err)
@@ -661,6 +671,23 @@ func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string,
 		for _, stask := range task.SubTasks {
 			AddSubtaskToStage(&stages, stask, target.Name)
 		}
+		if err := addReleaseCharts(releaseCharts, chartDeploys, env, workflow.DistributeStage, args.VersionArgs, task.TaskArgs.Deploy.Image); err != nil {
+			log.Errorf("add release chart error: %v", err)
+			return nil, e.ErrCreateTask.AddErr(err)
+		}
+	}
+	if err := addReleaseChartsToStage(&stages, releaseCharts); err != nil {
+		log.Errorf("release chart to subtasks error: %v", err)
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+
+	approvalTask, err := approvalToSubTask(workflow, env, stages)
+	if err != nil {
+		log.Errorf("approvalToSubTask workflow:[%s] err:%v", workflow.Name, err)
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+	if approvalTask != nil {
+		AddSubtaskToStage(&stages, approvalTask, approvalTarget)
 	}
 
 	testTask := &task.Task{
@@ -676,6 +703,7 @@ func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string,
 
 	for _, testTask := range testTasks {
 		FmtBuilds(testTask.JobCtx.Builds, log)
+		testTask.QuarantinedCases = quarantinedTestCases(workflow.TestStage, testTask.TestModuleName, log)
 		testSubTask, err := testTask.ToSubTask()
 		if err != nil {
 			log.Errorf("workflow_task ToSubTask err:%v", err)
@@ -990,17 +1018,19 @@ func GetS3Storage(id string, logger *zap.SugaredLogger) (*commonmodels.S3Storage
 	return store, nil
 }
 
-func deployEnvToSubTasks(env commonmodels.DeployEnv, prodEnv *commonmodels.Product, timeout int) (map[string]interface{}, error) {
+func deployEnvToSubTasks(env commonmodels.DeployEnv, prodEnv *commonmodels.Product, timeout int, rollout *commonmodels.RolloutStrategy, autoRollback bool) (map[string]interface{}, error) {
 	var (
 		resp       map[string]interface{}
 		deployTask = task.Deploy{
-			TaskType:    config.TaskDeploy,
-			Enabled:     true,
-			Namespace:   prodEnv.Namespace,
-			ProductName: prodEnv.ProductName,
-			EnvName:     prodEnv.EnvName,
-			Timeout:     timeout,
-			ClusterID:   prodEnv.ClusterID,
+			TaskType:        config.TaskDeploy,
+			Enabled:         true,
+			Namespace:       prodEnv.Namespace,
+			ProductName:     prodEnv.ProductName,
+			EnvName:         prodEnv.EnvName,
+			Timeout:         timeout,
+			ClusterID:       prodEnv.ClusterID,
+			RolloutStrategy: rollout,
+			AutoRollback:    autoRollback,
 		}
 	)
 
@@ -1094,6 +1124,83 @@ func formatDistributeSubtasks(releaseImages []commonmodels.RepoImage, imageRepo,
 	return resp, nil
 }
 
+// validateChartVersion 开启版本且推送 chart 时，版本号作为 chart 版本，必须是合法的 SemVer（如 1.2.0，不能是 v1.0）
+func validateChartVersion(workflow *commonmodels.Workflow, versionArgs *commonmodels.VersionArgs) error {
+	if versionArgs == nil || !versionArgs.Enabled || workflow.DistributeStage == nil || !workflow.DistributeStage.Enabled {
+		return nil
+	}
+	for _, distribute := range workflow.DistributeStage.Distributes {
+		if !distribute.ChartDistribute {
+			continue
+		}
+		if _, err := semver.Parse(versionArgs.Version); err != nil {
+			return fmt.Errorf("version %q is not a valid chart version, a SemVer like 1.2.0 is required: %v", versionArgs.Version, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// addReleaseCharts 按服务汇总部署到 helm 环境的容器及其镜像，同一服务的所有容器推送到同一个 chart 中
+func addReleaseCharts(releaseCharts map[string]*task.ReleaseChart, deployEnvs []commonmodels.DeployEnv, prodEnv *commonmodels.Product,
+	distributeStage *commonmodels.DistributeStage, versionArgs *commonmodels.VersionArgs, image string) error {
+	if distributeStage == nil || distributeStage.ChartRepoName == "" || prodEnv == nil {
+		return nil
+	}
+
+	var version string
+	if versionArgs != nil && versionArgs.Enabled {
+		version = versionArgs.Version
+	}
+
+	for _, deployEnv := range deployEnvs {
+		if deployEnv.Type != setting.HelmDeployType {
+			continue
+		}
+		envList := strings.Split(deployEnv.Env, "/")
+		if len(envList) != 2 {
+			return fmt.Errorf("[%s]split target env error", deployEnv.Env)
+		}
+
+		serviceName := envList[0]
+		if _, ok := releaseCharts[serviceName]; !ok {
+			releaseCharts[serviceName] = &task.ReleaseChart{
+				TaskType:      config.TaskReleaseChart,
+				Enabled:       true,
+				ProductName:   prodEnv.ProductName,
+				EnvName:       prodEnv.EnvName,
+				ServiceName:   serviceName,
+				ChartRepoName: distributeStage.ChartRepoName,
+				ChartVersion:  version,
+			}
+		}
+		releaseCharts[serviceName].AddImage(envList[1], image)
+	}
+	return nil
+}
+
+// addReleaseChartsToStage 每个服务生成一个 chart 推送任务
+func addReleaseChartsToStage(stages *[]*commonmodels.Stage, releaseCharts map[string]*task.ReleaseChart) error {
+	serviceNames := make([]string, 0, len(releaseCharts))
+	for serviceName := range releaseCharts {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	for _, serviceName := range serviceNames {
+		releaseChart := releaseCharts[serviceName]
+		if len(releaseChart.Images) == 0 {
+			continue
+		}
+		subTask, err := releaseChart.ToSubTask()
+		if err != nil {
+			return err
+		}
+		AddSubtaskToStage(stages, subTask, serviceName)
+	}
+	return nil
+}
+
 func AddJiraSubTask(moduleName, target, serviceName, productName string, log *zap.SugaredLogger) (map[string]interface{}, error) {
 	repos := make([]*types.Repository, 0)
 
@@ -1122,8 +1229,8 @@ func AddJiraSubTask(moduleName, target, serviceName, productName string, log *za
 	return jira.ToSubTask()
 }
 
-func addSecurityToSubTasks() (map[string]interface{}, error) {
-	securityTask := task.Security{TaskType: config.TaskSecurity, Enabled: true}
+func addSecurityToSubTasks(stage *commonmodels.SecurityStage) (map[string]interface{}, error) {
+	securityTask := task.Security{TaskType: config.TaskSecurity, Enabled: true, Thresholds: stage.Thresholds}
 	return securityTask.ToSubTask()
 }
 
@@ -1222,6 +1329,12 @@ func testArgsToSubtask(args *commonmodels.WorkflowTaskArgs, pt *task.Task, log *
 				}
 			}
 			testTask.ResReq = testModule.PreTest.ResReq
+
+			testTask.Matrix, err = applyMatrix(testModule.Matrix, &testTask.ImageID, &testTask.BuildOS, &testTask.ImageFrom, &testTask.JobCtx.EnvVars, log)
+			if err != nil {
+				log.Errorf("[%s]expand test matrix error: %v", testModule.Name, err)
+				return resp, err
+			}
 		}
 		// 设置 build 安装脚本
 		testTask.InstallCtx, err = buildInstallCtx(testTask.InstallItems)
@@ -1237,6 +1350,7 @@ func testArgsToSubtask(args *commonmodels.WorkflowTaskArgs, pt *task.Task, log *
 		} else {
 			_ = setManunalBuilds(testTask.JobCtx.Builds, testArg.Builds, log)
 		}
+		setTestCoverage(testTask, testModule, log)
 
 		resp = append(resp, testTask)
 	}
@@ -1301,9 +1415,15 @@ func CreateArtifactWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator
 		return nil, err
 	}
 
+	if err := validateChartVersion(workflow, args.VersionArgs); err != nil {
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+
 	stages := make([]*commonmodels.Stage, 0)
+	releaseCharts := make(map[string]*task.ReleaseChart)
 	for _, artifact := range args.Artifact {
 		subTasks := make([]map[string]interface{}, 0)
+		var chartDeploys []commonmodels.DeployEnv
 		// image artifact deploy
 		if artifact.Image != "" {
 			artifactSubtask, err := artifactToSubTasks(artifact.Name, artifact.Image)
@@ -1315,7 +1435,7 @@ func CreateArtifactWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator
 			if env != nil {
 				// 生成部署的subtask
 				for _, deployEnv := range artifact.Deploy {
-					deployTask, err := deployEnvToSubTasks(deployEnv, env, productTempl.Timeout)
+					deployTask, err := deployEnvToSubTasks(deployEnv, env, productTempl.Timeout, workflow.RolloutStrategy, workflow.AutoRollback)
 					if err != nil {
 						log.Errorf("deploy env to subtask error: %v", err)
 						return nil, err
@@ -1380,13 +1500,16 @@ func CreateArtifactWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator
 						log.Errorf("distrbiute stages to subtasks error: %v", err)
 						return nil, err
 					}
+					if distribute.ChartDistribute && env != nil {
+						chartDeploys = artifact.Deploy
+					}
 				}
 			}
 			subTasks = append(subTasks, distributeTasks...)
 		}
 
 		if workflow.SecurityStage != nil && workflow.SecurityStage.Enabled {
-			securityTask, err := addSecurityToSubTasks()
+			securityTask, err := addSecurityToSubTasks(workflow.SecurityStage)
 			if err != nil {
 				log.Errorf("add security task error: %v", err)
 				return nil, err
@@ -1433,6 +1556,23 @@ func CreateArtifactWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator
 		for _, stask := range task.SubTasks {
 			AddSubtaskToStage(&stages, stask, artifact.Name)
 		}
+		if err := addReleaseCharts(releaseCharts, chartDeploys, env, workflow.DistributeStage, args.VersionArgs, task.TaskArgs.Deploy.Image); err != nil {
+			log.Errorf("add release chart error: %v", err)
+			return nil, e.ErrCreateTask.AddErr(err)
+		}
+	}
+	if err := addReleaseChartsToStage(&stages, releaseCharts); err != nil {
+		log.Errorf("release chart to subtasks error: %v", err)
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+
+	approvalTask, err := approvalToSubTask(workflow, env, stages)
+	if err != nil {
+		log.Errorf("approvalToSubTask workflow:[%s] err:%v", workflow.Name, err)
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+	if approvalTask != nil {
+		AddSubtaskToStage(&stages, approvalTask, approvalTarget)
 	}
 
 	testTask := &task.Task{
@@ -1448,6 +1588,7 @@ func CreateArtifactWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator
 
 	for _, testTask := range testTasks {
 		FmtBuilds(testTask.JobCtx.Builds, log)
+		testTask.QuarantinedCases = quarantinedTestCases(workflow.TestStage, testTask.TestModuleName, log)
 		testSubTask, err := testTask.ToSubTask()
 		if err != nil {
 			log.Errorf("workflow_task ToSubTask err:%v", err)
@@ -1644,6 +1785,12 @@ func BuildModuleToSubTasks(args *commonmodels.BuildModuleArgs, log *zap.SugaredL
 			build.JobCtx.SSHs = privateKeys
 		}
 
+		// 分批部署时每批主机部署完成后执行服务的健康检查
+		if module.PMDeployStrategy != nil && module.PMDeployStrategy.Enabled && build.ServiceType == setting.PMDeployType {
+			build.JobCtx.PMDeployStrategy = module.PMDeployStrategy
+			build.JobCtx.PMHealthChecks = serviceTmpl.HealthChecks
+		}
+
 		build.JobCtx.EnvVars = module.PreBuild.Envs
 
 		if len(module.PreBuild.Envs) == 0 {
@@ -1662,6 +1809,14 @@ func BuildModuleToSubTasks(args *commonmodels.BuildModuleArgs, log *zap.SugaredL
 			}
 		}
 
+		// 交付物部署不运行构建，不需要展开构建矩阵
+		if args.TaskType == "" {
+			build.Matrix, err = applyMatrix(module.Matrix, &build.ImageID, &build.BuildOS, &build.ImageFrom, &build.JobCtx.EnvVars, log)
+			if err != nil {
+				return subTasks, e.ErrConvertSubTasks.AddErr(err)
+			}
+		}
+
 		build.JobCtx.UploadPkg = module.PreBuild.UploadPkg
 		build.JobCtx.CleanWorkspace = module.PreBuild.CleanWorkspace
 		build.JobCtx.EnableProxy = module.PreBuild.EnableProxy
@@ -1687,6 +1842,8 @@ func BuildModuleToSubTasks(args *commonmodels.BuildModuleArgs, log *zap.SugaredL
 		}
 
 		build.JobCtx.Caches = module.Caches
+		build.JobCtx.CacheKeyFiles = module.CacheKeyFiles
+		build.JobCtx.CacheSizeLimit = buildCacheLimit(module.ProductName)
 
 		if args.FileName != "" {
 			build.ArtifactInfo = &task.ArtifactInfo{
@@ -1707,6 +1864,15 @@ func BuildModuleToSubTasks(args *commonmodels.BuildModuleArgs, log *zap.SugaredL
 	return subTasks, nil
 }
 
+// buildCacheLimit 返回项目的构建缓存大小上限，单位 MB，未配置时由 reaper 使用默认值
+func buildCacheLimit(productName string) int64 {
+	productTmpl, err := template.NewProductColl().Find(productName)
+	if err != nil {
+		return 0
+	}
+	return productTmpl.BuildCacheLimit
+}
+
 func extractHostIPs(privateKeys []*commonmodels.PrivateKey, ips sets.String) sets.String {
 	for _, privateKey := range privateKeys {
 		ips.Insert(privateKey.IP)
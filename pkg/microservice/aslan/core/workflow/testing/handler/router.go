@@ -50,6 +50,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		tester.PUT("", GetTestProductName, gin2.UpdateOperationLogStatus, UpdateTestModule)
 		tester.GET("", ListTestModules)
 		tester.GET("/:name", GetTestModule)
+		tester.GET("/:name/cases", ListTestCaseHistory)
+		tester.GET("/:name/coverage", ListTestCoverage)
 		tester.DELETE("/:name", gin2.UpdateOperationLogStatus, DeleteTestModule)
 	}
 
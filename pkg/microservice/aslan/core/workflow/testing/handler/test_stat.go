@@ -21,6 +21,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func ListTestStat(c *gin.Context) {
@@ -29,3 +30,29 @@ func ListTestStat(c *gin.Context) {
 
 	ctx.Resp, ctx.Err = service.ListTestStat(ctx.Logger)
 }
+
+func ListTestCaseHistory(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	name := c.Param("name")
+	if name == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty Name")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListTestCaseHistory(name, ctx.Logger)
+}
+
+func ListTestCoverage(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	name := c.Param("name")
+	if name == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty Name")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListTestCoverage(name, ctx.Logger)
+}
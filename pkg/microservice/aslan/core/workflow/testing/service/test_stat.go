@@ -21,6 +21,7 @@ import (
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 )
 
 func ListTestStat(log *zap.SugaredLogger) ([]*commonmodels.TestTaskStat, error) {
@@ -31,3 +32,11 @@ func ListTestStat(log *zap.SugaredLogger) ([]*commonmodels.TestTaskStat, error)
 	}
 	return testStat, nil
 }
+
+func ListTestCaseHistory(testName string, log *zap.SugaredLogger) ([]*workflowservice.TestCaseHistory, error) {
+	return workflowservice.ListTestCaseHistory(testName, log)
+}
+
+func ListTestCoverage(testName string, log *zap.SugaredLogger) ([]*commonmodels.TestCoverage, error) {
+	return workflowservice.ListTestCoverage(testName, log)
+}
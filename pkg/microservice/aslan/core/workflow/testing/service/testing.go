@@ -43,6 +43,9 @@ func CreateTesting(username string, testing *commonmodels.Testing, log *zap.Suga
 	if len(testing.Name) == 0 {
 		return e.ErrCreateTestModule.AddDesc("empty Name")
 	}
+	if err := testing.CoverageGate.Validate(); err != nil {
+		return e.ErrCreateTestModule.AddErr(err)
+	}
 
 	err := HandleCronjob(testing, log)
 	if err != nil {
@@ -96,6 +99,9 @@ func UpdateTesting(username string, testing *commonmodels.Testing, log *zap.Suga
 	if len(testing.Name) == 0 {
 		return e.ErrUpdateTestModule.AddDesc("empty Name")
 	}
+	if err := testing.CoverageGate.Validate(); err != nil {
+		return e.ErrUpdateTestModule.AddErr(err)
+	}
 
 	err := HandleCronjob(testing, log)
 	if err != nil {
@@ -234,6 +240,7 @@ func GetTesting(name, productName string, log *zap.SugaredLogger) (*commonmodels
 				TestArgs:     v.TestArgs,
 				Type:         config.ScheduleType(v.JobType),
 				Cron:         v.Cron,
+				TimeZone:     v.TimeZone,
 				Enabled:      v.Enabled,
 			})
 		}
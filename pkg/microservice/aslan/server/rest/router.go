@@ -36,6 +36,7 @@ import (
 	templatehandler "github.com/koderover/zadig/pkg/microservice/aslan/core/templatestore/handler"
 	workflowhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/handler"
 	testinghandler "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/handler"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 
 	// Note: have to load docs for swagger to work. See https://blog.csdn.net/weixin_43249914/article/details/103035711
 	_ "github.com/koderover/zadig/pkg/microservice/aslan/server/rest/doc"
@@ -68,6 +69,8 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 
 	router.GET("/api/kodespace/downloadUrl", commonhandler.GetToolDownloadURL)
 
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	for name, r := range map[string]injector{
 		"/api/project":     new(projecthandler.Router),
 		"/api/code":        new(codehosthandler.Router),
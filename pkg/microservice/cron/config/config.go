@@ -32,3 +32,11 @@ func CollieAPI() string {
 func NsqLookupAddrs() []string {
 	return strings.Split(viper.GetString(setting.ENVNsqLookupAddrs), ",")
 }
+
+func PodName() string {
+	return viper.GetString(setting.ENVPodName)
+}
+
+func Namespace() string {
+	return viper.GetString(setting.ENVNamespace)
+}
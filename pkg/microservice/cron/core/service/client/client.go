@@ -19,6 +19,9 @@ package client
 import (
 	"net/http"
 	"net/http/cookiejar"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 )
 
 type Client struct {
@@ -32,8 +35,19 @@ func NewAslanClient(host string) *Client {
 
 	c := &Client{
 		APIBase: host,
-		Conn:    &http.Client{Transport: http.DefaultTransport, Jar: jar},
+		Conn:    &http.Client{Transport: &internalTransport{base: http.DefaultTransport}, Jar: jar},
 	}
 
 	return c
 }
+
+// internalTransport 为发往 aslan 的请求加上内部调用凭证，aslan 据此识别定时任务触发的内部调用
+type internalTransport struct {
+	base http.RoundTripper
+}
+
+func (t *internalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(setting.InternalTokenHeader, config.InternalToken())
+	return t.base.RoundTrip(req)
+}
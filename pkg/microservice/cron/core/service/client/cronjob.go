@@ -66,6 +66,16 @@ func (c *Client) TriggerCleanProducts(log *zap.SugaredLogger) error {
 	return err
 }
 
+// TriggerApprovalTimeout ...
+func (c *Client) TriggerApprovalTimeout(log *zap.SugaredLogger) error {
+	url := fmt.Sprintf("%s/workflow/cron/approvaltimeout", c.APIBase)
+	err := c.sendRequest(url)
+	if err != nil {
+		log.Errorf("trigger approval timeout error :%v", err)
+	}
+	return err
+}
+
 // RunPipelineTask ...
 func (c *Client) RunPipelineTask(args *service.TaskArgs, log *zap.SugaredLogger) error {
 	url := fmt.Sprintf("%s/workflow/v2/tasks", c.APIBase)
@@ -33,6 +33,7 @@ type Cronjob struct {
 	Frequency    string            `json:"frequency"`
 	Time         string            `json:"time"`
 	Cron         string            `json:"cron"`
+	TimeZone     string            `json:"time_zone,omitempty"`
 	ProductName  string            `json:"product_name,omitempty"`
 	MaxFailure   int               `json:"max_failures,omitempty"`
 	TaskArgs     *TaskArgs         `json:"task_args,omitempty"`
@@ -31,6 +31,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service/client"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/cron"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/util"
@@ -41,6 +42,9 @@ const (
 	PullInterval        = 3 * time.Second
 )
 
+// zonedScheduler 调度指定了时区的 cron 表达式，cronlib 只能按服务所在时区计算触发时间
+var zonedScheduler = cron.NewScheduler()
+
 type CronjobHandler struct {
 	aslanCli  *client.Client
 	Scheduler *cronlib.CronSchduler
@@ -158,28 +162,22 @@ func (h *CronjobHandler) updateCronjob(name, productName, jobType string, jobLis
 	for _, deleteID := range deleteList {
 		jobID := deleteID
 		log.Infof("stopping Job of ID: %s", jobID)
-		h.Scheduler.StopService(jobID)
+		stopJob(h.Scheduler, jobID)
 	}
 	// 根据job内容来在scheduler中新增cronjob
 	for _, job := range jobList {
-		var cron string
-		if job.Type == setting.FixedGapCronjob || job.Type == setting.FixedDayTimeCronjob {
-			cronString, err := convertFixedTimeToCron(job)
-			if err != nil {
-				return err
-			}
-			cron = cronString
-		} else {
-			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
+		spec, zoned, err := jobSchedule(string(job.Type), job.Time, job.Frequency, job.Cron, job.TimeZone, job.Number)
+		if err != nil {
+			return err
 		}
 		switch jobType {
 		case setting.WorkflowCronjob:
-			err := h.registerWorkFlowJob(name, cron, job)
+			err := h.registerWorkFlowJob(name, spec, zoned, job)
 			if err != nil {
 				return err
 			}
 		case setting.TestingCronjob:
-			err := h.registerTestJob(name, productName, cron, job)
+			err := h.registerTestJob(name, productName, spec, zoned, job)
 			if err != nil {
 				return err
 			}
@@ -190,8 +188,43 @@ func (h *CronjobHandler) updateCronjob(name, productName, jobType string, jobLis
 	return nil
 }
 
-func convertFixedTimeToCron(job *service.Schedule) (string, error) {
-	return convertCronString(string(job.Type), job.Time, job.Frequency, job.Number)
+// jobSchedule 返回注册到 cronlib 的表达式，指定了时区的 cron 表达式同时返回解析后的 schedule，
+// 由 zonedScheduler 按该时区调度
+func jobSchedule(jobType, jobTime, frequency, cronExpr, timeZone string, number uint64) (string, *cron.Schedule, error) {
+	if jobType != setting.CrontabCronjob {
+		spec, err := convertCronString(jobType, jobTime, frequency, number)
+		return spec, nil, err
+	}
+
+	schedule, err := cron.Parse(cronExpr, timeZone)
+	if err != nil {
+		return "", nil, err
+	}
+	if timeZone == "" {
+		return schedule.Spec(), nil, nil
+	}
+	return schedule.Spec(), schedule, nil
+}
+
+// registerJob 注册或替换任务，同一个任务只会存在于其中一个调度器中
+func registerJob(scheduler *cronlib.CronSchduler, id, spec string, zoned *cron.Schedule, f func()) error {
+	if zoned != nil {
+		scheduler.StopService(id)
+		zonedScheduler.Register(id, zoned, f)
+		return nil
+	}
+
+	zonedScheduler.Stop(id)
+	scheduleJob, err := cronlib.NewJobModel(spec, f)
+	if err != nil {
+		return err
+	}
+	return scheduler.UpdateJobModel(id, scheduleJob)
+}
+
+func stopJob(scheduler *cronlib.CronSchduler, id string) {
+	scheduler.StopService(id)
+	zonedScheduler.Stop(id)
 }
 
 func convertCronString(jobType, time, frequency string, number uint64) (string, error) {
@@ -236,7 +269,7 @@ func convertCronString(jobType, time, frequency string, number uint64) (string,
 	return buf.String(), nil
 }
 
-func (h *CronjobHandler) registerWorkFlowJob(name, schedule string, job *service.Schedule) error {
+func (h *CronjobHandler) registerWorkFlowJob(name, schedule string, zoned *cron.Schedule, job *service.Schedule) error {
 	args := &service.WorkflowTaskArgs{
 		WorkflowName:       name,
 		WorklowTaskCreator: setting.CronTaskCreator,
@@ -249,18 +282,12 @@ func (h *CronjobHandler) registerWorkFlowJob(name, schedule string, job *service
 		args.Tests = job.WorkflowArgs.Tests
 		args.DistributeEnabled = job.WorkflowArgs.DistributeEnabled
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
+	err := registerJob(h.Scheduler, job.ID.Hex(), schedule, zoned, func() {
 		if err := h.aslanCli.ScheduleCall("workflow/workflowtask", args, log.SugaredLogger()); err != nil {
 			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
 		}
 	})
-	if err != nil {
-		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
-		return err
-	}
-
-	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
-	err = h.Scheduler.UpdateJobModel(job.ID.Hex(), scheduleJob)
 	if err != nil {
 		log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 		return err
@@ -268,24 +295,18 @@ func (h *CronjobHandler) registerWorkFlowJob(name, schedule string, job *service
 	return nil
 }
 
-func (h *CronjobHandler) registerTestJob(name, productName, schedule string, job *service.Schedule) error {
+func (h *CronjobHandler) registerTestJob(name, productName, schedule string, zoned *cron.Schedule, job *service.Schedule) error {
 	args := &service.TestTaskArgs{
 		TestName:        name,
 		ProductName:     productName,
 		TestTaskCreator: setting.CronTaskCreator,
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
+	err := registerJob(h.Scheduler, job.ID.Hex(), schedule, zoned, func() {
 		if err := h.aslanCli.ScheduleCall("testing/testtask", args, log.SugaredLogger()); err != nil {
 			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
 		}
 	})
-	if err != nil {
-		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
-		return err
-	}
-
-	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
-	err = h.Scheduler.UpdateJobModel(job.ID.Hex(), scheduleJob)
 	if err != nil {
 		log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 		return err
@@ -313,7 +334,7 @@ func (h *CronjobHandler) stopCronjob(name, ptype string) error {
 
 	for _, job := range jobList {
 		log.Infof("stopping cronjob of ID: %s", job.ID)
-		h.Scheduler.StopService(job.ID)
+		stopJob(h.Scheduler, job.ID)
 	}
 
 	disableAPI := fmt.Sprintf("%s/cron/cronjob/disable", h.aslanCli.APIBase)
@@ -347,23 +368,17 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 			args.Tests = job.WorkflowArgs.Tests
 			args.DistributeEnabled = job.WorkflowArgs.DistributeEnabled
 		}
-		var cron string
-		if job.JobType == setting.CrontabCronjob {
-			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
-		} else {
-			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
+		spec, zoned, err := jobSchedule(job.JobType, job.Time, job.Frequency, job.Cron, job.TimeZone, job.Number)
+		if err != nil {
+			log.Errorf("Failed to parse schedule of job ID: %s, the error is: %v", job.ID, err)
+			return err
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
+		log.Infof("registering jobID: %s with cron: %s", job.ID, spec)
+		err = registerJob(scheduler, job.ID, spec, zoned, func() {
 			if err := client.ScheduleCall("workflow/workflowtask", args, log.SugaredLogger()); err != nil {
 				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
 			}
 		})
-		if err != nil {
-			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
-			return err
-		}
-		log.Infof("registering jobID: %s with cron: %s", job.ID, cron)
-		err = scheduler.UpdateJobModel(job.ID, scheduleJob)
 		if err != nil {
 			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
@@ -374,23 +389,17 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 			ProductName:     job.ProductName,
 			TestTaskCreator: setting.CronTaskCreator,
 		}
-		var cron string
-		if job.JobType == setting.CrontabCronjob {
-			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
-		} else {
-			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
+		spec, zoned, err := jobSchedule(job.JobType, job.Time, job.Frequency, job.Cron, job.TimeZone, job.Number)
+		if err != nil {
+			log.Errorf("Failed to parse schedule of job ID: %s, the error is: %v", job.ID, err)
+			return err
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
+		log.Infof("registering jobID: %s with cron: %s", job.ID, spec)
+		err = registerJob(scheduler, job.ID, spec, zoned, func() {
 			if err := client.ScheduleCall("testing/testtask", args, log.SugaredLogger()); err != nil {
 				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
 			}
 		})
-		if err != nil {
-			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
-			return err
-		}
-		log.Infof("registering jobID: %s with cron: %s", job.ID, cron)
-		err = scheduler.UpdateJobModel(job.ID, scheduleJob)
 		if err != nil {
 			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
@@ -22,6 +22,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 )
 
 func (c *CronClient) RunScheduledPipelineTask(pipeline *service.Pipeline, params *service.TaskArgs, log *zap.SugaredLogger) {
@@ -37,9 +38,11 @@ func (c *CronClient) RunScheduledPipelineTask(pipeline *service.Pipeline, params
 		args.Deploy = params.Deploy
 		args.Test = params.Test
 	}
-	if err := c.AslanCli.RunPipelineTask(args, log); err != nil {
+	err := c.AslanCli.RunPipelineTask(args, log)
+	if err != nil {
 		log.Errorf("[%s]RunScheduledTask err: %v", pipeline.Name, err)
 	}
+	metrics.CronTriggers.WithLabelValues(pipeline.ProductName, pipeline.Name, string(service.SingleType), metrics.Result(err)).Inc()
 }
 
 // BuildScheduledPipelineJob ...
@@ -24,6 +24,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 )
 
 // UpsertTestScheduler ...
@@ -107,7 +108,9 @@ func (c *CronClient) RunTestScheduledTask(test *service.TestingOpt, log *zap.Sug
 		TestTaskCreator: setting.CronTaskCreator,
 	}
 
-	if err := c.AslanCli.RunTestTask(args, log); err != nil {
+	err := c.AslanCli.RunTestTask(args, log)
+	if err != nil {
 		log.Errorf("[%s]RunTestScheduledTask err: %v", test.Name, err)
 	}
+	metrics.CronTriggers.WithLabelValues(test.ProductName, test.Name, string(service.TestType), metrics.Result(err)).Inc()
 }
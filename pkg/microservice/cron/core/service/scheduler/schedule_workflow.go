@@ -26,6 +26,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 )
 
 func (c *CronClient) UpsertWorkflowScheduler(log *zap.SugaredLogger) {
@@ -169,9 +170,11 @@ func (c *CronClient) RunScheduledTask(workflow *service.Workflow, params *servic
 		args.DistributeEnabled = params.DistributeEnabled
 	}
 
-	if err := c.AslanCli.RunWorkflowTask(args, log); err != nil {
+	err := c.AslanCli.RunWorkflowTask(args, log)
+	if err != nil {
 		log.Errorf("[%s]RunScheduledTask err: %v", workflow.Name, err)
 	}
+	metrics.CronTriggers.WithLabelValues(workflow.ProductTmplName, workflow.Name, string(service.WorkflowType), metrics.Result(err)).Inc()
 }
 
 // BuildScheduledJob ...
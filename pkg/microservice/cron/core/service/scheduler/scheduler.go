@@ -60,6 +60,8 @@ const (
 	UpsertColliePipelineScheduler = "UpsertColliePipelineScheduler"
 	//CleanProductScheduler ...
 	CleanProductScheduler = "CleanProductScheduler"
+	// ApprovalTimeoutScheduler ...
+	ApprovalTimeoutScheduler = "ApprovalTimeoutScheduler"
 	//InitBuildStatScheduler
 	InitStatScheduler = "InitStatScheduler"
 	//InitOperationStatScheduler
@@ -146,6 +148,8 @@ func (c *CronClient) Init() {
 
 	// 定时清理环境
 	c.InitCleanProductScheduler()
+	// 定时检查工作流任务审批是否超时
+	c.InitApprovalTimeoutScheduler()
 	// 定时初始化构建数据
 	c.InitBuildStatScheduler()
 	// 定时器初始化话运营统计数据
@@ -177,6 +181,16 @@ func (c *CronClient) InitCleanProductScheduler() {
 	c.Schedulers[CleanProductScheduler].Start()
 }
 
+// InitApprovalTimeoutScheduler ...
+func (c *CronClient) InitApprovalTimeoutScheduler() {
+
+	c.Schedulers[ApprovalTimeoutScheduler] = gocron.NewScheduler()
+
+	c.Schedulers[ApprovalTimeoutScheduler].Every(1).Minutes().Do(c.AslanCli.TriggerApprovalTimeout, c.log)
+
+	c.Schedulers[ApprovalTimeoutScheduler].Start()
+}
+
 // InitJobScheduler ...
 func (c *CronClient) InitJobScheduler() {
 
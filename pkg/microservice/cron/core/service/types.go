@@ -85,6 +85,7 @@ type Schedule struct {
 	TestArgs     *TestTaskArgs      `bson:"test_args,omitempty"           json:"test_args,omitempty"`
 	Type         ScheduleType       `bson:"type"                          json:"type"`
 	Cron         string             `bson:"cron"                          json:"cron"`
+	TimeZone     string             `bson:"time_zone,omitempty"           json:"time_zone,omitempty"`
 	IsModified   bool               `bson:"-"                             json:"-"`
 	// 自由编排工作流的开关是放在schedule里面的
 	Enabled bool `bson:"enabled"                       json:"enabled"`
@@ -629,6 +630,8 @@ type DistributeStage struct {
 
 	// repos to release images
 	Releases []RepoImage `bson:"releases" json:"releases"`
+	// ChartRepoName helm chart 仓库（可以是 OCI 仓库）的名称，用于分发环境中渲染后的 chart
+	ChartRepoName string `bson:"chart_repo_name,omitempty" json:"chart_repo_name,omitempty"`
 }
 
 type RepoImage struct {
@@ -645,6 +648,7 @@ type ProductDistribute struct {
 	ImageDistribute   bool                 `bson:"image_distribute"       json:"image_distribute"`
 	JumpBoxDistribute bool                 `bson:"jump_box_distribute"    json:"jump_box_distribute"`
 	QstackDistribute  bool                 `bson:"qstack_distribute"      json:"qstack_distribute"`
+	ChartDistribute   bool                 `bson:"chart_distribute"       json:"chart_distribute"`
 }
 
 type HookCtrl struct {
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/koderover/zadig/pkg/microservice/cron/config"
+	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	leaseName = "zadig-cron"
+
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// serviceAccountNamespaceFile 未设置 BE_POD_NAMESPACE 时从 service account 中读取所在的命名空间
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// runAsLeader 多副本部署时通过 Kubernetes Lease 选主，只有 leader 执行 run，其余副本等待接管
+// leader 失去 Lease 后直接退出进程，由 Kubernetes 重启后重新参与选主，避免内存中的定时任务重复触发
+func runAsLeader(ctx context.Context, run func(ctx context.Context)) {
+	// 无法选主时直接退出，不能让每个副本都执行定时任务
+	namespace, err := leaderNamespace()
+	if err != nil {
+		log.Fatalf("Failed to get the namespace for leader election, error: %s", err)
+	}
+
+	identity := config.PodName()
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Fatalf("Failed to get hostname for leader election, error: %s", err)
+		}
+		identity = hostname
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: namespace,
+		},
+		Client: krkubeclient.Clientset().CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	runWithLock(ctx, lock, run)
+}
+
+// leaderNamespace 返回 Lease 所在的命名空间，优先使用 BE_POD_NAMESPACE，其次是 service account 的命名空间
+func leaderNamespace() (string, error) {
+	if namespace := config.Namespace(); namespace != "" {
+		return namespace, nil
+	}
+
+	content, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", err
+	}
+	namespace := strings.TrimSpace(string(content))
+	if namespace == "" {
+		return "", errors.New("namespace of the service account is empty")
+	}
+
+	return namespace, nil
+}
+
+// runWithLock 持有 lock 时执行 run，lock 被其他副本持有时阻塞等待
+func runWithLock(ctx context.Context, lock resourcelock.Interface, run func(ctx context.Context)) {
+	identity := lock.Identity()
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Infof("%s became the leader, starting schedulers", identity)
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					log.Infof("%s released the leadership", identity)
+					return
+				}
+				log.Fatalf("%s lost the leadership, exiting", identity)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					log.Infof("%s is the current leader, waiting as standby", current)
+				}
+			},
+		},
+	})
+}
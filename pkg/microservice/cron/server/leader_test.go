@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/koderover/zadig/pkg/setting"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+func TestRunWithLockRunsOnlyOnLeader(t *testing.T) {
+	assert := assert.New(t)
+
+	client := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan string, 2)
+
+	var wg sync.WaitGroup
+	for _, identity := range []string{"cron-0", "cron-1"} {
+		lock := &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: "zadig"},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		}
+		identity := identity
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWithLock(ctx, lock, func(ctx context.Context) {
+				started <- identity
+				<-ctx.Done()
+			})
+		}()
+	}
+
+	var leader string
+	select {
+	case leader = <-started:
+	case <-time.After(leaseDuration):
+		t.Fatal("no replica became the leader")
+	}
+
+	// the standby retries every retryPeriod and must not run while the lease is held
+	select {
+	case standby := <-started:
+		t.Fatalf("both %s and %s are running the schedulers", leader, standby)
+	case <-time.After(2 * retryPeriod):
+	}
+
+	lease, err := client.CoordinationV1().Leases("zadig").Get(context.Background(), leaseName, metav1.GetOptions{})
+	assert.Nil(err)
+	assert.Equal(leader, *lease.Spec.HolderIdentity)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestLeaderNamespace(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(file string) { serviceAccountNamespaceFile = file }(serviceAccountNamespaceFile)
+	serviceAccountNamespaceFile = filepath.Join(t.TempDir(), "namespace")
+	defer viper.Set(setting.ENVNamespace, "")
+
+	viper.Set(setting.ENVNamespace, "zadig")
+	namespace, err := leaderNamespace()
+	assert.Nil(err)
+	assert.Equal("zadig", namespace)
+
+	viper.Set(setting.ENVNamespace, "")
+	_, err = leaderNamespace()
+	assert.NotNil(err)
+
+	assert.Nil(os.WriteFile(serviceAccountNamespaceFile, []byte("zadig-sa\n"), 0644))
+	namespace, err = leaderNamespace()
+	assert.Nil(err)
+	assert.Equal("zadig-sa", namespace)
+
+	assert.Nil(os.WriteFile(serviceAccountNamespaceFile, []byte(" \n"), 0644))
+	_, err = leaderNamespace()
+	assert.NotNil(err)
+}
@@ -24,6 +24,7 @@ import (
 	commonconfig "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service/scheduler"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -36,10 +37,14 @@ func Serve(ctx context.Context) error {
 	})
 
 	log.Infof("App Cron Started at %s", time.Now())
-	cronClient := scheduler.NewCronClient()
-	cronClient.Init()
+	// 只有 leader 注册和触发定时任务，standby 副本仅提供健康检查
+	go runAsLeader(ctx, func(context.Context) {
+		cronClient := scheduler.NewCronClient()
+		cronClient.Init()
+	})
 
 	http.HandleFunc("/ping", ping)
+	http.Handle("/metrics", metrics.Handler())
 	server := &http.Server{Addr: ":8091", Handler: nil}
 
 	stopChan := make(chan struct{})
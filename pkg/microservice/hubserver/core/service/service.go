@@ -33,6 +33,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/hubserver/core/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/hubserver/core/repository/mongodb"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/crypto"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/tool/remotedialer"
@@ -248,6 +249,8 @@ func Reset() {
 func Sync(server *remotedialer.Server, stopCh <-chan struct{}) {
 	ticker := time.NewTicker(10 * time.Second)
 	log := log.SugaredLogger()
+	// 已上报指标的集群，集群删除或断开后需要删除对应的指标
+	reported := make(map[string]bool)
 
 	for {
 		select {
@@ -259,9 +262,13 @@ func Sync(server *remotedialer.Server, stopCh <-chan struct{}) {
 					return
 				}
 
+				current := make(map[string]bool)
 				for _, cluster := range clusterInfos {
 					statusChanged := false
+					clusterLabel := metrics.Cluster(cluster.ID.Hex())
+					current[clusterLabel] = true
 					if _, ok := clusters.Load(cluster.ID.Hex()); ok && server.HasSession(cluster.ID.Hex()) {
+						metrics.HubTunnelSessions.WithLabelValues(clusterLabel).Set(1)
 						if cluster.Status != config.Normal {
 							log.Infof(
 								"cluster %s connected changed %s => %s",
@@ -271,6 +278,7 @@ func Sync(server *remotedialer.Server, stopCh <-chan struct{}) {
 							statusChanged = true
 						}
 					} else {
+						metrics.HubTunnelSessions.WithLabelValues(clusterLabel).Set(0)
 						if cluster.Status == config.Normal {
 							log.Infof(
 								"cluster %s disconnected changed %s => %s",
@@ -287,6 +295,13 @@ func Sync(server *remotedialer.Server, stopCh <-chan struct{}) {
 						}
 					}
 				}
+
+				for clusterLabel := range reported {
+					if !current[clusterLabel] {
+						metrics.HubTunnelSessions.DeleteLabelValues(clusterLabel)
+					}
+				}
+				reported = current
 			}()
 		case <-stopCh:
 			return
@@ -22,6 +22,7 @@ import (
 	"github.com/gorilla/mux"
 
 	h "github.com/koderover/zadig/pkg/microservice/hubserver/core/handler"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/remotedialer"
 )
 
@@ -44,6 +45,8 @@ func (s *engine) injectRouters(handler *remotedialer.Server) {
 
 	r.Handle("/connect", handler)
 
+	r.Handle("/metrics", metrics.Handler())
+
 	r.HandleFunc("/disconnect/{id}", func(rw http.ResponseWriter, req *http.Request) {
 		h.Disconnect(handler, rw, req)
 	})
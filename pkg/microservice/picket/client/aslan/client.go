@@ -18,6 +18,7 @@ package aslan
 
 import (
 	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -31,7 +32,8 @@ func New() *Client {
 	host := config.AslanServiceAddress()
 
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api"),
+		httpclient.SetHostURL(host+"/api"),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 	)
 
 	return &Client{
@@ -2,6 +2,7 @@ package policy
 
 import (
 	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -15,7 +16,8 @@ func New() *Client {
 	host := config.PolicyServiceAddress()
 
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api/v1"),
+		httpclient.SetHostURL(host+"/api/v1"),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 	)
 
 	return &Client{
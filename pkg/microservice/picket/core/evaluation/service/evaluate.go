@@ -24,8 +24,11 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/picket/client/opa"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 )
 
+const rbacAllowQuery = "rbac.allow"
+
 type evaluateResult struct {
 	Result bool `json:"result"`
 }
@@ -39,13 +42,15 @@ func Evaluate(header http.Header, projectName string, grantsReq []GrantReq, logg
 		parsedPath := strings.Split(strings.Trim(v.EndPoint, "/"), "/")
 		res := &evaluateResult{}
 		err := opaClient.Evaluate(
-			"rbac.allow", res,
+			rbacAllowQuery, res,
 			func() (*opa.Input, error) { return generateOPAInput(header, projectName, v.Method, parsedPath), nil })
 		if err != nil {
 			logger.Errorf("opa evaluate endpoint: %v method: %v err: %s", v.EndPoint, v.Method, err)
+			metrics.OPADecisions.WithLabelValues(rbacAllowQuery, metrics.DecisionError).Inc()
 			grantsRes = append(grantsRes, GrantRes{v, false})
 			continue
 		}
+		metrics.OPADecisions.WithLabelValues(rbacAllowQuery, metrics.Decision(res.Result)).Inc()
 		grantsRes = append(grantsRes, GrantRes{v, res.Result})
 	}
 	return grantsRes, nil
@@ -23,6 +23,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/config"
 	ginmiddleware "github.com/koderover/zadig/pkg/middleware/gin"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -69,6 +70,8 @@ func (s *engine) injectRouters() {
 		c.String(http.StatusMethodNotAllowed, "Method not allowed: %s %s", c.Request.Method, c.Request.URL.Path)
 	})
 
+	g.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	apiRouters := g.Group("")
 	s.injectRouterGroup(apiRouters)
 
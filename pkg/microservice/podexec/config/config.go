@@ -17,9 +17,21 @@ limitations under the License.
 package config
 
 import (
+	"github.com/spf13/viper"
+
 	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 )
 
 func HubServerAddr() string {
 	return configbase.HubServerServiceAddress()
 }
+
+func AslanServiceAddr() string {
+	return configbase.AslanServiceAddress()
+}
+
+// LocalClusterPodExecPolicy 本地集群没有集群记录，终端策略在部署时通过环境变量配置
+func LocalClusterPodExecPolicy() setting.PodExecPolicy {
+	return setting.PodExecPolicy(viper.GetString(setting.ENVLocalClusterPodExecPolicy))
+}
@@ -20,9 +20,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	conf "github.com/koderover/zadig/pkg/microservice/podexec/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/aslan"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -35,6 +40,7 @@ func ServeWs(w http.ResponseWriter, r *http.Request) {
 	// 获取query中的参数
 	queryList := r.URL.Query()
 	clusterID := queryList.Get("clusterId")
+	record := queryList.Get("record") == "true"
 
 	if namespace == "" || podName == "" || containerName == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -55,6 +61,32 @@ func ServeWs(w http.ResponseWriter, r *http.Request) {
 		_ = pty.Close()
 	}()
 
+	policy, err := podExecPolicy(clusterID)
+	if err != nil {
+		msg := err.Error()
+		log.Errorf(msg)
+		_, _ = pty.Write([]byte(msg))
+		pty.Done()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(&EndpointResponse{ResultCode: http.StatusInternalServerError, ErrorMsg: msg})
+		return
+	}
+	// 集群的终端策略优先于请求参数
+	switch policy {
+	case setting.PodExecDisabled:
+		msg := "exec is disabled in this cluster"
+		log.Warnf("%s, cluster: %s", msg, clusterID)
+		_, _ = pty.Write([]byte(msg))
+		pty.Done()
+
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(&EndpointResponse{ResultCode: http.StatusForbidden, ErrorMsg: msg})
+		return
+	case setting.PodExecRecorded:
+		record = true
+	}
+
 	kubeCli, cfg, err := NewKubeOutClusterClient(clusterID)
 	if err != nil {
 		msg := fmt.Sprintf("get kubecli err :%v", err)
@@ -79,7 +111,46 @@ func ServeWs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = ExecPod(kubeCli, cfg, []string{"/bin/sh"}, pty, namespace, podName, containerName)
+	var handler PtyHandler = pty
+	var recorder *Recorder
+	var session *aslan.PodExecSession
+	var recording *os.File
+	aslanClient := aslan.New(conf.AslanServiceAddr())
+	startTime := time.Now()
+	if record {
+		// 需要录像时先创建会话并确认录像可以保存，失败时不允许进入终端
+		// 录像写入本地文件，会话结束后以流的方式上传，不在内存中缓存
+		session, recording, err = startRecording(r, aslanClient, &aslan.PodExecSession{
+			ProductName:   queryList.Get("projectName"),
+			ClusterID:     clusterID,
+			Namespace:     namespace,
+			PodName:       podName,
+			ContainerName: containerName,
+			StartTime:     startTime.Unix(),
+		})
+		if err != nil {
+			msg := fmt.Sprintf("session can not be recorded: %v", err)
+			log.Errorf(msg)
+			_, _ = pty.Write([]byte(msg))
+			pty.Done()
+
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(&EndpointResponse{ResultCode: http.StatusInternalServerError, ErrorMsg: msg})
+			return
+		}
+
+		recorder = NewRecorder(recording, fmt.Sprintf("%s/%s/%s", namespace, podName, containerName), startTime)
+		handler = &recordedPty{PtyHandler: pty, recorder: recorder}
+	}
+
+	err = ExecPod(kubeCli, cfg, []string{"/bin/sh"}, handler, namespace, podName, containerName)
+	if recorder != nil {
+		_ = recording.Close()
+		meta := &recordingMeta{EndTime: time.Now().Unix(), Truncated: recorder.Truncated()}
+		if uploadErr := finishRecording(aslanClient, session.ID, meta); uploadErr != nil {
+			log.Errorf("Failed to upload recording of session %s, it will be retried later, err: %s", session.ID, uploadErr)
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Exec to pod error! err: %v", err)
 		log.Errorf(msg)
@@ -90,3 +161,24 @@ func ServeWs(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(&EndpointResponse{ResultCode: http.StatusInternalServerError, ErrorMsg: fmt.Sprintf("Exec to pod error! err: %v", err)})
 	}
 }
+
+// podExecPolicy 返回集群的终端策略，策略仅对生产集群和本地集群生效
+func podExecPolicy(clusterID string) (setting.PodExecPolicy, error) {
+	if clusterID == "" {
+		policy := conf.LocalClusterPodExecPolicy()
+		switch policy {
+		case setting.PodExecAllowed, setting.PodExecRecorded, setting.PodExecDisabled:
+			return policy, nil
+		}
+		return "", fmt.Errorf("unsupported pod exec policy %s of local cluster", policy)
+	}
+
+	cluster, err := aslan.New(conf.AslanServiceAddr()).GetCluster(clusterID)
+	if err != nil {
+		return "", fmt.Errorf("get cluster %s err :%v", clusterID, err)
+	}
+	if !cluster.Production {
+		return setting.PodExecAllowed, nil
+	}
+	return cluster.PodExecPolicy, nil
+}
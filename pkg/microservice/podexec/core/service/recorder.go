@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// maxRecordingSize 录像超过该大小后不再记录后续内容，避免占用过多的磁盘和对象存储空间
+	maxRecordingSize = 64 << 20
+
+	defaultTerminalWidth  = 80
+	defaultTerminalHeight = 24
+)
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env"`
+	Title     string            `json:"title"`
+}
+
+// Recorder 以 asciinema v2 格式把终端会话写入 w，不在内存中缓存录像
+type Recorder struct {
+	mu        sync.Mutex
+	w         io.Writer
+	size      int
+	start     time.Time
+	limit     int
+	truncated bool
+}
+
+func NewRecorder(w io.Writer, title string, start time.Time) *Recorder {
+	r := &Recorder{w: w, start: start, limit: maxRecordingSize}
+	header, _ := json.Marshal(&castHeader{
+		Version:   2,
+		Width:     defaultTerminalWidth,
+		Height:    defaultTerminalHeight,
+		Timestamp: start.Unix(),
+		Env:       map[string]string{"SHELL": "/bin/sh", "TERM": "xterm"},
+		Title:     title,
+	})
+	r.write(header)
+	return r
+}
+
+// Input 记录用户输入
+func (r *Recorder) Input(data []byte) {
+	r.record("i", string(data))
+}
+
+// Output 记录终端输出
+func (r *Recorder) Output(data []byte) {
+	r.record("o", string(data))
+}
+
+// Resize 记录终端大小变化
+func (r *Recorder) Resize(size remotecommand.TerminalSize) {
+	r.record("r", fmt.Sprintf("%dx%d", size.Width, size.Height))
+}
+
+func (r *Recorder) record(code, data string) {
+	if data == "" {
+		return
+	}
+
+	event, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), code, data})
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return
+	}
+	if r.size+len(event)+1 > r.limit {
+		r.truncated = true
+		return
+	}
+	r.write(event)
+}
+
+// write 写入失败后录像已不完整，同样视为被截断
+func (r *Recorder) write(line []byte) {
+	n, err := r.w.Write(append(line, '\n'))
+	r.size += n
+	if err != nil {
+		r.truncated = true
+	}
+}
+
+// Truncated 录像是否因超过大小限制而不完整
+func (r *Recorder) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+// recordedPty 在转发终端数据的同时记录到 Recorder 中
+type recordedPty struct {
+	PtyHandler
+	recorder *Recorder
+}
+
+func (p *recordedPty) Read(b []byte) (int, error) {
+	n, err := p.PtyHandler.Read(b)
+	if n > 0 {
+		p.recorder.Input(b[:n])
+	}
+	return n, err
+}
+
+func (p *recordedPty) Write(b []byte) (int, error) {
+	n, err := p.PtyHandler.Write(b)
+	if n > 0 {
+		p.recorder.Output(b[:n])
+	}
+	return n, err
+}
+
+func (p *recordedPty) Next() *remotecommand.TerminalSize {
+	size := p.PtyHandler.Next()
+	if size != nil {
+		p.recorder.Resize(*size)
+	}
+	return size
+}
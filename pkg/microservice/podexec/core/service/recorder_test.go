@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+func TestRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRecorder(buf, "default/nginx/nginx", time.Unix(1600000000, 0))
+	r.Input([]byte("ls\r"))
+	r.Output([]byte("bin etc\r\n"))
+	r.Resize(remotecommand.TerminalSize{Width: 120, Height: 40})
+	r.Output(nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expect 4 lines, got %d: %q", len(lines), lines)
+	}
+
+	header := &castHeader{}
+	if err := json.Unmarshal([]byte(lines[0]), header); err != nil {
+		t.Fatalf("unmarshal header err: %v", err)
+	}
+	if header.Version != 2 || header.Timestamp != 1600000000 || header.Title != "default/nginx/nginx" {
+		t.Errorf("unexpected header: %+v", header)
+	}
+
+	expected := []struct {
+		code string
+		data string
+	}{
+		{"i", "ls\r"},
+		{"o", "bin etc\r\n"},
+		{"r", "120x40"},
+	}
+	for i, e := range expected {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(lines[i+1]), &event); err != nil {
+			t.Fatalf("unmarshal event %d err: %v", i, err)
+		}
+		if len(event) != 3 || event[1] != e.code || event[2] != e.data {
+			t.Errorf("event %d: expect [_, %q, %q], got %v", i, e.code, e.data, event)
+		}
+	}
+}
+
+func TestRecorderTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRecorder(buf, "default/nginx/nginx", time.Now())
+	r.limit = buf.Len() + 32
+
+	r.Output([]byte("ok"))
+	r.Output([]byte(strings.Repeat("x", 64)))
+	r.Output([]byte("ok"))
+
+	if !r.Truncated() {
+		t.Fatal("expect recording to be truncated")
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("expect header and one event, got %q", buf.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecorderWriteFailed(t *testing.T) {
+	r := NewRecorder(failingWriter{}, "default/nginx/nginx", time.Now())
+	if !r.Truncated() {
+		t.Fatal("expect recording to be truncated after a failed write")
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	conf "github.com/koderover/zadig/pkg/microservice/podexec/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const recordingRetryInterval = time.Minute
+
+// recordingDir 录像上传成功前保存在该目录中，上传失败时保留，由 RetryRecordingUploads 重新上传
+var recordingDir = filepath.Join(os.TempDir(), "podexec-recordings")
+
+// recordingMeta 会话结束时写入，存在该文件说明录像已经完整，可以上传
+type recordingMeta struct {
+	EndTime   int64 `json:"end_time"`
+	Truncated bool  `json:"truncated"`
+}
+
+func recordingFile(id string) string {
+	return filepath.Join(recordingDir, id+".cast")
+}
+
+func recordingMetaFile(id string) string {
+	return filepath.Join(recordingDir, id+".json")
+}
+
+// startRecording 在会话开始前创建会话记录，aslan 会确认对象存储可用，失败时不允许进入终端
+func startRecording(r *http.Request, cli *aslan.Client, session *aslan.PodExecSession) (*aslan.PodExecSession, *os.File, error) {
+	token := r.Header.Get(setting.AuthorizationHeader)
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token != "" {
+		name, uid, err := internalhandler.ParseUserFromJWT(token)
+		if err != nil {
+			log.Warnf("Failed to get user from token, err: %s", err)
+		}
+		session.Username, session.UserID = name, uid
+	}
+
+	created, err := cli.CreatePodExecSession(session)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create session err: %v", err)
+	}
+
+	if err = os.MkdirAll(recordingDir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("create recording dir err: %v", err)
+	}
+	f, err := os.OpenFile(recordingFile(created.ID), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create recording file err: %v", err)
+	}
+	return created, f, nil
+}
+
+// finishRecording 记录会话的结束信息后上传录像
+func finishRecording(cli *aslan.Client, id string, meta *recordingMeta) error {
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(recordingMetaFile(id), content, 0600); err != nil {
+		return err
+	}
+	return uploadRecording(cli, id)
+}
+
+// uploadRecording 上传成功后删除本地文件，失败时保留文件等待重试
+func uploadRecording(cli *aslan.Client, id string) error {
+	content, err := ioutil.ReadFile(recordingMetaFile(id))
+	if err != nil {
+		return err
+	}
+	meta := &recordingMeta{}
+	if err = json.Unmarshal(content, meta); err != nil {
+		return err
+	}
+
+	f, err := os.Open(recordingFile(id))
+	if err != nil {
+		return err
+	}
+	err = cli.UploadPodExecSessionRecording(id, f, meta.EndTime, meta.Truncated)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	_ = os.Remove(recordingFile(id))
+	_ = os.Remove(recordingMetaFile(id))
+	return nil
+}
+
+// RetryRecordingUploads 启动时补全上次进程退出时未结束的会话，之后定期重新上传失败的录像
+func RetryRecordingUploads(ctx context.Context) {
+	cli := aslan.New(conf.AslanServiceAddr())
+	recoverInterruptedRecordings()
+	retryRecordingUploads(cli)
+
+	ticker := time.NewTicker(recordingRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			retryRecordingUploads(cli)
+		}
+	}
+}
+
+// recoverInterruptedRecordings 启动时没有进行中的会话，没有结束信息的录像来自进程退出时中断的会话，
+// 以文件的修改时间作为结束时间，并标记为不完整
+func recoverInterruptedRecordings() {
+	files, err := filepath.Glob(filepath.Join(recordingDir, "*.cast"))
+	if err != nil {
+		log.Errorf("Failed to list recordings, err: %s", err)
+		return
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".cast")
+		if _, err := os.Stat(recordingMetaFile(id)); err == nil {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		content, _ := json.Marshal(&recordingMeta{EndTime: info.ModTime().Unix(), Truncated: true})
+		if err = ioutil.WriteFile(recordingMetaFile(id), content, 0600); err != nil {
+			log.Errorf("Failed to save recording info of session %s, err: %s", id, err)
+		}
+	}
+}
+
+func retryRecordingUploads(cli *aslan.Client) {
+	files, err := filepath.Glob(filepath.Join(recordingDir, "*.json"))
+	if err != nil {
+		log.Errorf("Failed to list recordings, err: %s", err)
+		return
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".json")
+		if err = uploadRecording(cli, id); err != nil {
+			log.Errorf("Failed to upload recording of session %s, err: %s", id, err)
+			continue
+		}
+		log.Infof("recording of session %s is uploaded", id)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+func useRecordingDir(t *testing.T) {
+	dir := recordingDir
+	recordingDir = t.TempDir()
+	t.Cleanup(func() { recordingDir = dir })
+}
+
+func TestUploadRecordingRetry(t *testing.T) {
+	useRecordingDir(t)
+
+	failed := true
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path != "/api/environment/podexec/sessions/s1/recording" || r.URL.Query().Get("endTime") != "1600000000" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		uploaded = string(body)
+	}))
+	defer server.Close()
+	cli := aslan.New(server.URL)
+
+	if err := ioutil.WriteFile(recordingFile("s1"), []byte("recording"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := finishRecording(cli, "s1", &recordingMeta{EndTime: 1600000000}); err == nil {
+		t.Fatal("expect upload to fail")
+	}
+	for _, file := range []string{recordingFile("s1"), recordingMetaFile("s1")} {
+		if _, err := os.Stat(file); err != nil {
+			t.Fatalf("expect %s to be kept after upload failed, err: %v", file, err)
+		}
+	}
+
+	failed = false
+	retryRecordingUploads(cli)
+	if uploaded != "recording" {
+		t.Errorf("expect recording to be uploaded, got %q", uploaded)
+	}
+	for _, file := range []string{recordingFile("s1"), recordingMetaFile("s1")} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("expect %s to be removed after upload, err: %v", file, err)
+		}
+	}
+}
+
+func TestRecoverInterruptedRecordings(t *testing.T) {
+	useRecordingDir(t)
+
+	if err := ioutil.WriteFile(recordingFile("s2"), []byte("recording"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	recoverInterruptedRecordings()
+
+	content, err := ioutil.ReadFile(recordingMetaFile("s2"))
+	if err != nil {
+		t.Fatalf("expect interrupted recording to be finished, err: %v", err)
+	}
+	meta := &recordingMeta{}
+	if err = json.Unmarshal(content, meta); err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Truncated || meta.EndTime == 0 {
+		t.Errorf("expect interrupted recording to be truncated, got %+v", meta)
+	}
+}
@@ -38,6 +38,8 @@ func Serve(ctx context.Context) error {
 
 	log.Infof("App podexec Started at %s", time.Now())
 
+	go service.RetryRecordingUploads(ctx)
+
 	router := mux.NewRouter()
 	router.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]string{"message": "success"})
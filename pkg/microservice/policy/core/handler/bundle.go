@@ -24,6 +24,8 @@ import (
 
 	"github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/policy/core/service/bundle"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func DownloadBundle(c *gin.Context) {
@@ -40,3 +42,26 @@ func DownloadBundle(c *gin.Context) {
 	}
 	c.File(filepath.Join(config.DataPath(), c.Param("name")))
 }
+
+func RefreshBundle(c *gin.Context) {
+	bundle.RefreshOPABundle()
+	c.Status(http.StatusOK)
+}
+
+// ExplainBundle evaluates a request against the current OPA bundle and tells why it is allowed or denied.
+func ExplainBundle(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &bundle.ExplainArgs{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.UID == "" || args.Method == "" || args.Path == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("uid, method and path are required")
+		return
+	}
+
+	ctx.Resp, ctx.Err = bundle.Explain(args)
+}
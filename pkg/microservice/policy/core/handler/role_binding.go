@@ -118,6 +118,13 @@ func DeleteRoleBindings(c *gin.Context) {
 	ctx.Err = service.DeleteRoleBindings(args.Names, projectName, ctx.Logger)
 }
 
+func DeleteGroupRoleBindings(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Err = service.DeleteGroupRoleBindings(c.Param("gid"), ctx.Logger)
+}
+
 func DeleteSystemRoleBinding(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -69,6 +69,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		systemRoleBindings.PUT("/:name", CreateOrUpdateSystemRoleBinding)
 	}
 
+	groupRoleBindings := router.Group("group-rolebindings")
+	{
+		groupRoleBindings.DELETE("/:gid", DeleteGroupRoleBindings)
+	}
+
 	userBindings := router.Group("userbindings")
 	{
 		userBindings.GET("", ListUserBindings)
@@ -77,6 +82,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	bundles := router.Group("bundles")
 	{
 		bundles.GET("/:name", DownloadBundle)
+		bundles.POST("/refresh", RefreshBundle)
+		bundles.POST("/explain", ExplainBundle)
 	}
 
 	policyRegistrations := router.Group("policies")
@@ -155,6 +155,14 @@ func (c *RoleBindingColl) DeleteByRoles(roleNames []string, projectName string)
 	return err
 }
 
+// DeleteBySubject deletes all the role bindings of the given subject in all namespaces.
+func (c *RoleBindingColl) DeleteBySubject(kind models.SubjectKind, uid string) error {
+	query := bson.M{"subjects": bson.M{"$elemMatch": bson.M{"kind": kind, "uid": uid}}}
+	_, err := c.Collection.DeleteMany(context.TODO(), query)
+
+	return err
+}
+
 func (c *RoleBindingColl) Create(obj *models.RoleBinding) error {
 	if obj == nil {
 		return fmt.Errorf("nil object")
@@ -8,6 +8,7 @@ type exemptionURLs struct {
 	Public     rules `json:"public"`     // public urls are not controlled by AuthN and AuthZ
 	Privileged rules `json:"privileged"` // privileged urls can only be visited by system admins
 	Registered rules `json:"registered"` // registered urls are the entire list of urls which are controlled by AuthZ, which means that if an url is not in this list, it is not controlled by AuthZ
+	Internal   rules `json:"internal"`   // internal urls are only called between services, they are denied for everyone through the gateway
 }
 
 type policyRule struct {
@@ -192,6 +193,22 @@ var systemAdminURLs = []*policyRule{
 		Methods:   []string{"PUT"},
 		Endpoints: []string{"api/aslan/system/operation/?*"},
 	},
+	{
+		Methods:   []string{"GET"},
+		Endpoints: []string{"api/aslan/system/audit"},
+	},
+	{
+		Methods:   []string{"GET"},
+		Endpoints: []string{"api/aslan/system/audit/export"},
+	},
+	{
+		Methods:   []string{"GET"},
+		Endpoints: []string{"api/aslan/environment/podexec/sessions"},
+	},
+	{
+		Methods:   []string{"GET"},
+		Endpoints: []string{"api/aslan/environment/podexec/sessions/?*/recording"},
+	},
 	{
 		Methods:   []string{"GET"},
 		Endpoints: []string{"api/aslan/system/proxy/config"},
@@ -228,6 +245,10 @@ var systemAdminURLs = []*policyRule{
 		Methods:   []string{"DELETE"},
 		Endpoints: []string{"api/v1/system-rolebindings/?*"},
 	},
+	{
+		Methods:   []string{"POST"},
+		Endpoints: []string{"api/v1/bundles/explain"},
+	},
 }
 
 // actions which are allowed for project admins.
@@ -299,3 +320,19 @@ var projectAdminURLs = []*policyRule{
 }
 
 var adminURLs = append(systemAdminURLs, projectAdminURLs...)
+
+// internalURLs are called by services with the in-cluster addresses, no one is allowed to call them through the gateway.
+var internalURLs = []*policyRule{
+	{
+		Methods:   []string{"POST"},
+		Endpoints: []string{"api/aslan/system/audit"},
+	},
+	{
+		Methods:   []string{"POST"},
+		Endpoints: []string{"api/aslan/environment/podexec/sessions"},
+	},
+	{
+		Methods:   []string{"PUT"},
+		Endpoints: []string{"api/aslan/environment/podexec/sessions/?*/recording"},
+	},
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/27149chen/afero"
+	"github.com/gobwas/glob"
+	"github.com/golang-jwt/jwt"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/picket/client/opa"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	systemAdminRole  = "admin"
+	projectAdminRole = "project-admin"
+	// allUsers is the uid of role bindings which are effective for all users.
+	allUsers = "*"
+
+	rbacAllowQuery = "rbac.allow"
+)
+
+// ExplainArgs describes a request which is evaluated against the current OPA bundle without calling the real endpoint.
+type ExplainArgs struct {
+	UID         string `json:"uid"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	ProjectName string `json:"projectName"`
+}
+
+// Explanation is the result of a dry-run, it tells the decision made by OPA and which data in the bundle leads to it.
+type Explanation struct {
+	Allowed  bool   `json:"allowed"`
+	Reason   string `json:"reason"`
+	Revision string `json:"revision"`
+
+	Public     bool `json:"public"`
+	Exempted   bool `json:"exempted"`
+	Privileged bool `json:"privileged"`
+	Internal   bool `json:"internal"`
+
+	SystemAdmin  bool `json:"systemAdmin"`
+	ProjectAdmin bool `json:"projectAdmin"`
+
+	Groups []string `json:"groups"`
+	// Bindings are all the role bindings of the user in the given project, including the ones bound to the user's groups or to all users.
+	Bindings []*ExplainBinding `json:"bindings"`
+	// Roles are the roles which take effect in the given project.
+	Roles []*ExplainRole `json:"roles"`
+	// MatchedRules are the rules which grant the request.
+	MatchedRules []*ExplainRule `json:"matchedRules"`
+}
+
+type ExplainBinding struct {
+	// Subject is "user:<uid>", "group:<gid>" or "*".
+	Subject   string         `json:"subject"`
+	Namespace string         `json:"namespace"`
+	Roles     []*ExplainRole `json:"roles"`
+}
+
+type ExplainRole struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type ExplainRule struct {
+	Role     *ExplainRole `json:"role"`
+	Method   string       `json:"method"`
+	Endpoint string       `json:"endpoint"`
+}
+
+// bundleData is the data part of the OPA bundle.
+type bundleData struct {
+	roles      *opaRoles
+	bindings   *opaRoleBindings
+	groups     *opaUserGroups
+	exemptions *exemptionURLs
+}
+
+// Explain evaluates the request by OPA with a token of the given user, so the decision is always the one made by
+// authz.rego. The current bundle is only used to annotate which bindings and rules are matched.
+func Explain(args *ExplainArgs) (*Explanation, error) {
+	return explainWithOPA(opa.NewDefault(), args)
+}
+
+func explainWithOPA(client *opa.Client, args *ExplainArgs) (*Explanation, error) {
+	req, err := newExplainRequest(args)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := loadBundleData()
+	if err != nil {
+		log.Errorf("Failed to load OPA bundle, err: %s", err)
+		return nil, err
+	}
+	res := explain(data, req)
+	res.Revision = GetRevision()
+
+	allowed, err := evaluate(client, req)
+	if err != nil {
+		log.Errorf("Failed to evaluate %s %s by OPA, err: %s", req.method, req.path, err)
+		return nil, err
+	}
+	res.decide(allowed, req)
+
+	return res, nil
+}
+
+// explainRequest is the normalized ExplainArgs.
+type explainRequest struct {
+	uid         string
+	method      string
+	path        string
+	projectName string
+}
+
+func newExplainRequest(args *ExplainArgs) (*explainRequest, error) {
+	if args.UID == "" || args.Method == "" || args.Path == "" {
+		return nil, fmt.Errorf("uid, method and path are required")
+	}
+
+	u, err := url.Parse(args.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %s", args.Path, err)
+	}
+	req := &explainRequest{
+		uid:         args.UID,
+		method:      strings.ToUpper(args.Method),
+		path:        strings.Trim(u.Path, "/"),
+		projectName: args.ProjectName,
+	}
+	if req.projectName == "" {
+		req.projectName = u.Query().Get("projectName")
+	}
+
+	return req, nil
+}
+
+type evaluateResult struct {
+	Result bool `json:"result"`
+}
+
+// evaluate queries rbac.allow with the same input as the gateway, the user is authenticated by a short-lived token
+// which is signed by the secret key shared with OPA.
+func evaluate(client *opa.Client, req *explainRequest) (bool, error) {
+	secret := config.SecretKey()
+	if secret == "" {
+		return false, fmt.Errorf("secret key is not set")
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid": req.uid,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		return false, err
+	}
+
+	res := &evaluateResult{}
+	err = client.Evaluate(rbacAllowQuery, res, func() (*opa.Input, error) {
+		return &opa.Input{
+			ParsedQuery: &opa.ParseQuery{ProjectName: []string{req.projectName}},
+			ParsedPath:  strings.Split(req.path, "/"),
+			Attributes: &opa.Attributes{
+				Request: &opa.Request{HTTP: &opa.HTTPSpec{
+					Method:  req.method,
+					Headers: map[string]string{strings.ToLower(setting.AuthorizationHeader): "Bearer " + token},
+				}},
+			},
+		}, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return res.Result, nil
+}
+
+func loadBundleData() (*bundleData, error) {
+	if cacheFS == nil {
+		return nil, fmt.Errorf("OPA bundle is not generated yet")
+	}
+
+	data := &bundleData{
+		roles:      &opaRoles{},
+		bindings:   &opaRoleBindings{},
+		groups:     &opaUserGroups{},
+		exemptions: &exemptionURLs{},
+	}
+	for path, obj := range map[string]interface{}{
+		rolesPath:        data.roles,
+		rolebindingsPath: data.bindings,
+		userGroupsPath:   data.groups,
+		exemptionPath:    data.exemptions,
+	} {
+		content, err := afero.ReadFile(cacheFS, path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(content, obj); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %s", path, err)
+		}
+	}
+
+	return data, nil
+}
+
+// explain annotates the request with the data in the bundle, it doesn't make the decision.
+func explain(data *bundleData, req *explainRequest) *Explanation {
+	method, path, projectName := req.method, req.path, req.projectName
+
+	res := &Explanation{
+		Public:     matchRules(data.exemptions.Public, method, path),
+		Exempted:   !matchRules(data.exemptions.Registered, method, path),
+		Privileged: matchRules(data.exemptions.Privileged, method, path),
+		Internal:   matchRules(data.exemptions.Internal, method, path),
+		Groups:     data.groups.UserGroups[req.uid],
+	}
+
+	groups := make(map[string]bool)
+	for _, g := range res.Groups {
+		groups[g] = true
+	}
+
+	var userBindings []*ExplainBinding
+	for _, rb := range data.bindings.RoleBindings {
+		switch rb.UID {
+		case req.uid:
+			userBindings = append(userBindings, toExplainBindings("user:"+rb.UID, rb.Bindings)...)
+		case allUsers:
+			// for bindings of all users, roles of all the namespaces take effect once the project is matched, which is
+			// the same as the rule "allowed_roles" in authz.rego.
+			for _, b := range rb.Bindings {
+				if b.Namespace != projectName {
+					continue
+				}
+				binding := &ExplainBinding{Subject: allUsers, Namespace: projectName}
+				for _, nb := range rb.Bindings {
+					binding.Roles = append(binding.Roles, toExplainRoles(nb.RoleRefs)...)
+				}
+				res.Bindings = append(res.Bindings, binding)
+			}
+		}
+	}
+	for _, grb := range data.bindings.GroupRoleBindings {
+		if groups[grb.GID] {
+			userBindings = append(userBindings, toExplainBindings("group:"+grb.GID, grb.Bindings)...)
+		}
+	}
+
+	roles := make(map[ExplainRole]bool)
+	for _, b := range userBindings {
+		for _, r := range b.Roles {
+			if r.Name == systemAdminRole && r.Namespace == "*" {
+				res.SystemAdmin = true
+			}
+		}
+		if b.Namespace == projectName {
+			res.Bindings = append(res.Bindings, b)
+		}
+	}
+	for _, b := range res.Bindings {
+		for _, r := range b.Roles {
+			if roles[*r] {
+				continue
+			}
+			roles[*r] = true
+			res.Roles = append(res.Roles, r)
+			if r.Name == projectAdminRole {
+				res.ProjectAdmin = true
+			}
+		}
+	}
+
+	for _, ro := range data.roles.Roles {
+		if !roles[ExplainRole{Name: ro.Name, Namespace: ro.Namespace}] {
+			continue
+		}
+		for _, r := range ro.Rules {
+			if r.Method == method && matchEndpoint(r.Endpoint, path) {
+				res.MatchedRules = append(res.MatchedRules, &ExplainRule{
+					Role:     &ExplainRole{Name: ro.Name, Namespace: ro.Namespace},
+					Method:   r.Method,
+					Endpoint: r.Endpoint,
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+// decide sets the decision of OPA and tells the most relevant reason according to the annotations.
+func (e *Explanation) decide(allowed bool, req *explainRequest) {
+	e.Allowed = allowed
+	if allowed {
+		switch {
+		case e.Internal:
+			e.Reason = "allowed by OPA, the bundle loaded by OPA may be different from the current revision"
+		case e.Public:
+			e.Reason = "url is public"
+		case e.Exempted:
+			e.Reason = "url is not registered, it is visible for all authenticated users"
+		case e.SystemAdmin:
+			e.Reason = "user is system admin"
+		case e.ProjectAdmin && !e.Privileged:
+			e.Reason = fmt.Sprintf("user is project admin of %q", req.projectName)
+		case len(e.MatchedRules) > 0 && !e.Privileged:
+			r := e.MatchedRules[0]
+			e.Reason = fmt.Sprintf("granted by rule %s %s of role %s/%s", r.Method, r.Endpoint, r.Role.Namespace, r.Role.Name)
+		case req.path == "api/aslan/workflow/workflow" && req.method == MethodGet && req.projectName == "":
+			e.Reason = "url is temporarily skipped"
+		default:
+			e.Reason = "allowed by OPA"
+		}
+		return
+	}
+
+	switch {
+	case e.Internal:
+		e.Reason = "url is internal, it can not be visited through the gateway"
+	case e.Privileged && !e.SystemAdmin:
+		e.Reason = "url is privileged, it is visible for system admins only"
+	case !e.Public && !e.Exempted && !e.SystemAdmin && !e.ProjectAdmin && len(e.MatchedRules) == 0:
+		e.Reason = fmt.Sprintf("no rule of the roles in project %q matches the request", req.projectName)
+	default:
+		e.Reason = "denied by OPA, the bundle loaded by OPA may be different from the current revision"
+	}
+}
+
+func toExplainBindings(subject string, bs bindings) []*ExplainBinding {
+	var res []*ExplainBinding
+	for _, b := range bs {
+		res = append(res, &ExplainBinding{Subject: subject, Namespace: b.Namespace, Roles: toExplainRoles(b.RoleRefs)})
+	}
+	return res
+}
+
+func toExplainRoles(refs roleRefs) []*ExplainRole {
+	var res []*ExplainRole
+	for _, r := range refs {
+		res = append(res, &ExplainRole{Name: r.Name, Namespace: r.Namespace})
+	}
+	return res
+}
+
+func matchRules(rs rules, method, path string) bool {
+	for _, r := range rs {
+		if r.Method == method && matchEndpoint(r.Endpoint, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEndpoint is the same as `glob.match(trim(endpoint, "/"), ["/"], path)` in authz.rego.
+func matchEndpoint(endpoint, path string) bool {
+	g, err := glob.Compile(strings.Trim(endpoint, "/"), '/')
+	if err != nil {
+		return false
+	}
+	return g.Match(path)
+}
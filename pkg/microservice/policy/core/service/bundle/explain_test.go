@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	"github.com/27149chen/afero"
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	"github.com/koderover/zadig/pkg/microservice/picket/client/opa"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Testing explain a request", func() {
+
+	data := &bundleData{
+		roles: &opaRoles{
+			Roles: roles{
+				{Name: "admin", Namespace: "*"},
+				{Name: "project-admin", Namespace: ""},
+				{Name: "read-only", Namespace: "", Rules: rules{{Method: MethodGet, Endpoint: "/api/aslan/environment/environments/?*"}}},
+			},
+		},
+		bindings: &opaRoleBindings{
+			RoleBindings: roleBindings{
+				{UID: "*", Bindings: bindings{{Namespace: "public", RoleRefs: roleRefs{{Name: "read-only"}}}}},
+				{UID: "admin", Bindings: bindings{{Namespace: "*", RoleRefs: roleRefs{{Name: "admin", Namespace: "*"}}}}},
+				{UID: "alice", Bindings: bindings{{Namespace: "project1", RoleRefs: roleRefs{{Name: "project-admin"}}}}},
+			},
+			GroupRoleBindings: groupRoleBindings{
+				{GID: "developers", Bindings: bindings{{Namespace: "project2", RoleRefs: roleRefs{{Name: "read-only"}}}}},
+			},
+		},
+		groups: &opaUserGroups{UserGroups: map[string][]string{"bob": {"developers"}}},
+		exemptions: &exemptionURLs{
+			Public:     rules{{Method: MethodGet, Endpoint: "api/aslan/health"}},
+			Privileged: rules{{Method: MethodPost, Endpoint: "api/aslan/cluster/clusters"}},
+			Registered: rules{
+				{Method: MethodPost, Endpoint: "api/aslan/cluster/clusters"},
+				{Method: MethodGet, Endpoint: "/api/aslan/environment/environments/?*"},
+			},
+			Internal: rules{{Method: MethodPost, Endpoint: "api/aslan/system/audit"}},
+		},
+	}
+
+	request := func(args *ExplainArgs) *explainRequest {
+		req, err := newExplainRequest(args)
+		Expect(err).ShouldNot(HaveOccurred())
+		return req
+	}
+
+	It("should require uid, method and path", func() {
+		_, err := newExplainRequest(&ExplainArgs{UID: "bob", Method: MethodGet})
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should annotate public, exempted, privileged and internal urls", func() {
+		res := explain(data, request(&ExplainArgs{UID: "bob", Method: "get", Path: "/api/aslan/health"}))
+		Expect(res.Public).To(BeTrue())
+
+		res = explain(data, request(&ExplainArgs{UID: "bob", Method: MethodGet, Path: "/api/aslan/system/unknown"}))
+		Expect(res.Exempted).To(BeTrue())
+
+		res = explain(data, request(&ExplainArgs{UID: "alice", Method: MethodPost, Path: "/api/aslan/cluster/clusters?projectName=project1"}))
+		Expect(res.Privileged).To(BeTrue())
+		Expect(res.ProjectAdmin).To(BeTrue())
+		Expect(res.SystemAdmin).To(BeFalse())
+
+		res = explain(data, request(&ExplainArgs{UID: "admin", Method: MethodPost, Path: "/api/aslan/system/audit"}))
+		Expect(res.Internal).To(BeTrue())
+		Expect(res.SystemAdmin).To(BeTrue())
+	})
+
+	It("should explain the matched roles and rules", func() {
+		res := explain(data, request(&ExplainArgs{UID: "bob", Method: MethodGet, Path: "/api/aslan/environment/environments/dev", ProjectName: "project2"}))
+		Expect(res.Groups).To(Equal([]string{"developers"}))
+		Expect(res.Bindings).To(HaveLen(1))
+		Expect(res.Bindings[0].Subject).To(Equal("group:developers"))
+		Expect(res.MatchedRules).To(HaveLen(1))
+		Expect(res.MatchedRules[0].Role.Name).To(Equal("read-only"))
+
+		res = explain(data, request(&ExplainArgs{UID: "alice", Method: MethodGet, Path: "/api/aslan/environment/environments/dev?projectName=public"}))
+		Expect(res.Bindings[0].Subject).To(Equal("*"))
+		Expect(res.MatchedRules).To(HaveLen(1))
+
+		res = explain(data, request(&ExplainArgs{UID: "bob", Method: MethodGet, Path: "/api/aslan/environment/environments/dev", ProjectName: "project1"}))
+		Expect(res.MatchedRules).To(BeEmpty())
+	})
+
+	It("should tell the reason of the decision", func() {
+		req := request(&ExplainArgs{UID: "alice", Method: MethodPost, Path: "/api/aslan/cluster/clusters?projectName=project1"})
+		res := explain(data, req)
+		res.decide(false, req)
+		Expect(res.Reason).To(ContainSubstring("privileged"))
+
+		req = request(&ExplainArgs{UID: "admin", Method: MethodPost, Path: "/api/aslan/system/audit"})
+		res = explain(data, req)
+		res.decide(false, req)
+		Expect(res.Reason).To(ContainSubstring("internal"))
+
+		req = request(&ExplainArgs{UID: "bob", Method: MethodGet, Path: "/api/aslan/environment/environments/dev", ProjectName: "project2"})
+		res = explain(data, req)
+		res.decide(true, req)
+		Expect(res.Reason).To(Equal("granted by rule GET /api/aslan/environment/environments/?* of role /read-only"))
+
+		// the decision is always the one of OPA even if the annotations don't agree with it
+		res.decide(false, req)
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Reason).To(ContainSubstring("denied by OPA"))
+	})
+})
+
+// regoTestData loads rego/test_data, which is the data used by authz_test.rego.
+func regoTestData() *bundleData {
+	data := &bundleData{
+		roles:      &opaRoles{},
+		bindings:   &opaRoleBindings{},
+		groups:     &opaUserGroups{},
+		exemptions: &exemptionURLs{},
+	}
+	for dir, obj := range map[string]interface{}{
+		"roles":      data.roles,
+		"bindings":   data.bindings,
+		"groups":     data.groups,
+		"exemptions": data.exemptions,
+	} {
+		content, err := ioutil.ReadFile(filepath.Join("rego", "test_data", dir, "data.json"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(json.Unmarshal(content, obj)).To(Succeed())
+	}
+
+	return data
+}
+
+var _ = Describe("Testing explain with OPA", func() {
+	const secret = "explain-secret"
+
+	var (
+		server *httptest.Server
+		inputs []*opa.Input
+	)
+
+	BeforeEach(func() {
+		viper.Set(setting.ENVSecretKey, secret)
+		inputs = nil
+
+		data := regoTestData()
+		cacheFS = afero.NewMemMapFs()
+		for path, obj := range map[string]interface{}{
+			rolesPath:        data.roles,
+			rolebindingsPath: data.bindings,
+			userGroupsPath:   data.groups,
+			exemptionPath:    data.exemptions,
+		} {
+			content, err := json.Marshal(obj)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(afero.WriteFile(cacheFS, path, content, 0644)).To(Succeed())
+		}
+
+		// the fake OPA server allows the request only if the token is signed by the secret key and the uid is "bob"
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.URL.Path).To(Equal("/v1/data/rbac/allow"))
+
+			req := &struct {
+				Input *opa.Input `json:"input"`
+			}{}
+			Expect(json.NewDecoder(r.Body).Decode(req)).To(Succeed())
+			inputs = append(inputs, req.Input)
+
+			token := strings.TrimPrefix(req.Input.Attributes.Request.HTTP.Headers["authorization"], "Bearer ")
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte(secret), nil })
+			Expect(err).ShouldNot(HaveOccurred())
+
+			w.Header().Set("Content-Type", "application/json")
+			Expect(json.NewEncoder(w).Encode(map[string]bool{"result": claims["uid"] == "bob"})).To(Succeed())
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		cacheFS = nil
+		viper.Set(setting.ENVSecretKey, "")
+	})
+
+	It("should take the decision of OPA and annotate it with the bundle", func() {
+		res, err := explainWithOPA(opa.New(server.URL), &ExplainArgs{UID: "bob", Method: "post", Path: "/api/aslan/system/audit?projectName=project6"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Internal).To(BeTrue())
+		Expect(res.Reason).To(ContainSubstring("allowed by OPA"))
+
+		Expect(inputs).To(HaveLen(1))
+		Expect(inputs[0].ParsedPath).To(Equal([]string{"api", "aslan", "system", "audit"}))
+		Expect(inputs[0].ParsedQuery.ProjectName).To(Equal([]string{"project6"}))
+		Expect(inputs[0].Attributes.Request.HTTP.Method).To(Equal(MethodPost))
+
+		res, err = explainWithOPA(opa.New(server.URL), &ExplainArgs{UID: "2e6c5200-358f-11ec-981f-de2269351a1e", Method: MethodGet, Path: "/api/articles?projectName=project6"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Groups).To(Equal([]string{"developers"}))
+		Expect(res.MatchedRules).NotTo(BeEmpty())
+	})
+
+	It("should fail without secret key", func() {
+		viper.Set(setting.ENVSecretKey, "")
+
+		_, err := explainWithOPA(opa.New(server.URL), &ExplainArgs{UID: "bob", Method: MethodGet, Path: "/api/articles"})
+		Expect(err).Should(HaveOccurred())
+		Expect(inputs).To(BeEmpty())
+	})
+})
@@ -28,6 +28,7 @@ import (
 	"github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/policy/core/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/policy/core/repository/mongodb"
+	"github.com/koderover/zadig/pkg/shared/client/user"
 	"github.com/koderover/zadig/pkg/tool/log"
 	fsutil "github.com/koderover/zadig/pkg/util/fs"
 )
@@ -37,6 +38,7 @@ const (
 	policyPath       = "authz.rego"
 	rolesPath        = "roles/data.json"
 	rolebindingsPath = "bindings/data.json"
+	userGroupsPath   = "groups/data.json"
 	exemptionPath    = "exemptions/data.json"
 )
 
@@ -57,7 +59,13 @@ type opaRoles struct {
 }
 
 type opaRoleBindings struct {
-	RoleBindings roleBindings `json:"role_bindings"`
+	RoleBindings      roleBindings      `json:"role_bindings"`
+	GroupRoleBindings groupRoleBindings `json:"group_role_bindings,omitempty"`
+}
+
+// opaUserGroups maps a user to all the groups which the user belongs to.
+type opaUserGroups struct {
+	UserGroups map[string][]string `json:"user_groups"`
 }
 
 type opaManifest struct {
@@ -81,6 +89,11 @@ type roleBinding struct {
 	Bindings bindings `json:"bindings"`
 }
 
+type groupRoleBinding struct {
+	GID      string   `json:"gid"`
+	Bindings bindings `json:"bindings"`
+}
+
 type binding struct {
 	Namespace string   `json:"namespace"`
 	RoleRefs  roleRefs `json:"role_refs"`
@@ -186,6 +199,14 @@ func (o roleBindings) Less(i, j int) bool {
 	return o[i].UID < o[j].UID
 }
 
+type groupRoleBindings []*groupRoleBinding
+
+func (o groupRoleBindings) Len() int      { return len(o) }
+func (o groupRoleBindings) Swap(i, j int) { o[i], o[j] = o[j], o[i] }
+func (o groupRoleBindings) Less(i, j int) bool {
+	return o[i].GID < o[j].GID
+}
+
 func generateOPARoles(roles []*models.Role, policies []*models.Policy) *opaRoles {
 	data := &opaRoles{}
 	resourceMappings := getResourceActionMappings(policies)
@@ -223,29 +244,63 @@ func generateOPARoleBindings(rbs []*models.RoleBinding) *opaRoleBindings {
 	data := &opaRoleBindings{}
 
 	userRoleMap := make(map[string]map[string][]*roleRef)
+	groupRoleMap := make(map[string]map[string][]*roleRef)
 
 	for _, rb := range rbs {
 		for _, s := range rb.Subjects {
-			if s.Kind == models.UserKind {
-				if _, ok := userRoleMap[s.UID]; !ok {
-					userRoleMap[s.UID] = make(map[string][]*roleRef)
-				}
-				userRoleMap[s.UID][rb.Namespace] = append(userRoleMap[s.UID][rb.Namespace], &roleRef{Name: rb.RoleRef.Name, Namespace: rb.RoleRef.Namespace})
+			var subjectRoleMap map[string]map[string][]*roleRef
+			switch s.Kind {
+			case models.UserKind:
+				subjectRoleMap = userRoleMap
+			case models.GroupKind:
+				subjectRoleMap = groupRoleMap
+			default:
+				continue
 			}
+
+			if _, ok := subjectRoleMap[s.UID]; !ok {
+				subjectRoleMap[s.UID] = make(map[string][]*roleRef)
+			}
+			subjectRoleMap[s.UID][rb.Namespace] = append(subjectRoleMap[s.UID][rb.Namespace], &roleRef{Name: rb.RoleRef.Name, Namespace: rb.RoleRef.Namespace})
 		}
 	}
 
 	for u, nb := range userRoleMap {
-		var bindingsData []*binding
-		for n, b := range nb {
-			sort.Sort(roleRefs(b))
-			bindingsData = append(bindingsData, &binding{Namespace: n, RoleRefs: b})
-		}
-		sort.Sort(bindings(bindingsData))
-		data.RoleBindings = append(data.RoleBindings, &roleBinding{UID: u, Bindings: bindingsData})
+		data.RoleBindings = append(data.RoleBindings, &roleBinding{UID: u, Bindings: generateOPABindings(nb)})
+	}
+	for g, nb := range groupRoleMap {
+		data.GroupRoleBindings = append(data.GroupRoleBindings, &groupRoleBinding{GID: g, Bindings: generateOPABindings(nb)})
 	}
 
 	sort.Sort(data.RoleBindings)
+	sort.Sort(data.GroupRoleBindings)
+
+	return data
+}
+
+func generateOPABindings(nb map[string][]*roleRef) bindings {
+	var bindingsData []*binding
+	for n, b := range nb {
+		sort.Sort(roleRefs(b))
+		bindingsData = append(bindingsData, &binding{Namespace: n, RoleRefs: b})
+	}
+	sort.Sort(bindings(bindingsData))
+
+	return bindingsData
+}
+
+func generateOPAUserGroups(groups []*user.UserGroup) *opaUserGroups {
+	data := &opaUserGroups{UserGroups: make(map[string][]string)}
+
+	for _, g := range groups {
+		for _, uid := range g.UIDs {
+			data.UserGroups[uid] = append(data.UserGroups[uid], g.GroupID)
+		}
+	}
+
+	for _, gs := range data.UserGroups {
+		sort.Strings(gs)
+	}
 
 	return data
 }
@@ -277,6 +332,15 @@ func generateOPAExemptionURLs(policies []*models.Policy) *exemptionURLs {
 	}
 	sort.Sort(data.Privileged)
 
+	for _, r := range internalURLs {
+		for _, method := range r.Methods {
+			for _, endpoint := range r.Endpoints {
+				data.Internal = append(data.Internal, &rule{Method: method, Endpoint: endpoint})
+			}
+		}
+	}
+	sort.Sort(data.Internal)
+
 	resourceMappings := getResourceActionMappings(policies)
 	for _, resourceMappings := range resourceMappings {
 		for _, rs := range resourceMappings {
@@ -328,12 +392,20 @@ func GenerateOPABundle() error {
 	if err != nil {
 		log.Errorf("Failed to list policies, err: %s", err)
 	}
+	// the user groups are used to grant permissions, generating a bundle without them would drop all the
+	// group role bindings, so keep the current bundle until the user service is available.
+	gs, err := user.New().ListUserGroups()
+	if err != nil {
+		log.Errorf("Failed to list user groups, err: %s", err)
+		return err
+	}
 
 	data := &opaData{
 		{data: generateOPAManifest(), path: manifestPath},
 		{data: generateOPAPolicy(), path: policyPath},
 		{data: generateOPARoles(rs, ps), path: rolesPath},
 		{data: generateOPARoleBindings(bs), path: rolebindingsPath},
+		{data: generateOPAUserGroups(gs), path: userGroupsPath},
 		{data: generateOPAExemptionURLs(ps), path: exemptionPath},
 	}
 
@@ -24,6 +24,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/koderover/zadig/pkg/microservice/policy/core/repository/models"
+	"github.com/koderover/zadig/pkg/shared/client/user"
 	_ "github.com/koderover/zadig/pkg/util/testing"
 )
 
@@ -90,6 +91,23 @@ var testBinding2 = `
 }
 `
 
+var testBinding3 = `
+{
+    "name": "b3",
+    "namespace": "project2",
+    "subjects": [
+        {
+            "kind": "group",
+            "uid": "developers"
+        }
+    ],
+    "roleRef": {
+        "name": "author",
+        "namespace": ""
+    }
+}
+`
+
 var expectOPARoles = `
 {
     "roles": [
@@ -174,6 +192,42 @@ var expectOPARoleBindings = `
 }
 `
 
+var expectOPAGroupRoleBindings = `
+{
+    "role_bindings": null,
+    "group_role_bindings": [
+        {
+            "gid": "developers",
+            "bindings": [
+                {
+                    "namespace": "project2",
+                    "role_refs": [
+                        {
+                            "name": "author",
+                            "namespace": ""
+                        }
+                    ]
+                }
+            ]
+        }
+    ]
+}
+`
+
+var expectOPAUserGroups = `
+{
+    "user_groups": {
+        "alice": [
+            "developers",
+            "testers"
+        ],
+        "bob": [
+            "developers"
+        ]
+    }
+}
+`
+
 var _ = Describe("Testing generate OPA roles and bindings", func() {
 
 	Context("generateOPARoles", func() {
@@ -225,4 +279,34 @@ var _ = Describe("Testing generate OPA roles and bindings", func() {
 		})
 
 	})
+
+	Context("generateOPARoleBindings with group subjects", func() {
+
+		It("should put group subjects into group role bindings", func() {
+			b3 := &models.RoleBinding{}
+			err := json.Unmarshal([]byte(testBinding3), b3)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			data := generateOPARoleBindings([]*models.RoleBinding{b3})
+			actual, err := json.MarshalIndent(data, "", "    ")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(actual)).To(Equal(strings.TrimSpace(expectOPAGroupRoleBindings)))
+		})
+
+	})
+
+	Context("generateOPAUserGroups", func() {
+
+		It("should work as expected", func() {
+			groups := []*user.UserGroup{
+				{GroupID: "testers", UIDs: []string{"alice"}},
+				{GroupID: "developers", UIDs: []string{"bob", "alice"}},
+			}
+			data := generateOPAUserGroups(groups)
+			actual, err := json.MarshalIndent(data, "", "    ")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(actual)).To(Equal(strings.TrimSpace(expectOPAUserGroups)))
+		})
+
+	})
 })
@@ -28,7 +28,7 @@ import (
 
 const UpdateKey = "opa_bundle"
 
-var q = workqueue.New()
+var q = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
 func RefreshOPABundle() {
 	q.Add(UpdateKey)
@@ -71,8 +71,12 @@ func (c *controller) processNextWorkItem() bool {
 
 	err := GenerateOPABundle()
 	if err != nil {
-		c.logger.With(zap.Error(err)).Error("Failed to generate OPA bundle")
+		// retry with backoff, otherwise no bundle is built until the next refresh if the dependencies are down
+		c.logger.With(zap.Error(err)).Error("Failed to generate OPA bundle, requeue it")
+		q.AddRateLimited(t)
+		return true
 	}
 
+	q.Forget(t)
 	return true
 }
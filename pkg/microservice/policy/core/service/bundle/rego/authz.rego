@@ -37,10 +37,12 @@ default allow = false
 
 # Allow everyone to visit public urls.
 allow {
+    not url_is_internal
     url_is_public
 }
 
 allow {
+    not url_is_internal
     is_authenticated
     access_is_granted
 
@@ -103,6 +105,13 @@ url_is_public {
     glob.match(trim(data.exemptions.public[i].endpoint, "/"), ["/"], concat("/", input.parsed_path))
 }
 
+# internal urls are only called between services, they are denied for everyone through the gateway
+url_is_internal {
+    some i
+    data.exemptions.internal[i].method == http_request.method
+    glob.match(trim(data.exemptions.internal[i].endpoint, "/"), ["/"], concat("/", input.parsed_path))
+}
+
 # exempted urls are visible for all authenticated users
 url_is_exempted {
     not url_is_registered
@@ -125,11 +134,29 @@ project_name := pn {
     pn := input.parsed_query.projectName[0]
 }
 
-# get all projects which are visible by current user
-user_projects[project] {
+# get all groups which the current user belongs to
+user_groups[group] {
+    group := data.groups.user_groups[claims.uid][_]
+}
+
+# get all role bindings of current user, including the ones bound to the groups which the user belongs to
+user_role_bindings[role_binding] {
     some i
     data.bindings.role_bindings[i].uid == claims.uid
-    project := data.bindings.role_bindings[i].bindings[_].namespace
+    role_binding := data.bindings.role_bindings[i]
+}
+
+user_role_bindings[role_binding] {
+    some i
+    user_groups[data.bindings.group_role_bindings[i].gid]
+    role_binding := data.bindings.group_role_bindings[i]
+}
+
+# get all projects which are visible by current user
+user_projects[project] {
+    some role_binding
+    user_role_bindings[role_binding]
+    project := role_binding.bindings[_].namespace
 }
 
 # get all projects which are visible by all users (the user name is "*")
@@ -168,17 +195,17 @@ user_visible_projects[project] {
 
 
 all_roles[role_ref] {
-    some i
-    data.bindings.role_bindings[i].uid == claims.uid
-    role_ref := data.bindings.role_bindings[i].bindings[j].role_refs[_]
+    some role_binding
+    user_role_bindings[role_binding]
+    role_ref := role_binding.bindings[_].role_refs[_]
 }
 
 # only roles under the given project are allowed
 allowed_roles[role_ref] {
-    some i
-    data.bindings.role_bindings[i].uid == claims.uid
-    data.bindings.role_bindings[i].bindings[j].namespace == project_name
-    role_ref := data.bindings.role_bindings[i].bindings[j].role_refs[_]
+    some role_binding, j
+    user_role_bindings[role_binding]
+    role_binding.bindings[j].namespace == project_name
+    role_ref := role_binding.bindings[j].role_refs[_]
 }
 
 # if the proejct is visible by all users (the user name is "*"), the bound roles are also allowed
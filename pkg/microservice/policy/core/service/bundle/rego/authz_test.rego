@@ -1 +1,51 @@
-package rbac
\ No newline at end of file
+package rbac
+
+# Run with: opa test authz.rego authz_test.rego test_data/
+# The claims rule is replaced in the tests so that no signed token is needed.
+
+group_member_claims := {"uid": "2e6c5200-358f-11ec-981f-de2269351a1e", "exp": 4102444800}
+
+non_member_claims := {"uid": "bob", "exp": 4102444800}
+
+articles_request(project) = r {
+    r := {
+        "attributes": {"request": {"http": {"method": "GET", "headers": {}}}},
+        "parsed_path": ["api", "articles"],
+        "parsed_query": {"projectName": [project]}
+    }
+}
+
+test_group_member_is_allowed_by_group_role_binding {
+    allow with input as articles_request("project6") with data.rbac.claims as group_member_claims
+}
+
+test_group_member_is_denied_in_projects_not_bound_to_group {
+    not allow with input as articles_request("project4") with data.rbac.claims as group_member_claims
+}
+
+test_non_member_is_denied_by_group_role_binding {
+    not allow with input as articles_request("project6") with data.rbac.claims as non_member_claims
+}
+
+test_group_member_can_see_group_projects {
+    user_visible_projects["project6"] with input as articles_request("project6") with data.rbac.claims as group_member_claims
+}
+
+test_non_member_can_not_see_group_projects {
+    not user_visible_projects["project6"] with input as articles_request("project6") with data.rbac.claims as non_member_claims
+}
+
+test_user_groups_come_from_group_data {
+    user_groups["developers"] with data.rbac.claims as group_member_claims
+    not user_groups["developers"] with data.rbac.claims as non_member_claims
+}
+
+audit_request := {
+    "attributes": {"request": {"http": {"method": "POST", "headers": {}}}},
+    "parsed_path": ["api", "aslan", "system", "audit"],
+    "parsed_query": {}
+}
+
+test_internal_url_is_denied_for_admins {
+    not allow with input as audit_request with data.rbac.claims as group_member_claims with data.rbac.user_is_admin as true
+}
@@ -25,9 +25,11 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/policy/core/repository/mongodb"
 )
 
+// RoleBinding binds a role to a user or to a user group, only one of UID and GID should be set.
 type RoleBinding struct {
 	Name   string `json:"name"`
 	UID    string `json:"uid"`
+	GID    string `json:"gid,omitempty"`
 	Role   string `json:"role"`
 	Public bool   `json:"public"`
 }
@@ -73,12 +75,7 @@ func ListRoleBindings(ns, uid string, _ *zap.SugaredLogger) ([]*RoleBinding, err
 	}
 
 	for _, v := range modelRoleBindings {
-		roleBindings = append(roleBindings, &RoleBinding{
-			Name:   v.Name,
-			Role:   v.RoleRef.Name,
-			UID:    v.Subjects[0].UID,
-			Public: v.RoleRef.Namespace == "",
-		})
+		roleBindings = append(roleBindings, toRoleBinding(v))
 	}
 
 	return roleBindings, nil
@@ -97,12 +94,7 @@ func ListRoleBindingsByRole(ns, roleName string, publicRole bool, _ *zap.Sugared
 	}
 
 	for _, v := range modelRoleBindings {
-		roleBindings = append(roleBindings, &RoleBinding{
-			Name:   v.Name,
-			Role:   v.RoleRef.Name,
-			UID:    v.Subjects[0].UID,
-			Public: v.RoleRef.Namespace == "",
-		})
+		roleBindings = append(roleBindings, toRoleBinding(v))
 	}
 
 	return roleBindings, nil
@@ -124,6 +116,11 @@ func DeleteRoleBindings(names []string, projectName string, _ *zap.SugaredLogger
 	return mongodb.NewRoleBindingColl().DeleteMany(names, projectName)
 }
 
+// DeleteGroupRoleBindings deletes all the role bindings of the given user group, it is called when the group is deleted.
+func DeleteGroupRoleBindings(gid string, _ *zap.SugaredLogger) error {
+	return mongodb.NewRoleBindingColl().DeleteBySubject(models.GroupKind, gid)
+}
+
 func createRoleBindingObject(ns string, rb *RoleBinding, logger *zap.SugaredLogger) (*models.RoleBinding, error) {
 	nsRole := ns
 	if rb.Public {
@@ -138,13 +135,34 @@ func createRoleBindingObject(ns string, rb *RoleBinding, logger *zap.SugaredLogg
 		return nil, fmt.Errorf("role %s not found", rb.Role)
 	}
 
+	subject := &models.Subject{Kind: models.UserKind, UID: rb.UID}
+	if rb.GID != "" {
+		subject = &models.Subject{Kind: models.GroupKind, UID: rb.GID}
+	}
+
 	return &models.RoleBinding{
 		Name:      rb.Name,
 		Namespace: ns,
-		Subjects:  []*models.Subject{{Kind: models.UserKind, UID: rb.UID}},
+		Subjects:  []*models.Subject{subject},
 		RoleRef: &models.RoleRef{
 			Name:      role.Name,
 			Namespace: role.Namespace,
 		},
 	}, nil
 }
+
+func toRoleBinding(rb *models.RoleBinding) *RoleBinding {
+	res := &RoleBinding{
+		Name:   rb.Name,
+		Role:   rb.RoleRef.Name,
+		Public: rb.RoleRef.Namespace == "",
+	}
+
+	if rb.Subjects[0].Kind == models.GroupKind {
+		res.GID = rb.Subjects[0].UID
+	} else {
+		res.UID = rb.Subjects[0].UID
+	}
+
+	return res
+}
@@ -161,7 +161,8 @@ func (c *WorkspaceAchiever) enumerate() error {
 	return nil
 }
 
-func (c *WorkspaceAchiever) Achieve(target string) error {
+// Compress 把匹配缓存路径的文件打包到 dest
+func (c *WorkspaceAchiever) Compress(dest string) error {
 	if err := c.enumerate(); err != nil {
 		return err
 	}
@@ -183,6 +184,21 @@ func (c *WorkspaceAchiever) Achieve(target string) error {
 		}
 	}
 
+	log.Info("achieving caches ...")
+	cmd := exec.Command("tar", "czf", dest, "--no-recursion", "-C", c.wd, "-T", f.Name())
+	cmd.Dir = c.wd
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+
+	if err := cmd.Run(); err != nil {
+		log.Errorf("failed to compress %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *WorkspaceAchiever) Achieve(target string) error {
 	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
 		return err
 	}
@@ -195,14 +211,7 @@ func (c *WorkspaceAchiever) Achieve(target string) error {
 
 	_ = temp.Close()
 
-	log.Info("achieving caches ...")
-	cmd := exec.Command("tar", "czf", temp.Name(), "--no-recursion", "-C", c.wd, "-T", f.Name())
-	cmd.Dir = c.wd
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-
-	if err := cmd.Run(); err != nil {
-		log.Errorf("failed to compress %v", err)
+	if err := c.Compress(temp.Name()); err != nil {
 		return err
 	}
 
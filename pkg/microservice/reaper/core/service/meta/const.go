@@ -28,6 +28,12 @@ const (
 	// ProviderCodehub
 	ProviderCodehub = "codehub"
 
+	// ProviderGitee
+	ProviderGitee = "gitee"
+
+	// ProviderBitbucket Bitbucket Server
+	ProviderBitbucket = "bitbucket"
+
 	//	Oauth prefix
 	OauthTokenPrefix = "oauth2"
 
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+const (
+	CoverageFormatCobertura = "cobertura"
+	CoverageFormatJaCoCo    = "jacoco"
+	CoverageFormatGo        = "go"
+)
+
+// Coverage 覆盖率统计结果，覆盖率为百分比
+type Coverage struct {
+	Format          string  `json:"format"`
+	LinesCovered    int     `json:"lines_covered"`
+	LinesValid      int     `json:"lines_valid"`
+	BranchesCovered int     `json:"branches_covered"`
+	BranchesValid   int     `json:"branches_valid"`
+	LineRate        float64 `json:"line_rate"`
+	BranchRate      float64 `json:"branch_rate"`
+}
+
+// CoberturaReport Cobertura XML 根节点
+type CoberturaReport struct {
+	LineRate        float64 `xml:"line-rate,attr"`
+	BranchRate      float64 `xml:"branch-rate,attr"`
+	LinesCovered    int     `xml:"lines-covered,attr"`
+	LinesValid      int     `xml:"lines-valid,attr"`
+	BranchesCovered int     `xml:"branches-covered,attr"`
+	BranchesValid   int     `xml:"branches-valid,attr"`
+}
+
+// JaCoCoReport JaCoCo XML 根节点，只需要报告级别的汇总计数
+type JaCoCoReport struct {
+	Counters []JaCoCoCounter `xml:"counter"`
+}
+
+type JaCoCoCounter struct {
+	Type    string `xml:"type,attr"`
+	Missed  int    `xml:"missed,attr"`
+	Covered int    `xml:"covered,attr"`
+}
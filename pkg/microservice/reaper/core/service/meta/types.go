@@ -67,9 +67,18 @@ type Context struct {
 	// SSH ssh连接参数
 	SSHs []*SSH `yaml:"sshs"`
 
+	// PMDeployStrategy 物理机分批部署策略
+	PMDeployStrategy *PMDeployStrategy `yaml:"pm_deploy_strategy"`
+
+	// PMHealthChecks 每批部署后执行的健康检查
+	PMHealthChecks []*PMHealthCheck `yaml:"pm_health_checks"`
+
 	// GinkgoTest 执行 ginkgo test 配置
 	GinkgoTest *GinkgoTest `yaml:"ginkgo_test"`
 
+	// CoverageReportPath 覆盖率报告，支持 Cobertura、JaCoCo XML 和 Go coverprofile，与测试结果相互独立
+	CoverageReportPath string `yaml:"coverage_report_path"`
+
 	// Archive: 归档配置 [optional]
 	Archive *Archive `yaml:"archive"`
 
@@ -88,6 +97,12 @@ type Context struct {
 	// Caches Caches配置
 	Caches []string `yaml:"caches"`
 
+	// CacheKeyFiles 非空时按文件内容哈希保存和恢复 Caches
+	CacheKeyFiles []string `yaml:"cache_key_files"`
+
+	// CacheSizeLimit 项目缓存大小上限，单位 MB
+	CacheSizeLimit int64 `yaml:"cache_size_limit"`
+
 	// testType
 	TestType string `yaml:"test_type"`
 
@@ -98,6 +113,8 @@ type Context struct {
 	StorageURI string `yaml:"storage_uri"`
 	// PipelineName
 	PipelineName string `yaml:"pipeline_name"`
+	// ProjectName
+	ProjectName string `yaml:"project_name"`
 	// TaskID
 	TaskID int64 `yaml:"task_id"`
 	// ServiceName
@@ -117,6 +134,17 @@ type Context struct {
 	ArtifactInfo    *ArtifactInfo `yaml:"artifact_info"`
 }
 
+// UseCache 本次构建是否恢复缓存
+func (c *Context) UseCache() bool {
+	return !c.CleanWorkspace && !c.ResetCache
+}
+
+// KeyedCache 是否按 CacheKeyFiles 的内容哈希保存和恢复缓存
+// Caches 为空时不启用，否则整个工作目录（包括代码）都会被缓存，恢复时旧代码会覆盖新拉取的代码
+func (c *Context) KeyedCache() bool {
+	return len(c.CacheKeyFiles) > 0 && len(c.Caches) > 0
+}
+
 type ArtifactInfo struct {
 	URL          string `yaml:"url"`
 	WorkflowName string `yaml:"workflow_name"`
@@ -166,6 +194,32 @@ type SSH struct {
 	PrivateKey string `json:"private_key"`
 }
 
+// PMDeployStrategy 物理机分批部署策略
+type PMDeployStrategy struct {
+	Enabled bool `yaml:"enabled"`
+	// BatchSize 每批部署的主机数，为 0 时按 BatchPercent 计算
+	BatchSize    int `yaml:"batch_size"`
+	BatchPercent int `yaml:"batch_percent"`
+	// PauseSeconds 两批部署之间的等待时间
+	PauseSeconds int `yaml:"pause_seconds"`
+	// InitialDelaySeconds 每批部署完成后等待该时间再开始健康检查
+	InitialDelaySeconds int `yaml:"initial_delay_seconds"`
+	// RollbackOnFailure 某一批失败时在已部署的主机上执行 RollbackScripts
+	RollbackOnFailure bool   `yaml:"rollback_on_failure"`
+	RollbackScripts   string `yaml:"rollback_scripts"`
+}
+
+// PMHealthCheck 服务健康检查配置
+type PMHealthCheck struct {
+	Protocol           string `yaml:"protocol"`
+	Port               int    `yaml:"port"`
+	Path               string `yaml:"path"`
+	TimeOut            int64  `yaml:"time_out"`
+	Interval           uint64 `yaml:"interval"`
+	HealthyThreshold   int    `yaml:"healthy_threshold"`
+	UnhealthyThreshold int    `yaml:"unhealthy_threshold"`
+}
+
 // Proxy 翻墙配置信息
 type Proxy struct {
 	Type                   string `yaml:"type"`
@@ -260,11 +314,14 @@ type Repo struct {
 //
 // e.g. github returns refs/pull/1/head
 // e.g. gitlab returns merge-requests/1/head
+// e.g. bitbucket returns refs/pull-requests/1/from
 func (r *Repo) PRRef() string {
 	if strings.ToLower(r.Source) == ProviderGitlab || strings.ToLower(r.Source) == ProviderCodehub {
 		return fmt.Sprintf("merge-requests/%d/head", r.PR)
 	} else if strings.ToLower(r.Source) == ProviderGerrit {
 		return r.CheckoutRef
+	} else if strings.ToLower(r.Source) == ProviderBitbucket {
+		return fmt.Sprintf("refs/pull-requests/%d/from", r.PR)
 	}
 	return fmt.Sprintf("refs/pull/%d/head", r.PR)
 }
@@ -170,3 +170,45 @@ func (r *Reaper) archiveHTMLTestReportFile() error {
 
 	return nil
 }
+
+// archiveCoverageFile 上传覆盖率统计结果，和测试结果保存在同一目录
+func (r *Reaper) archiveCoverageFile() error {
+	if r.Ctx.Archive == nil || r.Ctx.StorageURI == "" {
+		return nil
+	}
+
+	fileName := r.Ctx.Archive.File + setting.CoverageFileSuffix
+	filePath := path.Join(r.Ctx.Archive.Dir, fileName)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	store, err := s3.NewS3StorageFromEncryptedURI(r.Ctx.StorageURI)
+	if err != nil {
+		log.Errorf("failed to create s3 storage %s, err: %s", r.Ctx.StorageURI, err)
+		return err
+	}
+
+	if store.Subfolder != "" {
+		store.Subfolder = fmt.Sprintf("%s/%s/%d/%s", store.Subfolder, r.Ctx.PipelineName, r.Ctx.TaskID, "test")
+	} else {
+		store.Subfolder = fmt.Sprintf("%s/%d/%s", r.Ctx.PipelineName, r.Ctx.TaskID, "test")
+	}
+
+	forcedPathStyle := true
+	if store.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	s3client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Insecure, forcedPathStyle)
+	if err != nil {
+		log.Errorf("failed to create s3 client, error is: %+v", err)
+		return err
+	}
+	objectKey := store.GetObjectPath(fileName)
+	if err = s3client.Upload(store.Bucket, filePath, objectKey); err != nil {
+		log.Errorf("failed to upload coverage %s, %v", filePath, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reaper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/archive"
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+	"github.com/koderover/zadig/pkg/microservice/reaper/internal/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+const (
+	// defaultCacheSizeLimit 项目未配置缓存大小上限时使用，单位 MB
+	defaultCacheSizeLimit = 10 * 1024
+
+	defaultCacheKey = "default"
+	cacheIndexFile  = "index.json"
+)
+
+var invalidCacheKeyChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// KeyCacheManager 按声明文件的内容哈希保存和恢复缓存
+// 恢复时依次尝试文件哈希、分支和默认三个 key，只有文件哈希未命中时才上传
+// 同一项目的所有缓存共享一个大小上限，超出时按最近使用时间淘汰
+type KeyCacheManager struct {
+	StorageURI  string
+	ProjectName string
+	ServiceName string
+	Branch      string
+	KeyFiles    []string
+	// Paths 需要缓存的目录，不能为空
+	Paths []string
+	// SizeLimit 项目缓存的大小上限，单位字节
+	SizeLimit int64
+
+	restoredKey string
+}
+
+func NewKeyCacheManager(ctx *meta.Context) *KeyCacheManager {
+	limit := ctx.CacheSizeLimit
+	if limit <= 0 {
+		limit = defaultCacheSizeLimit
+	}
+
+	var branch string
+	for _, repo := range ctx.Repos {
+		if repo.Branch != "" {
+			branch = repo.Branch
+			break
+		}
+	}
+
+	// 兼容未传递项目名称的任务
+	projectName := ctx.ProjectName
+	if projectName == "" {
+		projectName = ctx.PipelineName
+	}
+
+	return &KeyCacheManager{
+		StorageURI:  ctx.StorageURI,
+		ProjectName: projectName,
+		ServiceName: ctx.ServiceName,
+		Branch:      branch,
+		KeyFiles:    ctx.CacheKeyFiles,
+		Paths:       ctx.Caches,
+		SizeLimit:   limit << 20,
+	}
+}
+
+// Unarchive 按 key 的优先级恢复第一个存在的缓存
+func (m *KeyCacheManager) Unarchive(source, dest string) error {
+	hash, err := hashKeyFiles(dest, m.KeyFiles)
+	if err != nil {
+		log.Warningf("failed to hash cache key files: %v", err)
+	}
+
+	store, client, err := m.getS3Client()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range m.restoreKeys(hash) {
+		objectKey := store.GetObjectPath(m.objectName(key))
+		files, _ := client.ListFiles(store.Bucket, objectKey, false)
+		if len(files) == 0 {
+			continue
+		}
+
+		if err := m.extract(client, store.Bucket, objectKey, dest); err != nil {
+			return err
+		}
+		log.Infof("cache restored from key %s", key)
+		m.restoredKey = key
+		return nil
+	}
+
+	return fmt.Errorf("cache not found")
+}
+
+// Archive 文件哈希命中时只更新使用时间，否则上传缓存并同步到分支 key，默认 key 不存在时才写入
+// 避免任意分支的构建覆盖所有分支共享的默认缓存
+func (m *KeyCacheManager) Archive(source, dest string) error {
+	hash, err := hashKeyFiles(source, m.KeyFiles)
+	if err != nil {
+		log.Warningf("failed to hash cache key files: %v", err)
+	}
+	keys := m.restoreKeys(hash)
+
+	store, client, err := m.getS3Client()
+	if err != nil {
+		return err
+	}
+
+	if hash != "" && m.restoredKey == keys[0] {
+		log.Infof("cache key %s is hit, skip uploading", keys[0])
+		return m.updateIndex(client, store, nil)
+	}
+
+	temp, err := ioutil.TempFile("", "*reaper.tar.gz")
+	if err != nil {
+		log.Errorf("failed to create temp file %v", err)
+		return err
+	}
+	_ = temp.Close()
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	if err := m.compress(source, temp.Name()); err != nil {
+		return err
+	}
+	info, err := os.Stat(temp.Name())
+	if err != nil {
+		return err
+	}
+
+	primary := store.GetObjectPath(m.objectName(keys[0]))
+	if err := client.Upload(store.Bucket, temp.Name(), primary); err != nil {
+		log.Errorf("failed to upload cache %s: %v", primary, err)
+		return err
+	}
+	log.Infof("cache saved with key %s", keys[0])
+
+	written := map[string]int64{m.entryName(keys[0]): info.Size()}
+	exists := func(key string) bool {
+		files, _ := client.ListFiles(store.Bucket, store.GetObjectPath(m.objectName(key)), false)
+		return len(files) > 0
+	}
+	for _, key := range copyKeys(keys, exists) {
+		if err := client.CopyObject(store.Bucket, primary, store.GetObjectPath(m.objectName(key))); err != nil {
+			log.Warningf("failed to copy cache to key %s: %v", key, err)
+			continue
+		}
+		written[m.entryName(key)] = info.Size()
+	}
+
+	return m.updateIndex(client, store, written)
+}
+
+// copyKeys 返回上传后需要同步的 key，默认 key 已存在时不覆盖
+func copyKeys(keys []string, exists func(key string) bool) []string {
+	var res []string
+	for _, key := range keys[1:] {
+		if key == defaultCacheKey && exists(key) {
+			continue
+		}
+		res = append(res, key)
+	}
+	return res
+}
+
+// restoreKeys 返回按优先级排列的缓存 key，文件哈希为空时跳过
+func (m *KeyCacheManager) restoreKeys(hash string) []string {
+	var keys []string
+	if hash != "" {
+		keys = append(keys, "files-"+hash)
+	}
+	if m.Branch != "" {
+		keys = append(keys, "branch-"+invalidCacheKeyChars.ReplaceAllString(m.Branch, "_"))
+	}
+	return append(keys, defaultCacheKey)
+}
+
+func (m *KeyCacheManager) entryName(key string) string {
+	return fmt.Sprintf("%s/%s.tar.gz", m.ServiceName, key)
+}
+
+func (m *KeyCacheManager) objectName(key string) string {
+	return fmt.Sprintf("cache/%s/%s", m.ProjectName, m.entryName(key))
+}
+
+func (m *KeyCacheManager) indexName() string {
+	return fmt.Sprintf("cache/%s/%s", m.ProjectName, cacheIndexFile)
+}
+
+// compress 只打包 Paths 中的目录，不缓存整个工作目录，避免恢复时旧代码覆盖新拉取的代码
+func (m *KeyCacheManager) compress(source, dest string) error {
+	if len(m.Paths) == 0 {
+		return fmt.Errorf("no cache paths are specified")
+	}
+	return archive.NewWorkspaceAchiever(m.StorageURI, "", m.ServiceName, source, m.Paths, []string{}).Compress(dest)
+}
+
+func (m *KeyCacheManager) extract(client *s3tool.Client, bucket, objectKey, dest string) error {
+	temp, err := ioutil.TempFile("", "*reaper.tar.gz")
+	if err != nil {
+		return err
+	}
+	_ = temp.Close()
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	if err := client.Download(bucket, objectKey, temp.Name()); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return err
+	}
+
+	out := bytes.NewBufferString("")
+	cmd := exec.Command("tar", "xzf", temp.Name(), "-C", dest)
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %v", out.String(), err)
+	}
+	return nil
+}
+
+// updateIndex 记录缓存的大小和使用时间，并淘汰超出项目上限的缓存
+// 并发构建可能同时修改索引，最坏情况是少淘汰或漏记一次使用，不影响缓存的正确性
+func (m *KeyCacheManager) updateIndex(client *s3tool.Client, store *s3.S3, written map[string]int64) error {
+	temp, err := ioutil.TempFile("", "*index.json")
+	if err != nil {
+		return err
+	}
+	_ = temp.Close()
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	indexKey := store.GetObjectPath(m.indexName())
+	index := &cacheIndex{}
+	if err := client.DownloadWithOption(store.Bucket, indexKey, temp.Name(), &s3tool.DownloadOption{IgnoreNotExistError: true, RetryNum: 3}); err == nil {
+		if data, err := ioutil.ReadFile(temp.Name()); err == nil && len(data) > 0 {
+			if err := json.Unmarshal(data, index); err != nil {
+				log.Warningf("failed to parse cache index, rebuild it: %v", err)
+				index = &cacheIndex{}
+			}
+		}
+	}
+
+	now := time.Now().Unix()
+	keep := make(map[string]bool)
+	for name, size := range written {
+		index.touch(name, size, now)
+		keep[name] = true
+	}
+	if m.restoredKey != "" {
+		name := m.entryName(m.restoredKey)
+		index.touch(name, -1, now)
+		keep[name] = true
+	}
+
+	evicted := index.evict(m.SizeLimit, keep)
+	if len(evicted) > 0 {
+		objectKeys := make([]string, 0, len(evicted))
+		for _, name := range evicted {
+			objectKeys = append(objectKeys, store.GetObjectPath(fmt.Sprintf("cache/%s/%s", m.ProjectName, name)))
+		}
+		if err := client.DeleteObjects(store.Bucket, objectKeys); err != nil {
+			log.Warningf("failed to evict caches %v: %v", evicted, err)
+		} else {
+			log.Infof("evicted caches %v", evicted)
+		}
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(temp.Name(), data, 0644); err != nil {
+		return err
+	}
+	return client.Upload(store.Bucket, temp.Name(), indexKey)
+}
+
+func (m *KeyCacheManager) getS3Client() (*s3.S3, *s3tool.Client, error) {
+	store, err := s3.NewS3StorageFromEncryptedURI(m.StorageURI)
+	if err != nil {
+		log.Errorf("failed to create s3 storage %s", m.StorageURI)
+		return nil, nil, err
+	}
+
+	forcedPathStyle := true
+	if store.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Insecure, forcedPathStyle)
+	if err != nil {
+		log.Errorf("failed to create s3 client: %v", err)
+		return nil, nil, err
+	}
+	return store, client, nil
+}
+
+// hashKeyFiles 计算匹配 patterns 的所有文件的路径和内容的哈希，没有匹配的文件时返回空
+func hashKeyFiles(wd string, patterns []string) (string, error) {
+	files := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(wd, pattern))
+		if err != nil {
+			return "", err
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				files[match] = true
+			}
+		}
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	sorted := make([]string, 0, len(files))
+	for file := range files {
+		sorted = append(sorted, file)
+	}
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, file := range sorted {
+		rel, err := filepath.Rel(wd, file)
+		if err != nil {
+			return "", err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		_, _ = io.WriteString(h, filepath.ToSlash(rel)+"\x00")
+		_, err = io.Copy(h, f)
+		_ = f.Close()
+		if err != nil {
+			return "", err
+		}
+		_, _ = h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type cacheIndex struct {
+	Entries []*cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	LastUsed int64  `json:"last_used"`
+}
+
+// touch 更新缓存的使用时间，size 小于 0 时保留原有大小，且不新增记录
+func (idx *cacheIndex) touch(name string, size, now int64) {
+	for _, entry := range idx.Entries {
+		if entry.Name == name {
+			entry.LastUsed = now
+			if size >= 0 {
+				entry.Size = size
+			}
+			return
+		}
+	}
+	if size < 0 {
+		return
+	}
+	idx.Entries = append(idx.Entries, &cacheEntry{Name: name, Size: size, LastUsed: now})
+}
+
+// evict 按最近使用时间从旧到新淘汰缓存，直到总大小不超过 limit，keep 中的缓存不会被淘汰
+func (idx *cacheIndex) evict(limit int64, keep map[string]bool) []string {
+	var total int64
+	for _, entry := range idx.Entries {
+		total += entry.Size
+	}
+	if total <= limit {
+		return nil
+	}
+
+	sort.SliceStable(idx.Entries, func(i, j int) bool {
+		return idx.Entries[i].LastUsed < idx.Entries[j].LastUsed
+	})
+
+	var evicted []string
+	remain := make([]*cacheEntry, 0, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		if total > limit && !keep[entry.Name] {
+			total -= entry.Size
+			evicted = append(evicted, entry.Name)
+			continue
+		}
+		remain = append(remain, entry)
+	}
+	idx.Entries = remain
+
+	return evicted
+}
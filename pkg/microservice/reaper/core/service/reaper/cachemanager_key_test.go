@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reaper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+)
+
+func TestHashKeyFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	wd, err := ioutil.TempDir(os.TempDir(), "reaper")
+	assert.Nil(err)
+	defer func() {
+		_ = os.RemoveAll(wd)
+	}()
+
+	assert.Nil(os.MkdirAll(filepath.Join(wd, "web"), os.ModePerm))
+	assert.Nil(ioutil.WriteFile(filepath.Join(wd, "go.sum"), []byte("a v1.0.0"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(wd, "web", "package-lock.json"), []byte("{}"), 0644))
+
+	hash, err := hashKeyFiles(wd, []string{"go.sum", "*/package-lock.json"})
+	assert.Nil(err)
+	assert.Len(hash, 64)
+
+	same, err := hashKeyFiles(wd, []string{"*/package-lock.json", "go.sum", "go.sum"})
+	assert.Nil(err)
+	assert.Equal(hash, same)
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(wd, "go.sum"), []byte("a v1.0.1"), 0644))
+	changed, err := hashKeyFiles(wd, []string{"go.sum", "*/package-lock.json"})
+	assert.Nil(err)
+	assert.NotEqual(hash, changed)
+
+	empty, err := hashKeyFiles(wd, []string{"yarn.lock"})
+	assert.Nil(err)
+	assert.Empty(empty)
+}
+
+func TestKeyCacheManagerRestoreKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewKeyCacheManager(&meta.Context{
+		PipelineName:  "demo-workflow",
+		ServiceName:   "api",
+		CacheKeyFiles: []string{"go.sum"},
+		Repos:         []*meta.Repo{{Name: "api", Branch: "feature/login"}},
+	})
+	assert.Equal("demo-workflow", m.ProjectName)
+	assert.Equal(int64(defaultCacheSizeLimit)<<20, m.SizeLimit)
+
+	assert.Equal([]string{"files-abc", "branch-feature_login", "default"}, m.restoreKeys("abc"))
+	assert.Equal([]string{"branch-feature_login", "default"}, m.restoreKeys(""))
+	assert.Equal("cache/demo-workflow/api/default.tar.gz", m.objectName("default"))
+
+	m.Branch = ""
+	assert.Equal([]string{"default"}, m.restoreKeys(""))
+}
+
+func TestCopyKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	existing := map[string]bool{}
+	exists := func(key string) bool { return existing[key] }
+
+	// the default key is only seeded when it doesn't exist
+	assert.Equal([]string{"branch-feature", "default"}, copyKeys([]string{"files-abc", "branch-feature", "default"}, exists))
+	assert.Equal([]string{"default"}, copyKeys([]string{"branch-feature", "default"}, exists))
+	assert.Empty(copyKeys([]string{"default"}, exists))
+
+	existing["default"] = true
+	assert.Equal([]string{"branch-feature"}, copyKeys([]string{"files-abc", "branch-feature", "default"}, exists))
+	assert.Empty(copyKeys([]string{"files-abc", "default"}, exists))
+}
+
+func TestCacheIndexEvict(t *testing.T) {
+	assert := assert.New(t)
+
+	index := &cacheIndex{}
+	index.touch("api/files-a.tar.gz", 40, 1)
+	index.touch("api/default.tar.gz", 40, 2)
+	index.touch("web/default.tar.gz", 40, 3)
+	index.touch("api/unknown.tar.gz", -1, 4)
+	assert.Len(index.Entries, 3)
+
+	assert.Nil(index.evict(120, nil))
+
+	index.touch("api/files-a.tar.gz", -1, 5)
+	index.touch("api/files-b.tar.gz", 40, 6)
+	evicted := index.evict(100, map[string]bool{"api/files-b.tar.gz": true})
+	assert.Equal([]string{"api/default.tar.gz", "web/default.tar.gz"}, evicted)
+	assert.Len(index.Entries, 2)
+	assert.Equal(int64(40), index.Entries[0].Size)
+
+	evicted = index.evict(10, map[string]bool{"api/files-a.tar.gz": true, "api/files-b.tar.gz": true})
+	assert.Nil(evicted)
+	assert.Len(index.Entries, 2)
+}
+
+func TestKeyCacheManagerRequiresPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := &meta.Context{CacheKeyFiles: []string{"go.sum"}}
+	assert.False(ctx.KeyedCache())
+	ctx.Caches = []string{"vendor"}
+	assert.True(ctx.KeyedCache())
+
+	m := &KeyCacheManager{ServiceName: "api"}
+	assert.NotNil(m.compress(os.TempDir(), filepath.Join(os.TempDir(), "cache.tar.gz")))
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reaper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const goCoverProfilePrefix = "mode:"
+
+// generateCoverage 解析覆盖率报告，将统计结果写入到测试结果的上传目录中，不要求存在测试结果
+func generateCoverage(coverageReportPath, testResultFile, testUploadPath string) error {
+	data, err := ioutil.ReadFile(coverageReportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read coverage report %s: %v", coverageReportPath, err)
+	}
+
+	coverage, err := parseCoverage(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse coverage report %s: %v", coverageReportPath, err)
+	}
+
+	b, err := json.Marshal(coverage)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(testUploadPath, os.ModePerm); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(path.Join(testUploadPath, testResultFile+setting.CoverageFileSuffix), b, 0644); err != nil {
+		return err
+	}
+
+	log.Infof("%s coverage: line %.2f%%, branch %.2f%%", coverage.Format, coverage.LineRate, coverage.BranchRate)
+	return nil
+}
+
+// parseCoverage 根据内容识别覆盖率报告格式
+func parseCoverage(data []byte) (*meta.Coverage, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(goCoverProfilePrefix)) {
+		return parseGoCoverProfile(data)
+	}
+
+	root, err := xmlRootName(data)
+	if err != nil {
+		return nil, err
+	}
+	switch root {
+	case "coverage":
+		return parseCobertura(data)
+	case "report":
+		return parseJaCoCo(data)
+	default:
+		return nil, fmt.Errorf("unsupported coverage report with root element %q", root)
+	}
+}
+
+func xmlRootName(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// JaCoCo 报告中带有 DOCTYPE，不需要校验
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to find root element: %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func parseCobertura(data []byte) (*meta.Coverage, error) {
+	report := &meta.CoberturaReport{}
+	if err := xml.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+
+	coverage := &meta.Coverage{
+		Format:          meta.CoverageFormatCobertura,
+		LinesCovered:    report.LinesCovered,
+		LinesValid:      report.LinesValid,
+		BranchesCovered: report.BranchesCovered,
+		BranchesValid:   report.BranchesValid,
+		LineRate:        report.LineRate * 100,
+		BranchRate:      report.BranchRate * 100,
+	}
+	// 新版本的 Cobertura 报告带有计数，优先使用计数计算
+	if report.LinesValid > 0 {
+		coverage.LineRate = percent(report.LinesCovered, report.LinesValid)
+	}
+	if report.BranchesValid > 0 {
+		coverage.BranchRate = percent(report.BranchesCovered, report.BranchesValid)
+	}
+	return coverage, nil
+}
+
+func parseJaCoCo(data []byte) (*meta.Coverage, error) {
+	report := &meta.JaCoCoReport{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	if err := decoder.Decode(report); err != nil {
+		return nil, err
+	}
+
+	coverage := &meta.Coverage{Format: meta.CoverageFormatJaCoCo}
+	for _, counter := range report.Counters {
+		switch counter.Type {
+		case "LINE":
+			coverage.LinesCovered = counter.Covered
+			coverage.LinesValid = counter.Covered + counter.Missed
+		case "BRANCH":
+			coverage.BranchesCovered = counter.Covered
+			coverage.BranchesValid = counter.Covered + counter.Missed
+		}
+	}
+	coverage.LineRate = percent(coverage.LinesCovered, coverage.LinesValid)
+	coverage.BranchRate = percent(coverage.BranchesCovered, coverage.BranchesValid)
+	return coverage, nil
+}
+
+// parseGoCoverProfile Go coverprofile 没有分支覆盖率，行覆盖率按语句数统计
+// 每行格式为 name.go:line.column,line.column numberOfStatements count
+func parseGoCoverProfile(data []byte) (*meta.Coverage, error) {
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := make(map[string]*block)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// 合并多个 profile 时会出现多个 mode 行
+		if line == "" || strings.HasPrefix(line, goCoverProfilePrefix) {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid coverprofile line: %s", line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line: %s", line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line: %s", line)
+		}
+
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	coverage := &meta.Coverage{Format: meta.CoverageFormatGo}
+	for _, b := range blocks {
+		coverage.LinesValid += b.statements
+		if b.covered {
+			coverage.LinesCovered += b.statements
+		}
+	}
+	coverage.LineRate = percent(coverage.LinesCovered, coverage.LinesValid)
+	return coverage, nil
+}
+
+func percent(covered, valid int) float64 {
+	if valid == 0 {
+		return 0
+	}
+	return float64(covered) * 100 / float64(valid)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reaper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+func TestParseCoverage(t *testing.T) {
+	assert := assert.New(t)
+
+	cobertura := `<?xml version="1.0" ?>
+<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">
+<coverage line-rate="0.5" branch-rate="0.25" lines-covered="40" lines-valid="50" branches-covered="3" branches-valid="4" version="5.5">
+	<packages></packages>
+</coverage>`
+	coverage, err := parseCoverage([]byte(cobertura))
+	assert.Nil(err)
+	assert.Equal(meta.CoverageFormatCobertura, coverage.Format)
+	assert.InDelta(80, coverage.LineRate, 0.001)
+	assert.InDelta(75, coverage.BranchRate, 0.001)
+
+	jacoco := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="demo">
+	<package name="demo">
+		<counter type="LINE" missed="100" covered="100"/>
+	</package>
+	<counter type="INSTRUCTION" missed="10" covered="90"/>
+	<counter type="BRANCH" missed="1" covered="3"/>
+	<counter type="LINE" missed="10" covered="30"/>
+</report>`
+	coverage, err = parseCoverage([]byte(jacoco))
+	assert.Nil(err)
+	assert.Equal(meta.CoverageFormatJaCoCo, coverage.Format)
+	assert.Equal(30, coverage.LinesCovered)
+	assert.Equal(40, coverage.LinesValid)
+	assert.InDelta(75, coverage.LineRate, 0.001)
+	assert.InDelta(75, coverage.BranchRate, 0.001)
+
+	profile := `mode: set
+github.com/demo/a.go:3.20,5.2 2 1
+github.com/demo/a.go:7.20,9.2 3 0
+mode: set
+github.com/demo/a.go:7.20,9.2 3 1
+github.com/demo/b.go:3.20,5.2 5 0
+`
+	coverage, err = parseCoverage([]byte(profile))
+	assert.Nil(err)
+	assert.Equal(meta.CoverageFormatGo, coverage.Format)
+	assert.Equal(5, coverage.LinesCovered)
+	assert.Equal(10, coverage.LinesValid)
+	assert.InDelta(50, coverage.LineRate, 0.001)
+
+	_, err = parseCoverage([]byte(`<testsuite tests="1"></testsuite>`))
+	assert.NotNil(err)
+}
+
+func TestGenerateCoverageWithoutTestResults(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "coverage")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	reportPath := filepath.Join(dir, "cover.out")
+	assert.Nil(ioutil.WriteFile(reportPath, []byte("mode: set\ngithub.com/demo/a.go:3.20,5.2 2 1\n"), 0644))
+
+	// 没有测试结果时上传目录可能还不存在
+	uploadPath := filepath.Join(dir, "dist")
+	assert.Nil(generateCoverage(reportPath, "demo", uploadPath))
+
+	b, err := ioutil.ReadFile(filepath.Join(uploadPath, "demo"+setting.CoverageFileSuffix))
+	assert.Nil(err)
+	coverage := new(meta.Coverage)
+	assert.Nil(json.Unmarshal(b, coverage))
+	assert.InDelta(100, coverage.LineRate, 0.001)
+}
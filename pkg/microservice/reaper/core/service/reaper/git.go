@@ -192,6 +192,29 @@ func (r *Reaper) buildGitCommands(repo *meta.Repo) []*c.Command {
 			Cmd:          c.RemoteAdd(repo.RemoteName, fmt.Sprintf("%s://%s:%s@%s/%s/%s.git", u.Scheme, user, repo.Password, u.Host, repo.Owner, repo.Name)),
 			DisableTrace: true,
 		})
+	} else if repo.Source == meta.ProviderGitee {
+		u, _ := url.Parse(repo.Address)
+		user := meta.OauthTokenPrefix
+		if repo.User != "" {
+			user = repo.User
+		}
+		u.Path = fmt.Sprintf("/%s/%s.git", repo.Owner, repo.Name)
+		u.User = url.UserPassword(user, repo.OauthToken)
+
+		cmds = append(cmds, &c.Command{
+			Cmd:          c.RemoteAdd(repo.RemoteName, u.String()),
+			DisableTrace: true,
+		})
+	} else if repo.Source == meta.ProviderBitbucket {
+		// Bitbucket Server 的仓库地址为 /scm/{project}/{repo}.git，使用用户名和 access token 认证
+		u, _ := url.Parse(repo.Address)
+		u.Path = fmt.Sprintf("%s/scm/%s/%s.git", strings.TrimSuffix(u.Path, "/"), repo.Owner, repo.Name)
+		u.User = url.UserPassword(repo.User, repo.OauthToken)
+
+		cmds = append(cmds, &c.Command{
+			Cmd:          c.RemoteAdd(repo.RemoteName, u.String()),
+			DisableTrace: true,
+		})
 	} else {
 		// github
 		cmds = append(cmds, &c.Command{Cmd: c.RemoteAdd(repo.RemoteName, r.Ctx.Git.HTTPSCloneURL(repo.Source, repo.OauthToken, repo.Owner, repo.Name)), DisableTrace: true})
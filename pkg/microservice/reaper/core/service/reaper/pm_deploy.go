@@ -0,0 +1,309 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reaper
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	pmDeployScriptFile   = "pm_deploy_script.sh"
+	pmRollbackScriptFile = "pm_rollback_script.sh"
+
+	defaultProbeInterval      = 2
+	defaultProbeTimeout       = 3
+	defaultUnhealthyThreshold = 3
+)
+
+// runPMDeployBatches 按批次部署主机，同一批主机并行执行部署脚本，
+// 每批部署后对该批主机做健康检查，失败时停止后续批次，开启回滚时在已部署的主机上执行回滚脚本
+func (r *Reaper) runPMDeployBatches() error {
+	strategy := r.Ctx.PMDeployStrategy
+	batches := splitHostBatches(r.Ctx.SSHs, strategy)
+	deployed := make([]*meta.SSH, 0, len(r.Ctx.SSHs))
+
+	for i, batch := range batches {
+		if i > 0 && strategy.PauseSeconds > 0 {
+			log.Infof("pause %d seconds before next batch", strategy.PauseSeconds)
+			time.Sleep(time.Duration(strategy.PauseSeconds) * time.Second)
+		}
+
+		log.Infof("deploying batch %d/%d: %s", i+1, len(batches), hostNames(batch))
+		deployed = append(deployed, batch...)
+		err := r.runPMScriptOnHosts(pmDeployScriptFile, strings.Join(r.Ctx.PMDeployScripts, "\n"), batch)
+		if err == nil {
+			err = r.checkPMHealth(batch, strategy.InitialDelaySeconds)
+		}
+		if err == nil {
+			log.Infof("batch %d/%d is deployed", i+1, len(batches))
+			continue
+		}
+
+		err = fmt.Errorf("batch %d/%d failed: %v", i+1, len(batches), err)
+		if !strategy.RollbackOnFailure || strategy.RollbackScripts == "" {
+			return err
+		}
+		log.Infof("rolling back %s", hostNames(deployed))
+		rollbackScripts := strings.Replace(strategy.RollbackScripts, "\r\n", "\n", -1)
+		if rollbackErr := r.runPMScriptOnHosts(pmRollbackScriptFile, rollbackScripts, deployed); rollbackErr != nil {
+			return fmt.Errorf("%v, rollback failed: %v", err, rollbackErr)
+		}
+		return fmt.Errorf("%v, rolled back %s", err, hostNames(deployed))
+	}
+
+	return nil
+}
+
+// runPMScriptOnHosts 在每台主机上并行执行一次脚本，除了 <name>_IP 等变量外，
+// 还通过 PM_HOST_NAME、PM_HOST_IP、PM_HOST_USERNAME、PM_HOST_PK 提供当前主机的连接参数
+func (r *Reaper) runPMScriptOnHosts(file, scripts string, hosts []*meta.SSH) error {
+	scriptFile := filepath.Join(os.TempDir(), file)
+	if err := ioutil.WriteFile(scriptFile, []byte(scripts), 0700); err != nil {
+		return fmt.Errorf("write script file error: %v", err)
+	}
+
+	cmds := make([]*exec.Cmd, 0, len(hosts))
+	for _, host := range hosts {
+		envs, err := r.sshEnvs(host)
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.Command("/bin/bash", scriptFile)
+		cmd.Dir = r.ActiveWorkspace
+		cmd.Env = append(r.getUserEnvs(), envs...)
+		cmd.Env = append(cmd.Env,
+			fmt.Sprintf("PM_HOST_NAME=%s", host.Name),
+			fmt.Sprintf("PM_HOST_IP=%s", host.IP),
+			fmt.Sprintf("PM_HOST_USERNAME=%s", host.UserName),
+			fmt.Sprintf("PM_HOST_PK=%s", filepath.Join(os.TempDir(), host.Name+"_PK")),
+		)
+		cmds = append(cmds, cmd)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []string
+	)
+	for i := range cmds {
+		wg.Add(1)
+		go func(host *meta.SSH, cmd *exec.Cmd) {
+			defer wg.Done()
+			if err := r.runHostCommand(host.Name, cmd); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%s: %v", host.Name, err))
+				mu.Unlock()
+			}
+		}(hosts[i], cmds[i])
+	}
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// runHostCommand 执行命令，输出以主机名为前缀
+func (r *Reaper) runHostCommand(name string, cmd *exec.Cmd) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, reader := range []io.Reader{stdout, stderr} {
+		wg.Add(1)
+		go func(reader io.Reader) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(reader)
+			for scanner.Scan() {
+				fmt.Printf("[%s] %s\n", name, r.maskSecretEnvs(scanner.Text()))
+			}
+		}(reader)
+	}
+	wg.Wait()
+
+	return cmd.Wait()
+}
+
+// checkPMHealth 等待 initialDelay 秒让服务启动后，对每台主机执行服务的所有健康检查
+func (r *Reaper) checkPMHealth(hosts []*meta.SSH, initialDelay int) error {
+	if len(r.Ctx.PMHealthChecks) == 0 {
+		return nil
+	}
+	if initialDelay > 0 {
+		log.Infof("wait %d seconds before health checks", initialDelay)
+		time.Sleep(time.Duration(initialDelay) * time.Second)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []string
+	)
+	for _, host := range hosts {
+		for _, check := range r.Ctx.PMHealthChecks {
+			wg.Add(1)
+			go func(host *meta.SSH, check *meta.PMHealthCheck) {
+				defer wg.Done()
+				if err := waitHealthy(check, host.IP); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Sprintf("%s: %v", host.Name, err))
+					mu.Unlock()
+				}
+			}(host, check)
+		}
+	}
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return fmt.Errorf("health check failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// waitHealthy 连续成功 HealthyThreshold 次认为健康，连续失败 UnhealthyThreshold 次认为不健康
+func waitHealthy(check *meta.PMHealthCheck, address string) error {
+	interval := time.Duration(check.Interval) * time.Second
+	if check.Interval == 0 {
+		interval = defaultProbeInterval * time.Second
+	}
+	healthyThreshold := check.HealthyThreshold
+	if healthyThreshold <= 0 {
+		healthyThreshold = 1
+	}
+	unhealthyThreshold := check.UnhealthyThreshold
+	if unhealthyThreshold <= 0 {
+		unhealthyThreshold = defaultUnhealthyThreshold
+	}
+
+	var healthy, unhealthy int
+	for {
+		err := probe(check, address)
+		if err == nil {
+			healthy, unhealthy = healthy+1, 0
+			if healthy >= healthyThreshold {
+				return nil
+			}
+		} else {
+			healthy, unhealthy = 0, unhealthy+1
+			if unhealthy >= unhealthyThreshold {
+				return fmt.Errorf("%s probe on %s failed %d times: %v", check.Protocol, address, unhealthy, err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+func probe(check *meta.PMHealthCheck, address string) error {
+	timeout := time.Duration(check.TimeOut) * time.Second
+	if check.TimeOut == 0 {
+		timeout = defaultProbeTimeout * time.Second
+	}
+	host := address
+	if check.Port != 0 {
+		host = net.JoinHostPort(address, strconv.Itoa(check.Port))
+	}
+
+	switch check.Protocol {
+	case setting.ProtocolHTTP, setting.ProtocolHTTPS:
+		client := &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+				Proxy:             http.ProxyURL(nil),
+			},
+		}
+		path := check.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		res, err := client.Get(fmt.Sprintf("%s://%s%s", check.Protocol, host, path))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("HTTP probe failed with statuscode: %d", res.StatusCode)
+		}
+		return nil
+	case setting.ProtocolTCP:
+		conn, err := net.DialTimeout("tcp", host, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		return fmt.Errorf("unsupported health check protocol %q", check.Protocol)
+	}
+}
+
+// splitHostBatches 按 BatchSize 或 BatchPercent 把主机分批，都未设置时所有主机为一批
+func splitHostBatches(hosts []*meta.SSH, strategy *meta.PMDeployStrategy) [][]*meta.SSH {
+	size := strategy.BatchSize
+	if size <= 0 && strategy.BatchPercent > 0 {
+		size = (len(hosts)*strategy.BatchPercent + 99) / 100
+	}
+	if size <= 0 || size > len(hosts) {
+		size = len(hosts)
+	}
+
+	batches := make([][]*meta.SSH, 0, (len(hosts)+size-1)/size)
+	for start := 0; start < len(hosts); start += size {
+		end := start + size
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+		batches = append(batches, hosts[start:end])
+	}
+	return batches
+}
+
+func hostNames(hosts []*meta.SSH) string {
+	names := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reaper
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+	"github.com/koderover/zadig/pkg/setting"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+func testHosts(names ...string) []*meta.SSH {
+	hosts := make([]*meta.SSH, 0, len(names))
+	for _, name := range names {
+		hosts = append(hosts, &meta.SSH{Name: name, IP: "127.0.0.1", UserName: "root"})
+	}
+	return hosts
+}
+
+func TestSplitHostBatches(t *testing.T) {
+	assert := assert.New(t)
+	hosts := testHosts("a", "b", "c", "d", "e")
+
+	batches := splitHostBatches(hosts, &meta.PMDeployStrategy{BatchSize: 2})
+	assert.Len(batches, 3)
+	assert.Equal("e", hostNames(batches[2]))
+
+	batches = splitHostBatches(hosts, &meta.PMDeployStrategy{BatchPercent: 30})
+	assert.Len(batches, 3)
+	assert.Equal("a, b", hostNames(batches[0]))
+
+	batches = splitHostBatches(hosts, &meta.PMDeployStrategy{})
+	assert.Len(batches, 1)
+
+	batches = splitHostBatches(hosts, &meta.PMDeployStrategy{BatchSize: 10})
+	assert.Len(batches, 1)
+}
+
+func TestWaitHealthy(t *testing.T) {
+	assert := assert.New(t)
+
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy || r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	assert.Nil(err)
+	host, portStr, err := net.SplitHostPort(u.Host)
+	assert.Nil(err)
+	port, err := strconv.Atoi(portStr)
+	assert.Nil(err)
+
+	check := &meta.PMHealthCheck{Protocol: setting.ProtocolHTTP, Port: port, Path: "healthz", UnhealthyThreshold: 1}
+	assert.Nil(waitHealthy(check, host))
+
+	healthy = false
+	assert.NotNil(waitHealthy(check, host))
+
+	tcpCheck := &meta.PMHealthCheck{Protocol: setting.ProtocolTCP, Port: port, UnhealthyThreshold: 1}
+	assert.Nil(waitHealthy(tcpCheck, host))
+
+	server.Close()
+	assert.NotNil(waitHealthy(tcpCheck, host))
+}
+
+func TestReaper_CheckPMHealthInitialDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	ready := time.Now().Add(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if time.Now().Before(ready) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	assert.Nil(err)
+	_, portStr, err := net.SplitHostPort(u.Host)
+	assert.Nil(err)
+	port, err := strconv.Atoi(portStr)
+	assert.Nil(err)
+
+	r := &Reaper{
+		Ctx: &meta.Context{
+			PMHealthChecks: []*meta.PMHealthCheck{{Protocol: setting.ProtocolHTTP, Port: port, UnhealthyThreshold: 1}},
+		},
+	}
+	hosts := testHosts("pmtest-a")
+
+	// 服务还未启动完成时立即检查会失败，等待启动后再检查则通过
+	assert.NotNil(r.checkPMHealth(hosts, 0))
+	assert.Nil(r.checkPMHealth(hosts, 1))
+}
+
+func TestReaper_RunPMDeployBatches(t *testing.T) {
+	assert := assert.New(t)
+
+	wd, err := ioutil.TempDir(os.TempDir(), "reaper")
+	assert.Nil(err)
+	defer func() {
+		_ = os.RemoveAll(wd)
+		for _, name := range []string{"pmtest-a", "pmtest-b", "pmtest-c", "pmtest-d"} {
+			_ = os.Remove(filepath.Join(os.TempDir(), name+"_PK"))
+		}
+	}()
+
+	r := &Reaper{
+		Ctx: &meta.Context{
+			PMDeployScripts: []string{
+				`echo "$PM_HOST_NAME" >> deployed.txt`,
+				`[ "$PM_HOST_NAME" != "pmtest-c" ]`,
+			},
+			SSHs: testHosts("pmtest-a", "pmtest-b", "pmtest-c", "pmtest-d"),
+			PMDeployStrategy: &meta.PMDeployStrategy{
+				Enabled:           true,
+				BatchSize:         2,
+				RollbackOnFailure: true,
+				RollbackScripts:   `echo "$PM_HOST_NAME" >> rolledback.txt`,
+			},
+		},
+		ActiveWorkspace: wd,
+	}
+
+	err = r.RunPMDeployScripts()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "batch 2/2 failed")
+	assert.Contains(err.Error(), "rolled back")
+
+	assert.Equal([]string{"pmtest-a", "pmtest-b", "pmtest-c", "pmtest-d"}, readHostLines(t, filepath.Join(wd, "deployed.txt")))
+	assert.Equal([]string{"pmtest-a", "pmtest-b", "pmtest-c", "pmtest-d"}, readHostLines(t, filepath.Join(wd, "rolledback.txt")))
+
+	// 第一批失败时不会部署后续批次
+	assert.Nil(os.Remove(filepath.Join(wd, "deployed.txt")))
+	r.Ctx.PMDeployStrategy.RollbackOnFailure = false
+	r.Ctx.SSHs = testHosts("pmtest-c", "pmtest-a")
+	r.Ctx.PMDeployStrategy.BatchSize = 1
+	assert.NotNil(r.RunPMDeployScripts())
+	assert.Equal([]string{"pmtest-c"}, readHostLines(t, filepath.Join(wd, "deployed.txt")))
+}
+
+func readHostLines(t *testing.T, file string) []string {
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	lines := strings.Fields(string(data))
+	sort.Strings(lines)
+	return lines
+}
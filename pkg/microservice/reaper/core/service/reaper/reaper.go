@@ -76,6 +76,9 @@ func NewReaper() (*Reaper, error) {
 		Ctx: ctx,
 		cm:  NewTarCacheManager(ctx.StorageURI, ctx.PipelineName, ctx.ServiceName),
 	}
+	if ctx.KeyedCache() {
+		reaper.cm = NewKeyCacheManager(ctx)
+	}
 
 	return reaper, nil
 }
@@ -97,7 +100,13 @@ func (r *Reaper) CompressCache(storageURI string) error {
 		log.Errorf("EnsureActiveWorkspace err:%v", err)
 		return err
 	}
-	if len(r.Ctx.Caches) > 0 {
+	if r.Ctx.KeyedCache() {
+		log.Infof("caches will be saved by key files: %v", r.Ctx.CacheKeyFiles)
+		if err := r.cm.Archive(r.ActiveWorkspace, r.GetCacheFile()); err != nil {
+			return err
+		}
+		log.Info("succeed to save caches")
+	} else if len(r.Ctx.Caches) > 0 {
 		log.Infof("custom caches will be cached: %v", r.Ctx.Caches)
 		if err := r.archiveCustomCaches(r.ActiveWorkspace, r.GetCacheFile(), r.Ctx.Caches); err != nil {
 			return err
@@ -195,7 +204,8 @@ func (r *Reaper) BeforeExec() error {
 	// 如果 CleanWorkspace=True，永远不使用缓存
 	// 如果 CleanWorkspace=False，本次工作流 ResetCache=False，使用缓存；本次工作流 ResetCache=True，不使用缓存
 	// TODO: CleanWorkspace 和 ResetCache 严重词不达意，需要改成更合理的值
-	if !r.Ctx.CleanWorkspace && !r.Ctx.ResetCache {
+	// 按文件哈希保存的缓存依赖代码中的文件，在拉取代码后恢复
+	if r.Ctx.UseCache() && !r.Ctx.KeyedCache() {
 		// 恢复缓存
 		//if _, err := os.Stat(r.GetCacheFile()); err == nil {
 		// 解压缓存
@@ -361,6 +371,15 @@ func (r *Reaper) Exec() error {
 		return err
 	}
 
+	if r.Ctx.UseCache() && r.Ctx.KeyedCache() {
+		log.Info("restoring caches by key files ...")
+		if err := r.DecompressCache(); err != nil {
+			log.Infof("no previous cache is found: %v", err)
+		} else {
+			log.Info("succeed to restore caches")
+		}
+	}
+
 	// 生成Git commits信息
 	if err := r.createReadme(ReadmeFile); err != nil {
 		log.Warningf("create readme file error: %v", err)
@@ -429,6 +448,21 @@ func (r *Reaper) AfterExec(upStreamErr error) error {
 
 	}
 
+	// 覆盖率报告不依赖测试结果和测试类型，解析失败不影响测试结果，是否通过由覆盖率门禁判断
+	if r.Ctx.CoverageReportPath != "" && r.Ctx.Archive != nil {
+		coverageReportPath := r.Ctx.CoverageReportPath
+		if !strings.HasPrefix(coverageReportPath, "/") {
+			coverageReportPath = filepath.Join(r.ActiveWorkspace, coverageReportPath)
+		}
+		if err = generateCoverage(coverageReportPath, r.Ctx.Archive.File, r.Ctx.Archive.Dir); err != nil {
+			log.Warningf("generate coverage err %v", err)
+		} else if err = r.archiveCoverageFile(); err != nil {
+			// 将覆盖率统计结果上传到S3
+			log.Errorf("archiveCoverageFile err %v", err)
+			return err
+		}
+	}
+
 	// should archive file first, since compress cache will clean the workspace
 	if upStreamErr == nil {
 		if r.Ctx.ArtifactInfo == nil {
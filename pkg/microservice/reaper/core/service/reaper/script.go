@@ -28,6 +28,7 @@ import (
 	"strings"
 
 	"github.com/koderover/zadig/pkg/microservice/reaper/config"
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
 	"github.com/koderover/zadig/pkg/microservice/reaper/internal/s3"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
@@ -320,8 +321,11 @@ func (r *Reaper) RunPMDeployScripts() error {
 		return nil
 	}
 
+	if r.Ctx.PMDeployStrategy != nil && r.Ctx.PMDeployStrategy.Enabled && len(r.Ctx.SSHs) > 0 {
+		return r.runPMDeployBatches()
+	}
+
 	scripts := r.Ctx.PMDeployScripts
-	pmDeployScriptFile := "pm_deploy_script.sh"
 	if err := ioutil.WriteFile(filepath.Join(os.TempDir(), pmDeployScriptFile), []byte(strings.Join(scripts, "\n")), 0700); err != nil {
 		return fmt.Errorf("write script file error: %v", err)
 	}
@@ -332,19 +336,11 @@ func (r *Reaper) RunPMDeployScripts() error {
 	cmd.Env = r.getUserEnvs()
 	// ssh连接参数
 	for _, ssh := range r.Ctx.SSHs {
-		decodeBytes, err := base64.StdEncoding.DecodeString(ssh.PrivateKey)
+		envs, err := r.sshEnvs(ssh)
 		if err != nil {
-			return fmt.Errorf("decode private_key failed, error: %v", err)
-		}
-		if err = ioutil.WriteFile(filepath.Join(os.TempDir(), ssh.Name+"_PK"), decodeBytes, 0600); err != nil {
-			return fmt.Errorf("write private_key file error: %v", err)
+			return err
 		}
-
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s_PK=%s", ssh.Name, filepath.Join(os.TempDir(), ssh.Name+"_PK")))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s_IP=%s", ssh.Name, ssh.IP))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s_USERNAME=%s", ssh.Name, ssh.UserName))
-
-		r.Ctx.SecretEnvs = append(r.Ctx.SecretEnvs, fmt.Sprintf("%s_PK=%s", ssh.Name, filepath.Join(os.TempDir(), ssh.Name+"_PK")))
+		cmd.Env = append(cmd.Env, envs...)
 	}
 
 	cmdOutReader, err := cmd.StdoutPipe()
@@ -374,6 +370,25 @@ func (r *Reaper) RunPMDeployScripts() error {
 	return cmd.Run()
 }
 
+// sshEnvs 写入主机私钥文件，返回脚本中使用的主机连接变量
+func (r *Reaper) sshEnvs(ssh *meta.SSH) ([]string, error) {
+	decodeBytes, err := base64.StdEncoding.DecodeString(ssh.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode private_key failed, error: %v", err)
+	}
+	pkFile := filepath.Join(os.TempDir(), ssh.Name+"_PK")
+	if err = ioutil.WriteFile(pkFile, decodeBytes, 0600); err != nil {
+		return nil, fmt.Errorf("write private_key file error: %v", err)
+	}
+
+	r.Ctx.SecretEnvs = append(r.Ctx.SecretEnvs, fmt.Sprintf("%s_PK=%s", ssh.Name, pkFile))
+	return []string{
+		fmt.Sprintf("%s_PK=%s", ssh.Name, pkFile),
+		fmt.Sprintf("%s_IP=%s", ssh.Name, ssh.IP),
+		fmt.Sprintf("%s_USERNAME=%s", ssh.Name, ssh.UserName),
+	}, nil
+}
+
 func (r *Reaper) downloadArtifactFile() error {
 	var err error
 	var store *s3.S3
@@ -9,6 +9,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
@@ -41,7 +42,12 @@ func (c *CodehostColl) EnsureIndex(ctx context.Context) error {
 
 func (c *CodehostColl) AddCodeHost(iCodeHost *models.CodeHost) (*models.CodeHost, error) {
 
-	_, err := c.Collection.InsertOne(context.TODO(), iCodeHost)
+	doc, err := encryptCodeHost(iCodeHost)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.Collection.InsertOne(context.TODO(), doc)
 	if err != nil {
 		log.Error("repository AddCodeHost err : %v", err)
 		return nil, err
@@ -70,6 +76,9 @@ func (c *CodehostColl) GetCodeHostByID(ID int) (*models.CodeHost, error) {
 	if err := c.Collection.FindOne(context.TODO(), query).Decode(codehost); err != nil {
 		return nil, err
 	}
+	if err := decryptCodeHost(codehost); err != nil {
+		return nil, err
+	}
 	return codehost, nil
 }
 
@@ -98,6 +107,11 @@ func (c *CodehostColl) List(args *ListArgs) ([]*models.CodeHost, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, codeHost := range codeHosts {
+		if err = decryptCodeHost(codeHost); err != nil {
+			return nil, err
+		}
+	}
 	return codeHosts, nil
 }
 
@@ -112,6 +126,11 @@ func (c *CodehostColl) CodeHostList() ([]*models.CodeHost, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, codeHost := range codeHosts {
+		if err = decryptCodeHost(codeHost); err != nil {
+			return nil, err
+		}
+	}
 	return codeHosts, nil
 }
 
@@ -129,30 +148,53 @@ func (c *CodehostColl) DeleteCodeHostByID(ID int) error {
 }
 
 func (c *CodehostColl) UpdateCodeHost(host *models.CodeHost) (*models.CodeHost, error) {
+	doc, err := encryptCodeHost(host)
+	if err != nil {
+		return nil, err
+	}
+
 	query := bson.M{"id": host.ID, "deleted_at": 0}
 	change := bson.M{"$set": bson.M{
 		"type":           host.Type,
 		"address":        host.Address,
 		"namespace":      host.Namespace,
 		"application_id": host.ApplicationId,
-		"client_secret":  host.ClientSecret,
+		"client_secret":  doc.ClientSecret,
 		"region":         host.Region,
 		"username":       host.Username,
-		"password":       host.Password,
+		"password":       doc.Password,
 		"updated_at":     time.Now().Unix(),
 	}}
-	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
+	_, err = c.Collection.UpdateOne(context.TODO(), query, change)
 	return host, err
 }
 
 func (c *CodehostColl) UpdateCodeHostByToken(host *models.CodeHost) (*models.CodeHost, error) {
+	doc, err := encryptCodeHost(host)
+	if err != nil {
+		return nil, err
+	}
+
 	query := bson.M{"id": host.ID, "deleted_at": 0}
 	change := bson.M{"$set": bson.M{
 		"is_ready":      "2",
-		"access_token":  host.AccessToken,
+		"access_token":  doc.AccessToken,
 		"updated_at":    time.Now().Unix(),
-		"refresh_token": host.RefreshToken,
+		"refresh_token": doc.RefreshToken,
 	}}
-	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
+	_, err = c.Collection.UpdateOne(context.TODO(), query, change)
 	return host, err
 }
+
+// encryptCodeHost returns a copy of the code host whose credentials are encrypted
+func encryptCodeHost(host *models.CodeHost) (*models.CodeHost, error) {
+	doc := *host
+	if err := crypto.EncryptSecrets(&doc.AccessToken, &doc.RefreshToken, &doc.Password, &doc.ClientSecret); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func decryptCodeHost(host *models.CodeHost) error {
+	return crypto.DecryptSecrets(&host.AccessToken, &host.RefreshToken, &host.Password, &host.ClientSecret)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/email/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
@@ -44,16 +45,24 @@ func (c *EmailHostColl) Find() (*models.EmailHost, error) {
 	if err != nil {
 		return nil, nil
 	}
+	if err = crypto.DecryptSecrets(&emailHost.Password); err != nil {
+		return nil, err
+	}
 	return emailHost, nil
 }
 
 func (c *EmailHostColl) Update(emailHost *models.EmailHost) (*models.EmailHost, error) {
+	password := emailHost.Password
+	if err := crypto.EncryptSecrets(&password); err != nil {
+		return nil, err
+	}
+
 	query := bson.M{"deleted_at": 0}
 	change := bson.M{"$set": bson.M{
 		"name":       emailHost.Name,
 		"port":       emailHost.Port,
 		"username":   emailHost.Username,
-		"password":   emailHost.Password,
+		"password":   password,
 		"is_tls":     emailHost.IsTLS,
 		"updated_at": time.Now().Unix(),
 	}}
@@ -96,7 +105,12 @@ func (c *EmailHostColl) Add(emailHost *models.EmailHost) (*models.EmailHost, err
 		return nil, errors.New("cant add more than one emailhost")
 	}
 
-	_, err = c.Collection.InsertOne(context.TODO(), emailHost)
+	doc := *emailHost
+	if err = crypto.EncryptSecrets(&doc.Password); err != nil {
+		return nil, err
+	}
+
+	_, err = c.Collection.InsertOne(context.TODO(), doc)
 	if err != nil {
 		log.Error("repository AddEmailHost err : %v", err)
 		return nil, err
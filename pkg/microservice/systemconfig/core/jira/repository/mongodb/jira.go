@@ -9,6 +9,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/jira/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
@@ -34,7 +35,12 @@ func (c *JiraColl) EnsureIndex(ctx context.Context) error {
 }
 
 func (c *JiraColl) AddJira(iJira *models.Jira) (*models.Jira, error) {
-	_, err := c.Collection.InsertOne(context.TODO(), iJira)
+	doc := *iJira
+	if err := crypto.EncryptSecrets(&doc.AccessToken); err != nil {
+		return nil, err
+	}
+
+	_, err := c.Collection.InsertOne(context.TODO(), doc)
 	if err != nil {
 		log.Error("repository AddJira err : %v", err)
 		return nil, err
@@ -43,12 +49,16 @@ func (c *JiraColl) AddJira(iJira *models.Jira) (*models.Jira, error) {
 }
 
 func (c *JiraColl) UpdateJira(iJira *models.Jira) (*models.Jira, error) {
+	accessToken := iJira.AccessToken
+	if err := crypto.EncryptSecrets(&accessToken); err != nil {
+		return nil, err
+	}
 
 	query := bson.M{"deleted_at": 0}
 	change := bson.M{"$set": bson.M{
 		"host":         iJira.Host,
 		"user":         iJira.User,
-		"access_token": iJira.AccessToken,
+		"access_token": accessToken,
 		"updated_at":   time.Now().Unix(),
 	}}
 
@@ -83,5 +93,8 @@ func (c *JiraColl) GetJira() (*models.Jira, error) {
 	if err != nil {
 		return nil, nil
 	}
+	if err = crypto.DecryptSecrets(&jira.AccessToken); err != nil {
+		return nil, err
+	}
 	return jira, nil
 }
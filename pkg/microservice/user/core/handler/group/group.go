@@ -0,0 +1,70 @@
+package group
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core/service/group"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+)
+
+func CreateUserGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	args := &group.UserGroup{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Resp, ctx.Err = group.CreateUserGroup(args, ctx.Logger)
+}
+
+func UpdateUserGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	args := &group.UserGroup{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Err = group.UpdateUserGroup(c.Param("id"), args, ctx.Logger)
+}
+
+func GetUserGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	ctx.Resp, ctx.Err = group.GetUserGroup(c.Param("id"), ctx.Logger)
+}
+
+func ListUserGroups(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	ctx.Resp, ctx.Err = group.ListUserGroups(ctx.Logger)
+}
+
+func DeleteUserGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	ctx.Err = group.DeleteUserGroup(c.Param("id"), ctx.Logger)
+}
+
+func AddGroupMembers(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	args := &group.MembersArgs{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Err = group.AddGroupMembers(c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteGroupMembers(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	args := &group.MembersArgs{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Err = group.DeleteGroupMembers(c.Param("id"), args, ctx.Logger)
+}
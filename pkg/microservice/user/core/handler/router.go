@@ -19,6 +19,7 @@ package handler
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/koderover/zadig/pkg/microservice/user/core/handler/group"
 	"github.com/koderover/zadig/pkg/microservice/user/core/handler/login"
 	"github.com/koderover/zadig/pkg/microservice/user/core/handler/user"
 )
@@ -48,6 +49,20 @@ func (*Router) Inject(router *gin.RouterGroup) {
 
 		users.POST("/users/ldap/:ldapId", user.SyncLdapUser)
 
+		users.POST("/user-groups", group.CreateUserGroup)
+
+		users.GET("/user-groups", group.ListUserGroups)
+
+		users.GET("/user-groups/:id", group.GetUserGroup)
+
+		users.PUT("/user-groups/:id", group.UpdateUserGroup)
+
+		users.DELETE("/user-groups/:id", group.DeleteUserGroup)
+
+		users.POST("/user-groups/:id/members", group.AddGroupMembers)
+
+		users.POST("/user-groups/:id/members/bulk-delete", group.DeleteGroupMembers)
+
 		router.GET("login", login.Login)
 
 		router.GET("login-enabled", login.ThirdPartyLoginEnabled)
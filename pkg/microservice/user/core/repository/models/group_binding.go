@@ -0,0 +1,12 @@
+package models
+
+type GroupBinding struct {
+	Model
+	GroupID string `json:"group_id"`
+	UID     string `json:"uid"`
+}
+
+// TableName sets the insert table name for this struct type
+func (GroupBinding) TableName() string {
+	return "group_binding"
+}
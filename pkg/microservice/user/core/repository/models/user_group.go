@@ -0,0 +1,13 @@
+package models
+
+type UserGroup struct {
+	Model
+	GroupID     string `json:"group_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserGroup) TableName() string {
+	return "user_group"
+}
@@ -0,0 +1,107 @@
+package orm
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+)
+
+// CreateUserGroup create a user group
+func CreateUserGroup(group *models.UserGroup, db *gorm.DB) error {
+	if err := db.Create(&group).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetUserGroup Get a user group based on groupID
+func GetUserGroup(groupID string, db *gorm.DB) (*models.UserGroup, error) {
+	var group models.UserGroup
+	err := db.Where("group_id = ?", groupID).First(&group).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &group, nil
+}
+
+// ListUserGroups gets all user groups
+func ListUserGroups(db *gorm.DB) ([]models.UserGroup, error) {
+	var groups []models.UserGroup
+	err := db.Order("name ASC").Find(&groups).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// UpdateUserGroup update user group info
+func UpdateUserGroup(groupID string, group *models.UserGroup, db *gorm.DB) error {
+	if err := db.Model(&models.UserGroup{}).Where("group_id = ?", groupID).Updates(group).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteUserGroup Delete a user group based on groupID
+func DeleteUserGroup(groupID string, db *gorm.DB) error {
+	var group models.UserGroup
+	err := db.Where("group_id = ?", groupID).Delete(&group).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateGroupBindings adds users to a user group
+func CreateGroupBindings(bindings []*models.GroupBinding, db *gorm.DB) error {
+	if len(bindings) == 0 {
+		return nil
+	}
+	if err := db.Create(&bindings).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListGroupBindings gets all group bindings, if groupIDs is not empty, only bindings of these groups are returned
+func ListGroupBindings(groupIDs []string, db *gorm.DB) ([]models.GroupBinding, error) {
+	var (
+		bindings []models.GroupBinding
+		err      error
+	)
+	if len(groupIDs) > 0 {
+		err = db.Find(&bindings, "group_id in ?", groupIDs).Error
+	} else {
+		err = db.Find(&bindings).Error
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// DeleteGroupBindings removes users from a user group, if uids is empty, all users are removed
+func DeleteGroupBindings(groupID string, uids []string, db *gorm.DB) error {
+	var binding models.GroupBinding
+	query := db.Where("group_id = ?", groupID)
+	if len(uids) > 0 {
+		query = query.Where("uid in ?", uids)
+	}
+	if err := query.Delete(&binding).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteGroupBindingsByUid removes a user from all user groups
+func DeleteGroupBindingsByUid(uid string, db *gorm.DB) error {
+	var binding models.GroupBinding
+	err := db.Where("uid = ?", uid).Delete(&binding).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package group
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/orm"
+	"github.com/koderover/zadig/pkg/shared/client/policy"
+)
+
+type UserGroup struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UserGroupInfo struct {
+	GroupID     string   `json:"group_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	UIDs        []string `json:"uids"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
+type MembersArgs struct {
+	UIDs []string `json:"uids"`
+}
+
+func CreateUserGroup(args *UserGroup, logger *zap.SugaredLogger) (*models.UserGroup, error) {
+	if args.Name == "" {
+		return nil, fmt.Errorf("group name is empty")
+	}
+	gid, _ := uuid.NewUUID()
+	group := &models.UserGroup{
+		GroupID:     gid.String(),
+		Name:        args.Name,
+		Description: args.Description,
+	}
+	if err := orm.CreateUserGroup(group, core.DB); err != nil {
+		logger.Errorf("CreateUserGroup CreateUserGroup:%s error, error msg:%s", args.Name, err.Error())
+		return nil, err
+	}
+	return group, nil
+}
+
+func UpdateUserGroup(groupID string, args *UserGroup, _ *zap.SugaredLogger) error {
+	group := &models.UserGroup{
+		Name:        args.Name,
+		Description: args.Description,
+	}
+	return orm.UpdateUserGroup(groupID, group, core.DB)
+}
+
+func GetUserGroup(groupID string, logger *zap.SugaredLogger) (*UserGroupInfo, error) {
+	group, err := orm.GetUserGroup(groupID, core.DB)
+	if err != nil {
+		logger.Errorf("GetUserGroup GetUserGroup:%s error, error msg:%s", groupID, err.Error())
+		return nil, err
+	}
+	if group == nil {
+		return nil, nil
+	}
+	bindings, err := orm.ListGroupBindings([]string{groupID}, core.DB)
+	if err != nil {
+		logger.Errorf("GetUserGroup ListGroupBindings:%s error, error msg:%s", groupID, err.Error())
+		return nil, err
+	}
+	return &mergeGroupBindings([]models.UserGroup{*group}, bindings)[0], nil
+}
+
+func ListUserGroups(logger *zap.SugaredLogger) ([]UserGroupInfo, error) {
+	groups, err := orm.ListUserGroups(core.DB)
+	if err != nil {
+		logger.Errorf("ListUserGroups ListUserGroups error, error msg:%s", err.Error())
+		return nil, err
+	}
+	bindings, err := orm.ListGroupBindings(nil, core.DB)
+	if err != nil {
+		logger.Errorf("ListUserGroups ListGroupBindings error, error msg:%s", err.Error())
+		return nil, err
+	}
+	return mergeGroupBindings(groups, bindings), nil
+}
+
+func DeleteUserGroup(groupID string, logger *zap.SugaredLogger) error {
+	// delete the role bindings first, so that the group is kept and the deletion can be retried if it fails.
+	if err := policy.NewDefault().DeleteGroupRoleBindings(groupID); err != nil {
+		logger.Errorf("DeleteUserGroup DeleteGroupRoleBindings:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+
+	tx := core.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	err := orm.DeleteUserGroup(groupID, tx)
+	if err != nil {
+		tx.Rollback()
+		logger.Errorf("DeleteUserGroup DeleteUserGroup:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+	err = orm.DeleteGroupBindings(groupID, nil, tx)
+	if err != nil {
+		tx.Rollback()
+		logger.Errorf("DeleteUserGroup DeleteGroupBindings:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return err
+	}
+
+	refreshPolicyBundle(logger)
+	return nil
+}
+
+func AddGroupMembers(groupID string, args *MembersArgs, logger *zap.SugaredLogger) error {
+	group, err := orm.GetUserGroup(groupID, core.DB)
+	if err != nil {
+		logger.Errorf("AddGroupMembers GetUserGroup:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+	if group == nil {
+		return fmt.Errorf("user group not exist")
+	}
+
+	existing, err := orm.ListGroupBindings([]string{groupID}, core.DB)
+	if err != nil {
+		logger.Errorf("AddGroupMembers ListGroupBindings:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+	members := make(map[string]bool)
+	for _, b := range existing {
+		members[b.UID] = true
+	}
+
+	var bindings []*models.GroupBinding
+	for _, uid := range args.UIDs {
+		if members[uid] {
+			continue
+		}
+		members[uid] = true
+		bindings = append(bindings, &models.GroupBinding{GroupID: groupID, UID: uid})
+	}
+	if err = orm.CreateGroupBindings(bindings, core.DB); err != nil {
+		logger.Errorf("AddGroupMembers CreateGroupBindings:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+
+	refreshPolicyBundle(logger)
+	return nil
+}
+
+func DeleteGroupMembers(groupID string, args *MembersArgs, logger *zap.SugaredLogger) error {
+	if len(args.UIDs) == 0 {
+		return nil
+	}
+	if err := orm.DeleteGroupBindings(groupID, args.UIDs, core.DB); err != nil {
+		logger.Errorf("DeleteGroupMembers DeleteGroupBindings:%s error, error msg:%s", groupID, err.Error())
+		return err
+	}
+
+	refreshPolicyBundle(logger)
+	return nil
+}
+
+func mergeGroupBindings(groups []models.UserGroup, bindings []models.GroupBinding) []UserGroupInfo {
+	groupMembers := make(map[string][]string)
+	for _, b := range bindings {
+		groupMembers[b.GroupID] = append(groupMembers[b.GroupID], b.UID)
+	}
+	groupsInfo := make([]UserGroupInfo, 0, len(groups))
+	for _, g := range groups {
+		groupsInfo = append(groupsInfo, UserGroupInfo{
+			GroupID:     g.GroupID,
+			Name:        g.Name,
+			Description: g.Description,
+			UIDs:        groupMembers[g.GroupID],
+			CreatedAt:   g.CreatedAt,
+			UpdatedAt:   g.UpdatedAt,
+		})
+	}
+	return groupsInfo
+}
+
+// refreshPolicyBundle notifies the policy service that group memberships are changed,
+// the membership is part of the OPA bundle.
+func refreshPolicyBundle(logger *zap.SugaredLogger) {
+	if err := policy.NewDefault().RefreshBundle(); err != nil {
+		logger.Warnf("Failed to refresh policy bundle, error msg:%s", err)
+	}
+}
@@ -25,4 +25,24 @@ CREATE TABLE IF NOT EXISTS `user`(
     `updated_at` int(11) unsigned NOT NULL COMMENT '修改时间',
     UNIQUE KEY `account` (`account`,`identity_type`),
     PRIMARY KEY (`uid`)
-) ENGINE = InnoDB AUTO_INCREMENT = 59 CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '用户信息表' ROW_FORMAT = Compact;
\ No newline at end of file
+) ENGINE = InnoDB AUTO_INCREMENT = 59 CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '用户信息表' ROW_FORMAT = Compact;
+CREATE TABLE IF NOT EXISTS `user_group`(
+    `group_id` varchar(64) NOT NULL COMMENT '用户组ID',
+    `name` varchar(64) NOT NULL DEFAULT '' COMMENT '用户组名',
+    `description` varchar(255) NOT NULL DEFAULT '' COMMENT '描述',
+    `created_at` int(11) unsigned NOT NULL COMMENT '创建时间',
+    `updated_at` int(11) unsigned NOT NULL COMMENT '修改时间',
+    UNIQUE KEY `name` (`name`),
+    PRIMARY KEY (`group_id`)
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '用户组表' ROW_FORMAT = Compact;
+
+CREATE TABLE IF NOT EXISTS `group_binding`(
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `group_id` varchar(64) NOT NULL COMMENT '用户组ID',
+    `uid` varchar(64) NOT NULL COMMENT '用户ID',
+    `created_at` int(11) unsigned NOT NULL COMMENT '创建时间',
+    `updated_at` int(11) unsigned NOT NULL COMMENT '修改时间',
+    UNIQUE KEY `binding` (`group_id`,`uid`),
+    PRIMARY KEY (`id`),
+    KEY `idx_uid` (`uid`) USING BTREE
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '用户组成员表' ROW_FORMAT = Compact;
@@ -20,6 +20,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/user/core/repository/orm"
 	"github.com/koderover/zadig/pkg/microservice/user/core/service/login"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/policy"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/pkg/tool/mail"
 )
@@ -290,7 +291,21 @@ func DeleteUserByUID(uid string, logger *zap.SugaredLogger) error {
 		logger.Errorf("DeleteUserByUID DeleteUserLoginByUid:%s error, error msg:%s", uid, err.Error())
 		return err
 	}
-	return tx.Commit().Error
+	err = orm.DeleteGroupBindingsByUid(uid, tx)
+	if err != nil {
+		tx.Rollback()
+		logger.Errorf("DeleteUserByUID DeleteGroupBindingsByUid:%s error, error msg:%s", uid, err.Error())
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// the group memberships of the user are part of the OPA bundle
+	if err = policy.NewDefault().RefreshBundle(); err != nil {
+		logger.Warnf("Failed to refresh policy bundle, error msg:%s", err)
+	}
+	return nil
 }
 
 //go:embed retrieve.html
@@ -26,6 +26,10 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 )
 
+func init() {
+	viper.SetDefault(setting.WarpDriveTaskConcurrency, 1)
+}
+
 func WarpDrivePodName() string {
 	return viper.GetString(setting.WarpDrivePodName)
 }
@@ -34,6 +38,14 @@ func NSQLookupAddrs() []string {
 	return strings.Split(viper.GetString(setting.ENVNsqLookupAddrs), ",")
 }
 
+// TaskConcurrency is the max number of pipeline tasks running in one warpdrive instance at the same time.
+func TaskConcurrency() int {
+	if c := viper.GetInt(setting.WarpDriveTaskConcurrency); c > 0 {
+		return c
+	}
+	return 1
+}
+
 func ReleaseImageTimeout() string {
 	return viper.GetString(setting.ReleaseImageTimeout)
 }
@@ -33,6 +33,16 @@ const (
 	TaskSecurity       TaskType = "security"
 	TaskResetImage     TaskType = "reset_image"
 	TaskDistribute     TaskType = "distribute"
+	TaskApproval       TaskType = "approval"
+	TaskReleaseChart   TaskType = "release_chart"
+)
+
+type ApprovalStatus string
+
+const (
+	ApprovalWaiting  ApprovalStatus = "waiting"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
 )
 
 type Status string
@@ -50,6 +60,8 @@ const (
 	StatusQueued     Status = "queued"
 	StatusBlocked    Status = "blocked"
 	QueueItemPending Status = "pending"
+	// StatusWaitForApprove 任务在审批处暂停，已释放 warpdrive，审批后重新进入队列
+	StatusWaitForApprove Status = "waitforapprove"
 )
 
 type PipelineType string
@@ -94,7 +106,7 @@ const (
 // CIStatus ...
 type CIStatus string
 
-//ProductPermission ...
+// ProductPermission ...
 type ProductPermission string
 
 // ProductAuthType ...
@@ -42,9 +42,10 @@ func InitTaskController(ctx context.Context) error {
 	cfg := nsq.NewConfig()
 	// 注意 WD_POD_NAME 必须使用 Downward API 配置环境变量
 	cfg.UserAgent = config.WarpDrivePodName()
-	// FIXME: Min: FIX MAGIC NUMBER
-	cfg.MaxAttempts = 50
+	// 消息只会因为没有空闲的执行槽位而重新入队，不限制重试次数，否则排队较久的任务会被丢弃
+	cfg.MaxAttempts = 0
 	cfg.LookupdPollInterval = 1 * time.Second
+	cfg.MaxInFlight = config.TaskConcurrency()
 	//nsqd 和服务起在一起 FIXME: Min: FIX MAGIC ADDR
 	nsqClient := nsqcli.NewNsqClient(config.NSQLookupAddrs(), "127.0.0.1:4151")
 
@@ -77,16 +78,15 @@ func InitTaskController(ctx context.Context) error {
 		return fmt.Errorf("ensure nsq topic error: %v", err)
 	}
 
-	execHandler := &ExecHandler{
-		Sender: sender,
-	}
+	// 每个warpdrive实例可以同时运行多个pipeline task
+	execHandler := NewExecHandler(sender, config.TaskConcurrency())
 
 	processor.AddHandler(execHandler)
 
 	//Add task plugin initiators to exec Handler
 	initTaskPlugins(execHandler)
 
-	cancelHandler := &CancelHandler{}
+	cancelHandler := NewCancelHandler(execHandler)
 
 	canceller.AddHandler(cancelHandler)
 
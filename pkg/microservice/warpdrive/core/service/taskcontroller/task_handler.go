@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nsqio/go-nsq"
@@ -32,68 +33,216 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/util/rand"
 )
 
-var (
-	ctx          context.Context
-	cancel       context.CancelFunc
-	pipelineTask *task.Task
-	pipelineCtx  *task.PipelineCtx
-	itReport     *types.ItReport
-	xl           *zap.SugaredLogger
-)
+const maxRequeueDelay = 10 * time.Second
 
 // ExecHandler ...
 // Sender: sender to send ack/notification
 // TaskPlugins: registered task plugin initiators to initiate specific plugin to execute task
+// slots: limits the number of pipeline tasks running concurrently on this instance
+// executors: running pipeline task executors, keyed by pipeline name and task id
 type ExecHandler struct {
 	Sender      *nsq.Producer
 	TaskPlugins map[config.TaskType]plugins.Initiator
+
+	slots     chan struct{}
+	lock      sync.RWMutex
+	executors map[string]*executor
+}
+
+// NewExecHandler creates an ExecHandler which runs at most concurrency pipeline tasks at the same time.
+func NewExecHandler(sender *nsq.Producer, concurrency int) *ExecHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &ExecHandler{
+		Sender:    sender,
+		slots:     make(chan struct{}, concurrency),
+		executors: make(map[string]*executor),
+	}
 }
 
 // CancelHandler ...
-type CancelHandler struct{}
+type CancelHandler struct {
+	execHandler *ExecHandler
+}
+
+// NewCancelHandler creates a CancelHandler which cancels pipeline tasks running in execHandler.
+func NewCancelHandler(execHandler *ExecHandler) *CancelHandler {
+	return &CancelHandler{execHandler: execHandler}
+}
 
 // HandleMessage ...
 // Message handler to handle task execution message
 func (h *ExecHandler) HandleMessage(message *nsq.Message) error {
-	defer func() {
-		// 每次处理完消息, 等待一段时间不处理新消息
-		time.Sleep(time.Second * 10)
-	}()
-
-	xl = log.SugaredLogger()
+	xl := log.SugaredLogger()
 
-	// 如果存在运行中的 PipelineTask, 则重新requeue pipeline task
+	// 如果没有空闲的执行槽位, 则重新requeue pipeline task
 	// task处理逻辑全部放在requeue之后，防止requeue影响正在运行的task
-	if pipelineTask != nil {
-		xl.Infof("warpdrive instance have one running pipeline task %s:%d", pipelineTask.PipelineName, pipelineTask.TaskID)
-		message.Requeue(time.Millisecond * 100)
+	if !h.acquireSlot() {
+		xl.Infof("warpdrive instance have %d running pipeline tasks, no free slot", cap(h.slots))
+		message.RequeueWithoutBackoff(requeueDelay(message.Attempts))
 		return nil
 	}
 
 	// 获取 PipelineTask 内容
+	var pipelineTask *task.Task
 	if err := json.Unmarshal(message.Body, &pipelineTask); err != nil {
 		xl.Errorf("unmarshal PipelineTask error: %v", err)
+		h.releaseSlot()
 		return nil
 	}
 	xl.Infof("receiving pipeline task %s:%d message", pipelineTask.PipelineName, pipelineTask.TaskID)
 
-	// xl - global logger
-	xl = Logger(pipelineTask)
+	e := newExecutor(h, pipelineTask)
+	if !h.addExecutor(e) {
+		xl.Warnf("pipeline task %s is already running on this instance, ignore it", e.key())
+		h.releaseSlot()
+		return nil
+	}
 
-	// 初始化 Context, CancelFunc, PipelineTask
-	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		defer func() {
+			h.removeExecutor(e)
+			h.releaseSlot()
+		}()
 
-	go h.runPipelineTask(ctx, cancel, xl)
+		e.run()
+	}()
 	return nil
 }
 
-func (h *ExecHandler) runPipelineTask(ctx context.Context, cancel context.CancelFunc, xl *zap.SugaredLogger) {
+// requeueDelay backs off the requeue of a message waiting for a free slot, it grows with the attempts up to 10s.
+func requeueDelay(attempts uint16) time.Duration {
+	delay := time.Duration(attempts) * time.Second
+	if delay > maxRequeueDelay {
+		return maxRequeueDelay
+	}
+	return delay
+}
+
+// acquireSlot takes a free slot without blocking, it returns false if all slots are in use.
+func (h *ExecHandler) acquireSlot() bool {
+	select {
+	case h.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *ExecHandler) releaseSlot() {
+	<-h.slots
+}
+
+func (h *ExecHandler) addExecutor(e *executor) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if _, ok := h.executors[e.key()]; ok {
+		return false
+	}
+	h.executors[e.key()] = e
+	return true
+}
+
+func (h *ExecHandler) removeExecutor(e *executor) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.executors, e.key())
+}
+
+func (h *ExecHandler) getExecutor(pipelineName string, taskID int64) (*executor, bool) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	e, ok := h.executors[executorKey(pipelineName, taskID)]
+	return e, ok
+}
+
+// HandleMessage ...
+func (h *CancelHandler) HandleMessage(message *nsq.Message) error {
+	xl := log.SugaredLogger()
+
+	// 获取 cancel message
+	var msg *CancelMessage
+	if err := json.Unmarshal(message.Body, &msg); err != nil {
+		xl.Errorf("unmarshal CancelMessage error: %v", err)
+		return nil
+	}
+
+	xl.Infof("receiving cancel task %s:%d message", msg.PipelineName, msg.TaskID)
+
+	// 如果存在处理的 PipelineTask 并且匹配 PipelineName 和 TaskID, 则取消PipelineTask
+	e, ok := h.execHandler.getExecutor(msg.PipelineName, msg.TaskID)
+	if !ok {
+		return nil
+	}
+
+	e.xl.Infof("cancelling message: %+v", msg)
+	e.cancelTask(msg.Revoker)
+	return nil
+}
+
+// ----------------------------------------------------------------------------------------------
+// executor
+// ----------------------------------------------------------------------------------------------
+
+// executor holds all states of one running pipeline task
+type executor struct {
+	sender      *nsq.Producer
+	taskPlugins map[config.TaskType]plugins.Initiator
+
+	ctx          context.Context
+	cancel       context.CancelFunc
+	pipelineTask *task.Task
+	pipelineCtx  *task.PipelineCtx
+	itReport     *types.ItReport
+	xl           *zap.SugaredLogger
+}
+
+func newExecutor(h *ExecHandler, pipelineTask *task.Task) *executor {
+	// 初始化 Context, CancelFunc
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &executor{
+		sender:       h.Sender,
+		taskPlugins:  h.TaskPlugins,
+		ctx:          ctx,
+		cancel:       cancel,
+		pipelineTask: pipelineTask,
+		xl:           Logger(pipelineTask),
+	}
+}
+
+func executorKey(pipelineName string, taskID int64) string {
+	return fmt.Sprintf("%s:%d", pipelineName, taskID)
+}
+
+func (e *executor) key() string {
+	return executorKey(e.pipelineTask.PipelineName, e.pipelineTask.TaskID)
+}
+
+func (e *executor) cancelTask(revoker string) {
+	e.pipelineTask.RwLock.Lock()
+	e.pipelineTask.TaskRevoker = revoker
+	e.pipelineTask.RwLock.Unlock()
+
+	//取消pipelineTask
+	e.cancel()
+}
+
+func (e *executor) run() {
+	pipelineTask, xl := e.pipelineTask, e.xl
 	defer func() {
-		h.SendNotification()
+		e.cancel()
+		e.SendNotification()
 
 		if pipelineTask.Type == config.SingleType || pipelineTask.Type == config.WorkflowType {
 			xl.Infof("Pipeline completeGitCheck %s:%d:%s", pipelineTask.PipelineName, pipelineTask.TaskID, pipelineTask.Status)
@@ -102,13 +251,9 @@ func (h *ExecHandler) runPipelineTask(ctx context.Context, cancel context.Cancel
 			}
 		}
 
-		h.SendAck()
+		e.SendAck()
 
-		// 重置 task/itrepot 防止新的task Unmarshal到上次内容
-		pipelineTask = nil
-		pipelineCtx = nil
-		itReport = nil
-		xl.Info("Pipeline task all done, tear down vars.")
+		xl.Info("Pipeline task all done, tear down executor.")
 	}()
 
 	// Step 1.1 - 检查配置，如果配置为空，则结束此次Task执行
@@ -145,7 +290,7 @@ func (h *ExecHandler) runPipelineTask(ctx context.Context, cancel context.Cancel
 	// DistDir: pipeline distribute dir
 	// DockerMountDir: docker mount dir
 	// ConfigMapMountDir: config map mount dir
-	pipelineCtx = &task.PipelineCtx{
+	e.pipelineCtx = &task.PipelineCtx{
 		DockerHost:        dockerHost,
 		Workspace:         fmt.Sprintf("%s/%s", pipelineTask.ConfigPayload.S3Storage.Path, pipelineTask.PipelineName),
 		DistDir:           fmt.Sprintf("%s/%s/dist/%d", pipelineTask.ConfigPayload.S3Storage.Path, pipelineTask.PipelineName, pipelineTask.TaskID),
@@ -158,48 +303,23 @@ func (h *ExecHandler) runPipelineTask(ctx context.Context, cancel context.Cancel
 	xl.Infof("start to run pipeline task %s:%d ......", pipelineTask.PipelineName, pipelineTask.TaskID)
 	initPipelineTask(pipelineTask, xl)
 	// 发送初始状态ACK给backend，更新pipeline状态
-	h.SendAck()
-	h.SendNotification()
+	e.SendAck()
+	e.SendNotification()
 
 	// Step 3 - pipelineTask执行，真的开始了...
-	h.execute(ctx, pipelineTask, pipelineCtx, xl)
+	e.execute()
 
 	// Return 之前会执行defer内容，更新pipeline end time, 发送ACK，发送notification
 }
 
-// HandleMessage ...
-func (h *CancelHandler) HandleMessage(message *nsq.Message) error {
-	xl = Logger(pipelineTask)
-
-	// 获取 cancel message
-	var msg *CancelMessage
-	if err := json.Unmarshal(message.Body, &msg); err != nil {
-		xl.Errorf("unmarshal CancelMessage error: %v", err)
-		return nil
-	}
-
-	xl.Infof("receiving cancel task %s:%d message", msg.PipelineName, msg.TaskID)
-
-	// 如果存在处理的 PipelineTask 并且匹配 PipelineName, 则取消PipelineTask
-	if pipelineTask != nil && pipelineTask.PipelineName == msg.PipelineName && pipelineTask.TaskID == msg.TaskID {
-		xl.Infof("cancelling message: %+v", msg)
-		pipelineTask.TaskRevoker = msg.Revoker
-
-		//取消pipelineTask
-		cancel()
-		//xl.Debug("no matched pipeline task found on this warpdrive")
-	}
-	return nil
-}
-
 // ----------------------------------------------------------------------------------------------
 // helper functions
 // ----------------------------------------------------------------------------------------------
 
 // SendAck 发送task实时状态信息
 // 无需发送cancel信息
-func (h *ExecHandler) SendAck() {
-	xl = Logger(pipelineTask)
+func (e *executor) SendAck() {
+	pipelineTask, xl := e.pipelineTask, e.xl
 	pb, err := func() ([]byte, error) {
 		pipelineTask.RwLock.Lock()
 		defer pipelineTask.RwLock.Unlock()
@@ -219,28 +339,32 @@ func (h *ExecHandler) SendAck() {
 	//DEBUG ONLY
 	xl.Infof("Sending ACK: %#v", pipelineTask)
 
-	if err := h.Sender.Publish(setting.TopicAck, pb); err != nil {
+	if err := e.sender.Publish(setting.TopicAck, pb); err != nil {
 		xl.Errorf("publish [%s] error: %v", setting.TopicAck, err)
+		metrics.NSQPublishFailures.WithLabelValues(setting.TopicAck).Inc()
 		return
 	}
 }
 
 // SendItReport ...
-func (h *ExecHandler) SendItReport() {
-	pb, err := json.Marshal(&itReport)
+func (e *executor) SendItReport() {
+	xl := e.xl
+	pb, err := json.Marshal(&e.itReport)
 	if err != nil {
 		xl.Errorf("marshal itReport error: %v", err)
 		return
 	}
 
-	if err := h.Sender.Publish(setting.TopicItReport, pb); err != nil {
+	if err := e.sender.Publish(setting.TopicItReport, pb); err != nil {
 		xl.Errorf("publish [%s] error: %v", setting.TopicItReport, err)
+		metrics.NSQPublishFailures.WithLabelValues(setting.TopicItReport).Inc()
 		return
 	}
 }
 
 // SendNotification ...
-func (h *ExecHandler) SendNotification() {
+func (e *executor) SendNotification() {
+	pipelineTask, xl := e.pipelineTask, e.xl
 	notify := &types.Notify{
 		Type:     config.PipelineStatus,
 		Receiver: pipelineTask.TaskCreator,
@@ -262,15 +386,17 @@ func (h *ExecHandler) SendNotification() {
 		return
 	}
 
-	if err := h.Sender.Publish(setting.TopicNotification, nb); err != nil {
+	if err := e.sender.Publish(setting.TopicNotification, nb); err != nil {
 		xl.Errorf("publish [%s] error: %v", setting.TopicNotification, err)
+		metrics.NSQPublishFailures.WithLabelValues(setting.TopicNotification).Inc()
 		return
 	}
 }
 
-func (h *ExecHandler) runStage(stagePosition int, stage *task.Stage) {
+func (e *executor) runStage(stagePosition int, stage *task.Stage) {
+	pipelineTask, xl := e.pipelineTask, e.xl
 	xl.Infof("start to execute pipeline stage: %s at position: %d", stage.TaskType, stagePosition)
-	pluginInitiator, ok := h.TaskPlugins[stage.TaskType]
+	pluginInitiator, ok := e.taskPlugins[stage.TaskType]
 	if !ok {
 		xl.Errorf("Error to find plugin initiator to init task plugin of type %s", stage.TaskType)
 		return
@@ -279,7 +405,7 @@ func (h *ExecHandler) runStage(stagePosition int, stage *task.Stage) {
 	xl.Info("start to init worker pool for execute tasks in stage")
 	// 初始化stage status为running
 	updatePipelineStageStatus(config.StatusRunning, pipelineTask, stagePosition, xl)
-	h.SendAck()
+	e.SendAck()
 	// runParallel: Stage内部是否支持并发
 	runParallel := stage.RunParallel
 	// Default worker concurrency is 1, run tasks sequentially
@@ -304,7 +430,7 @@ func (h *ExecHandler) runStage(stagePosition int, stage *task.Stage) {
 		xl.Infof("new sub task of service name: %s, type: %s", serviceName, stage.TaskType)
 		pluginInstance = pluginInitiator(stage.TaskType)
 		//xl.Errorf("%v", ctx.Value(CtxKeyBuildInfos))
-		tasks = append(tasks, NewTask(ctx, h.executeTask, pluginInstance, subTask, stagePosition, serviceName, xl))
+		tasks = append(tasks, NewTask(e.ctx, e.executeTask, pluginInstance, subTask, stagePosition, serviceName, xl))
 	}
 	// 判断subTask是否是deploy，如果是的话判断是否是helm类型的服务，
 	//todo helm类型的服务的部署暂时只支持串行执行
@@ -328,13 +454,14 @@ func (h *ExecHandler) runStage(stagePosition int, stage *task.Stage) {
 	stage.Status = stageStatus
 	// 更新Stage状态
 	updatePipelineStageStatus(stage.Status, pipelineTask, stagePosition, xl)
-	h.SendAck()
+	e.SendAck()
 }
 
 // execute: PipelineTask Executor
 // 兼容支持1.0和2.0的数据结构
 // 支持根据RunParallel参数指定的并发或串行执行
-func (h *ExecHandler) execute(ctx context.Context, pipelineTask *task.Task, pipelineCtx *task.PipelineCtx, xl *zap.SugaredLogger) {
+func (e *executor) execute() {
+	pipelineTask, xl := e.pipelineTask, e.xl
 	xl.Info("start pipeline task executor...")
 	// 如果是pipeline 1.0， 先将subtasks进行transform，转化为stages结构
 	if pipelineTask.Type == config.SingleType || pipelineTask.Type == "" {
@@ -348,32 +475,39 @@ func (h *ExecHandler) execute(ctx context.Context, pipelineTask *task.Task, pipe
 	}
 
 	// Stage之间仅支持串行
+	paused := false
 	for stagePosition, stage := range pipelineTask.Stages {
 		if !stage.AfterAll {
-			h.runStage(stagePosition, stage)
+			e.runStage(stagePosition, stage)
 			// 如果一个Stage执行失败了，跳出执行循环，并且更新pipelinetask状态为失败，发送ACK，并返回
 			if stage.Status == config.StatusFailed || stage.Status == config.StatusCancelled || stage.Status == config.StatusTimeout {
 				break
 			}
+			// 等待审批时暂停任务，审批后任务重新下发，从审批 stage 继续执行
+			if stage.Status == config.StatusWaitForApprove {
+				paused = true
+				break
+			}
 		}
 	}
 
 	for stagePosition, stage := range pipelineTask.Stages {
-		if stage.AfterAll {
-			h.runStage(stagePosition, stage)
+		if stage.AfterAll && !paused {
+			e.runStage(stagePosition, stage)
 		}
 	}
 
 	// 根据stage status汇总pipeline task状态，并且更新pipeline状态，发送ACK
 	updatePipelineStatus(pipelineTask, xl)
-	h.SendAck()
+	e.SendAck()
 }
 
 // executeTask
 // 执行单个subtask，并将subtask执行状态更新到pipelineTask中
 // 返回Task状态+Error，Task Status将在Stage Level进行Aggregation到Stage Status
 // SubTask终止状态包括：disabled, passed, skipped, timeout, failed, cancelled.
-func (h *ExecHandler) executeTask(taskCtx context.Context, plugin plugins.TaskPlugin, subTask map[string]interface{}, pos int, servicename string, xl *zap.SugaredLogger) (config.Status, error) {
+func (e *executor) executeTask(taskCtx context.Context, plugin plugins.TaskPlugin, subTask map[string]interface{}, pos int, servicename string, xl *zap.SugaredLogger) (config.Status, error) {
+	pipelineTask, pipelineCtx := e.pipelineTask, e.pipelineCtx
 	//设置Plugin执行参数：JOBNAME; 设置plugin logger;设置plugin log文件名称
 	//e.g. build task JOBNAME = pipelinename-taskid-buildv2-bsonId
 	//e.g. build task FILENAME(singgle模式) = pipelinename-taskid-buildv2-servicename
@@ -439,16 +573,22 @@ func (h *ExecHandler) executeTask(taskCtx context.Context, plugin plugins.TaskPl
 
 	// 设置 SubTask 开始时间
 	plugin.SetStartTime()
+	start := time.Now()
+	defer func() {
+		metrics.SubTaskDuration.WithLabelValues(
+			pipelineTask.ProductName, pipelineTask.PipelineName, string(plugin.Type()), string(plugin.Status()),
+		).Observe(time.Since(start).Seconds())
+	}()
 
 	// 清除上一次错误信息
 	plugin.ResetError()
 
 	updatePipelineSubTask(plugin.GetTask(), pipelineTask, pos, servicename, xl)
-	h.SendAck()
+	e.SendAck()
 
 	plugin.SetAckFunc(func() {
 		updatePipelineSubTask(plugin.GetTask(), pipelineTask, pos, servicename, xl)
-		h.SendAck()
+		e.SendAck()
 	})
 
 	xl.Info("start to call plugin.Run")
@@ -458,7 +598,7 @@ func (h *ExecHandler) executeTask(taskCtx context.Context, plugin plugins.TaskPl
 		runCtx.Workspace = fmt.Sprintf("%s/%s", pipelineCtx.Workspace, servicename)
 	}
 	// 运行 SubTask, 如果需要异步，请在方法内实现
-	plugin.Run(ctx, pipelineTask, &runCtx, servicename)
+	plugin.Run(taskCtx, pipelineTask, &runCtx, servicename)
 
 	// 如果 SubTask 执行失败, 则不继续执行, 发送 Task 失败执行结果
 	// Failed, Timeout, Cancelled
@@ -472,24 +612,24 @@ func (h *ExecHandler) executeTask(taskCtx context.Context, plugin plugins.TaskPl
 
 	// 等待完成前, 更新 SubTask 执行结果到 PipelineTask
 	updatePipelineSubTask(plugin.GetTask(), pipelineTask, pos, servicename, xl)
-	h.SendAck()
+	e.SendAck()
 
 	// 等待 SubTask 结束
 	xl.Infof("waiting %s task to complete ...", plugin.Type())
-	plugin.Wait(ctx)
+	plugin.Wait(taskCtx)
 	xl.Infof("task status: %s", plugin.Status())
 
-	plugin.Complete(ctx, pipelineTask, servicename)
+	plugin.Complete(taskCtx, pipelineTask, servicename)
 	xl.Infof("task status: %s", plugin.Status())
 
 	// XXX - TODO需要确认这里的逻辑是？
-	if itReport != nil {
-		h.SendItReport()
+	if e.itReport != nil {
+		e.SendItReport()
 	}
 	// 更新 SubTask 执行结果到 PipelineTask
 	plugin.SetEndTime()
 	updatePipelineSubTask(plugin.GetTask(), pipelineTask, pos, servicename, xl)
-	h.SendAck()
+	e.SendAck()
 
 	xl.Infof("end sub task [%s:%s]", plugin.Type(), plugin.Status())
 	return plugin.Status(), nil
@@ -499,7 +639,7 @@ func Logger(pipelineTask *task.Task) *zap.SugaredLogger {
 	// 初始化Logger
 	l := log.Logger()
 	if pipelineTask != nil {
-		l.With(zap.String(setting.RequestID, pipelineTask.ReqID))
+		l = l.With(zap.String(setting.RequestID, pipelineTask.ReqID))
 	}
 
 	return l.Sugar()
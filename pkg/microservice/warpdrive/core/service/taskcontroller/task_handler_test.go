@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskcontroller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+func TestExecHandlerSlots(t *testing.T) {
+	assert := assert.New(t)
+	h := NewExecHandler(nil, 2)
+
+	assert.True(h.acquireSlot())
+	assert.True(h.acquireSlot())
+	assert.False(h.acquireSlot())
+
+	h.releaseSlot()
+	assert.True(h.acquireSlot())
+}
+
+func TestExecHandlerExecutors(t *testing.T) {
+	assert := assert.New(t)
+	h := NewExecHandler(nil, 2)
+
+	e1 := newExecutor(h, &task.Task{PipelineName: "foo", TaskID: 1})
+	e2 := newExecutor(h, &task.Task{PipelineName: "foo", TaskID: 2})
+	assert.True(h.addExecutor(e1))
+	assert.True(h.addExecutor(e2))
+	assert.False(h.addExecutor(newExecutor(h, &task.Task{PipelineName: "foo", TaskID: 1})))
+
+	e, ok := h.getExecutor("foo", 2)
+	assert.True(ok)
+	e.cancelTask("admin")
+	assert.Equal("admin", e2.pipelineTask.TaskRevoker)
+	assert.Error(e2.ctx.Err())
+	assert.NoError(e1.ctx.Err())
+
+	h.removeExecutor(e2)
+	_, ok = h.getExecutor("foo", 2)
+	assert.False(ok)
+}
+
+func TestRequeueDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Second, requeueDelay(1))
+	assert.Equal(5*time.Second, requeueDelay(5))
+	assert.Equal(maxRequeueDelay, requeueDelay(100))
+}
@@ -153,7 +153,7 @@ func updatePipelineStatus(pipelineTask *task.Task, xl *zap.SugaredLogger) {
 	pipelineTask.EndTime = time.Now().Unix()
 	//这里不需要处理1.0还是2.0了，因为stage内容已经都更新了，所以根据stage来判断就好
 	for _, stage := range pipelineTask.Stages {
-		if stage.Status == config.StatusFailed || stage.Status == config.StatusCancelled || stage.Status == config.StatusTimeout {
+		if stage.Status == config.StatusFailed || stage.Status == config.StatusCancelled || stage.Status == config.StatusTimeout || stage.Status == config.StatusWaitForApprove {
 			pipelineTask.Status = stage.Status
 			xl.Infof("Pipeline task completed abnormal: %s:%d:%s %+v", pipelineTask.PipelineName, pipelineTask.TaskID, pipelineTask.Status, pipelineTask)
 			return
@@ -169,11 +169,12 @@ func updatePipelineStatus(pipelineTask *task.Task, xl *zap.SugaredLogger) {
 //最后取值最大的那个状态。
 func getStageStatus(tasks []*Task, xl *zap.SugaredLogger) config.Status {
 	taskStatusMap := map[config.Status]int{
-		config.StatusCancelled: 4,
-		config.StatusTimeout:   3,
-		config.StatusFailed:    2,
-		config.StatusPassed:    1,
-		config.StatusSkipped:   0,
+		config.StatusCancelled:      5,
+		config.StatusTimeout:        4,
+		config.StatusFailed:         3,
+		config.StatusWaitForApprove: 2,
+		config.StatusPassed:         1,
+		config.StatusSkipped:        0,
 	}
 
 	// 初始化stageStatus为创建状态
@@ -549,6 +550,8 @@ func initTaskPlugins(execHandler *ExecHandler) {
 		config.TaskReleaseImage:   plugins.InitializeReleaseImagePlugin,
 		config.TaskDistributeToS3: plugins.InitializeDistribute2S3TaskPlugin,
 		config.TaskResetImage:     plugins.InitializeDeployTaskPlugin,
+		config.TaskApproval:       plugins.InitializeApprovalTaskPlugin,
+		config.TaskReleaseChart:   plugins.InitializeReleaseChartTaskPlugin,
 	}
 	for name, pluginInitiator := range pluginConf {
 		registerTaskPlugin(execHandler, name, pluginInitiator)
@@ -40,6 +40,13 @@ func TestGetStageStatus(t *testing.T) {
 	log.Info(stageStatus)
 	assert.Equal(config.StatusPassed, stageStatus)
 
+	approval := &Task{
+		Err:    nil,
+		Status: config.StatusWaitForApprove,
+	}
+	stageStatus = getStageStatus([]*Task{task1, approval}, log)
+	assert.Equal(config.StatusWaitForApprove, stageStatus)
+
 	task2 := &Task{
 		Err:    fmt.Errorf("test failed"),
 		Status: config.StatusFailed,
@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	// ApprovalTimeout ...
+	ApprovalTimeout = 60 * 60 // 60 minutes
+)
+
+// InitializeApprovalTaskPlugin to init plugin
+func InitializeApprovalTaskPlugin(taskType config.TaskType) TaskPlugin {
+	return &ApprovalTaskPlugin{
+		Name: taskType,
+		httpClient: httpclient.New(
+			httpclient.SetHostURL(configbase.AslanServiceAddress()),
+			httpclient.SetClientHeader(setting.InternalTokenHeader, configbase.InternalToken()),
+		),
+	}
+}
+
+// ApprovalTaskPlugin 还没有审批结果时暂停工作流任务并释放 warpdrive，
+// 审批人在 aslan 中通过或拒绝后任务重新进入队列，再次执行时根据审批结果继续或失败
+type ApprovalTaskPlugin struct {
+	Name         config.TaskType
+	Task         *task.Approval
+	Log          *zap.SugaredLogger
+	pipelineName string
+	taskID       int64
+
+	httpClient *httpclient.Client
+}
+
+// taskApproval aslan 返回的审批结果
+type taskApproval struct {
+	Status   config.ApprovalStatus `json:"status"`
+	UserName string                `json:"user_name"`
+	Comment  string                `json:"comment"`
+}
+
+func (p *ApprovalTaskPlugin) SetAckFunc(func()) {
+}
+
+// Init ...
+func (p *ApprovalTaskPlugin) Init(jobname, filename string, xl *zap.SugaredLogger) {
+	p.Log = xl
+}
+
+// Type ...
+func (p *ApprovalTaskPlugin) Type() config.TaskType {
+	return p.Name
+}
+
+// Status ...
+func (p *ApprovalTaskPlugin) Status() config.Status {
+	return p.Task.TaskStatus
+}
+
+// SetStatus ...
+func (p *ApprovalTaskPlugin) SetStatus(status config.Status) {
+	p.Task.TaskStatus = status
+}
+
+// TaskTimeout 审批超时时间配置单位为分钟，等待审批期间任务不在 warpdrive 中，由 aslan 判断是否超时
+func (p *ApprovalTaskPlugin) TaskTimeout() int {
+	if p.Task.Timeout == 0 {
+		return ApprovalTimeout
+	}
+	return p.Task.Timeout * 60
+}
+
+func (p *ApprovalTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipelineCtx *task.PipelineCtx, serviceName string) {
+	p.pipelineName = pipelineTask.PipelineName
+	p.taskID = pipelineTask.TaskID
+	p.Task.Decision = ""
+	p.Task.DecidedBy = ""
+	p.Task.Comment = ""
+
+	approval, err := p.getApproval()
+	if err != nil {
+		p.Log.Errorf("failed to get approval of %s:%d - %v", p.pipelineName, p.taskID, err)
+		p.Task.TaskStatus = config.StatusFailed
+		p.Task.Error = fmt.Sprintf("failed to get approval: %v", err)
+		return
+	}
+	p.applyApproval(approval)
+}
+
+// Wait 审批结果在 Run 中已经获取，不在 warpdrive 中等待
+func (p *ApprovalTaskPlugin) Wait(ctx context.Context) {
+}
+
+func (p *ApprovalTaskPlugin) applyApproval(approval *taskApproval) {
+	switch approval.Status {
+	case config.ApprovalApproved:
+		p.Task.TaskStatus = config.StatusPassed
+	case config.ApprovalRejected:
+		p.Task.TaskStatus = config.StatusFailed
+		p.Task.Error = fmt.Sprintf("rejected by %s: %s", approval.UserName, approval.Comment)
+	default:
+		// 还没有人审批，暂停任务，审批后 aslan 会重新下发
+		p.Task.TaskStatus = config.StatusWaitForApprove
+		return
+	}
+	p.Task.Decision = approval.Status
+	p.Task.DecidedBy = approval.UserName
+	p.Task.Comment = approval.Comment
+}
+
+func (p *ApprovalTaskPlugin) getApproval() (*taskApproval, error) {
+	url := fmt.Sprintf("/api/workflow/workflowtask/id/%d/pipelines/%s/approval", p.taskID, p.pipelineName)
+
+	approval := &taskApproval{}
+	_, err := p.httpClient.Get(url, httpclient.SetResult(approval))
+	if err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// Complete ...
+func (p *ApprovalTaskPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serviceName string) {
+}
+
+// SetTask ...
+func (p *ApprovalTaskPlugin) SetTask(t map[string]interface{}) error {
+	task, err := ToApprovalTask(t)
+	if err != nil {
+		return err
+	}
+	p.Task = task
+	return nil
+}
+
+// GetTask ...
+func (p *ApprovalTaskPlugin) GetTask() interface{} {
+	return p.Task
+}
+
+// IsTaskDone ...
+func (p *ApprovalTaskPlugin) IsTaskDone() bool {
+	if p.Task.TaskStatus != config.StatusCreated && p.Task.TaskStatus != config.StatusRunning {
+		return true
+	}
+	return false
+}
+
+// IsTaskFailed ...
+func (p *ApprovalTaskPlugin) IsTaskFailed() bool {
+	if p.Task.TaskStatus == config.StatusFailed || p.Task.TaskStatus == config.StatusTimeout || p.Task.TaskStatus == config.StatusCancelled {
+		return true
+	}
+	return false
+}
+
+// SetStartTime ...
+func (p *ApprovalTaskPlugin) SetStartTime() {
+	p.Task.StartTime = time.Now().Unix()
+}
+
+// SetEndTime ...
+func (p *ApprovalTaskPlugin) SetEndTime() {
+	p.Task.EndTime = time.Now().Unix()
+}
+
+// IsTaskEnabled ...
+func (p *ApprovalTaskPlugin) IsTaskEnabled() bool {
+	return p.Task.Enabled
+}
+
+// ResetError ...
+func (p *ApprovalTaskPlugin) ResetError() {
+	p.Task.Error = ""
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+)
+
+var _ = Describe("Testing approval", func() {
+	var p *ApprovalTaskPlugin
+
+	BeforeEach(func() {
+		p = &ApprovalTaskPlugin{Task: &task.Approval{Enabled: true, TaskStatus: config.StatusRunning}}
+	})
+
+	It("pauses the task before anyone approves", func() {
+		p.applyApproval(&taskApproval{Status: config.ApprovalWaiting})
+		Expect(p.Status()).To(Equal(config.StatusWaitForApprove))
+		Expect(p.IsTaskDone()).To(BeTrue())
+		Expect(p.IsTaskFailed()).To(BeFalse())
+		Expect(p.Task.Decision).To(BeEmpty())
+	})
+
+	It("passes when approved", func() {
+		p.applyApproval(&taskApproval{Status: config.ApprovalApproved, UserName: "alice"})
+		Expect(p.IsTaskDone()).To(BeTrue())
+		Expect(p.IsTaskFailed()).To(BeFalse())
+		Expect(p.Task.DecidedBy).To(Equal("alice"))
+	})
+
+	It("fails when rejected", func() {
+		p.applyApproval(&taskApproval{Status: config.ApprovalRejected, UserName: "bob", Comment: "not now"})
+		Expect(p.IsTaskFailed()).To(BeTrue())
+		Expect(p.Task.Error).To(Equal("rejected by bob: not now"))
+	})
+
+	It("converts timeout from minutes", func() {
+		Expect(p.TaskTimeout()).To(Equal(ApprovalTimeout))
+		p.Task.Timeout = 30
+		Expect(p.TaskTimeout()).To(Equal(30 * 60))
+	})
+})
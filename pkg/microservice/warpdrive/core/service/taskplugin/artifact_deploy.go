@@ -25,7 +25,6 @@ import (
 	"time"
 
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -172,7 +171,7 @@ func (p *ArtifactDeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.T
 	p.Task.BuildStatus.StartTime = time.Now().Unix()
 	p.ack()
 
-	jobCtxBytes, err := yaml.Marshal(jobCtx.BuildReaperContext(pipelineTask, serviceName))
+	jobCtxBytes, err := marshalReaperContext(&jobCtx, pipelineTask, serviceName, p.KubeNamespace, p.kubeClient)
 	if err != nil {
 		msg := fmt.Sprintf("cannot reaper.Context data: %v", err)
 		p.Log.Error(msg)
@@ -258,7 +257,7 @@ func (p *ArtifactDeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.T
 
 // Wait ...
 func (p *ArtifactDeployTaskPlugin) Wait(ctx context.Context) {
-	status := waitJobEndWithFile(ctx, p.TaskTimeout(), p.KubeNamespace, p.JobName, true, p.kubeClient, p.Log)
+	status := waitJobEndWithFile(ctx, p.TaskTimeout(), "", p.KubeNamespace, p.JobName, true, p.kubeClient, p.Log)
 	p.SetBuildStatusCompleted(status)
 
 	if status == config.StatusPassed {
@@ -25,7 +25,6 @@ import (
 	"time"
 
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -60,6 +59,8 @@ type BuildTaskPlugin struct {
 	Log           *zap.SugaredLogger
 
 	ack func()
+	// matrixJobs 构建矩阵中其它组合的 job
+	matrixJobs []*matrixJob
 }
 
 func (p *BuildTaskPlugin) SetAckFunc(ack func()) {
@@ -187,7 +188,7 @@ func (p *BuildTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipe
 	p.Task.BuildStatus.StartTime = time.Now().Unix()
 	p.ack()
 
-	jobCtxBytes, err := yaml.Marshal(jobCtx.BuildReaperContext(pipelineTask, serviceName))
+	jobCtxBytes, err := marshalReaperContext(&jobCtx, pipelineTask, serviceName, p.KubeNamespace, p.kubeClient)
 	if err != nil {
 		msg := fmt.Sprintf("cannot reaper.Context data: %v", err)
 		p.Log.Error(msg)
@@ -269,11 +270,22 @@ func (p *BuildTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipe
 		return
 	}
 	p.Log.Infof("succeed to create build job %s", p.JobName)
+
+	p.matrixJobs = newMatrixJobs(p.Task.Matrix, p.JobName, p.FileName, serviceName, p.Type(), pipelineTask)
+	startMatrixJobs(p.matrixJobs, func(j *matrixJob) error {
+		return createMatrixJob(j, jobCtx, p.Type(), pipelineTask, serviceName, p.Task.ResReq, p.Task.Registries, p.KubeNamespace, "", p.kubeClient)
+	}, p.Log)
 }
 
 // Wait ...
 func (p *BuildTaskPlugin) Wait(ctx context.Context) {
-	status := waitJobEndWithFile(ctx, p.TaskTimeout(), p.KubeNamespace, p.JobName, true, p.kubeClient, p.Log)
+	matrixDone := make(chan struct{})
+	go func() {
+		defer close(matrixDone)
+		waitMatrixJobs(ctx, p.TaskTimeout(), p.KubeNamespace, p.matrixJobs, p.kubeClient, p.Log)
+	}()
+
+	status := waitJobEndWithFile(ctx, p.TaskTimeout(), "", p.KubeNamespace, p.JobName, true, p.kubeClient, p.Log)
 	p.SetBuildStatusCompleted(status)
 
 	if status == config.StatusPassed {
@@ -298,6 +310,11 @@ func (p *BuildTaskPlugin) Wait(ctx context.Context) {
 		}
 	}
 
+	<-matrixDone
+	status, msg := matrixResult(status, p.Task.StartTime, p.Task.Matrix)
+	if msg != "" {
+		p.Task.Error = msg
+	}
 	p.SetStatus(status)
 }
 
@@ -322,6 +339,8 @@ func (p *BuildTaskPlugin) Complete(ctx context.Context, pipelineTask *task.Task,
 		}
 	}()
 
+	completeMatrixJobs(pipelineTask, p.KubeNamespace, p.Task.TaskStatus, p.matrixJobs, p.kubeClient, p.Log)
+
 	err := saveContainerLog(pipelineTask, p.KubeNamespace, p.FileName, jobLabel, p.kubeClient)
 	if err != nil {
 		p.Log.Error(err)
@@ -330,6 +349,9 @@ func (p *BuildTaskPlugin) Complete(ctx context.Context, pipelineTask *task.Task,
 	}
 
 	p.Task.LogFile = p.FileName
+	if len(p.Task.Matrix) > 0 {
+		p.Task.Matrix[0].LogFile = p.FileName
+	}
 }
 
 // SetTask ...
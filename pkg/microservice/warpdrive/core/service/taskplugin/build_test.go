@@ -30,7 +30,7 @@ import (
 
 func TestBuildTaskPlugin_TaskTimeout_Default(t *testing.T) {
 	assert := assert.New(t)
-	plugin := InitializeBuildTaskPlugin(config.TaskBuild).(*BuildTaskPlugin)
+	plugin := &BuildTaskPlugin{Name: config.TaskBuild, kubeClient: FakeKubeCli}
 
 	plugin.Task = buildTaskForTest()
 	assert.Equal(BuildTaskV2Timeout, plugin.TaskTimeout())
@@ -38,7 +38,7 @@ func TestBuildTaskPlugin_TaskTimeout_Default(t *testing.T) {
 
 func TestBuildTaskPlugin_TaskTimeout_Restart(t *testing.T) {
 	assert := assert.New(t)
-	plugin := InitializeBuildTaskPlugin(config.TaskBuild).(*BuildTaskPlugin)
+	plugin := &BuildTaskPlugin{Name: config.TaskBuild, kubeClient: FakeKubeCli}
 
 	initTimeout := 20
 	plugin.Task = buildTaskForTest()
@@ -50,7 +50,7 @@ func TestBuildTaskPlugin_TaskTimeout_Restart(t *testing.T) {
 
 func TestBuildTaskPlugin_TaskTimeout_NotRestart(t *testing.T) {
 	assert := assert.New(t)
-	plugin := InitializeBuildTaskPlugin(config.TaskBuild).(*BuildTaskPlugin)
+	plugin := &BuildTaskPlugin{Name: config.TaskBuild, kubeClient: FakeKubeCli}
 
 	initTimeout := 20
 	plugin.Task = buildTaskForTest()
@@ -64,7 +64,7 @@ func TestBuildTaskPlugin_TaskTimeout_NotRestart(t *testing.T) {
 }
 
 func TestBuildTaskPlugin_SetBuildStatusCompleted(t *testing.T) {
-	plugin := InitializeBuildTaskPlugin(config.TaskBuild).(*BuildTaskPlugin)
+	plugin := &BuildTaskPlugin{Name: config.TaskBuild, kubeClient: FakeKubeCli}
 	plugin.Task = buildTaskForTest()
 	plugin.SetBuildStatusCompleted(config.StatusPassed)
 
@@ -84,7 +84,7 @@ func TestBuildTaskPlugin_Run(t *testing.T) {
 		FakeKubeCli.DeleteJob(namespace, jobname)
 	}()
 
-	buildTaskPlugin := InitializeBuildTaskPlugin(config.TaskBuild).(*BuildTaskPlugin)
+	buildTaskPlugin := &BuildTaskPlugin{Name: config.TaskBuild, kubeClient: FakeKubeCli}
 	assert.NotNil(buildTaskPlugin)
 	assert.Equal(buildTaskPlugin.Type(), config.TaskBuild)
 	buildTaskPlugin.Init(jobname, jobname, log)
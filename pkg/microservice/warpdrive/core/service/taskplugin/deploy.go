@@ -62,6 +62,7 @@ func InitializeDeployTaskPlugin(taskType config.TaskType) TaskPlugin {
 		restConfig: krkubeclient.RESTConfig(),
 		httpClient: httpclient.New(
 			httpclient.SetHostURL(configbase.AslanServiceAddress()),
+			httpclient.SetClientHeader(setting.InternalTokenHeader, configbase.InternalToken()),
 		),
 	}
 }
@@ -77,6 +78,7 @@ type DeployTaskPlugin struct {
 	ReplaceImage string
 
 	httpClient *httpclient.Client
+	rollouts   []*rolloutState
 }
 
 func (p *DeployTaskPlugin) SetAckFunc(func()) {
@@ -196,6 +198,12 @@ func (p *DeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, _ *
 			p.Log.Error(err)
 			p.Task.TaskStatus = config.StatusFailed
 			p.Task.Error = err.Error()
+			// 清理已经开始的金丝雀/蓝绿发布，部分资源已经更新为新镜像，开启自动回滚时恢复这些资源
+			rollback := p.shouldRollback()
+			p.abortRollouts(rollback)
+			if rollback {
+				p.rollbackImages()
+			}
 			return
 		}
 	}()
@@ -247,7 +255,7 @@ func (p *DeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, _ *
 			for _, deploy := range deployments {
 				for _, container := range deploy.Spec.Template.Spec.Containers {
 					if container.Name == p.Task.ContainerName {
-						err = updater.UpdateDeploymentImage(deploy.Namespace, deploy.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient)
+						err = p.updateDeploymentImage(deploy, container.Image)
 						if err != nil {
 							err = errors.WithMessagef(
 								err,
@@ -270,6 +278,7 @@ func (p *DeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, _ *
 			for _, sts := range statefulSets {
 				for _, container := range sts.Spec.Template.Spec.Containers {
 					if container.Name == p.Task.ContainerName {
+						p.warnStatefulSetRollout(sts)
 						err = updater.UpdateStatefulSetImage(sts.Namespace, sts.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient)
 						if err != nil {
 							err = errors.WithMessagef(
@@ -299,6 +308,7 @@ func (p *DeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, _ *
 				}
 				for _, container := range statefulSet.Spec.Template.Spec.Containers {
 					if container.Name == p.Task.ContainerName {
+						p.warnStatefulSetRollout(statefulSet)
 						err = updater.UpdateStatefulSetImage(statefulSet.Namespace, statefulSet.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient)
 						if err != nil {
 							err = errors.WithMessagef(
@@ -325,7 +335,7 @@ func (p *DeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, _ *
 				}
 				for _, container := range deployment.Spec.Template.Spec.Containers {
 					if container.Name == p.Task.ContainerName {
-						err = updater.UpdateDeploymentImage(deployment.Namespace, deployment.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient)
+						err = p.updateDeploymentImage(deployment, container.Image)
 						if err != nil {
 							err = errors.WithMessagef(
 								err,
@@ -501,7 +511,7 @@ func (p *DeployTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, _ *
 			return
 		}
 
-		servicePath, err = p.downloadService(pipelineTask.ProductName, p.Task.ServiceName, pipelineTask.StorageURI)
+		servicePath, err = downloadService(pipelineTask.ProductName, p.Task.ServiceName, pipelineTask.StorageURI, p.Log)
 		if err != nil {
 			err = errors.WithMessagef(
 				err,
@@ -585,8 +595,7 @@ func (p *DeployTaskPlugin) getService(ctx context.Context, name, serviceType, pr
 	return s, nil
 }
 
-func (p *DeployTaskPlugin) downloadService(productName, serviceName, storageURI string) (string, error) {
-	logger := p.Log
+func downloadService(productName, serviceName, storageURI string, logger *zap.SugaredLogger) (string, error) {
 
 	base := configbase.LocalServicePath(productName, serviceName)
 	s3Storage, err := s3.NewS3StorageFromEncryptedURI(storageURI)
@@ -603,7 +612,7 @@ func (p *DeployTaskPlugin) downloadService(productName, serviceName, storageURI
 	}
 	client, err := s3tool.NewClient(s3Storage.Endpoint, s3Storage.Ak, s3Storage.Sk, s3Storage.Insecure, forcedPathStyle)
 	if err != nil {
-		p.Log.Errorf("failed to create s3 client, err: %+v", err)
+		logger.Errorf("failed to create s3 client, err: %+v", err)
 		return "", err
 	}
 	if err = client.Download(s3Storage.Bucket, s3Storage.GetObjectPath(tarball), tarFilePath); err != nil {
@@ -730,6 +739,18 @@ func (p *DeployTaskPlugin) Wait(ctx context.Context) {
 		return
 	}
 
+	// canary and blue-green rollouts are driven step by step here
+	if len(p.rollouts) > 0 {
+		p.waitRollout(ctx)
+		return
+	}
+
+	defer func() {
+		if p.shouldRollback() {
+			p.rollbackImages()
+		}
+	}()
+
 	timeout := time.After(time.Duration(p.TaskTimeout()) * time.Second)
 
 	selector := labels.Set{setting.ProductLabel: p.Task.ProductName, setting.ServiceLabel: p.Task.ServiceName}.AsSelector()
@@ -822,6 +843,12 @@ func (p *DeployTaskPlugin) Wait(ctx context.Context) {
 
 			if ready {
 				p.Task.TaskStatus = config.StatusPassed
+			} else if p.Task.AutoRollback {
+				// 开启自动回滚时，新镜像持续 crash 无需等到超时
+				if msg := p.crashLoopMessage(selector); msg != "" {
+					p.Task.TaskStatus = config.StatusFailed
+					p.Task.Error = msg
+				}
 			}
 
 			if p.IsTaskDone() {
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+)
+
+const (
+	crashLoopReason = "CrashLoopBackOff"
+	// crashLoopRestartThreshold 新镜像的容器重启超过该次数才认为处于 crash loop，避免依赖服务启动慢导致误判
+	crashLoopRestartThreshold = 3
+)
+
+// shouldRollback 健康检查失败或超时，且开启了自动回滚
+func (p *DeployTaskPlugin) shouldRollback() bool {
+	if !p.Task.AutoRollback || len(p.Task.ReplaceResources) == 0 {
+		return false
+	}
+	return p.Task.TaskStatus == config.StatusFailed || p.Task.TaskStatus == config.StatusTimeout
+}
+
+// rollbackImages 把 ReplaceResources 中记录的资源恢复为部署前的镜像
+func (p *DeployTaskPlugin) rollbackImages() {
+	if p.Task.ServiceType == setting.HelmDeployType {
+		p.Log.Warnf("auto rollback is not supported for helm service %s", p.Task.ServiceName)
+		return
+	}
+
+	var errs []string
+	for _, res := range p.Task.ReplaceResources {
+		var err error
+		switch res.Kind {
+		case setting.Deployment:
+			err = updater.UpdateDeploymentImage(p.Task.Namespace, res.Name, res.Container, res.Origin, p.kubeClient)
+		case setting.StatefulSet:
+			err = updater.UpdateStatefulSetImage(p.Task.Namespace, res.Name, res.Container, res.Origin, p.kubeClient)
+		default:
+			continue
+		}
+		if err != nil {
+			p.Log.Errorf("failed to rollback %s/%s/%s to %s: %v", p.Task.Namespace, res.Kind, res.Name, res.Origin, err)
+			errs = append(errs, fmt.Sprintf("%s/%s: %v", res.Kind, res.Name, err))
+			continue
+		}
+		p.Log.Infof("rolled back %s/%s/%s container %s to %s", p.Task.Namespace, res.Kind, res.Name, res.Container, res.Origin)
+	}
+
+	if len(errs) != 0 {
+		p.Task.Error = joinTaskError(p.Task.Error, "auto rollback failed: "+strings.Join(errs, "; "))
+		return
+	}
+
+	p.Task.RolledBack = true
+	p.Task.Error = joinTaskError(p.Task.Error, fmt.Sprintf("rolled back to previous image %s", rolledBackImages(p.Task.ReplaceResources)))
+}
+
+// crashLoopMessage 返回运行新镜像且处于 crash loop 的容器信息，没有则返回空
+func (p *DeployTaskPlugin) crashLoopMessage(selector labels.Selector) string {
+	pods, err := getter.ListPods(p.Task.Namespace, selector, p.kubeClient)
+	if err != nil {
+		p.Log.Errorf("failed to list pods %s/%s - %v", p.Task.Namespace, selector, err)
+		return ""
+	}
+
+	var msg []string
+	for _, pod := range pods {
+		for _, cs := range wrapper.Pod(pod).Resource().ContainerStatuses {
+			if !sameImage(cs.Image, p.Task.Image) || cs.Reason != crashLoopReason || cs.RestartCount < crashLoopRestartThreshold {
+				continue
+			}
+			msg = append(msg, fmt.Sprintf("Pod: %s, Container: %s, Reason: %s, RestartCount: %d, Message: %s", pod.Name, cs.Name, cs.Reason, cs.RestartCount, cs.Message))
+		}
+	}
+
+	return strings.Join(msg, "\n")
+}
+
+// sameImage 比较规范化后的镜像地址，容器状态中的镜像可能带有默认的 registry 和 tag，如 nginx 和 docker.io/library/nginx:latest
+func sameImage(a, b string) bool {
+	return normalizeImage(a) == normalizeImage(b)
+}
+
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+func rolledBackImages(resources []task.Resource) string {
+	images := make([]string, 0, len(resources))
+	seen := make(map[string]bool)
+	for _, res := range resources {
+		if seen[res.Origin] {
+			continue
+		}
+		seen[res.Origin] = true
+		images = append(images, res.Origin)
+	}
+	return strings.Join(images, ", ")
+}
+
+func joinTaskError(origin, msg string) string {
+	if origin == "" {
+		return msg
+	}
+	return origin + "\n" + msg
+}
@@ -0,0 +1,511 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+)
+
+const (
+	canaryTrack    = "canary"
+	greenTrack     = "green"
+	rolloutPollGap = 2 * time.Second
+)
+
+var (
+	defaultCanaryWeights = []int{20, 50, 100}
+
+	errRolloutCancelled = errors.New("rollout is cancelled")
+	errRolloutTimeout   = errors.New("rollout is timeout")
+)
+
+// rolloutState 记录一个 deployment 在金丝雀/蓝绿发布过程中创建和修改的资源，用于推进或回退
+// 每个被更新的 deployment 各自对应一个 rolloutState
+type rolloutState struct {
+	stable        *appsv1.Deployment
+	candidate     *appsv1.Deployment
+	replicas      int32
+	weights       []int
+	originImage   string
+	stablePatched bool
+
+	// 蓝绿发布时需要切换的 service 及其原始 selector
+	services         []*corev1.Service
+	servicesSwitched bool
+}
+
+func (p *DeployTaskPlugin) rolloutType() string {
+	if p.Task.RolloutStrategy == nil || p.Task.RolloutStrategy.Type == "" {
+		return setting.RolloutTypeRolling
+	}
+	return p.Task.RolloutStrategy.Type
+}
+
+func (p *DeployTaskPlugin) canaryWeights() []int {
+	if p.Task.RolloutStrategy == nil || len(p.Task.RolloutStrategy.CanaryWeights) == 0 {
+		return defaultCanaryWeights
+	}
+	weights := append([]int{}, p.Task.RolloutStrategy.CanaryWeights...)
+	// 最后一步必须全量切到新版本才能晋级
+	if weights[len(weights)-1] != 100 {
+		weights = append(weights, 100)
+	}
+	return weights
+}
+
+// canaryWeightsFor 返回 replicas 个副本时金丝雀每一步实际使用的权重
+// 流量按副本数分配，只能表示 canary/(canary+stable) 这样的比例：配置的权重无法表示时直接报错并给出可用的权重，
+// 使用默认权重时调整为最接近的可表示的值
+func (p *DeployTaskPlugin) canaryWeightsFor(replicas int32) ([]int, error) {
+	configured := p.Task.RolloutStrategy != nil && len(p.Task.RolloutStrategy.CanaryWeights) > 0
+
+	weights := make([]int, 0, len(p.canaryWeights()))
+	for _, weight := range p.canaryWeights() {
+		effective := effectiveWeight(replicas, weight)
+		if configured && effective != weight {
+			return nil, fmt.Errorf("canary weight %d%% can not be represented by %d replicas because traffic is split by replicas, valid weights are %s",
+				weight, replicas, representableWeights(replicas))
+		}
+		if len(weights) > 0 && weights[len(weights)-1] == effective {
+			continue
+		}
+		weights = append(weights, effective)
+	}
+	return weights, nil
+}
+
+func (p *DeployTaskPlugin) stepInterval() time.Duration {
+	if p.Task.RolloutStrategy == nil {
+		return 0
+	}
+	return time.Duration(p.Task.RolloutStrategy.StepInterval) * time.Second
+}
+
+// updateDeploymentImage 按照发布策略更新 deployment 的镜像
+// rolling 直接原地更新，canary 和 blue_green 先创建新版本的 deployment，在 Wait 中逐步推进
+//
+// 发布策略只对 deployment 生效，statefulset 的 pod 有固定的身份和存储，无法另建一份新版本，始终原地滚动更新。
+// 金丝雀的流量比例通过新旧两个 deployment 的副本数之比实现：两者的 pod 同时被原 service 选中，
+// 由 kube-proxy 按 endpoint 均分流量，没有 ingress 或服务网格层面的按权重切流，
+// 因此权重只能取副本数可以表示的比例，例如 2 个副本时只能是 50%，配置了无法表示的权重时发布直接失败，不会创建任何资源。
+func (p *DeployTaskPlugin) updateDeploymentImage(deploy *appsv1.Deployment, origin string) error {
+	switch p.rolloutType() {
+	case setting.RolloutTypeCanary, setting.RolloutTypeBlueGreen:
+		return p.startRollout(deploy, origin)
+	default:
+		return updater.UpdateDeploymentImage(deploy.Namespace, deploy.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient)
+	}
+}
+
+// warnStatefulSetRollout statefulset 不支持金丝雀和蓝绿发布，始终原地滚动更新
+func (p *DeployTaskPlugin) warnStatefulSetRollout(sts *appsv1.StatefulSet) {
+	if p.rolloutType() != setting.RolloutTypeRolling {
+		p.Log.Warnf("%s rollout is not supported by statefulset %s/%s, rolling update it instead", p.rolloutType(), sts.Namespace, sts.Name)
+	}
+}
+
+func (p *DeployTaskPlugin) startRollout(deploy *appsv1.Deployment, origin string) error {
+	state := &rolloutState{
+		stable:      deploy,
+		replicas:    1,
+		originImage: origin,
+	}
+	if deploy.Spec.Replicas != nil && *deploy.Spec.Replicas > 0 {
+		state.replicas = *deploy.Spec.Replicas
+	}
+
+	switch p.rolloutType() {
+	case setting.RolloutTypeCanary:
+		weights, err := p.canaryWeightsFor(state.replicas)
+		if err != nil {
+			return err
+		}
+		state.weights = weights
+		canaryReplicas, _ := splitReplicas(state.replicas, weights[0])
+		state.candidate = newCandidateDeployment(deploy, canaryTrack, p.Task.ContainerName, p.Task.Image, canaryReplicas, nil)
+	case setting.RolloutTypeBlueGreen:
+		services, err := servicesSelecting(deploy, p.kubeClient)
+		if err != nil {
+			return err
+		}
+		if len(services) == 0 {
+			p.Log.Warnf("no service selects deployment %s/%s, fall back to rolling update", deploy.Namespace, deploy.Name)
+			return updater.UpdateDeploymentImage(deploy.Namespace, deploy.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient)
+		}
+		state.services = services
+		state.candidate = newCandidateDeployment(deploy, greenTrack, p.Task.ContainerName, p.Task.Image, state.replicas, services)
+	}
+
+	if err := updater.CreateOrPatchDeployment(state.candidate, p.kubeClient); err != nil {
+		return errors.WithMessagef(err, "failed to create %s/deployments/%s", deploy.Namespace, state.candidate.Name)
+	}
+	p.Log.Infof("%s rollout started, created deployment %s/%s", p.rolloutType(), deploy.Namespace, state.candidate.Name)
+
+	p.rollouts = append(p.rollouts, state)
+	return nil
+}
+
+// waitRollout 依次推进每个 deployment 的发布流程，任何一个失败、超时或取消时，都会把流量切回原 deployment 并清理新建的 deployment，
+// 开启了自动回滚时已经晋级的 deployment 还会回退到原来的镜像
+func (p *DeployTaskPlugin) waitRollout(ctx context.Context) {
+	timeout := time.After(time.Duration(p.TaskTimeout()) * time.Second)
+
+	var err error
+	for _, state := range p.rollouts {
+		switch p.rolloutType() {
+		case setting.RolloutTypeCanary:
+			err = p.runCanary(ctx, timeout, state)
+		case setting.RolloutTypeBlueGreen:
+			err = p.runBlueGreen(ctx, timeout, state)
+		}
+		if err != nil {
+			p.Log.Errorf("%s rollout of %s/%s aborted: %v", p.rolloutType(), p.Task.Namespace, state.stable.Name, err)
+			break
+		}
+	}
+
+	if err == nil {
+		p.Task.TaskStatus = config.StatusPassed
+		return
+	}
+
+	switch err {
+	case errRolloutCancelled:
+		p.Task.TaskStatus = config.StatusCancelled
+	case errRolloutTimeout:
+		p.Task.TaskStatus = config.StatusTimeout
+	default:
+		p.Task.TaskStatus = config.StatusFailed
+		p.Task.Error = err.Error()
+	}
+
+	// 自动回滚只控制是否恢复镜像，流量和新建的 deployment 始终要清理，不能停留在发布的中间状态
+	p.abortRollouts(p.Task.AutoRollback)
+	if !p.Task.AutoRollback {
+		p.Log.Warnf("auto rollback is disabled, promoted deployments in %s keep the new image", p.Task.Namespace)
+		p.Task.Error = joinTaskError(p.Task.Error, fmt.Sprintf("%s deployments are cleaned up, auto rollback is disabled so promoted deployments keep the new image", p.rolloutType()))
+		return
+	}
+	p.Task.RolledBack = true
+	p.Task.Error = joinTaskError(p.Task.Error, fmt.Sprintf("rolled back to previous image %s", rolledBackImages(p.Task.ReplaceResources)))
+}
+
+func (p *DeployTaskPlugin) runCanary(ctx context.Context, timeout <-chan time.Time, state *rolloutState) error {
+	ns := state.stable.Namespace
+
+	for _, weight := range state.weights {
+		canaryReplicas, stableReplicas := splitReplicas(state.replicas, weight)
+		p.Log.Infof("canary step %d%%: %s replicas %d, %s replicas %d", weight, state.candidate.Name, canaryReplicas, state.stable.Name, stableReplicas)
+
+		if err := updater.ScaleDeployment(ns, state.candidate.Name, int(canaryReplicas), p.kubeClient); err != nil {
+			return err
+		}
+		if err := p.waitDeploymentReady(ctx, timeout, state.candidate.Name); err != nil {
+			return err
+		}
+		if err := updater.ScaleDeployment(ns, state.stable.Name, int(stableReplicas), p.kubeClient); err != nil {
+			return err
+		}
+		if err := p.observe(ctx, timeout, state.candidate.Name); err != nil {
+			return err
+		}
+	}
+
+	// 晋级：原 deployment 更新为新镜像并恢复副本数，就绪后删除金丝雀
+	state.stablePatched = true
+	if err := updater.UpdateDeploymentImage(ns, state.stable.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient); err != nil {
+		return err
+	}
+	if err := updater.ScaleDeployment(ns, state.stable.Name, int(state.replicas), p.kubeClient); err != nil {
+		return err
+	}
+	if err := p.waitDeploymentReady(ctx, timeout, state.stable.Name); err != nil {
+		return err
+	}
+
+	if err := updater.DeleteDeployment(ns, state.candidate.Name, p.kubeClient); err != nil {
+		p.Log.Errorf("failed to delete canary deployment %s/%s: %v", ns, state.candidate.Name, err)
+	}
+	return nil
+}
+
+func (p *DeployTaskPlugin) runBlueGreen(ctx context.Context, timeout <-chan time.Time, state *rolloutState) error {
+	ns := state.stable.Namespace
+
+	if err := p.waitDeploymentReady(ctx, timeout, state.candidate.Name); err != nil {
+		return err
+	}
+
+	// green 就绪后一次性把流量切过去
+	state.servicesSwitched = true
+	greenSelector := rolloutSelector(state.stable.Name, greenTrack)
+	for _, svc := range state.services {
+		if err := updater.UpdateServiceSelector(ns, svc.Name, greenSelector, p.kubeClient); err != nil {
+			return errors.WithMessagef(err, "failed to switch selector of %s/services/%s", ns, svc.Name)
+		}
+		p.Log.Infof("service %s/%s switched to %s", ns, svc.Name, state.candidate.Name)
+	}
+	if err := p.observe(ctx, timeout, state.candidate.Name); err != nil {
+		return err
+	}
+
+	// 原 deployment 在没有流量的情况下更新镜像，就绪后切回并删除 green
+	state.stablePatched = true
+	if err := updater.UpdateDeploymentImage(ns, state.stable.Name, p.Task.ContainerName, p.Task.Image, p.kubeClient); err != nil {
+		return err
+	}
+	if err := p.waitDeploymentReady(ctx, timeout, state.stable.Name); err != nil {
+		return err
+	}
+	if err := p.restoreServiceSelectors(state); err != nil {
+		return err
+	}
+	state.servicesSwitched = false
+
+	if err := updater.DeleteDeployment(ns, state.candidate.Name, p.kubeClient); err != nil {
+		p.Log.Errorf("failed to delete green deployment %s/%s: %v", ns, state.candidate.Name, err)
+	}
+	return nil
+}
+
+// abortRollouts 将所有 deployment 的流量恢复到发布前的状态，restoreImage 为 true 时已经晋级的 deployment 也恢复原来的镜像
+func (p *DeployTaskPlugin) abortRollouts(restoreImage bool) {
+	for _, state := range p.rollouts {
+		p.abortRollout(state, restoreImage)
+	}
+}
+
+// abortRollout 恢复原 deployment 的副本数和 service 的 selector，并清理新建的 deployment
+func (p *DeployTaskPlugin) abortRollout(state *rolloutState, restoreImage bool) {
+	ns := state.stable.Namespace
+
+	if restoreImage && state.stablePatched {
+		if err := updater.UpdateDeploymentImage(ns, state.stable.Name, p.Task.ContainerName, state.originImage, p.kubeClient); err != nil {
+			p.Log.Errorf("failed to restore image of %s/deployments/%s: %v", ns, state.stable.Name, err)
+		}
+	}
+	if err := updater.ScaleDeployment(ns, state.stable.Name, int(state.replicas), p.kubeClient); err != nil {
+		p.Log.Errorf("failed to restore replicas of %s/deployments/%s: %v", ns, state.stable.Name, err)
+	}
+	if state.servicesSwitched {
+		if err := p.restoreServiceSelectors(state); err != nil {
+			p.Log.Error(err)
+		}
+	}
+	if err := updater.DeleteDeployment(ns, state.candidate.Name, p.kubeClient); client.IgnoreNotFound(err) != nil {
+		p.Log.Errorf("failed to delete %s/deployments/%s: %v", ns, state.candidate.Name, err)
+	}
+}
+
+func (p *DeployTaskPlugin) restoreServiceSelectors(state *rolloutState) error {
+	for _, svc := range state.services {
+		if err := updater.UpdateServiceSelector(svc.Namespace, svc.Name, svc.Spec.Selector, p.kubeClient); err != nil {
+			return errors.WithMessagef(err, "failed to restore selector of %s/services/%s", svc.Namespace, svc.Name)
+		}
+	}
+	return nil
+}
+
+func (p *DeployTaskPlugin) waitDeploymentReady(ctx context.Context, timeout <-chan time.Time, name string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return errRolloutCancelled
+		case <-timeout:
+			return errRolloutTimeout
+		default:
+			time.Sleep(rolloutPollGap)
+			d, found, err := getter.GetDeployment(p.Task.Namespace, name, p.kubeClient)
+			if err != nil || !found {
+				p.Log.Errorf("failed to check deployment ready status %s/%s - %v", p.Task.Namespace, name, err)
+				continue
+			}
+			if wrapper.Deployment(d).Ready() {
+				return nil
+			}
+		}
+	}
+}
+
+// observe 在每一步的观察期内新版本必须一直保持就绪
+func (p *DeployTaskPlugin) observe(ctx context.Context, timeout <-chan time.Time, name string) error {
+	interval := p.stepInterval()
+	if interval <= 0 {
+		return nil
+	}
+
+	done := time.After(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return errRolloutCancelled
+		case <-timeout:
+			return errRolloutTimeout
+		case <-done:
+			return nil
+		default:
+			time.Sleep(rolloutPollGap)
+			d, found, err := getter.GetDeployment(p.Task.Namespace, name, p.kubeClient)
+			if err != nil {
+				p.Log.Errorf("failed to check deployment ready status %s/%s - %v", p.Task.Namespace, name, err)
+				continue
+			}
+			if !found || !wrapper.Deployment(d).Ready() {
+				return fmt.Errorf("deployment %s/%s became unready during observation", p.Task.Namespace, name)
+			}
+		}
+	}
+}
+
+// splitReplicas 按权重把副本数四舍五入分配给金丝雀和稳定版本，未到 100% 时两者都至少保留一个副本
+func splitReplicas(total int32, weight int) (canary, stable int32) {
+	if weight >= 100 {
+		return total, 0
+	}
+	canary = (total*int32(weight) + 50) / 100
+	if canary < 1 {
+		canary = 1
+	}
+	stable = total - canary
+	if stable < 1 {
+		stable = 1
+	}
+	return canary, stable
+}
+
+// effectiveWeight 返回按副本数分配后金丝雀实际承担的流量比例
+func effectiveWeight(total int32, weight int) int {
+	canary, stable := splitReplicas(total, weight)
+	if stable == 0 {
+		return 100
+	}
+	return int((200*canary + canary + stable) / (2 * (canary + stable)))
+}
+
+// representableWeights 列出 total 个副本时可以准确表示的权重
+func representableWeights(total int32) string {
+	var weights []string
+	for weight := 1; weight <= 100; weight++ {
+		if effectiveWeight(total, weight) == weight {
+			weights = append(weights, strconv.Itoa(weight))
+		}
+	}
+	return strings.Join(weights, ", ")
+}
+
+func rolloutSelector(workload, track string) map[string]string {
+	return map[string]string{
+		setting.RolloutWorkloadLabel: workload,
+		setting.RolloutTrackLabel:    track,
+	}
+}
+
+// newCandidateDeployment 以原 deployment 为模板生成新版本的 deployment
+// 金丝雀的 pod 保留原有 label，和稳定版本共同承接 service 流量；
+// 蓝绿发布的 pod 去掉 service selector 中的 label，切换前不接收流量
+func newCandidateDeployment(stable *appsv1.Deployment, track, container, image string, replicas int32, services []*corev1.Service) *appsv1.Deployment {
+	selector := rolloutSelector(stable.Name, track)
+
+	podLabels := make(map[string]string)
+	for k, v := range stable.Spec.Template.Labels {
+		podLabels[k] = v
+	}
+	for _, svc := range services {
+		for k := range svc.Spec.Selector {
+			delete(podLabels, k)
+		}
+	}
+	for k, v := range selector {
+		podLabels[k] = v
+	}
+
+	matchLabels := make(map[string]string)
+	if track == canaryTrack && stable.Spec.Selector != nil {
+		for k, v := range stable.Spec.Selector.MatchLabels {
+			matchLabels[k] = v
+		}
+	}
+	for k, v := range selector {
+		matchLabels[k] = v
+	}
+
+	objLabels := make(map[string]string)
+	for k, v := range stable.Labels {
+		objLabels[k] = v
+	}
+	objLabels[setting.RolloutTrackLabel] = track
+
+	candidate := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       setting.Deployment,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", stable.Name, track),
+			Namespace: stable.Namespace,
+			Labels:    objLabels,
+		},
+		Spec: *stable.Spec.DeepCopy(),
+	}
+	candidate.Spec.Replicas = &replicas
+	candidate.Spec.Selector = &metav1.LabelSelector{MatchLabels: matchLabels}
+	candidate.Spec.Template.Labels = podLabels
+	for i, c := range candidate.Spec.Template.Spec.Containers {
+		if c.Name == container {
+			candidate.Spec.Template.Spec.Containers[i].Image = image
+		}
+	}
+
+	return candidate
+}
+
+// servicesSelecting 找出 selector 命中该 deployment 的 service
+func servicesSelecting(deploy *appsv1.Deployment, cl client.Client) ([]*corev1.Service, error) {
+	services, err := getter.ListServices(deploy.Namespace, nil, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []*corev1.Service
+	podLabels := labels.Set(deploy.Spec.Template.Labels)
+	for _, svc := range services {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(podLabels) {
+			resp = append(resp, svc)
+		}
+	}
+	return resp, nil
+}
@@ -17,9 +17,20 @@ limitations under the License.
 package taskplugin
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/util/converter"
 )
 
@@ -91,3 +102,249 @@ var _ = Describe("Testing replace image", func() {
 
 	})
 })
+
+var _ = Describe("Testing rollout", func() {
+	Context("split replicas", func() {
+
+		It("keeps at least one stable replica before full weight", func() {
+			canary, stable := splitReplicas(1, 20)
+			Expect(canary).To(Equal(int32(1)))
+			Expect(stable).To(Equal(int32(1)))
+
+			canary, stable = splitReplicas(10, 30)
+			Expect(canary).To(Equal(int32(3)))
+			Expect(stable).To(Equal(int32(7)))
+		})
+
+		It("moves all replicas to canary at full weight", func() {
+			canary, stable := splitReplicas(4, 100)
+			Expect(canary).To(Equal(int32(4)))
+			Expect(stable).To(Equal(int32(0)))
+		})
+	})
+
+	Context("canary weights", func() {
+		newPlugin := func(weights ...int) *DeployTaskPlugin {
+			return &DeployTaskPlugin{
+				Task: &task.Deploy{RolloutStrategy: &task.RolloutStrategy{Type: setting.RolloutTypeCanary, CanaryWeights: weights}},
+				Log:  log.SugaredLogger(),
+			}
+		}
+
+		It("adjusts the default weights to what the replicas can represent", func() {
+			weights, err := newPlugin().canaryWeightsFor(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weights).To(Equal([]int{20, 50, 100}))
+
+			weights, err = newPlugin().canaryWeightsFor(3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weights).To(Equal([]int{33, 67, 100}))
+
+			weights, err = newPlugin().canaryWeightsFor(2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weights).To(Equal([]int{50, 100}))
+		})
+
+		It("keeps configured weights the replicas can represent", func() {
+			weights, err := newPlugin(30, 60).canaryWeightsFor(10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weights).To(Equal([]int{30, 60, 100}))
+
+			weights, err = newPlugin(50).canaryWeightsFor(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weights).To(Equal([]int{50, 100}))
+		})
+
+		It("rejects configured weights the replicas can not represent", func() {
+			_, err := newPlugin(20, 50).canaryWeightsFor(3)
+			Expect(err).To(MatchError(ContainSubstring("canary weight 20% can not be represented by 3 replicas")))
+			Expect(err).To(MatchError(ContainSubstring("valid weights are 33, 67, 100")))
+		})
+
+		It("creates no canary deployment when the weights are rejected", func() {
+			var replicas int32 = 2
+			stable := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "rollout-reject"},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			}
+			p := newPlugin(20)
+			p.kubeClient = FakeKubeCli
+			Expect(p.startRollout(stable, "web:v1")).To(MatchError(ContainSubstring("valid weights are 50, 100")))
+			Expect(p.rollouts).To(BeEmpty())
+
+			_, found, err := getter.GetDeployment("rollout-reject", "web-canary", FakeKubeCli)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("candidate deployment", func() {
+		var replicas int32 = 2
+		stable := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{setting.ServiceLabel: "web"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{setting.ServiceLabel: "web", "app": "web"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "web", Image: "web:v1"}, {Name: "sidecar", Image: "sidecar:v1"}},
+					},
+				},
+			},
+		}
+
+		It("canary pods keep the labels selected by services", func() {
+			candidate := newCandidateDeployment(stable, canaryTrack, "web", "web:v2", 1, nil)
+			Expect(candidate.Name).To(Equal("web-canary"))
+			Expect(*candidate.Spec.Replicas).To(Equal(int32(1)))
+			Expect(candidate.Spec.Template.Labels).To(HaveKeyWithValue(setting.ServiceLabel, "web"))
+			Expect(candidate.Spec.Template.Labels).To(HaveKeyWithValue(setting.RolloutTrackLabel, canaryTrack))
+			Expect(candidate.Spec.Template.Spec.Containers[0].Image).To(Equal("web:v2"))
+			Expect(candidate.Spec.Template.Spec.Containers[1].Image).To(Equal("sidecar:v1"))
+			Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("web:v1"))
+		})
+
+		It("green pods drop the labels selected by services", func() {
+			svc := &corev1.Service{Spec: corev1.ServiceSpec{Selector: map[string]string{setting.ServiceLabel: "web"}}}
+			candidate := newCandidateDeployment(stable, greenTrack, "web", "web:v2", replicas, []*corev1.Service{svc})
+			Expect(candidate.Name).To(Equal("web-green"))
+			Expect(candidate.Spec.Template.Labels).NotTo(HaveKey(setting.ServiceLabel))
+			Expect(candidate.Spec.Template.Labels).To(HaveKeyWithValue("app", "web"))
+			Expect(candidate.Spec.Selector.MatchLabels).To(Equal(rolloutSelector("web", greenTrack)))
+		})
+	})
+})
+
+var _ = Describe("Testing rollout abort", func() {
+	It("restores every workload of the rollout", func() {
+		var replicas int32 = 2
+		newDeployment := func(name, image string) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "rollout"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+					},
+				},
+			}
+		}
+
+		p := &DeployTaskPlugin{
+			Task:       &task.Deploy{ContainerName: "web", Namespace: "rollout", RolloutStrategy: &task.RolloutStrategy{Type: setting.RolloutTypeCanary}},
+			Log:        log.SugaredLogger(),
+			kubeClient: FakeKubeCli,
+		}
+		for _, name := range []string{"web", "web-worker"} {
+			stable := newDeployment(name, "web:v2")
+			candidate := newDeployment(name+"-canary", "web:v2")
+			Expect(FakeKubeCli.Create(context.TODO(), stable)).To(Succeed())
+			Expect(FakeKubeCli.Create(context.TODO(), candidate)).To(Succeed())
+			p.rollouts = append(p.rollouts, &rolloutState{stable: stable, candidate: candidate, replicas: replicas, originImage: "web:v1", stablePatched: true})
+		}
+
+		p.abortRollouts(true)
+
+		for _, name := range []string{"web", "web-worker"} {
+			d, found, err := getter.GetDeployment("rollout", name, FakeKubeCli)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("web:v1"))
+
+			_, found, err = getter.GetDeployment("rollout", name+"-canary", FakeKubeCli)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		}
+	})
+})
+
+var _ = Describe("Testing rollout failure", func() {
+	newRollout := func(ns string, autoRollback bool) *DeployTaskPlugin {
+		var replicas int32 = 2
+		stable := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: ns},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:v1"}}},
+				},
+			},
+		}
+		candidate := newCandidateDeployment(stable, canaryTrack, "web", "web:v2", 1, nil)
+		Expect(FakeKubeCli.Create(context.TODO(), stable)).To(Succeed())
+		Expect(FakeKubeCli.Create(context.TODO(), candidate)).To(Succeed())
+
+		return &DeployTaskPlugin{
+			Task: &task.Deploy{
+				ContainerName:    "web",
+				Image:            "web:v2",
+				Namespace:        ns,
+				AutoRollback:     autoRollback,
+				RolloutStrategy:  &task.RolloutStrategy{Type: setting.RolloutTypeCanary},
+				ReplaceResources: []task.Resource{{Kind: setting.Deployment, Name: "web", Container: "web", Origin: "web:v1"}},
+			},
+			Log:        log.SugaredLogger(),
+			kubeClient: FakeKubeCli,
+			rollouts:   []*rolloutState{{stable: stable, candidate: candidate, replicas: replicas, weights: []int{50, 100}, originImage: "web:v1"}},
+		}
+	}
+
+	cancelled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		return ctx
+	}
+
+	It("cleans up the canary when auto rollback is disabled", func() {
+		p := newRollout("rollout-keep", false)
+		p.rollouts[0].stablePatched = true
+		Expect(updater.UpdateDeploymentImage("rollout-keep", "web", "web", "web:v2", FakeKubeCli)).To(Succeed())
+		Expect(updater.ScaleDeployment("rollout-keep", "web", 1, FakeKubeCli)).To(Succeed())
+		p.waitRollout(cancelled())
+
+		Expect(p.Task.TaskStatus).To(Equal(config.StatusCancelled))
+		Expect(p.Task.RolledBack).To(BeFalse())
+		_, found, err := getter.GetDeployment("rollout-keep", "web-canary", FakeKubeCli)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+
+		d, _, err := getter.GetDeployment("rollout-keep", "web", FakeKubeCli)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*d.Spec.Replicas).To(Equal(int32(2)))
+		Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("web:v2"))
+	})
+
+	It("aborts the canary when auto rollback is enabled", func() {
+		p := newRollout("rollout-abort", true)
+		p.waitRollout(cancelled())
+
+		Expect(p.Task.RolledBack).To(BeTrue())
+		Expect(p.Task.Error).To(ContainSubstring("rolled back to previous image web:v1"))
+		_, found, err := getter.GetDeployment("rollout-abort", "web-canary", FakeKubeCli)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+})
+
+var _ = Describe("Testing rollback", func() {
+	It("lists each previous image once", func() {
+		resources := []task.Resource{
+			{Kind: setting.Deployment, Name: "web", Container: "web", Origin: "web:v1"},
+			{Kind: setting.Deployment, Name: "web-worker", Container: "web", Origin: "web:v1"},
+			{Kind: setting.StatefulSet, Name: "db", Container: "db", Origin: "db:v3"},
+		}
+		Expect(rolledBackImages(resources)).To(Equal("web:v1, db:v3"))
+	})
+
+	It("compares normalized images", func() {
+		Expect(sameImage("docker.io/library/nginx:latest", "nginx")).To(BeTrue())
+		Expect(sameImage("registry.example.com/web:v2", "registry.example.com/web:v2")).To(BeTrue())
+		Expect(sameImage("docker.io/koderover/web:v2", "koderover/web:v1")).To(BeFalse())
+	})
+
+	It("appends rollback message to the task error", func() {
+		Expect(joinTaskError("", "rolled back")).To(Equal("rolled back"))
+		Expect(joinTaskError("timeout", "rolled back")).To(Equal("timeout\nrolled back"))
+	})
+})
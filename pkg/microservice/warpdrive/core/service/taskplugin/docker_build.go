@@ -218,7 +218,7 @@ func (p *DockerBuildPlugin) Run(ctx context.Context, pipelineTask *task.Task, pi
 
 // Wait ...
 func (p *DockerBuildPlugin) Wait(ctx context.Context) {
-	status := waitJobEnd(ctx, p.TaskTimeout(), p.KubeNamespace, p.JobName, p.kubeClient, p.Log)
+	status := waitJobEnd(ctx, p.TaskTimeout(), "", p.KubeNamespace, p.JobName, p.kubeClient, p.Log)
 	p.SetStatus(status)
 }
 
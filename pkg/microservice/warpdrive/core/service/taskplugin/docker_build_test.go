@@ -40,7 +40,7 @@ func TestDockerBuildTaskPlugin(t *testing.T) {
 		FakeKubeCli.DeleteJob(namespace, jobname)
 	}()
 
-	dockerBuildPlugin := InitializeDockerBuildTaskPlugin(config.TaskDockerBuild)
+	dockerBuildPlugin := &DockerBuildPlugin{Name: config.TaskDockerBuild, kubeClient: FakeKubeCli}
 	assert.NotNil(dockerBuildPlugin)
 	assert.Equal(dockerBuildPlugin.Type(), config.TaskDockerBuild)
 	dockerBuildPlugin.Init(jobname, jobname, log)
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+// FakeKubeCli is an in-memory kubernetes client shared by the plugin tests.
+var FakeKubeCli = newFakeKubeCli()
+
+type fakeKubeCli struct {
+	client.Client
+}
+
+func newFakeKubeCli() *fakeKubeCli {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	return &fakeKubeCli{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+}
+
+func (c *fakeKubeCli) CreateNamespace(ns string) error {
+	return updater.CreateNamespaceByName(ns, nil, c.Client)
+}
+
+func (c *fakeKubeCli) GetNamespace(ns string) (*corev1.Namespace, error) {
+	namespace, found, err := getter.GetNamespace(ns, c.Client)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, apierrors.NewNotFound(corev1.Resource("namespaces"), ns)
+	}
+	return namespace, nil
+}
+
+func (c *fakeKubeCli) DeleteNamespace(ns string) error {
+	return updater.DeleteNamespace(ns, c.Client)
+}
+
+func (c *fakeKubeCli) CreateConfigMap(ns string, cm *corev1.ConfigMap) error {
+	cm.Namespace = ns
+	return updater.CreateConfigMap(cm, c.Client)
+}
+
+func (c *fakeKubeCli) GetConfigMap(ns, name string) (*corev1.ConfigMap, error) {
+	cm, found, err := getter.GetConfigMap(ns, name, c.Client)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, apierrors.NewNotFound(corev1.Resource("configmaps"), name)
+	}
+	return cm, nil
+}
+
+func (c *fakeKubeCli) ListConfigMaps(ns string, selector labels.Selector) ([]*corev1.ConfigMap, error) {
+	return getter.ListConfigMaps(ns, selector, c.Client)
+}
+
+func (c *fakeKubeCli) DeleteConfigMap(ns, name string) error {
+	return updater.DeleteConfigMap(ns, name, c.Client)
+}
+
+func (c *fakeKubeCli) DeleteConfigMaps(ns string, selector labels.Selector) error {
+	return updater.DeleteConfigMaps(ns, selector, c.Client)
+}
+
+func (c *fakeKubeCli) CreateJob(ns string, job *batchv1.Job) error {
+	job.Namespace = ns
+	return updater.CreateJob(job, c.Client)
+}
+
+func (c *fakeKubeCli) GetJob(ns, name string) (*batchv1.Job, error) {
+	job, found, err := getter.GetJob(ns, name, c.Client)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, apierrors.NewNotFound(batchv1.Resource("jobs"), name)
+	}
+	return job, nil
+}
+
+func (c *fakeKubeCli) DeleteJob(ns, name string) error {
+	return updater.DeleteJob(ns, name, c.Client)
+}
+
+func (c *fakeKubeCli) DeleteJobs(ns string, selector labels.Selector) error {
+	return c.Client.DeleteAllOf(context.TODO(), &batchv1.Job{}, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: selector})
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
 	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -198,17 +199,20 @@ func (b *JobCtxBuilder) BuildReaperContext(pipelineTask *task.Task, serviceName
 			GitlabSSHKey: pipelineTask.ConfigPayload.Gitlab.SSHKey,
 			GitKnownHost: pipelineTask.ConfigPayload.GetGitKnownHost(),
 		},
-		Scripts:         make([]string, 0),
-		PostScripts:     make([]string, 0),
-		PMDeployScripts: make([]string, 0),
-		SSHs:            b.JobCtx.SSHs,
-		TestType:        b.JobCtx.TestType,
-		ClassicBuild:    pipelineTask.ConfigPayload.ClassicBuild,
-		StorageURI:      pipelineTask.StorageURI,
-		PipelineName:    pipelineTask.PipelineName,
-		TaskID:          pipelineTask.TaskID,
-		ServiceName:     serviceName,
-		StorageEndpoint: pipelineTask.StorageEndpoint,
+		Scripts:          make([]string, 0),
+		PostScripts:      make([]string, 0),
+		PMDeployScripts:  make([]string, 0),
+		SSHs:             b.JobCtx.SSHs,
+		PMDeployStrategy: b.JobCtx.PMDeployStrategy,
+		PMHealthChecks:   b.JobCtx.PMHealthChecks,
+		TestType:         b.JobCtx.TestType,
+		ClassicBuild:     pipelineTask.ConfigPayload.ClassicBuild,
+		StorageURI:       pipelineTask.StorageURI,
+		PipelineName:     pipelineTask.PipelineName,
+		ProjectName:      pipelineTask.ProductName,
+		TaskID:           pipelineTask.TaskID,
+		ServiceName:      serviceName,
+		StorageEndpoint:  pipelineTask.StorageEndpoint,
 	}
 	for _, install := range b.Installs {
 		inst := &types.Install{
@@ -297,6 +301,8 @@ func (b *JobCtxBuilder) BuildReaperContext(pipelineTask *task.Task, serviceName
 	}
 
 	ctx.Caches = b.JobCtx.Caches
+	ctx.CacheKeyFiles = b.JobCtx.CacheKeyFiles
+	ctx.CacheSizeLimit = b.JobCtx.CacheSizeLimit
 
 	if b.JobCtx.TestResultPath != "" {
 		ctx.GinkgoTest = &types.GinkgoTest{
@@ -305,6 +311,7 @@ func (b *JobCtxBuilder) BuildReaperContext(pipelineTask *task.Task, serviceName
 			ArtifactPaths:  b.JobCtx.ArtifactPaths,
 		}
 	}
+	ctx.CoverageReportPath = b.JobCtx.CoverageReportPath
 
 	ctx.StorageEndpoint = pipelineTask.ConfigPayload.S3Storage.Endpoint
 	ctx.StorageAK = pipelineTask.ConfigPayload.S3Storage.Ak
@@ -696,11 +703,12 @@ func getResourceRequirements(resReq setting.Request) corev1.ResourceRequirements
 
 //waitJobEnd
 //Returns job status
-func waitJobEnd(ctx context.Context, taskTimeout int, namspace, jobName string, kubeClient client.Client, xl *zap.SugaredLogger) (status config.Status) {
-	return waitJobEndWithFile(ctx, taskTimeout, namspace, jobName, false, kubeClient, xl)
+func waitJobEnd(ctx context.Context, taskTimeout int, clusterID, namspace, jobName string, kubeClient client.Client, xl *zap.SugaredLogger) (status config.Status) {
+	return waitJobEndWithFile(ctx, taskTimeout, clusterID, namspace, jobName, false, kubeClient, xl)
 }
 
-func waitJobEndWithFile(ctx context.Context, taskTimeout int, namespace, jobName string, checkFile bool, kubeClient client.Client, xl *zap.SugaredLogger) (status config.Status) {
+// waitJobEndWithFile clusterID 为 job 所在的集群，为空时表示 zadig 所在的集群
+func waitJobEndWithFile(ctx context.Context, taskTimeout int, clusterID, namespace, jobName string, checkFile bool, kubeClient client.Client, xl *zap.SugaredLogger) (status config.Status) {
 	xl.Infof("wait job to start: %s/%s", namespace, jobName)
 	timeout := time.After(time.Duration(taskTimeout) * time.Second)
 	podTimeout := time.After(120 * time.Second)
@@ -718,6 +726,10 @@ func waitJobEndWithFile(ctx context.Context, taskTimeout int, namespace, jobName
 			}
 			if job != nil {
 				started = job.Status.Active > 0
+				if started {
+					metrics.JobSchedulingLatency.WithLabelValues(job.Labels[jobLabelSTypeKey], metrics.Cluster(clusterID)).
+						Observe(time.Since(job.CreationTimestamp.Time).Seconds())
+				}
 			}
 		}
 		if started {
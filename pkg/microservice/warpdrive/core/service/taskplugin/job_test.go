@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
@@ -63,7 +64,7 @@ func TestCreateJobConfigMap(t *testing.T) {
 		TaskType:     fmt.Sprintf("%s", config.TaskBuild),
 	}
 
-	createJobConfigMap(FakeKubeCli, namespace, jobname, jobLabel, jobCtx)
+	createJobConfigMap(namespace, jobname, jobLabel, jobCtx, FakeKubeCli)
 	jobConfigmap, err := FakeKubeCli.GetConfigMap(namespace, jobname)
 	assert.Nil(err)
 	assert.Equal(jobname, jobConfigmap.Name)
@@ -88,7 +89,7 @@ func TestEnsureDeleteConfigMap(t *testing.T) {
 
 	log.Infof("%v", getJobLabels(jobLabel))
 
-	log.Infof("%v", getJobSelector(getJobLabels(jobLabel)))
+	log.Infof("%v", labels.Set(getJobLabels(jobLabel)).AsSelector())
 
 	// configmap
 	cm := &corev1.ConfigMap{
@@ -102,7 +103,7 @@ func TestEnsureDeleteConfigMap(t *testing.T) {
 		},
 	}
 
-	selector := getJobSelector(getJobLabels(jobLabel))
+	selector := labels.Set(getJobLabels(jobLabel)).AsSelector()
 
 	defer func() {
 		FakeKubeCli.DeleteNamespace(namespace)
@@ -167,8 +168,9 @@ func TestBuildJob(t *testing.T) {
 
 func TestGetVolumes(t *testing.T) {
 	vols := getVolumes("demo-job")
-	assert.Len(t, vols, 1)
+	assert.Len(t, vols, 2)
 	assert.Equal(t, "job-config", vols[0].Name)
+	assert.Equal(t, "aes-key", vols[1].Name)
 }
 
 func TestEnsureDeleteJob(t *testing.T) {
@@ -217,7 +219,7 @@ func TestEnsureDeleteJob(t *testing.T) {
 		TaskType:     fmt.Sprintf("%s", config.TaskBuild),
 	}
 
-	err = FakeKubeCli.DeleteJobs(namespace, getJobSelector(getJobLabels(jobLabel)))
+	err = FakeKubeCli.DeleteJobs(namespace, labels.Set(getJobLabels(jobLabel)).AsSelector())
 	log.Infof("%v", err)
 	assert.Nil(err)
 
@@ -0,0 +1,346 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+)
+
+// matrixJob 构建或测试矩阵中第一个组合之外的组合，每个组合运行一个独立的 job
+// 第一个组合即任务本身，由插件原有的 job 运行
+type matrixJob struct {
+	index       int
+	combination *task.MatrixCombination
+	jobName     string
+	fileName    string
+	jobLabel    *JobLabel
+}
+
+func newMatrixJobs(combinations []*task.MatrixCombination, jobName, fileName, serviceName string, taskType config.TaskType, pipelineTask *task.Task) []*matrixJob {
+	jobs := make([]*matrixJob, 0, len(combinations))
+	for i := 1; i < len(combinations); i++ {
+		suffix := matrixSuffix(i)
+		c := combinations[i]
+		c.Status, c.Error, c.StartTime, c.EndTime, c.LogFile, c.TestReportFile = "", "", 0, 0, "", ""
+
+		jobs = append(jobs, &matrixJob{
+			index:       i,
+			combination: c,
+			jobName:     matrixJobName(jobName, i),
+			fileName:    fileName + suffix,
+			jobLabel: &JobLabel{
+				PipelineName: pipelineTask.PipelineName,
+				ServiceName:  serviceName + suffix,
+				TaskID:       pipelineTask.TaskID,
+				TaskType:     string(taskType),
+				PipelineType: string(pipelineTask.Type),
+			},
+		})
+	}
+	return jobs
+}
+
+func matrixSuffix(index int) string {
+	return fmt.Sprintf("-m%d", index)
+}
+
+// matrixJobName job 名称不超过 57 个字符，保留随机后缀，从头部截断
+func matrixJobName(jobName string, index int) string {
+	name := jobName + matrixSuffix(index)
+	if len(name) > 57 {
+		name = strings.TrimLeft(name[len(name)-57:], "-")
+	}
+	return name
+}
+
+// matrixFileName 为组合的归档文件加上后缀，避免覆盖第一个组合产出的文件
+func matrixFileName(fileName string, index int) string {
+	if fileName == "" {
+		return ""
+	}
+	if strings.HasSuffix(fileName, ".tar.gz") {
+		return strings.TrimSuffix(fileName, ".tar.gz") + matrixSuffix(index) + ".tar.gz"
+	}
+	return fileName + matrixSuffix(index)
+}
+
+// matrixTestReportFile 为组合的 HTML 测试报告加上后缀，测试名称加上后缀后仍可通过原有接口获取报告
+func matrixTestReportFile(testReportFile string, index int) string {
+	if testReportFile == "" {
+		return ""
+	}
+	if strings.HasSuffix(testReportFile, "-html") {
+		return strings.TrimSuffix(testReportFile, "-html") + matrixSuffix(index) + "-html"
+	}
+	return testReportFile + matrixSuffix(index)
+}
+
+// matrixJobCtx 其它组合只运行脚本和测试：不构建镜像、不归档、不部署，也不读写缓存，避免并发覆盖同一份缓存
+// 测试结果和 HTML 测试报告使用各自带后缀的文件名上传，由任务汇总
+func matrixJobCtx(jobCtx task.JobCtx, c *task.MatrixCombination) task.JobCtx {
+	jobCtx.EnvVars = mergeMatrixEnvs(jobCtx.EnvVars, c.Envs)
+	jobCtx.UploadPkg = false
+	jobCtx.DockerBuildCtx = nil
+	jobCtx.FileArchiveCtx = nil
+	jobCtx.PostScripts = ""
+	jobCtx.PMDeployScripts = ""
+	jobCtx.SSHs = nil
+	jobCtx.Caches = nil
+	jobCtx.CacheKeyFiles = nil
+	jobCtx.ArtifactPaths = nil
+	jobCtx.CoverageReportPath = ""
+	return jobCtx
+}
+
+// mergeMatrixEnvs 使用组合的变量覆盖同名变量，返回新的列表
+func mergeMatrixEnvs(envs, overrides []*task.KeyVal) []*task.KeyVal {
+	merged := make([]*task.KeyVal, 0, len(envs)+len(overrides))
+	values := make(map[string]*task.KeyVal, len(overrides))
+	for _, kv := range overrides {
+		values[kv.Key] = kv
+	}
+	for _, kv := range envs {
+		if override, ok := values[kv.Key]; ok {
+			merged = append(merged, &task.KeyVal{Key: kv.Key, Value: override.Value, IsCredential: kv.IsCredential})
+			delete(values, kv.Key)
+			continue
+		}
+		merged = append(merged, kv)
+	}
+	for _, kv := range overrides {
+		if _, ok := values[kv.Key]; ok {
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
+
+func matrixJobImage(pipelineTask *task.Task, c *task.MatrixCombination) string {
+	if c.ImageFrom == setting.ImageFromCustom {
+		return c.BuildOS
+	}
+	return fmt.Sprintf("%s-%s", pipelineTask.ConfigPayload.Release.ReaperImage, c.BuildOS)
+}
+
+// createMatrixJob 按组合创建 configmap 和 job，builder 为第一个组合使用的 JobCtxBuilder
+func createMatrixJob(j *matrixJob, builder JobCtxBuilder, taskType config.TaskType, pipelineTask *task.Task, serviceName string, resReq setting.Request, registries []*task.RegistryNamespace, namespace, linkedNamespace string, kubeClient client.Client) error {
+	builder.JobName = j.jobName
+	builder.ArchiveFile = matrixFileName(builder.ArchiveFile, j.index)
+	builder.TestReportFile = matrixTestReportFile(builder.TestReportFile, j.index)
+	builder.JobCtx = matrixJobCtx(builder.JobCtx, j.combination)
+	j.combination.TestReportFile = builder.TestReportFile
+
+	jobCtxBytes, err := marshalReaperContext(&builder, pipelineTask, serviceName, namespace, kubeClient)
+	if err != nil {
+		return fmt.Errorf("cannot reaper.Context data: %v", err)
+	}
+
+	if err := ensureDeleteConfigMap(namespace, j.jobLabel, kubeClient); err != nil {
+		return err
+	}
+	if err := createJobConfigMap(namespace, j.jobName, j.jobLabel, string(jobCtxBytes), kubeClient); err != nil {
+		return fmt.Errorf("createJobConfigMap error: %v", err)
+	}
+
+	execNamespace := ""
+	if linkedNamespace != "" {
+		execNamespace = namespace
+	}
+	job, err := buildJobWithLinkedNs(
+		taskType, matrixJobImage(pipelineTask, j.combination), j.jobName, j.jobLabel.ServiceName, resReq, builder.PipelineCtx, pipelineTask, registries,
+		execNamespace,
+		linkedNamespace,
+	)
+	if err != nil {
+		return fmt.Errorf("create job context error: %v", err)
+	}
+	job.Namespace = namespace
+
+	if err := ensureDeleteJob(namespace, j.jobLabel, kubeClient); err != nil {
+		return fmt.Errorf("delete job error: %v", err)
+	}
+	if err := updater.CreateJob(job, kubeClient); err != nil {
+		return fmt.Errorf("create job error: %v", err)
+	}
+	return nil
+}
+
+// startMatrixJobs 创建所有组合的 job，创建失败的组合直接记为失败
+func startMatrixJobs(jobs []*matrixJob, create func(*matrixJob) error, xl *zap.SugaredLogger) {
+	for _, j := range jobs {
+		j.combination.StartTime = time.Now().Unix()
+		j.combination.Status = config.StatusRunning
+		if err := create(j); err != nil {
+			xl.Errorf("failed to create job for matrix combination %s: %v", j.combination.Name, err)
+			j.combination.Status = config.StatusFailed
+			j.combination.Error = err.Error()
+			j.combination.EndTime = time.Now().Unix()
+			continue
+		}
+		xl.Infof("succeed to create job %s for matrix combination %s", j.jobName, j.combination.Name)
+	}
+}
+
+// waitMatrixJobs 并行等待所有运行中的组合结束
+func waitMatrixJobs(ctx context.Context, taskTimeout int, namespace string, jobs []*matrixJob, kubeClient client.Client, xl *zap.SugaredLogger) {
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		if j.combination.Status != config.StatusRunning {
+			continue
+		}
+		wg.Add(1)
+		go func(j *matrixJob) {
+			defer wg.Done()
+			j.combination.Status = waitJobEndWithFile(ctx, taskTimeout, "", namespace, j.jobName, true, kubeClient, xl)
+			j.combination.EndTime = time.Now().Unix()
+		}(j)
+	}
+	wg.Wait()
+}
+
+// completeMatrixJobs 保存所有组合的日志，任务或组合被取消、超时时清理对应的 job
+func completeMatrixJobs(pipelineTask *task.Task, namespace string, status config.Status, jobs []*matrixJob, kubeClient client.Client, xl *zap.SugaredLogger) {
+	cleanAll := status == config.StatusCancelled || status == config.StatusTimeout
+	for _, j := range jobs {
+		if j.combination.Error == "" {
+			if err := saveContainerLog(pipelineTask, namespace, j.fileName, j.jobLabel, kubeClient); err != nil {
+				xl.Errorf("failed to save log of matrix combination %s: %v", j.combination.Name, err)
+			} else {
+				j.combination.LogFile = j.fileName
+			}
+		}
+
+		if cleanAll && j.combination.Status == config.StatusRunning {
+			j.combination.Status = status
+			j.combination.EndTime = time.Now().Unix()
+		}
+		if cleanAll || j.combination.Status == config.StatusCancelled || j.combination.Status == config.StatusTimeout {
+			if err := ensureDeleteJob(namespace, j.jobLabel, kubeClient); err != nil {
+				xl.Errorf("failed to delete job of matrix combination %s: %v", j.combination.Name, err)
+			}
+		}
+	}
+}
+
+// matrixResult 汇总矩阵的结果，status 为第一个组合（任务本身）的结果
+// 第一个组合通过时，其它不允许失败的组合未通过也会导致任务失败
+func matrixResult(status config.Status, startTime int64, combinations []*task.MatrixCombination) (config.Status, string) {
+	if len(combinations) == 0 {
+		return status, ""
+	}
+
+	primary := combinations[0]
+	primary.Status = status
+	primary.StartTime = startTime
+	primary.EndTime = time.Now().Unix()
+	if status != config.StatusPassed {
+		return status, ""
+	}
+
+	var failed []string
+	for _, c := range combinations[1:] {
+		if c.Status != config.StatusPassed && !c.AllowFailure {
+			failed = append(failed, fmt.Sprintf("%s(%s)", c.Name, c.Status))
+		}
+	}
+	if len(failed) != 0 {
+		return config.StatusFailed, fmt.Sprintf("matrix combinations failed: %s", strings.Join(failed, ", "))
+	}
+	return status, ""
+}
+
+// mergeMatrixTestResults 把其它组合的功能测试结果合并到第一个组合的结果中，download 按文件名下载组合上传的测试结果
+// 返回不允许失败的组合中除隔离用例外失败的用例数，以及失败的隔离用例
+func mergeMatrixTestResults(suite *types.TestSuite, fileName string, jobs []*matrixJob, quarantined []string, download func(fileName string) ([]byte, error), xl *zap.SugaredLogger) (int, []string) {
+	failures := 0
+	var quarantinedFailed []string
+	for _, j := range jobs {
+		// job 创建失败的组合没有测试结果
+		if j.combination.Error != "" {
+			continue
+		}
+
+		b, err := download(matrixFileName(fileName, j.index))
+		if err != nil {
+			xl.Warnf("failed to get test result of matrix combination %s: %v", j.combination.Name, err)
+			continue
+		}
+		s := new(types.TestSuite)
+		if err := xml.Unmarshal(b, s); err != nil {
+			xl.Errorf("failed to unmarshal test result of matrix combination %s: %v", j.combination.Name, err)
+			continue
+		}
+
+		qf := quarantinedFailures(s, quarantined)
+		for _, name := range qf {
+			quarantinedFailed = append(quarantinedFailed, fmt.Sprintf("%s(%s)", name, j.combination.Name))
+		}
+		if !j.combination.AllowFailure {
+			failures += s.Errors + s.Failures - len(qf)
+		}
+
+		suite.Tests += s.Tests
+		suite.Failures += s.Failures
+		suite.Successes += s.Successes
+		suite.Skips += s.Skips
+		suite.Errors += s.Errors
+		suite.Time += s.Time
+		suite.TestCases = append(suite.TestCases, s.TestCases...)
+	}
+	return failures, quarantinedFailed
+}
+
+// mergeMatrixPerformanceResults 返回其它组合的性能测试结果，label 加上组合名称以便区分
+func mergeMatrixPerformanceResults(fileName string, jobs []*matrixJob, download func(fileName string) ([]byte, error), xl *zap.SugaredLogger) []*types.PerformanceTestSuite {
+	var suites []*types.PerformanceTestSuite
+	for _, j := range jobs {
+		if j.combination.Error != "" {
+			continue
+		}
+
+		b, err := download(matrixFileName(fileName, j.index))
+		if err != nil {
+			xl.Warnf("failed to get test result of matrix combination %s: %v", j.combination.Name, err)
+			continue
+		}
+		ss, err := parsePerformanceTestSuites(bytes.NewReader(b))
+		if err != nil {
+			xl.Errorf("failed to parse test result of matrix combination %s: %v", j.combination.Name, err)
+			continue
+		}
+		for _, s := range ss {
+			s.Label = fmt.Sprintf("%s/%s", j.combination.Name, s.Label)
+		}
+		suites = append(suites, ss...)
+	}
+	return suites
+}
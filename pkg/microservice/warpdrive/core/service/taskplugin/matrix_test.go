@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+)
+
+var _ = Describe("Testing matrix", func() {
+
+	Context("matrixResult", func() {
+		var combinations []*task.MatrixCombination
+
+		BeforeEach(func() {
+			combinations = []*task.MatrixCombination{
+				{Name: "GO=1.16"},
+				{Name: "GO=1.17", Status: config.StatusPassed},
+				{Name: "GO=tip", Status: config.StatusFailed, AllowFailure: true},
+			}
+		})
+
+		It("passes when only allowed combinations fail", func() {
+			status, msg := matrixResult(config.StatusPassed, 0, combinations)
+			Expect(status).To(Equal(config.StatusPassed))
+			Expect(msg).To(BeEmpty())
+			Expect(combinations[0].Status).To(Equal(config.StatusPassed))
+		})
+
+		It("fails when a required combination fails", func() {
+			combinations[1].Status = config.StatusTimeout
+			status, msg := matrixResult(config.StatusPassed, 0, combinations)
+			Expect(status).To(Equal(config.StatusFailed))
+			Expect(msg).To(ContainSubstring("GO=1.17(timeout)"))
+			Expect(msg).NotTo(ContainSubstring("GO=tip"))
+		})
+
+		It("keeps the status of the primary combination", func() {
+			status, msg := matrixResult(config.StatusCancelled, 0, combinations)
+			Expect(status).To(Equal(config.StatusCancelled))
+			Expect(msg).To(BeEmpty())
+		})
+	})
+
+	Context("matrixJobCtx", func() {
+		It("only runs scripts with the combination envs", func() {
+			jobCtx := task.JobCtx{
+				EnvVars:        []*task.KeyVal{{Key: "GO", Value: "1.16"}, {Key: "TOKEN", Value: "x", IsCredential: true}},
+				UploadPkg:      true,
+				DockerBuildCtx: &task.DockerBuildCtx{},
+				FileArchiveCtx: &task.FileArchiveCtx{},
+				Caches:         []string{"/root/go"},
+			}
+			c := &task.MatrixCombination{Envs: []*task.KeyVal{{Key: "GO", Value: "1.17"}, {Key: "ARCH", Value: "arm64"}}}
+
+			matrixCtx := matrixJobCtx(jobCtx, c)
+			Expect(matrixCtx.EnvVars).To(Equal([]*task.KeyVal{
+				{Key: "GO", Value: "1.17"},
+				{Key: "TOKEN", Value: "x", IsCredential: true},
+				{Key: "ARCH", Value: "arm64"},
+			}))
+			Expect(matrixCtx.UploadPkg).To(BeFalse())
+			Expect(matrixCtx.DockerBuildCtx).To(BeNil())
+			Expect(matrixCtx.FileArchiveCtx).To(BeNil())
+			Expect(matrixCtx.Caches).To(BeNil())
+			Expect(jobCtx.EnvVars[0].Value).To(Equal("1.16"))
+		})
+
+		It("keeps the test results and reports of the combination", func() {
+			jobCtx := task.JobCtx{TestResultPath: "results", TestReportPath: "report.html"}
+
+			matrixCtx := matrixJobCtx(jobCtx, &task.MatrixCombination{})
+			Expect(matrixCtx.TestResultPath).To(Equal("results"))
+			Expect(matrixCtx.TestReportPath).To(Equal("report.html"))
+		})
+	})
+
+	Context("names", func() {
+		It("keeps job names short", func() {
+			name := matrixJobName("a-very-long-workflow-name-1234-buildv2-abcde-with-random", 12)
+			Expect(len(name)).To(BeNumerically("<=", 57))
+			Expect(name).To(HaveSuffix("-random-m12"))
+		})
+
+		It("keeps archive extension", func() {
+			Expect(matrixFileName("svc-20210901.tar.gz", 1)).To(Equal("svc-20210901-m1.tar.gz"))
+			Expect(matrixFileName("workflow-test-1-testingv2-svc", 2)).To(Equal("workflow-test-1-testingv2-svc-m2"))
+			Expect(matrixFileName("", 2)).To(BeEmpty())
+		})
+
+		It("keeps html report suffix", func() {
+			Expect(matrixTestReportFile("workflow-test-1-testingv2-unit-html", 1)).To(Equal("workflow-test-1-testingv2-unit-m1-html"))
+			Expect(matrixTestReportFile("", 1)).To(BeEmpty())
+		})
+	})
+
+	Context("merge test results", func() {
+		var (
+			jobs    []*matrixJob
+			results map[string]string
+		)
+
+		download := func(fileName string) ([]byte, error) {
+			if r, ok := results[fileName]; ok {
+				return []byte(r), nil
+			}
+			return nil, fmt.Errorf("%s not found", fileName)
+		}
+
+		BeforeEach(func() {
+			jobs = []*matrixJob{
+				{index: 1, combination: &task.MatrixCombination{Name: "GO=1.17", Status: config.StatusPassed}},
+				{index: 2, combination: &task.MatrixCombination{Name: "GO=tip", Status: config.StatusPassed, AllowFailure: true}},
+				{index: 3, combination: &task.MatrixCombination{Name: "GO=1.15", Status: config.StatusFailed, Error: "create job error"}},
+			}
+			results = map[string]string{
+				"test-m1": `<testsuite tests="3" failures="2" skips="1" time="1.5">
+<testcase name="a" classname="pkg"></testcase>
+<testcase name="flaky" classname="pkg"><failure message="x" type="y"></failure></testcase>
+<testcase name="b" classname="pkg"><failure message="x" type="y"></failure></testcase>
+</testsuite>`,
+				"test-m2": `<testsuite tests="1" failures="1" time="0.5">
+<testcase name="c" classname="pkg"><failure message="x" type="y"></failure></testcase>
+</testsuite>`,
+				"test-m3": `<testsuite tests="1" failures="1"></testsuite>`,
+			}
+		})
+
+		It("merges the function test results of all combinations", func() {
+			suite := &types.TestSuite{Tests: 2, Time: 1, TestCases: []types.TestCase{{Name: "d", ClassName: "pkg"}}}
+
+			failures, quarantined := mergeMatrixTestResults(suite, "test", jobs, []string{"pkg.flaky"}, download, zap.NewNop().Sugar())
+			// the failure of GO=tip is allowed and flaky is quarantined
+			Expect(failures).To(Equal(1))
+			Expect(quarantined).To(Equal([]string{"pkg.flaky(GO=1.17)"}))
+			Expect(suite.Tests).To(Equal(6))
+			Expect(suite.Failures).To(Equal(3))
+			Expect(suite.Skips).To(Equal(1))
+			Expect(suite.Time).To(Equal(3.0))
+			Expect(suite.TestCases).To(HaveLen(5))
+		})
+
+		It("skips combinations without test results", func() {
+			delete(results, "test-m1")
+			suite := &types.TestSuite{}
+
+			failures, _ := mergeMatrixTestResults(suite, "test", jobs, nil, download, zap.NewNop().Sugar())
+			Expect(failures).To(BeZero())
+			Expect(suite.Tests).To(Equal(1))
+		})
+
+		It("labels the performance test results by combination", func() {
+			header := "label,samples,average,min,max,line,stddev,error,throughput,receivedKb,avgByte\n"
+			results["test-m1"] = header + "login,10,1,1,1,1,0,0%,10,1,1\n"
+			results["test-m2"] = "broken"
+
+			suites := mergeMatrixPerformanceResults("test", jobs, download, zap.NewNop().Sugar())
+			Expect(suites).To(HaveLen(1))
+			Expect(suites[0].Label).To(Equal("GO=1.17/login"))
+			Expect(suites[0].Samples).To(Equal("10"))
+		})
+	})
+})
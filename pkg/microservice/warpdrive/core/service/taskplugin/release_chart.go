@@ -0,0 +1,340 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/chart/loader"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/setting"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	// ReleaseChartTimeout ...
+	ReleaseChartTimeout = 60 * 5 // 5 minutes
+)
+
+// InitializeReleaseChartTaskPlugin to init plugin
+func InitializeReleaseChartTaskPlugin(taskType config.TaskType) TaskPlugin {
+	return &ReleaseChartTaskPlugin{
+		Name: taskType,
+		httpClient: httpclient.New(
+			httpclient.SetHostURL(configbase.AslanServiceAddress()),
+			httpclient.SetClientHeader(setting.InternalTokenHeader, configbase.InternalToken()),
+		),
+	}
+}
+
+// ReleaseChartTaskPlugin 打包环境中渲染后的 chart 并推送到 chart 仓库
+type ReleaseChartTaskPlugin struct {
+	Name config.TaskType
+	Task *task.ReleaseChart
+	Log  *zap.SugaredLogger
+
+	httpClient *httpclient.Client
+}
+
+// helmRepo aslan 返回的 chart 仓库配置
+type helmRepo struct {
+	RepoName string `json:"repo_name"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (p *ReleaseChartTaskPlugin) SetAckFunc(func()) {
+}
+
+// Init ...
+func (p *ReleaseChartTaskPlugin) Init(jobname, filename string, xl *zap.SugaredLogger) {
+	p.Log = xl
+}
+
+// Type ...
+func (p *ReleaseChartTaskPlugin) Type() config.TaskType {
+	return p.Name
+}
+
+// Status ...
+func (p *ReleaseChartTaskPlugin) Status() config.Status {
+	return p.Task.TaskStatus
+}
+
+// SetStatus ...
+func (p *ReleaseChartTaskPlugin) SetStatus(status config.Status) {
+	p.Task.TaskStatus = status
+}
+
+// TaskTimeout ...
+func (p *ReleaseChartTaskPlugin) TaskTimeout() int {
+	if p.Task.Timeout == 0 {
+		p.Task.Timeout = ReleaseChartTimeout
+	}
+	return p.Task.Timeout
+}
+
+func (p *ReleaseChartTaskPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipelineCtx *task.PipelineCtx, serviceName string) {
+	if err := p.releaseChart(pipelineTask); err != nil {
+		p.Log.Errorf("failed to release chart of %s/%s: %v", p.Task.ProductName, p.Task.ServiceName, err)
+		p.Task.TaskStatus = config.StatusFailed
+		p.Task.Error = err.Error()
+		return
+	}
+	p.Task.TaskStatus = config.StatusPassed
+}
+
+func (p *ReleaseChartTaskPlugin) releaseChart(pipelineTask *task.Task) error {
+	repo, err := p.getHelmRepo(p.Task.ChartRepoName)
+	if err != nil {
+		return err
+	}
+
+	product, err := p.getProductInfo()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get product %s/%s", p.Task.ProductName, p.Task.EnvName)
+	}
+	renderSet, err := p.getRenderSet(product.Render.Name, product.Render.Revision)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get renderset %s/%d", product.Render.Name, product.Render.Revision)
+	}
+
+	renderChart, values, err := renderChartValues(product, renderSet, p.Task.ServiceName, p.Task.Images)
+	if err != nil {
+		return err
+	}
+
+	version := p.Task.ChartVersion
+	if version == "" {
+		version = fmt.Sprintf("%s+%d", renderChart.ChartVersion, pipelineTask.TaskID)
+	}
+
+	servicePath, err := downloadService(pipelineTask.ProductName, p.Task.ServiceName, pipelineTask.StorageURI, p.Log)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to download service %s", p.Task.ServiceName)
+	}
+
+	outDir, err := ioutil.TempDir("", "release-chart")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(outDir)
+
+	pkg, err := helmtool.PackageChart(servicePath, version, []byte(values), outDir)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to package chart of %s", p.Task.ServiceName)
+	}
+	c, err := loader.Load(pkg)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(pkg)
+	if err != nil {
+		return err
+	}
+
+	client := &helmtool.ChartRepo{URL: repo.URL, Username: repo.Username, Password: repo.Password}
+	if err = client.PushChart(data); err != nil {
+		return errors.WithMessagef(err, "failed to push chart %s-%s to %s", c.Metadata.Name, c.Metadata.Version, repo.RepoName)
+	}
+
+	p.Task.ChartName = c.Metadata.Name
+	p.Task.ChartVersion = c.Metadata.Version
+	p.Log.Infof("chart %s-%s is pushed to %s", c.Metadata.Name, c.Metadata.Version, repo.URL)
+	return nil
+}
+
+// renderChartValues 合并环境中的覆盖值，并把各容器的镜像替换为本次构建的镜像
+func renderChartValues(product *types.Product, renderSet *types.RenderSet, serviceName string, images []*task.ChartImage) (*types.RenderChart, string, error) {
+	if len(images) == 0 {
+		return nil, "", errors.Errorf("no image is built for service %s", serviceName)
+	}
+
+	containers := make(map[string]*types.Container)
+	for _, serviceGroup := range product.Services {
+		for _, service := range serviceGroup {
+			if service.ServiceName != serviceName {
+				continue
+			}
+			for _, container := range service.Containers {
+				containers[container.Name] = container
+			}
+		}
+	}
+
+	var renderChart *types.RenderChart
+	for _, chartInfo := range renderSet.ChartInfos {
+		if chartInfo.ServiceName == serviceName {
+			renderChart = chartInfo
+			break
+		}
+	}
+	if renderChart == nil {
+		return nil, "", errors.Errorf("failed to find chart of service %s in renderset %s", serviceName, renderSet.Name)
+	}
+
+	values, err := helmtool.MergeOverrideValues(renderChart.ValuesYaml, renderSet.DefaultValues, renderChart.GetOverrideYaml(), renderChart.OverrideValues)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "failed to merge override values of service %s", serviceName)
+	}
+
+	for _, image := range images {
+		container, ok := containers[image.ContainerName]
+		if !ok || container.ImagePath == nil {
+			return nil, "", errors.Errorf("failed to find image path of container %s in service %s", image.ContainerName, serviceName)
+		}
+
+		replaceValuesMap, err := assignImageData(image.Image, getValidMatchData(container.ImagePath))
+		if err != nil {
+			return nil, "", errors.WithMessagef(err, "failed to parse image uri %s", image.Image)
+		}
+		values, err = replaceImage(values, replaceValuesMap)
+		if err != nil {
+			return nil, "", errors.WithMessagef(err, "failed to replace image of service %s", serviceName)
+		}
+		if values == "" {
+			return nil, "", errors.Errorf("failed to set image %s into values.yaml of service %s", image.Image, serviceName)
+		}
+	}
+	return renderChart, values, nil
+}
+
+func (p *ReleaseChartTaskPlugin) getHelmRepo(name string) (*helmRepo, error) {
+	var repos []*helmRepo
+	if _, err := p.httpClient.Get("/api/system/helm", httpclient.SetResult(&repos)); err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		if repo.RepoName == name {
+			return repo, nil
+		}
+	}
+	return nil, errors.Errorf("chart repo %s is not found", name)
+}
+
+func (p *ReleaseChartTaskPlugin) getProductInfo() (*types.Product, error) {
+	url := fmt.Sprintf("/api/environment/environments/%s/productInfo", p.Task.ProductName)
+
+	prod := &types.Product{}
+	_, err := p.httpClient.Get(url, httpclient.SetResult(prod), httpclient.SetQueryParam("envName", p.Task.EnvName))
+	if err != nil {
+		return nil, err
+	}
+	return prod, nil
+}
+
+func (p *ReleaseChartTaskPlugin) getRenderSet(name string, revision int64) (*types.RenderSet, error) {
+	url := fmt.Sprintf("/api/project/renders/render/%s/revision/%d", name, revision)
+
+	rs := &types.RenderSet{}
+	_, err := p.httpClient.Get(url, httpclient.SetResult(rs))
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Wait ...
+func (p *ReleaseChartTaskPlugin) Wait(ctx context.Context) {
+	timeout := time.After(time.Duration(p.TaskTimeout()) * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.Task.TaskStatus = config.StatusCancelled
+			return
+
+		case <-timeout:
+			p.Task.TaskStatus = config.StatusTimeout
+			return
+
+		default:
+			time.Sleep(time.Second * 1)
+
+			if p.IsTaskDone() {
+				return
+			}
+		}
+	}
+}
+
+// Complete ...
+func (p *ReleaseChartTaskPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serviceName string) {
+}
+
+// SetTask ...
+func (p *ReleaseChartTaskPlugin) SetTask(t map[string]interface{}) error {
+	task, err := ToReleaseChartTask(t)
+	if err != nil {
+		return err
+	}
+	p.Task = task
+	return nil
+}
+
+// GetTask ...
+func (p *ReleaseChartTaskPlugin) GetTask() interface{} {
+	return p.Task
+}
+
+// IsTaskDone ...
+func (p *ReleaseChartTaskPlugin) IsTaskDone() bool {
+	if p.Task.TaskStatus != config.StatusCreated && p.Task.TaskStatus != config.StatusRunning {
+		return true
+	}
+	return false
+}
+
+// IsTaskFailed ...
+func (p *ReleaseChartTaskPlugin) IsTaskFailed() bool {
+	if p.Task.TaskStatus == config.StatusFailed || p.Task.TaskStatus == config.StatusTimeout || p.Task.TaskStatus == config.StatusCancelled {
+		return true
+	}
+	return false
+}
+
+// SetStartTime ...
+func (p *ReleaseChartTaskPlugin) SetStartTime() {
+	p.Task.StartTime = time.Now().Unix()
+}
+
+// SetEndTime ...
+func (p *ReleaseChartTaskPlugin) SetEndTime() {
+	p.Task.EndTime = time.Now().Unix()
+}
+
+// IsTaskEnabled ...
+func (p *ReleaseChartTaskPlugin) IsTaskEnabled() bool {
+	return p.Task.Enabled
+}
+
+// ResetError ...
+func (p *ReleaseChartTaskPlugin) ResetError() {
+	p.Task.Error = ""
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/util/converter"
+)
+
+var _ = Describe("Testing release chart", func() {
+	product := &types.Product{
+		Services: [][]*types.Service{{{
+			ServiceName: "go-sample-site",
+			Containers: []*types.Container{
+				{
+					Name:      "go-sample-site",
+					ImagePath: &types.ImagePathSpec{Repo: "imageNormal.repository", Tag: "imageNormal.tag"},
+				},
+				{
+					Name:      "go-sample-site-2",
+					ImagePath: &types.ImagePathSpec{Image: "sidecarImage"},
+				},
+			},
+		}}},
+	}
+	renderSet := &types.RenderSet{
+		Name:          "dev-go-sample-site",
+		DefaultValues: "env: prod",
+		ChartInfos: []*types.RenderChart{{
+			ServiceName:    "go-sample-site",
+			ChartVersion:   "0.1.0",
+			ValuesYaml:     testYaml,
+			OverrideValues: `[{"key":"service.port","value":"9090"},{"key":"sidecarImage","value":"sidecar:v1"}]`,
+		}},
+	}
+
+	It("writes the built images and env overrides into values", func() {
+		renderChart, values, err := renderChartValues(product, renderSet, "go-sample-site", []*task.ChartImage{
+			{ContainerName: "go-sample-site", Image: "koderover.tencentcloudcr.com/test/go-sample-site:20211112-1"},
+			{ContainerName: "go-sample-site-2", Image: "koderover.tencentcloudcr.com/test/go-sample-site-2:20211112-1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderChart.ChartVersion).To(Equal("0.1.0"))
+
+		flatMap, err := converter.YamlToFlatMap([]byte(values))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flatMap["imageNormal.repository"]).To(Equal("koderover.tencentcloudcr.com/test/go-sample-site"))
+		Expect(flatMap["imageNormal.tag"]).To(Equal("20211112-1"))
+		Expect(flatMap["sidecarImage"]).To(Equal("koderover.tencentcloudcr.com/test/go-sample-site-2:20211112-1"))
+		Expect(flatMap["env"]).To(Equal("prod"))
+		Expect(flatMap["service.port"]).To(BeEquivalentTo(9090))
+	})
+
+	It("fails when the service has no chart in the renderset", func() {
+		_, _, err := renderChartValues(product, &types.RenderSet{Name: "dev-go-sample-site"}, "go-sample-site",
+			[]*task.ChartImage{{ContainerName: "go-sample-site", Image: "go-sample-site:v1"}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails when no image is built", func() {
+		_, _, err := renderChartValues(product, renderSet, "go-sample-site", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails when the container has no image path", func() {
+		_, _, err := renderChartValues(product, renderSet, "go-sample-site", []*task.ChartImage{{ContainerName: "sidecar", Image: "sidecar:v1"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -206,7 +206,7 @@ func (p *ReleaseImagePlugin) Run(ctx context.Context, pipelineTask *task.Task, p
 
 // Wait ...
 func (p *ReleaseImagePlugin) Wait(ctx context.Context) {
-	status := waitJobEnd(ctx, p.TaskTimeout(), p.KubeNamespace, p.JobName, p.kubeClient, p.Log)
+	status := waitJobEnd(ctx, p.TaskTimeout(), "", p.KubeNamespace, p.JobName, p.kubeClient, p.Log)
 	p.SetStatus(status)
 }
 
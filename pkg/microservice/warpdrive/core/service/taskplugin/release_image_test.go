@@ -28,13 +28,13 @@ import (
 )
 
 func TestReleaseImagePlugin_TaskTimeout_Default(t *testing.T) {
-	plugin := InitializeReleaseImagePlugin(config.TaskReleaseImage).(*ReleaseImagePlugin)
+	plugin := &ReleaseImagePlugin{Name: config.TaskReleaseImage, kubeClient: FakeKubeCli}
 	plugin.Task = releaseTaskForTest()
 	assert.Equal(t, RelealseImageTaskTimeout, plugin.TaskTimeout())
 }
 
 func TestReleaseImagePlugin_TaskTimeout_GivenTimeout(t *testing.T) {
-	plugin := InitializeReleaseImagePlugin(config.TaskReleaseImage).(*ReleaseImagePlugin)
+	plugin := &ReleaseImagePlugin{Name: config.TaskReleaseImage, kubeClient: FakeKubeCli}
 	task := releaseTaskForTest()
 	task.Timeout = 20
 	plugin.Task = task
@@ -57,7 +57,7 @@ func TestReleaseImagePlugin_Run(t *testing.T) {
 		FakeKubeCli.DeleteJob(namespace, jobName)
 	}()
 
-	plugin := InitializeReleaseImagePlugin(config.TaskReleaseImage).(*ReleaseImagePlugin)
+	plugin := &ReleaseImagePlugin{Name: config.TaskReleaseImage, kubeClient: FakeKubeCli}
 	assert.NotNil(plugin)
 	assert.Equal(plugin.Type(), config.TaskReleaseImage)
 	plugin.Init(jobName, jobName, log)
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/shared/secretref"
+)
+
+// marshalReaperContext 生成 job configmap 中的 reaper 配置，环境变量中的 secret:// 引用在这里解析，
+// 解析后的值只写入 configmap，不会回写到任务中
+func marshalReaperContext(builder *JobCtxBuilder, pipelineTask *task.Task, serviceName, namespace string, kubeClient client.Client) ([]byte, error) {
+	reaperCtx := builder.BuildReaperContext(pipelineTask, serviceName)
+	if err := resolveSecretRefs(reaperCtx, secretref.NewResolver(kubeClient, namespace, pipelineTask.ProductName)); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(reaperCtx)
+}
+
+// resolveSecretRefs 解析后的变量总是作为 SecretEnvs 传给 reaper，避免在日志中输出
+func resolveSecretRefs(ctx *types.Context, resolver *secretref.Resolver) error {
+	for i, env := range ctx.SecretEnvs {
+		resolved, ok, err := resolveEnv(env, resolver)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ctx.SecretEnvs[i] = resolved
+		}
+	}
+
+	envs := make(types.EnvVar, 0, len(ctx.Envs))
+	for _, env := range ctx.Envs {
+		resolved, ok, err := resolveEnv(env, resolver)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ctx.SecretEnvs = append(ctx.SecretEnvs, resolved)
+			continue
+		}
+		envs = append(envs, env)
+	}
+	ctx.Envs = envs
+
+	return nil
+}
+
+// resolveEnv 解析 KEY=VALUE 形式的变量，VALUE 不是引用时返回 false
+func resolveEnv(env string, resolver *secretref.Resolver) (string, bool, error) {
+	kv := strings.SplitN(env, "=", 2)
+	if len(kv) != 2 || !secretref.IsRef(kv[1]) {
+		return env, false, nil
+	}
+
+	value, err := resolver.Resolve(kv[1])
+	if err != nil {
+		return "", false, fmt.Errorf("failed to resolve variable %s: %v", kv[0], err)
+	}
+	return fmt.Sprintf("%s=%s", kv[0], value), true, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/secretref"
+)
+
+var _ = Describe("Testing secret references", func() {
+	var resolver *secretref.Resolver
+
+	BeforeEach(func() {
+		kubeClient := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "zadig-build", Labels: map[string]string{setting.ProductLabel: "demo"}},
+			Data:       map[string][]byte{"password": []byte("s3cr3t"), "user": []byte("admin")},
+		}).Build()
+		resolver = secretref.NewResolver(kubeClient, "zadig-build", "demo")
+	})
+
+	It("moves resolved variables to secret envs", func() {
+		ctx := &types.Context{
+			Envs:       types.EnvVar{"GOPROXY=https://goproxy.cn", "DB_USER=secret://k8s/db#user"},
+			SecretEnvs: types.EnvVar{"DB_PASSWORD=secret://k8s/zadig-build/db#password", "TOKEN=inline"},
+		}
+
+		Expect(resolveSecretRefs(ctx, resolver)).To(Succeed())
+		Expect(ctx.Envs).To(Equal(types.EnvVar{"GOPROXY=https://goproxy.cn"}))
+		Expect(ctx.SecretEnvs).To(Equal(types.EnvVar{"DB_PASSWORD=s3cr3t", "TOKEN=inline", "DB_USER=admin"}))
+	})
+
+	It("fails without leaking other values", func() {
+		ctx := &types.Context{
+			Envs: types.EnvVar{"DB_PASSWORD=secret://k8s/db#missing"},
+		}
+
+		err := resolveSecretRefs(ctx, resolver)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("DB_PASSWORD"))
+	})
+})
@@ -29,6 +29,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -39,6 +40,7 @@ func InitializeSecurityPlugin(taskType config.TaskType) TaskPlugin {
 		errorChan: make(chan error, 1),
 		httpClient: httpclient.New(
 			httpclient.SetHostURL(configbase.AslanServiceAddress()),
+			httpclient.SetClientHeader(setting.InternalTokenHeader, configbase.InternalToken()),
 		),
 	}
 }
@@ -225,6 +227,30 @@ func (p *SecurityPlugin) Complete(ctx context.Context, pipelineTask *task.Task,
 		//安全测试报告
 		pipelineTask.TestReports[p.Task.ImageID] = testReport
 	}
+
+	if p.Task.TaskStatus != config.StatusPassed {
+		return
+	}
+	if exceeded := exceededThresholds(p.Task.Summary, p.Task.Thresholds); len(exceeded) != 0 {
+		p.Task.TaskStatus = config.StatusFailed
+		p.Task.Error = fmt.Sprintf("vulnerabilities of %s exceed thresholds: %s", p.Task.ImageName, strings.Join(exceeded, ", "))
+		p.Log.Error(p.Task.Error)
+	}
+}
+
+// exceededThresholds 返回漏洞数量超过阈值的等级，按严重程度从高到低排列
+func exceededThresholds(summary, thresholds map[string]int) []string {
+	var exceeded []string
+	for _, severity := range setting.SecuritySeverities {
+		max, ok := thresholds[severity]
+		if !ok {
+			continue
+		}
+		if count := summary[severity]; count > max {
+			exceeded = append(exceeded, fmt.Sprintf("%s %d > %d", severity, count, max))
+		}
+	}
+	return exceeded
 }
 
 // SetTask ...
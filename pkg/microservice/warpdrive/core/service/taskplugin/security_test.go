@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+)
+
+var _ = Describe("Testing security thresholds", func() {
+	var p *SecurityPlugin
+
+	BeforeEach(func() {
+		p = &SecurityPlugin{
+			Log: zap.NewNop().Sugar(),
+			Task: &task.Security{
+				Enabled:    true,
+				TaskStatus: config.StatusPassed,
+				ImageName:  "nginx:latest",
+				ImageID:    "sha256:abc",
+				Summary:    map[string]int{"Critical": 1, "High": 6, "Medium": 20},
+			},
+		}
+	})
+
+	It("passes without thresholds", func() {
+		p.Complete(context.TODO(), &task.Task{}, "")
+		Expect(p.IsTaskFailed()).To(BeFalse())
+	})
+
+	It("passes when all severities are within thresholds", func() {
+		p.Task.Thresholds = map[string]int{"Critical": 1, "High": 6}
+		p.Complete(context.TODO(), &task.Task{}, "")
+		Expect(p.IsTaskFailed()).To(BeFalse())
+	})
+
+	It("fails when thresholds are exceeded and keeps the report", func() {
+		p.Task.Thresholds = map[string]int{"High": 5, "Critical": 0, "Low": 0}
+		pipelineTask := &task.Task{}
+		p.Complete(context.TODO(), pipelineTask, "")
+		Expect(p.IsTaskFailed()).To(BeTrue())
+		Expect(p.Task.Error).To(ContainSubstring("Critical 1 > 0, High 6 > 5"))
+		Expect(pipelineTask.TestReports).To(HaveKey("sha256:abc"))
+	})
+
+	It("keeps the original error of a failed scan", func() {
+		p.Task.TaskStatus = config.StatusTimeout
+		p.Task.Error = "timeout"
+		p.Task.Thresholds = map[string]int{"Critical": 0}
+		p.Complete(context.TODO(), &task.Task{}, "")
+		Expect(p.Task.Error).To(Equal("timeout"))
+	})
+})
@@ -19,14 +19,17 @@ package taskplugin
 import (
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
@@ -57,6 +60,8 @@ type TestPlugin struct {
 	kubeClient    client.Client
 	Task          *task.Testing
 	Log           *zap.SugaredLogger
+	// matrixJobs 测试矩阵中其它组合的 job
+	matrixJobs []*matrixJob
 }
 
 func (p *TestPlugin) SetAckFunc(func()) {
@@ -134,6 +139,9 @@ func (p *TestPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipelineC
 	if strings.HasPrefix(p.Task.JobCtx.TestResultPath, prefix) {
 		p.Task.JobCtx.TestResultPath = p.Task.JobCtx.TestResultPath[len(prefix):len(p.Task.JobCtx.TestResultPath)]
 	}
+	if strings.HasPrefix(p.Task.JobCtx.CoverageReportPath, prefix) {
+		p.Task.JobCtx.CoverageReportPath = p.Task.JobCtx.CoverageReportPath[len(prefix):]
+	}
 
 	p.Task.JobCtx.EnvVars = append(p.Task.JobCtx.EnvVars, namespaceEnvVar)
 	p.Task.JobCtx.EnvVars = append(p.Task.JobCtx.EnvVars, linkedNamespaceEnvVar)
@@ -161,7 +169,7 @@ func (p *TestPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipelineC
 		Installs:       p.Task.InstallCtx,
 	}
 
-	jobCtxBytes, err := yaml.Marshal(jobCtx.BuildReaperContext(pipelineTask, serviceName))
+	jobCtxBytes, err := marshalReaperContext(&jobCtx, pipelineTask, serviceName, p.KubeNamespace, p.kubeClient)
 	if err != nil {
 		msg := fmt.Sprintf("cannot reaper.Context data: %v", err)
 		p.Log.Error(msg)
@@ -234,11 +242,27 @@ func (p *TestPlugin) Run(ctx context.Context, pipelineTask *task.Task, pipelineC
 		p.Task.Error = msg
 		return
 	}
+	p.matrixJobs = newMatrixJobs(p.Task.Matrix, p.JobName, p.FileName, serviceName, p.Type(), pipelineTask)
+	startMatrixJobs(p.matrixJobs, func(j *matrixJob) error {
+		return createMatrixJob(j, jobCtx, p.Type(), pipelineTask, serviceName, p.Task.ResReq, p.Task.Registries, p.KubeNamespace, linkedNamespace, p.kubeClient)
+	}, p.Log)
 }
 
 // Wait ...
 func (p *TestPlugin) Wait(ctx context.Context) {
-	status := waitJobEndWithFile(ctx, p.TaskTimeout(), p.KubeNamespace, p.JobName, true, p.kubeClient, p.Log)
+	matrixDone := make(chan struct{})
+	go func() {
+		defer close(matrixDone)
+		waitMatrixJobs(ctx, p.TaskTimeout(), p.KubeNamespace, p.matrixJobs, p.kubeClient, p.Log)
+	}()
+
+	status := waitJobEndWithFile(ctx, p.TaskTimeout(), "", p.KubeNamespace, p.JobName, true, p.kubeClient, p.Log)
+
+	<-matrixDone
+	status, msg := matrixResult(status, p.Task.StartTime, p.Task.Matrix)
+	if msg != "" {
+		p.Task.Error = msg
+	}
 	p.SetStatus(status)
 }
 
@@ -266,6 +290,8 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 		}
 	}()
 
+	completeMatrixJobs(pipelineTask, p.KubeNamespace, p.Task.TaskStatus, p.matrixJobs, p.kubeClient, p.Log)
+
 	err := saveContainerLog(pipelineTask, p.KubeNamespace, p.FileName, jobLabel, p.kubeClient)
 	if err != nil {
 		p.Log.Error(err)
@@ -273,6 +299,9 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 		return
 	}
 	p.Task.LogFile = p.JobName
+	if len(p.Task.Matrix) > 0 {
+		p.Task.Matrix[0].LogFile = p.JobName
+	}
 
 	fileName := fmt.Sprintf("%s-%s-%d-%s-%s", config.SingleType, pipelineTask.PipelineName, pipelineTask.TaskID, config.TaskTestingV2, pipelineTask.ServiceName)
 
@@ -285,6 +314,9 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 
 	fileName = strings.Replace(strings.ToLower(fileName), "_", "-", -1)
 
+	// 覆盖率与测试结果相互独立，在测试结果处理完成后检查
+	defer p.completeCoverage(pipelineTask, fileName)
+
 	//如果用户配置了测试结果目录需要收集,则下载测试结果,发送到aslan server
 	//Note here: p.Task.TestName目前只有默认值test
 	if p.Task.JobCtx.TestResultPath == "" {
@@ -338,6 +370,9 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 	if err != nil {
 		return
 	}
+	download := func(fileName string) ([]byte, error) {
+		return downloadTestResult(s3client, store.Bucket, store.GetObjectPath(fileName))
+	}
 
 	if p.Task.JobCtx.TestType == setting.FunctionTest {
 		b, err := os.ReadFile(tmpFilename)
@@ -359,20 +394,29 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 			return
 		}
 		p.Task.ReportReady = true
+		p.Task.QuarantinedFailures = quarantinedFailures(testReport.FunctionTestSuite, p.Task.QuarantinedCases)
+		// 隔离用例的失败只记录，不影响测试结果
+		failures := testReport.FunctionTestSuite.Errors + testReport.FunctionTestSuite.Failures - len(p.Task.QuarantinedFailures)
+
+		// 测试矩阵中其它组合的测试结果合并到任务的测试报告中
+		matrixFailures, matrixQuarantinedFailures := mergeMatrixTestResults(testReport.FunctionTestSuite, fileName, p.matrixJobs, p.Task.QuarantinedCases, download, p.Log)
+		failures += matrixFailures
+		p.Task.QuarantinedFailures = append(p.Task.QuarantinedFailures, matrixQuarantinedFailures...)
+
 		testReport.FunctionTestSuite.TestCases = []types.TestCase{}
 		//测试报告
 		pipelineTask.TestReports[serviceName] = testReport
 
-		if testReport.FunctionTestSuite.Errors > 0 || testReport.FunctionTestSuite.Failures > 0 {
-			msg := fmt.Sprintf(
-				"%d failure case(s) found",
-				testReport.FunctionTestSuite.Errors+testReport.FunctionTestSuite.Failures,
-			)
+		if failures > 0 {
+			msg := fmt.Sprintf("%d failure case(s) found", failures)
 			p.Log.Error(msg)
 			p.Task.Error = msg
 			p.Task.TaskStatus = config.StatusFailed
 			return
 		}
+		if len(p.Task.QuarantinedFailures) > 0 {
+			p.Log.Infof("%d quarantined case(s) failed: %s", len(p.Task.QuarantinedFailures), strings.Join(p.Task.QuarantinedFailures, ", "))
+		}
 
 	} else if p.Task.JobCtx.TestType == setting.PerformanceTest {
 		csvFile, err := os.Open(tmpFilename)
@@ -384,49 +428,16 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 			return
 		}
 		defer csvFile.Close()
-		csvReader := csv.NewReader(csvFile)
-		row, err := csvReader.Read()
-		if len(row) != 11 {
-			msg := "csv file type match error"
-			p.Log.Error(msg)
-			p.Task.Error = msg
-			p.Task.TaskStatus = config.StatusFailed
-			return
-		}
-
-		if err != nil {
-			msg := fmt.Sprintf("read performance csv first row error: %v", err)
-			p.Log.Error(msg)
-			p.Task.Error = msg
-			p.Task.TaskStatus = config.StatusFailed
-			return
-		}
-
-		rows, err := csvReader.ReadAll()
+		performanceTestSuites, err := parsePerformanceTestSuites(csvFile)
 		if err != nil {
-			msg := fmt.Sprintf("read csv readAll error: %v", err)
+			msg := err.Error()
 			p.Log.Error(msg)
 			p.Task.Error = msg
 			p.Task.TaskStatus = config.StatusFailed
 			return
 		}
-		performanceTestSuites := make([]*types.PerformanceTestSuite, 0)
-		for _, row := range rows {
-			performanceTestSuite := new(types.PerformanceTestSuite)
-			performanceTestSuite.Label = row[0]
-			performanceTestSuite.Samples = row[1]
-			performanceTestSuite.Average = row[2]
-			performanceTestSuite.Min = row[3]
-			performanceTestSuite.Max = row[4]
-			performanceTestSuite.Line = row[5]
-			performanceTestSuite.StdDev = row[6]
-			performanceTestSuite.Error = row[7]
-			performanceTestSuite.Throughput = row[8]
-			performanceTestSuite.ReceivedKb = row[9]
-			performanceTestSuite.AvgByte = row[10]
-
-			performanceTestSuites = append(performanceTestSuites, performanceTestSuite)
-		}
+		// 测试矩阵中其它组合的测试结果合并到任务的测试报告中
+		performanceTestSuites = append(performanceTestSuites, mergeMatrixPerformanceResults(fileName, p.matrixJobs, download, p.Log)...)
 		p.Task.ReportReady = true
 		testReport.PerformanceTestSuites = performanceTestSuites
 		//测试报告
@@ -442,6 +453,170 @@ func (p *TestPlugin) Complete(ctx context.Context, pipelineTask *task.Task, serv
 
 }
 
+// parsePerformanceTestSuites 解析 reaper 汇总的性能测试结果
+func parsePerformanceTestSuites(r io.Reader) ([]*types.PerformanceTestSuite, error) {
+	csvReader := csv.NewReader(r)
+	row, err := csvReader.Read()
+	if len(row) != 11 {
+		return nil, errors.New("csv file type match error")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read performance csv first row error: %v", err)
+	}
+
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv readAll error: %v", err)
+	}
+	performanceTestSuites := make([]*types.PerformanceTestSuite, 0)
+	for _, row := range rows {
+		performanceTestSuite := new(types.PerformanceTestSuite)
+		performanceTestSuite.Label = row[0]
+		performanceTestSuite.Samples = row[1]
+		performanceTestSuite.Average = row[2]
+		performanceTestSuite.Min = row[3]
+		performanceTestSuite.Max = row[4]
+		performanceTestSuite.Line = row[5]
+		performanceTestSuite.StdDev = row[6]
+		performanceTestSuite.Error = row[7]
+		performanceTestSuite.Throughput = row[8]
+		performanceTestSuite.ReceivedKb = row[9]
+		performanceTestSuite.AvgByte = row[10]
+
+		performanceTestSuites = append(performanceTestSuites, performanceTestSuite)
+	}
+	return performanceTestSuites, nil
+}
+
+// downloadTestResult 下载 reaper 上传的测试结果
+func downloadTestResult(s3client *s3tool.Client, bucket, objectKey string) ([]byte, error) {
+	tmpFilename, err := util.GenerateTmpFile()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.Remove(tmpFilename)
+	}()
+
+	if err = s3client.Download(bucket, objectKey, tmpFilename); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmpFilename)
+}
+
+// quarantinedFailures 返回失败的隔离用例
+func quarantinedFailures(suite *types.TestSuite, quarantined []string) []string {
+	if suite == nil || len(quarantined) == 0 {
+		return nil
+	}
+
+	quarantinedSet := sets.NewString(quarantined...)
+	var failures []string
+	for _, tc := range suite.TestCases {
+		if tc.Failed() && quarantinedSet.Has(tc.FullName()) {
+			failures = append(failures, tc.FullName())
+		}
+	}
+	return failures
+}
+
+// completeCoverage 下载覆盖率统计结果并检查覆盖率门禁，不要求配置测试结果目录，也不区分测试类型
+func (p *TestPlugin) completeCoverage(pipelineTask *task.Task, fileName string) {
+	if p.Task.JobCtx.CoverageReportPath == "" || p.Task.TaskStatus == config.StatusCancelled || p.Task.TaskStatus == config.StatusTimeout {
+		return
+	}
+
+	p.Task.Coverage = p.fetchCoverage(pipelineTask, fileName)
+	// 测试已经失败时保留原来的失败原因
+	if p.Task.TaskStatus == config.StatusFailed {
+		return
+	}
+	if violations := checkCoverageGate(p.Task.Coverage, p.Task.CoverageGate, p.Task.BaseCoverage); len(violations) > 0 {
+		msg := fmt.Sprintf("coverage gate failed: %s", strings.Join(violations, ", "))
+		p.Log.Error(msg)
+		p.Task.Error = msg
+		p.Task.TaskStatus = config.StatusFailed
+	}
+}
+
+func (p *TestPlugin) fetchCoverage(pipelineTask *task.Task, fileName string) *task.Coverage {
+	store, err := s3.NewS3StorageFromEncryptedURI(pipelineTask.StorageURI)
+	if err != nil {
+		p.Log.Errorf("failed to create s3 storage: %v", err)
+		return nil
+	}
+	if store.Subfolder != "" {
+		store.Subfolder = fmt.Sprintf("%s/%s/%d/%s", store.Subfolder, pipelineTask.PipelineName, pipelineTask.TaskID, "test")
+	} else {
+		store.Subfolder = fmt.Sprintf("%s/%d/%s", pipelineTask.PipelineName, pipelineTask.TaskID, "test")
+	}
+	forcedPathStyle := true
+	if store.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	s3client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Insecure, forcedPathStyle)
+	if err != nil {
+		p.Log.Errorf("failed to create s3 client: %v", err)
+		return nil
+	}
+
+	return p.downloadCoverage(s3client, store.Bucket, store.GetObjectPath(fileName+setting.CoverageFileSuffix))
+}
+
+// downloadCoverage 下载 reaper 生成的覆盖率统计结果，不存在时返回 nil
+func (p *TestPlugin) downloadCoverage(s3client *s3tool.Client, bucket, objectKey string) *task.Coverage {
+	tmpFilename, err := util.GenerateTmpFile()
+	if err != nil {
+		p.Log.Errorf("generate temp file error: %v", err)
+		return nil
+	}
+	defer func() {
+		_ = os.Remove(tmpFilename)
+	}()
+
+	if err = s3client.Download(bucket, objectKey, tmpFilename); err != nil {
+		p.Log.Warnf("failed to download coverage %s: %v", objectKey, err)
+		return nil
+	}
+
+	b, err := os.ReadFile(tmpFilename)
+	if err != nil {
+		p.Log.Errorf("failed to read coverage file: %v", err)
+		return nil
+	}
+
+	coverage := new(task.Coverage)
+	if err = json.Unmarshal(b, coverage); err != nil {
+		p.Log.Errorf("failed to unmarshal coverage: %v", err)
+		return nil
+	}
+	return coverage
+}
+
+// checkCoverageGate 返回不满足覆盖率门禁的项，base 为空时不检查覆盖率下降
+func checkCoverageGate(coverage *task.Coverage, gate *task.CoverageGate, base *task.Coverage) []string {
+	if gate == nil || (gate.MinLineCoverage == 0 && gate.MinBranchCoverage == 0 && gate.MaxDrop == 0) {
+		return nil
+	}
+	if coverage == nil {
+		return []string{"no coverage report is found"}
+	}
+
+	var violations []string
+	if gate.MinLineCoverage > 0 && coverage.LineRate < gate.MinLineCoverage {
+		violations = append(violations, fmt.Sprintf("line coverage %.2f%% < %.2f%%", coverage.LineRate, gate.MinLineCoverage))
+	}
+	if gate.MinBranchCoverage > 0 && coverage.BranchRate < gate.MinBranchCoverage {
+		violations = append(violations, fmt.Sprintf("branch coverage %.2f%% < %.2f%%", coverage.BranchRate, gate.MinBranchCoverage))
+	}
+	if gate.MaxDrop > 0 && base != nil {
+		if drop := base.LineRate - coverage.LineRate; drop > gate.MaxDrop {
+			violations = append(violations, fmt.Sprintf("line coverage dropped %.2f%% from %.2f%% > %.2f%%", drop, base.LineRate, gate.MaxDrop))
+		}
+	}
+	return violations
+}
+
 func (p *TestPlugin) SetTask(t map[string]interface{}) error {
 	task, err := ToTestingTask(t)
 	if err != nil {
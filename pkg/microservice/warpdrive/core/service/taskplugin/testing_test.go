@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package taskplugin
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
+)
+
+var _ = Describe("Testing quarantined test cases", func() {
+	suite := &types.TestSuite{
+		TestCases: []types.TestCase{
+			{ClassName: "api", Name: "login", Failure: &types.Failure{Message: "timeout"}},
+			{ClassName: "api", Name: "logout"},
+			{Name: "upload", Error: &types.Error{Message: "panic"}},
+			{ClassName: "api", Name: "profile", Failure: &types.Failure{Message: "500"}},
+		},
+	}
+
+	It("returns nothing without quarantined cases", func() {
+		Expect(quarantinedFailures(suite, nil)).To(BeEmpty())
+	})
+
+	It("returns only failed cases that are quarantined", func() {
+		failures := quarantinedFailures(suite, []string{"api.login", "api.logout", "upload"})
+		Expect(failures).To(Equal([]string{"api.login", "upload"}))
+	})
+})
+
+var _ = Describe("Testing coverage gate", func() {
+	coverage := &task.Coverage{LineRate: 75, BranchRate: 50}
+
+	It("passes without gate", func() {
+		Expect(checkCoverageGate(nil, nil, nil)).To(BeEmpty())
+		Expect(checkCoverageGate(nil, &task.CoverageGate{}, nil)).To(BeEmpty())
+	})
+
+	It("fails when coverage report is missing", func() {
+		Expect(checkCoverageGate(nil, &task.CoverageGate{MinLineCoverage: 60}, nil)).To(HaveLen(1))
+	})
+
+	It("checks minimum line and branch coverage", func() {
+		Expect(checkCoverageGate(coverage, &task.CoverageGate{MinLineCoverage: 70, MinBranchCoverage: 50}, nil)).To(BeEmpty())
+		Expect(checkCoverageGate(coverage, &task.CoverageGate{MinLineCoverage: 80, MinBranchCoverage: 60}, nil)).To(Equal([]string{
+			"line coverage 75.00% < 80.00%",
+			"branch coverage 50.00% < 60.00%",
+		}))
+	})
+
+	It("checks coverage drop only with base coverage", func() {
+		gate := &task.CoverageGate{MaxDrop: 2}
+		Expect(checkCoverageGate(coverage, gate, nil)).To(BeEmpty())
+		Expect(checkCoverageGate(coverage, gate, &task.Coverage{LineRate: 76.5})).To(BeEmpty())
+		Expect(checkCoverageGate(coverage, gate, &task.Coverage{LineRate: 80})).To(Equal([]string{
+			"line coverage dropped 5.00% from 80.00% > 2.00%",
+		}))
+	})
+})
@@ -159,6 +159,14 @@ func ToReleaseImageTask(sb map[string]interface{}) (*task.ReleaseImage, error) {
 	return t, nil
 }
 
+func ToReleaseChartTask(sb map[string]interface{}) (*task.ReleaseChart, error) {
+	var t *task.ReleaseChart
+	if err := IToi(sb, &t); err != nil {
+		return nil, fmt.Errorf("convert interface to ReleaseChartTask error: %v", err)
+	}
+	return t, nil
+}
+
 func ToJiraTask(sb map[string]interface{}) (*task.Jira, error) {
 	var t *task.Jira
 	if err := IToi(sb, &t); err != nil {
@@ -167,6 +175,14 @@ func ToJiraTask(sb map[string]interface{}) (*task.Jira, error) {
 	return t, nil
 }
 
+func ToApprovalTask(sb map[string]interface{}) (*task.Approval, error) {
+	var t *task.Approval
+	if err := IToi(sb, &t); err != nil {
+		return nil, fmt.Errorf("convert interface to ApprovalTask error: %v", err)
+	}
+	return t, nil
+}
+
 func ToSecurityTask(sb map[string]interface{}) (*task.Security, error) {
 	var t *task.Security
 	if err := IToi(sb, &t); err != nil {
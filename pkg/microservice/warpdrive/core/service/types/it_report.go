@@ -87,6 +87,19 @@ type TestCase struct {
 	Error     *Error   `bson:"error,omitempty"         json:"error"        xml:"error,omitempty"`
 }
 
+// FullName 用例的唯一名称，同名用例用 classname 区分
+func (tc *TestCase) FullName() string {
+	if tc.ClassName == "" {
+		return tc.Name
+	}
+	return tc.ClassName + "." + tc.Name
+}
+
+// Failed 用例执行失败或出错
+func (tc *TestCase) Failed() bool {
+	return tc.Failure != nil || tc.Error != nil
+}
+
 type Error struct {
 	Message string `bson:"message"  json:"message" xml:"message,attr"`
 	Type    string `bson:"type"     json:"type"    xml:"type,attr"`
@@ -65,9 +65,18 @@ type Context struct {
 	// SSH ssh连接参数
 	SSHs []*task.SSH `yaml:"sshs"`
 
+	// PMDeployStrategy 物理机分批部署策略
+	PMDeployStrategy *task.PMDeployStrategy `yaml:"pm_deploy_strategy"`
+
+	// PMHealthChecks 每批部署后执行的健康检查
+	PMHealthChecks []*task.PmHealthCheck `yaml:"pm_health_checks"`
+
 	// GinkgoTest 执行 ginkgo test 配置
 	GinkgoTest *GinkgoTest `yaml:"ginkgo_test"`
 
+	// CoverageReportPath 覆盖率报告，支持 Cobertura、JaCoCo XML 和 Go coverprofile，与测试结果相互独立
+	CoverageReportPath string `yaml:"coverage_report_path"`
+
 	// Archive: 归档配置 [optional]
 	Archive *Archive `yaml:"archive"`
 
@@ -86,6 +95,12 @@ type Context struct {
 	// Caches Caches配置
 	Caches []string `yaml:"caches"`
 
+	// CacheKeyFiles 非空时按文件内容哈希保存和恢复 Caches
+	CacheKeyFiles []string `yaml:"cache_key_files"`
+
+	// CacheSizeLimit 项目缓存大小上限，单位 MB
+	CacheSizeLimit int64 `yaml:"cache_size_limit"`
+
 	// testType
 	TestType string `yaml:"test_type"`
 
@@ -96,6 +111,8 @@ type Context struct {
 	StorageURI string `yaml:"storage_uri"`
 	// PipelineName
 	PipelineName string `yaml:"pipeline_name"`
+	// ProjectName
+	ProjectName string `yaml:"project_name"`
 	// TaskID
 	TaskID int64 `yaml:"task_id"`
 	// ServiceName
@@ -216,11 +233,14 @@ type Repo struct {
 //
 // e.g. github returns refs/pull/1/head
 // e.g. gitlab returns merge-requests/1/head
+// e.g. bitbucket returns refs/pull-requests/1/from
 func (r *Repo) PRRef() string {
 	if strings.ToLower(r.Source) == setting.SourceFromGitlab {
 		return fmt.Sprintf("merge-requests/%d/head", r.PR)
 	} else if strings.ToLower(r.Source) == setting.SourceFromGerrit {
 		return r.CheckoutRef
+	} else if strings.ToLower(r.Source) == setting.SourceFromBitbucket {
+		return fmt.Sprintf("refs/pull-requests/%d/from", r.PR)
 	}
 	return fmt.Sprintf("refs/pull/%d/head", r.PR)
 }
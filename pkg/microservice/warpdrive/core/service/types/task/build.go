@@ -57,6 +57,8 @@ type Build struct {
 	// Get the host bound to the environment of the cloud host service configuration
 	EnvHostInfo  map[string][]string `bson:"env_host_info,omitempty"         json:"env_host_info,omitempty"`
 	ArtifactInfo *ArtifactInfo       `bson:"artifact_info,omitempty"         json:"artifact_info,omitempty"`
+	// Matrix 构建矩阵的所有组合及其结果，第一个组合即任务本身
+	Matrix []*MatrixCombination `bson:"matrix,omitempty"                json:"matrix,omitempty"`
 }
 
 type ArtifactInfo struct {
@@ -165,6 +167,8 @@ type JobCtx struct {
 	TestResultPath string `bson:"test_result_path,omitempty"     json:"test_result_path,omitempty"`
 	TestReportPath string `bson:"test_report_path,omitempty"     json:"test_report_path,omitempty"`
 	TestJobName    string `bson:"test_job_name,omitempty"        json:"test_job_name,omitempty"`
+	// CoverageReportPath 覆盖率报告路径
+	CoverageReportPath string `bson:"coverage_report_path,omitempty" json:"coverage_report_path,omitempty"`
 	// DockerBuildCtx
 	DockerBuildCtx *DockerBuildCtx `bson:"docker_build_ctx,omitempty" json:"docker_build_ctx,omitempty"`
 	FileArchiveCtx *FileArchiveCtx `bson:"file_archive_ctx,omitempty" json:"file_archive_ctx,omitempty"`
@@ -174,6 +178,9 @@ type JobCtx struct {
 	Caches        []string `bson:"caches" json:"caches"`
 	ArtifactPaths []string `bson:"artifact_paths,omitempty" json:"artifact_paths,omitempty"`
 	IsHasArtifact bool     `bson:"is_has_artifact" json:"is_has_artifact"`
+	// CacheKeyFiles 按文件内容哈希保存缓存，CacheSizeLimit 为项目缓存大小上限，单位 MB
+	CacheKeyFiles  []string `bson:"cache_key_files,omitempty"  json:"cache_key_files,omitempty"`
+	CacheSizeLimit int64    `bson:"cache_size_limit,omitempty" json:"cache_size_limit,omitempty"`
 	// StorageUri is used for qbox release-candidates
 	//StorageUri string `bson:"storage_uri,omitempty" json:"storage_uri,omitempty"`
 
@@ -181,6 +188,29 @@ type JobCtx struct {
 	ClassicBuild    bool   `bson:"classic_build"                  json:"classic_build"`
 	PostScripts     string `bson:"post_scripts,omitempty"         json:"post_scripts"`
 	PMDeployScripts string `bson:"pm_deploy_scripts,omitempty"    json:"pm_deploy_scripts"`
+	// PMDeployStrategy 物理机分批部署策略，PMHealthChecks 为每批部署后执行的服务健康检查
+	PMDeployStrategy *PMDeployStrategy `bson:"pm_deploy_strategy,omitempty" json:"pm_deploy_strategy,omitempty"`
+	PMHealthChecks   []*PmHealthCheck  `bson:"pm_health_checks,omitempty"   json:"pm_health_checks,omitempty"`
+}
+
+type PMDeployStrategy struct {
+	Enabled             bool   `yaml:"enabled" bson:"enabled" json:"enabled"`
+	BatchSize           int    `yaml:"batch_size" bson:"batch_size" json:"batch_size"`
+	BatchPercent        int    `yaml:"batch_percent" bson:"batch_percent" json:"batch_percent"`
+	PauseSeconds        int    `yaml:"pause_seconds" bson:"pause_seconds" json:"pause_seconds"`
+	InitialDelaySeconds int    `yaml:"initial_delay_seconds" bson:"initial_delay_seconds" json:"initial_delay_seconds"`
+	RollbackOnFailure   bool   `yaml:"rollback_on_failure" bson:"rollback_on_failure" json:"rollback_on_failure"`
+	RollbackScripts     string `yaml:"rollback_scripts" bson:"rollback_scripts,omitempty" json:"rollback_scripts,omitempty"`
+}
+
+type PmHealthCheck struct {
+	Protocol           string `yaml:"protocol" bson:"protocol,omitempty" json:"protocol,omitempty"`
+	Port               int    `yaml:"port" bson:"port,omitempty" json:"port,omitempty"`
+	Path               string `yaml:"path" bson:"path,omitempty" json:"path,omitempty"`
+	TimeOut            int64  `yaml:"time_out" bson:"time_out,omitempty" json:"time_out,omitempty"`
+	Interval           uint64 `yaml:"interval" bson:"interval,omitempty" json:"interval,omitempty"`
+	HealthyThreshold   int    `yaml:"healthy_threshold" bson:"healthy_threshold,omitempty" json:"healthy_threshold,omitempty"`
+	UnhealthyThreshold int    `yaml:"unhealthy_threshold" bson:"unhealthy_threshold,omitempty" json:"unhealthy_threshold,omitempty"`
 }
 
 type SSH struct {
@@ -51,6 +51,22 @@ type Deploy struct {
 	SkipWaiting      bool            `bson:"skipWaiting"                   json:"skipWaiting"`
 	IsRestart        bool            `bson:"is_restart"                    json:"is_restart"`
 	ResetImage       bool            `bson:"reset_image"                   json:"reset_image"`
+	// AutoRollback 部署未通过健康检查时回滚到 ReplaceResources 中记录的原镜像
+	AutoRollback bool `bson:"auto_rollback"                 json:"auto_rollback"`
+	RolledBack   bool `bson:"rolled_back"                   json:"rolled_back"`
+	// RolloutStrategy 为空或 rolling 时原地更新镜像
+	RolloutStrategy *RolloutStrategy `bson:"rollout_strategy,omitempty" json:"rollout_strategy,omitempty"`
+}
+
+// RolloutStrategy 部署任务的发布策略
+// Type: rolling(默认), canary, blue_green，canary 和 blue_green 只对 deployment 生效，statefulset 始终原地更新
+// CanaryWeights: 金丝雀发布每一步新版本的副本占比(1-100)，需递增，流量按副本数在新旧版本间分配，没有基于 ingress 的精确切流，
+// 只能取副本数可以表示的比例(例如 3 个副本时为 33、67)，无法表示的权重会让部署任务直接失败
+// StepInterval: 每一步的观察时间，单位秒
+type RolloutStrategy struct {
+	Type          string `bson:"type"                      json:"type"`
+	CanaryWeights []int  `bson:"canary_weights,omitempty"  json:"canary_weights,omitempty"`
+	StepInterval  int    `bson:"step_interval,omitempty"   json:"step_interval,omitempty"`
 }
 
 // SetNamespace ...
@@ -0,0 +1,38 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package task
+
+import (
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+)
+
+// MatrixCombination 构建或测试矩阵展开后的一个组合
+// 第一个组合的镜像和变量直接应用到任务本身，由原有的 job 运行并产出构建物，其它组合只运行脚本
+type MatrixCombination struct {
+	Name           string        `bson:"name"                       json:"name"`
+	BuildOS        string        `bson:"build_os"                   json:"build_os"`
+	ImageFrom      string        `bson:"image_from"                 json:"image_from"`
+	ImageID        string        `bson:"image_id"                   json:"image_id"`
+	Envs           []*KeyVal     `bson:"envs"                       json:"envs"`
+	AllowFailure   bool          `bson:"allow_failure"              json:"allow_failure"`
+	Status         config.Status `bson:"status,omitempty"           json:"status,omitempty"`
+	Error          string        `bson:"error,omitempty"            json:"error,omitempty"`
+	StartTime      int64         `bson:"start_time,omitempty"       json:"start_time,omitempty"`
+	EndTime        int64         `bson:"end_time,omitempty"         json:"end_time,omitempty"`
+	LogFile        string        `bson:"log_file,omitempty"         json:"log_file,omitempty"`
+	TestReportFile string        `bson:"test_report_file,omitempty" json:"test_report_file,omitempty"`
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package task
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
+)
+
+// ReleaseChart 把环境中渲染后的 helm chart 写入构建的镜像后打包，推送到 chart 仓库
+// 每个服务对应一个任务，Images 为该服务所有本次构建的容器镜像
+// ChartVersion 为空时在原 chart 版本后追加任务 ID 作为推送的版本
+type ReleaseChart struct {
+	TaskType      config.TaskType `bson:"type"                           json:"type"`
+	Enabled       bool            `bson:"enabled"                        json:"enabled"`
+	TaskStatus    config.Status   `bson:"status"                         json:"status"`
+	ProductName   string          `bson:"product_name"                   json:"product_name"`
+	EnvName       string          `bson:"env_name"                       json:"env_name"`
+	ServiceName   string          `bson:"service_name"                   json:"service_name"`
+	Images        []*ChartImage   `bson:"images"                         json:"images"`
+	ChartRepoName string          `bson:"chart_repo_name"                json:"chart_repo_name"`
+	ChartVersion  string          `bson:"chart_version"                  json:"chart_version"`
+	ChartName     string          `bson:"chart_name,omitempty"           json:"chart_name,omitempty"`
+	Timeout       int             `bson:"timeout,omitempty"              json:"timeout,omitempty"`
+	Error         string          `bson:"error,omitempty"                json:"error,omitempty"`
+	StartTime     int64           `bson:"start_time,omitempty"           json:"start_time,omitempty"`
+	EndTime       int64           `bson:"end_time,omitempty"             json:"end_time,omitempty"`
+}
+
+// ChartImage 写入 values.yaml 的容器镜像
+type ChartImage struct {
+	ContainerName string `bson:"container_name" json:"container_name"`
+	Image         string `bson:"image"          json:"image"`
+}
+
+// ToSubTask ...
+func (rc *ReleaseChart) ToSubTask() (map[string]interface{}, error) {
+	var task map[string]interface{}
+	if err := IToi(rc, &task); err != nil {
+		return nil, fmt.Errorf("convert ReleaseChartTask to interface error: %v", err)
+	}
+	return task, nil
+}
@@ -45,6 +45,36 @@ type Testing struct {
 	ReportReady    bool                 `bson:"report_ready"                    json:"report_ready"`
 	IsRestart      bool                 `bson:"is_restart"                      json:"is_restart"`
 	Registries     []*RegistryNamespace `bson:"-"                               json:"registries"`
+	// QuarantinedCases 被隔离的用例，失败时不影响测试结果
+	QuarantinedCases []string `bson:"quarantined_cases,omitempty"      json:"quarantined_cases,omitempty"`
+	// QuarantinedFailures 本次执行中失败的隔离用例
+	QuarantinedFailures []string `bson:"quarantined_failures,omitempty"   json:"quarantined_failures,omitempty"`
+	// CoverageGate 覆盖率门禁
+	CoverageGate *CoverageGate `bson:"coverage_gate,omitempty"          json:"coverage_gate,omitempty"`
+	// BaseCoverage PR 触发时目标分支最近一次的覆盖率
+	BaseCoverage *Coverage `bson:"base_coverage,omitempty"          json:"base_coverage,omitempty"`
+	// Coverage 本次测试的覆盖率
+	Coverage *Coverage `bson:"coverage,omitempty"               json:"coverage,omitempty"`
+	// Matrix 测试矩阵的所有组合及其结果，第一个组合即任务本身
+	Matrix []*MatrixCombination `bson:"matrix,omitempty"               json:"matrix,omitempty"`
+}
+
+// CoverageGate 覆盖率门禁，所有值均为百分比，0 表示不检查
+type CoverageGate struct {
+	MinLineCoverage   float64 `bson:"min_line_coverage"   json:"min_line_coverage"`
+	MinBranchCoverage float64 `bson:"min_branch_coverage" json:"min_branch_coverage"`
+	MaxDrop           float64 `bson:"max_drop"            json:"max_drop"`
+}
+
+// Coverage reaper 解析覆盖率报告得到的结果，比例为百分比
+type Coverage struct {
+	Format          string  `bson:"format"           json:"format"`
+	LinesCovered    int     `bson:"lines_covered"    json:"lines_covered"`
+	LinesValid      int     `bson:"lines_valid"      json:"lines_valid"`
+	BranchesCovered int     `bson:"branches_covered" json:"branches_covered"`
+	BranchesValid   int     `bson:"branches_valid"   json:"branches_valid"`
+	LineRate        float64 `bson:"line_rate"        json:"line_rate"`
+	BranchRate      float64 `bson:"branch_rate"      json:"branch_rate"`
 }
 
 func (t *Testing) ToSubTask() (map[string]interface{}, error) {
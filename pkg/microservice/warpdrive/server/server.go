@@ -24,6 +24,7 @@ import (
 	commonconfig "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/taskcontroller"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/metrics"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -42,6 +43,7 @@ func Serve(ctx context.Context) error {
 	}
 
 	http.HandleFunc("/ping", ping)
+	http.Handle("/metrics", metrics.Handler())
 	server := &http.Server{Addr: ":25001", Handler: nil}
 
 	stopChan := make(chan struct{})
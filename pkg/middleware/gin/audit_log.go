@@ -17,13 +17,38 @@ limitations under the License.
 package gin
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	"github.com/koderover/zadig/pkg/util/ginzap"
 )
 
+const (
+	// maxAuditBodySize 审计日志中请求体的最大长度，超出部分会被截断
+	maxAuditBodySize = 64 * 1024
+	redactedValue    = "******"
+)
+
+// sensitiveFields 字段名包含这些关键字时，审计日志中的值会被隐藏
+var sensitiveFields = []string{"password", "passwd", "secret", "token", "private_key", "privatekey", "credential", "kubeconfig", "access_key", "accesskey"}
+
+// sensitiveExactFields 字段名与这些值完全相同时，审计日志中的值会被隐藏
+var sensitiveExactFields = []string{"ak", "sk", "pwd", "key_pair"}
+
 // 更新操作日志状态
 func UpdateOperationLogStatus(c *gin.Context) {
 	log := ginzap.WithContext(c).Sugar()
@@ -36,3 +61,199 @@ func UpdateOperationLogStatus(c *gin.Context) {
 		log.Errorf("UpdateOperation err:%v", err)
 	}
 }
+
+// AuditLog 自动记录所有变更类请求，包括操作人、项目、资源、脱敏后的请求体和返回状态
+// skipPaths 中的路径前缀不记录，用于跳过审计日志自身的写入接口，携带内部凭证的服务间调用也不记录
+func AuditLog(service string, skipPaths ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if isInternalCall(c) {
+			c.Next()
+			return
+		}
+		for _, p := range skipPaths {
+			if strings.HasPrefix(c.Request.URL.Path, p) {
+				c.Next()
+				return
+			}
+		}
+
+		start := time.Now()
+		body := auditRequestBody(c)
+
+		c.Next()
+
+		ctx := internalhandler.NewContext(c)
+		username, userID := auditUser(c, ctx)
+		auditLog := &aslan.AuditLog{
+			Service:     service,
+			UserID:      userID,
+			Username:    username,
+			ProjectName: auditProjectName(c),
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			Resource:    c.FullPath(),
+			RequestBody: body,
+			Status:      c.Writer.Status(),
+			RequestID:   c.GetString(setting.RequestID),
+			ClientIP:    c.ClientIP(),
+			Latency:     time.Since(start).Milliseconds(),
+			CreatedAt:   start.Unix(),
+		}
+
+		go func(logger *zap.SugaredLogger) {
+			if err := aslan.New(config.AslanServiceAddress()).CreateAuditLog(auditLog); err != nil {
+				logger.Errorf("Failed to create audit log for %s %s, err: %s", auditLog.Method, auditLog.Path, err)
+			}
+		}(ctx.Logger)
+	}
+}
+
+// isInternalCall 服务之间的调用携带由 SECRET_KEY 派生的内部凭证，用户无法伪造，
+// User-Agent 和是否携带 Authorization 头都可以由调用方任意设置，不能用来判断
+func isInternalCall(c *gin.Context) bool {
+	token := config.InternalToken()
+	if token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(c.GetHeader(setting.InternalTokenHeader)), []byte(token))
+}
+
+// auditUser 通过查询参数 token 认证的请求没有 Authorization 头，从 token 中获取操作人
+func auditUser(c *gin.Context, ctx *internalhandler.Context) (name, uid string) {
+	if ctx.UserID != "" {
+		return ctx.UserName, ctx.UserID
+	}
+	token := c.Query("token")
+	if token == "" {
+		return "", ""
+	}
+	name, uid, err := internalhandler.ParseUserFromJWT(token)
+	if err != nil {
+		ctx.Logger.Warnf("Failed to get user from query token, err: %s", err)
+	}
+	return name, uid
+}
+
+func auditRequestBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	contentType := c.ContentType()
+	if strings.HasPrefix(contentType, "multipart/") || contentType == "application/octet-stream" {
+		return omittedBody(contentType)
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+
+	return truncateBody(RedactBody(contentType, body))
+}
+
+func auditProjectName(c *gin.Context) string {
+	for _, name := range []string{c.Query("projectName"), c.Param("projectName"), c.Query("productName"), c.Param("productName")} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func truncateBody(body string) string {
+	if len(body) <= maxAuditBodySize {
+		return body
+	}
+	return body[:maxAuditBodySize] + "...(truncated)"
+}
+
+// RedactBody 隐藏 json 和表单请求体中的敏感字段，其他格式的请求体无法脱敏，不记录内容
+func RedactBody(contentType string, body []byte) string {
+	if contentType == gin.MIMEPOSTForm {
+		return redactForm(body)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return omittedBody(contentType)
+	}
+
+	redacted, err := json.Marshal(redact(data))
+	if err != nil {
+		return omittedBody(contentType)
+	}
+	return string(redacted)
+}
+
+func redactForm(body []byte) string {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return omittedBody(gin.MIMEPOSTForm)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if isSensitiveField(k) && v != "" {
+				pairs = append(pairs, url.QueryEscape(k)+"="+redactedValue)
+				continue
+			}
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func omittedBody(contentType string) string {
+	if contentType == "" {
+		contentType = "unknown"
+	}
+	return "[" + contentType + " body omitted]"
+}
+
+func redact(data interface{}) interface{} {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		for k, v := range d {
+			if isSensitiveField(k) {
+				if v != nil && v != "" {
+					d[k] = redactedValue
+				}
+				continue
+			}
+			d[k] = redact(v)
+		}
+	case []interface{}:
+		for i, v := range d {
+			d[i] = redact(v)
+		}
+	}
+	return data
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, f := range sensitiveExactFields {
+		if name == f {
+			return true
+		}
+	}
+	for _, f := range sensitiveFields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
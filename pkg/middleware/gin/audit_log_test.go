@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gin
+
+import (
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = ginkgo.Describe("Redact request body", func() {
+	DescribeTable("Testing with different bodies",
+		func(contentType, input, expect string) {
+			Expect(RedactBody(contentType, []byte(input))).To(Equal(expect))
+		},
+		Entry("form body", "application/x-www-form-urlencoded", "name=foo+bar&password=bar&token=", "name=foo+bar&password=******&token="),
+		Entry("plain text body", "text/plain", "password=bar", "[text/plain body omitted]"),
+		Entry("invalid json body", "application/json", `{"password":"bar"`, "[application/json body omitted]"),
+		Entry("plain fields", "application/json", `{"name":"foo","namespace":"dev"}`, `{"name":"foo","namespace":"dev"}`),
+		Entry("sensitive fields", "application/json", `{"name":"foo","password":"bar","oauth_token":"t","sk":"s","ak":""}`, `{"ak":"","name":"foo","oauth_token":"******","password":"******","sk":"******"}`),
+		Entry("nested fields", "", `{"registry":{"secretKey":"s","items":[{"kubeconfig":"k","region":"r"}]}}`, `{"registry":{"items":[{"kubeconfig":"******","region":"r"}],"secretKey":"******"}}`),
+	)
+})
+
+var _ = ginkgo.Describe("Skip internal calls", func() {
+	ginkgo.BeforeEach(func() {
+		viper.Set(setting.ENVSecretKey, "secret")
+	})
+
+	ginkgo.AfterEach(func() {
+		viper.Set(setting.ENVSecretKey, "")
+	})
+
+	DescribeTable("Testing with different callers",
+		func(userAgent, internalToken, query string, expect bool) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("POST", "/api/aslan/workflow/workflowtask"+query, nil)
+			c.Request.Header.Set("User-Agent", userAgent)
+			if internalToken == "valid" {
+				internalToken = config.InternalToken()
+			}
+			if internalToken != "" {
+				c.Request.Header.Set(setting.InternalTokenHeader, internalToken)
+			}
+			Expect(isInternalCall(c)).To(Equal(expect))
+		},
+		Entry("internal call", "Zadig REST Client", "valid", "", true),
+		Entry("go http client without internal token", "Go-http-client/1.1", "", "", false),
+		Entry("user with query token", "Go-http-client/1.1", "", "?token=t", false),
+		Entry("forged internal token", "Zadig REST Client", "t", "", false),
+	)
+
+	ginkgo.It("should not trust any caller without secret key", func() {
+		viper.Set(setting.ENVSecretKey, "")
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/aslan/workflow/workflowtask", nil)
+		Expect(isInternalCall(c)).To(BeFalse())
+	})
+})
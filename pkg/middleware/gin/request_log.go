@@ -30,7 +30,11 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 )
 
-const timeISO8601 = "2006-01-02T15:04:05.000Z0700"
+const (
+	timeISO8601 = "2006-01-02T15:04:05.000Z0700"
+	// binary bodies such as uploaded files are streamed to the handler and not logged
+	mimeOctetStream = "application/octet-stream"
+)
 
 var sensitiveHeaders = sets.NewString("authorization", "cookie", "token", "session")
 
@@ -47,7 +51,7 @@ func RequestLog(logger *zap.Logger) gin.HandlerFunc {
 		headers := make(map[string]string)
 		// request body is a ReadCloser, it can be read only once.
 		if c.Request != nil {
-			if c.Request.Body != nil {
+			if c.Request.Body != nil && c.ContentType() != mimeOctetStream {
 				var buf bytes.Buffer
 				tee := io.TeeReader(c.Request.Body, &buf)
 				body, _ = ioutil.ReadAll(tee)
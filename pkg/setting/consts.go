@@ -69,8 +69,15 @@ const (
 	// cron
 	ENVRootToken = "ROOT_TOKEN"
 
+	// podexec
+	ENVLocalClusterPodExecPolicy = "LOCAL_CLUSTER_POD_EXEC_POLICY"
+
 	ENVKodespaceVersion = "KODESPACE_VERSION"
 
+	// vault
+	ENVVaultAddr  = "VAULT_ADDR"
+	ENVVaultToken = "VAULT_TOKEN"
+
 	// hubagent
 	HubAgentToken         = "HUB_AGENT_TOKEN"
 	HubServerBaseAddr     = "HUB_SERVER_BASE_ADDR"
@@ -80,11 +87,12 @@ const (
 	Params                = "X-API-Tunnel-Params"
 
 	// warpdrive
-	WarpDrivePodName    = "WD_POD_NAME"
-	ReleaseImageTimeout = "RELEASE_IMAGE_TIMEOUT"
-	DefaultRegistryAddr = "DEFAULT_REG_ADDRESS"
-	DefaultRegistryAK   = "DEFAULT_REG_ACCESS_KEY"
-	DefaultRegistrySK   = "DEFAULT_REG_SECRET_KEY"
+	WarpDrivePodName         = "WD_POD_NAME"
+	WarpDriveTaskConcurrency = "WD_TASK_CONCURRENCY"
+	ReleaseImageTimeout      = "RELEASE_IMAGE_TIMEOUT"
+	DefaultRegistryAddr      = "DEFAULT_REG_ADDRESS"
+	DefaultRegistryAK        = "DEFAULT_REG_ACCESS_KEY"
+	DefaultRegistrySK        = "DEFAULT_REG_SECRET_KEY"
 
 	// reaper
 	Home          = "HOME"
@@ -162,6 +170,8 @@ const (
 	ModifiedByAnnotation            = companyLabel + "/" + "last-modified-by"
 	EditorIDAnnotation              = companyLabel + "/" + "editor-id"
 	LastUpdateTimeAnnotation        = companyLabel + "/" + "last-update-time"
+	RolloutTrackLabel               = companyLabel + "/" + "rollout-track"
+	RolloutWorkloadLabel            = companyLabel + "/" + "rollout-workload"
 
 	LabelValueTrue = "true"
 
@@ -217,6 +227,13 @@ const (
 	// PMDeployType physical machine deploy 脚本物理机部署方式
 	PMDeployType = "pm"
 
+	// RolloutTypeRolling 原地更新镜像（默认）
+	RolloutTypeRolling = "rolling"
+	// RolloutTypeCanary 金丝雀发布，按权重逐步切流
+	RolloutTypeCanary = "canary"
+	// RolloutTypeBlueGreen 蓝绿发布，就绪后一次性切换 Service selector
+	RolloutTypeBlueGreen = "blue_green"
+
 	// 基础设施 k8s类型
 	BasicFacilityK8S = "kubernetes"
 	// 基础设施 云主机
@@ -232,10 +249,16 @@ const (
 	SourceFromGerrit = "gerrit"
 	// SourceFromCodeHub 配置来源为codehub
 	SourceFromCodeHub = "codehub"
+	// SourceFromGitee 配置来源为gitee
+	SourceFromGitee = "gitee"
+	// SourceFromBitbucket 配置来源为bitbucket server
+	SourceFromBitbucket = "bitbucket"
 	// SourceFromChartTemplate 配置来源为helmTemplate
 	SourceFromChartTemplate = "chartTemplate"
 	// SourceFromPublicRepo 配置来源为publicRepo
 	SourceFromPublicRepo = "publicRepo"
+	// SourceFromChartRepo 配置来源为helm chart仓库或OCI仓库
+	SourceFromChartRepo = "chartRepo"
 
 	// SourceFromGUI 配置来源为gui
 	SourceFromGUI = "gui"
@@ -273,6 +296,8 @@ const (
 
 const (
 	AuthorizationHeader = "Authorization"
+	// InternalTokenHeader 服务之间调用时携带 config.InternalToken()，用于识别内部调用
+	InternalTokenHeader = "X-Zadig-Internal-Token"
 )
 
 //install script constants
@@ -424,6 +449,9 @@ const (
 	FunctionTest = "function"
 	// PerformanceTest 性能测试
 	PerformanceTest = "performance"
+
+	// CoverageFileSuffix 覆盖率统计结果与测试结果保存在同一目录，文件名为测试结果文件名加该后缀
+	CoverageFileSuffix = "-coverage.json"
 )
 
 const (
@@ -543,3 +571,15 @@ const (
 
 // ModernWorkflowType 自由编排工作流
 const ModernWorkflowType = "ModernWorkflow"
+
+// PodExecPolicy 生产集群的容器终端策略
+type PodExecPolicy string
+
+const (
+	// PodExecAllowed 允许使用终端，由用户选择是否录制
+	PodExecAllowed PodExecPolicy = ""
+	// PodExecRecorded 允许使用终端，所有会话必须录制
+	PodExecRecorded PodExecPolicy = "recorded"
+	// PodExecDisabled 禁止使用终端
+	PodExecDisabled PodExecPolicy = "disabled"
+)
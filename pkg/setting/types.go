@@ -34,6 +34,9 @@ const (
 var ValidName = regexp.MustCompile(`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`)
 var ValidNameHint = "a valid name must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character"
 
+// SecuritySeverities clair 扫描结果的漏洞等级，按严重程度从高到低排列
+var SecuritySeverities = []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
+
 const (
 	Aslan     = iota + 1 // 1
 	Aslanx               // 2
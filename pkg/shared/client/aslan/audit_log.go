@@ -81,3 +81,27 @@ func (c *Client) UpdateAuditLog(id string, status int, log *zap.SugaredLogger) e
 
 	return nil
 }
+
+// AuditLog 由中间件自动记录的变更类请求
+type AuditLog struct {
+	Service     string `json:"service"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	ProjectName string `json:"project_name"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Resource    string `json:"resource"`
+	RequestBody string `json:"request_body"`
+	Status      int    `json:"status"`
+	RequestID   string `json:"request_id"`
+	ClientIP    string `json:"client_ip"`
+	Latency     int64  `json:"latency"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+func (c *Client) CreateAuditLog(args *AuditLog) error {
+	url := "/system/audit"
+
+	_, err := c.Post(url, httpclient.SetBody(args))
+	return err
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package aslan
 
 import (
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -30,7 +32,8 @@ type Client struct {
 
 func New(host string) *Client {
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api"),
+		httpclient.SetHostURL(host+"/api"),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 	)
 
 	return &Client{
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aslan
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type Cluster struct {
+	ID            string                `json:"id"`
+	Name          string                `json:"name"`
+	Production    bool                  `json:"production"`
+	PodExecPolicy setting.PodExecPolicy `json:"pod_exec_policy"`
+}
+
+type PodExecSession struct {
+	ID            string `json:"id,omitempty"`
+	Username      string `json:"username"`
+	UserID        string `json:"user_id"`
+	ProductName   string `json:"product_name"`
+	ClusterID     string `json:"cluster_id"`
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"pod_name"`
+	ContainerName string `json:"container_name"`
+	StartTime     int64  `json:"start_time"`
+	EndTime       int64  `json:"end_time"`
+	Truncated     bool   `json:"truncated"`
+}
+
+func (c *Client) GetCluster(id string) (*Cluster, error) {
+	url := fmt.Sprintf("/cluster/clusters/%s", id)
+
+	res := &Cluster{}
+	_, err := c.Get(url, httpclient.SetResult(res))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) CreatePodExecSession(session *PodExecSession) (*PodExecSession, error) {
+	url := "/environment/podexec/sessions"
+
+	res := &PodExecSession{}
+	_, err := c.Post(url, httpclient.SetBody(session), httpclient.SetResult(res))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// UploadPodExecSessionRecording 以流的方式上传录像并更新会话的结束信息，录像较大时耗时较长，因此不设置超时
+func (c *Client) UploadPodExecSessionRecording(id string, recording io.Reader, endTime int64, truncated bool) error {
+	url := fmt.Sprintf("/environment/podexec/sessions/%s/recording", id)
+
+	cl := httpclient.New(
+		httpclient.SetHostURL(c.HostURL),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
+		httpclient.UnsetTimeout(),
+	)
+	_, err := cl.Put(url,
+		httpclient.SetHeader("Content-Type", "application/octet-stream"),
+		httpclient.SetQueryParam("endTime", strconv.FormatInt(endTime, 10)),
+		httpclient.SetQueryParam("truncated", strconv.FormatBool(truncated)),
+		httpclient.SetBody(recording),
+	)
+	return err
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package aslanx
 
 import (
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -29,6 +31,7 @@ type Client struct {
 func New(host string) *Client {
 	c := httpclient.New(
 		httpclient.SetHostURL(host),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 	)
 
 	return &Client{
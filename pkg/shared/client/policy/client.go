@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -36,6 +37,7 @@ func New() *Client {
 
 	c := httpclient.New(
 		httpclient.SetHostURL(host+"/api/v1"),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 		httpclient.SetRetryCount(100),
 		httpclient.SetRetryWaitTime(retryInterval),
 	)
@@ -50,7 +52,8 @@ func NewDefault() *Client {
 	host := config.PolicyServiceAddress()
 
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api/v1"),
+		httpclient.SetHostURL(host+"/api/v1"),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 	)
 
 	return &Client{
@@ -28,6 +28,7 @@ type ActionRule struct {
 type RoleBinding struct {
 	Name   string `json:"name"`
 	UID    string `json:"uid"`
+	GID    string `json:"gid,omitempty"`
 	Role   string `json:"role"`
 	Public bool   `json:"public"`
 }
@@ -81,6 +82,13 @@ func (c *Client) DeleteRoleBindings(names []string, projectName string) error {
 	return err
 }
 
+// DeleteGroupRoleBindings deletes all the role bindings of the given user group in all projects.
+func (c *Client) DeleteGroupRoleBindings(gid string) error {
+	url := fmt.Sprintf("/group-rolebindings/%s", gid)
+	_, err := c.Delete(url)
+	return err
+}
+
 func (c *Client) DeleteRoles(names []string, projectName string) error {
 	url := fmt.Sprintf("/roles/bulk-delete?projectName=%s", projectName)
 	nameArgs := &NameArgs{}
@@ -103,6 +111,14 @@ func (c *Client) CreatePublicRole(name string, role *Role) error {
 	return err
 }
 
+// RefreshBundle asks the policy service to regenerate the OPA bundle, it is used when some data in the bundle
+// is not managed by the policy service, such as the user group memberships.
+func (c *Client) RefreshBundle() error {
+	url := "/bundles/refresh"
+	_, err := c.Post(url)
+	return err
+}
+
 type Role struct {
 	Name  string `json:"name"`
 	Rules []*struct {
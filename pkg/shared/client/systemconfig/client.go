@@ -18,6 +18,7 @@ package systemconfig
 
 import (
 	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
@@ -30,7 +31,8 @@ type Client struct {
 func New() *Client {
 	host := config.ConfigServiceAddress()
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api/v1"),
+		httpclient.SetHostURL(host+"/api/v1"),
+		httpclient.SetClientHeader(setting.InternalTokenHeader, config.InternalToken()),
 	)
 
 	return &Client{
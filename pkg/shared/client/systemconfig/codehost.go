@@ -7,10 +7,12 @@ import (
 )
 
 const (
-	GitLabProvider  = "gitlab"
-	GitHubProvider  = "github"
-	GerritProvider  = "gerrit"
-	CodeHubProvider = "codehub"
+	GitLabProvider    = "gitlab"
+	GitHubProvider    = "github"
+	GerritProvider    = "gerrit"
+	CodeHubProvider   = "codehub"
+	GiteeProvider     = "gitee"
+	BitbucketProvider = "bitbucket"
 )
 
 type CodeHost struct {
@@ -69,3 +69,22 @@ func (c *Client) SearchUser(args *SearchUserArgs) (*SearchUserResp, error) {
 	_, err := c.Post(url, httpclient.SetBody(args), httpclient.SetResult(resp))
 	return resp, err
 }
+
+type UserGroup struct {
+	GroupID     string   `json:"group_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	UIDs        []string `json:"uids"`
+}
+
+func (c *Client) ListUserGroups() ([]*UserGroup, error) {
+	url := "/user-groups"
+
+	res := make([]*UserGroup, 0)
+	_, err := c.Get(url, httpclient.SetResult(&res))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -68,6 +68,15 @@ func NewContext(c *gin.Context) *Context {
 	}
 }
 
+// ParseUserFromJWT 供不使用 gin 的服务从 token 中获取用户信息
+func ParseUserFromJWT(token string) (name, uid string, err error) {
+	claims, err := getUserFromJWT(token)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.Name, claims.UID, nil
+}
+
 func getUserFromJWT(token string) (jwtClaims, error) {
 	cs := jwtClaims{}
 
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 所有服务的指标都以 zadig_ 开头，相同含义的 label 使用相同的名称
+const (
+	namespace = "zadig"
+
+	LabelProject  = "project"
+	LabelWorkflow = "workflow"
+	LabelTaskType = "task_type"
+	LabelCluster  = "cluster"
+	LabelStatus   = "status"
+	LabelTopic    = "topic"
+	LabelQuery    = "query"
+	LabelDecision = "decision"
+
+	// LocalCluster 运行在 zadig 所在集群时 cluster label 的取值
+	LocalCluster = "local"
+)
+
+// Cluster 把集群 ID 转换为 cluster label 的取值，集群 ID 为空表示 zadig 所在的集群
+func Cluster(clusterID string) string {
+	if clusterID == "" {
+		return LocalCluster
+	}
+	return clusterID
+}
+
+// 任务一般运行数分钟到数小时，job 调度一般在数秒到数分钟之间
+var (
+	taskDurationBuckets = []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 10800}
+	schedulingBuckets   = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120}
+)
+
+var (
+	// QueueTasks aslan 任务队列中各状态的任务数
+	QueueTasks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_tasks",
+		Help:      "Number of tasks in the aslan task queue.",
+	}, []string{LabelProject, LabelTaskType, LabelStatus})
+
+	// TaskDuration 工作流任务从开始到结束的耗时，task_type 为工作流类型
+	TaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Duration of finished workflow tasks.",
+		Buckets:   taskDurationBuckets,
+	}, []string{LabelProject, LabelWorkflow, LabelTaskType, LabelStatus})
+
+	// SubTaskDuration warpdrive 中子任务的耗时，task_type 为子任务类型
+	SubTaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "subtask_duration_seconds",
+		Help:      "Duration of finished subtasks executed by warpdrive.",
+		Buckets:   taskDurationBuckets,
+	}, []string{LabelProject, LabelWorkflow, LabelTaskType, LabelStatus})
+
+	// JobSchedulingLatency reaper job 从创建到 pod 开始运行的耗时
+	JobSchedulingLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_scheduling_latency_seconds",
+		Help:      "Latency between creating a reaper job and its pod starting to run.",
+		Buckets:   schedulingBuckets,
+	}, []string{LabelTaskType, LabelCluster})
+
+	// NSQPublishFailures 发送 nsq 消息失败的次数
+	NSQPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "nsq_publish_failures_total",
+		Help:      "Number of failed nsq publishes.",
+	}, []string{LabelTopic})
+
+	// HubTunnelSessions hubserver 与各集群 hubagent 之间的隧道是否连通，连通为 1
+	HubTunnelSessions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "hub_tunnel_sessions",
+		Help:      "Whether the tunnel session between hubserver and the agent of a cluster is connected.",
+	}, []string{LabelCluster})
+
+	// OPADecisions picket 调用 OPA 鉴权的决策次数，decision 为 allow、deny 或 error。
+	// 网关直接调用 OPA 鉴权，不经过 picket，这部分决策不在此统计，需要采集 OPA 自身的 /metrics
+	OPADecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "opa_decisions_total",
+		Help:      "Number of authorization decisions evaluated by OPA for picket, excluding decisions made by the gateway.",
+	}, []string{LabelQuery, LabelDecision})
+
+	// CronTriggers cron 服务触发定时任务的次数，status 为 success 或 failed
+	CronTriggers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_triggers_total",
+		Help:      "Number of scheduled tasks triggered by cron.",
+	}, []string{LabelProject, LabelWorkflow, LabelTaskType, LabelStatus})
+)
+
+// Handler 返回 Prometheus 格式的 /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Result 把错误转换为 status label 的取值
+func Result(err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return "success"
+}
+
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+	DecisionError = "error"
+)
+
+// Decision 把 OPA 的鉴权结果转换为 decision label 的取值
+func Decision(allow bool) string {
+	if allow {
+		return DecisionAllow
+	}
+	return DecisionDeny
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretref
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Scheme is the prefix of a secret reference, values with this prefix are resolved from the secret backend
+	// right before they are used, so that only the reference is saved in the database.
+	Scheme = "secret://"
+
+	// BackendVault references a key of a vault secret: secret://vault/<path>#<key>
+	// for KV version 2 secret engine, the path should include the `data` segment, e.g. secret://vault/secret/data/<project>/app#password
+	BackendVault = "vault"
+	// BackendKubernetes references a key of a kubernetes secret: secret://k8s/[<namespace>/]<name>#<key>
+	// the namespace where the secret is used is taken if it is omitted, secrets in other namespaces can't be referenced.
+	BackendKubernetes = "k8s"
+)
+
+// Ref is a parsed secret reference
+type Ref struct {
+	Backend string
+	Path    string
+	Key     string
+}
+
+func (r *Ref) String() string {
+	return fmt.Sprintf("%s%s/%s#%s", Scheme, r.Backend, r.Path, r.Key)
+}
+
+// IsRef returns true if the value is a secret reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), Scheme)
+}
+
+// Parse parses a secret reference
+func Parse(value string) (*Ref, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, Scheme) {
+		return nil, fmt.Errorf("%q is not a secret reference", value)
+	}
+
+	location := strings.TrimPrefix(value, Scheme)
+	i := strings.LastIndex(location, "#")
+	if i < 0 || i == len(location)-1 {
+		return nil, fmt.Errorf("key is missing in secret reference %q", value)
+	}
+	key := location[i+1:]
+	location = location[:i]
+
+	parts := strings.SplitN(location, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("path is missing in secret reference %q", value)
+	}
+
+	ref := &Ref{Backend: parts[0], Path: strings.Trim(parts[1], "/"), Key: key}
+	if !validPath(ref.Path) {
+		return nil, fmt.Errorf("invalid path in secret reference %q", value)
+	}
+	switch ref.Backend {
+	case BackendVault:
+	case BackendKubernetes:
+		if len(strings.Split(ref.Path, "/")) > 2 {
+			return nil, fmt.Errorf("invalid kubernetes secret reference %q", value)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported secret backend %q", ref.Backend)
+	}
+
+	return ref, nil
+}
+
+// validPath rejects empty, "." and ".." segments (also the escaped ones), the secret backends clean the path before
+// handling it, so these segments can be used to escape from the directory of the project.
+func validPath(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		s, err := url.PathUnescape(segment)
+		if err != nil || s == "" || s == "." || s == ".." || strings.Contains(s, "/") {
+			return false
+		}
+	}
+	return true
+}
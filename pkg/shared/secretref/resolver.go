@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretref
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+// Resolver resolves secret references of a project, the resolved values must never be saved or printed.
+//
+// References are scoped to the project:
+//   - kubernetes secrets are only read from the namespace the Resolver is created for, and the secret must be
+//     labeled with `s-product: <project>`, so that secrets of Zadig itself or of other projects can't be referenced.
+//   - vault paths must be under `<mount>/<project>/` (or `<mount>/data/<project>/` for KV version 2), and the token
+//     of the project (VAULT_TOKEN_<PROJECT>) is used if it is configured.
+type Resolver struct {
+	vault      *vaultClient
+	kubeClient client.Client
+	namespace  string
+	project    string
+
+	cache map[string]string
+}
+
+// NewResolver creates a Resolver for the project, kubernetes secrets are read by kubeClient from namespace.
+// Vault is configured by VAULT_ADDR and VAULT_TOKEN.
+func NewResolver(kubeClient client.Client, namespace, project string) *Resolver {
+	r := &Resolver{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		project:    project,
+		cache:      make(map[string]string),
+	}
+	if config.VaultAddress() != "" {
+		r.vault = newVaultClient(config.VaultAddress(), config.VaultProjectToken(project))
+	}
+
+	return r
+}
+
+// Resolve returns the value of the referenced secret, the value is returned as is if it is not a reference.
+func (r *Resolver) Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	ref, err := Parse(value)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := r.cache[ref.String()]; ok {
+		return v, nil
+	}
+
+	var res string
+	switch ref.Backend {
+	case BackendVault:
+		if r.vault == nil {
+			return "", errors.New("vault is not configured")
+		}
+		if err = r.checkVaultPath(ref.Path); err != nil {
+			return "", err
+		}
+		res, err = r.vault.read(ref.Path, ref.Key)
+	case BackendKubernetes:
+		res, err = r.readKubernetesSecret(ref)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.cache[ref.String()] = res
+	return res, nil
+}
+
+// checkVaultPath makes sure the path is under the directory of the project: <mount>/[data/]<project>/...
+func (r *Resolver) checkVaultPath(path string) error {
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 && parts[1] == "data" {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	if r.project == "" || len(parts) < 3 || parts[1] != r.project {
+		return fmt.Errorf("vault secret %s is not accessible by project %s", path, r.project)
+	}
+
+	return nil
+}
+
+func (r *Resolver) readKubernetesSecret(ref *Ref) (string, error) {
+	if r.kubeClient == nil {
+		return "", errors.New("kubernetes client is not configured")
+	}
+
+	namespace, name := r.namespace, ref.Path
+	if parts := strings.Split(ref.Path, "/"); len(parts) == 2 {
+		namespace, name = parts[0], parts[1]
+	}
+	if namespace != r.namespace {
+		return "", fmt.Errorf("secret %s/%s is not accessible, only secrets in namespace %s can be referenced", namespace, name, r.namespace)
+	}
+
+	secret, found, err := getter.GetSecret(namespace, name, r.kubeClient)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %v", namespace, name, err)
+	}
+	if !found {
+		return "", fmt.Errorf("secret %s/%s is not found", namespace, name)
+	}
+	if r.project == "" || secret.Labels[setting.ProductLabel] != r.project {
+		return "", fmt.Errorf("secret %s/%s is not accessible by project %s, label it with %s=%s to allow it",
+			namespace, name, r.project, setting.ProductLabel, r.project)
+	}
+
+	if value, ok := secret.Data[ref.Key]; ok {
+		return string(value), nil
+	}
+	if value, ok := secret.StringData[ref.Key]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("key %s is not found in secret %s/%s", ref.Key, namespace, name)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretref
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// fakeVault imitates the KV secret engines of a vault dev server (`vault server -dev`), which mounts KV version 2
+// at secret/. Set VAULT_ADDR and VAULT_TOKEN to run the specs against a real dev server instead.
+type fakeVault struct {
+	sync.Mutex
+	token   string
+	secrets map[string]map[string]interface{}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(vaultTokenHeader) != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasPrefix(path, "secret/data/") {
+			body = map[string]interface{}{"data": body["data"], "metadata": map[string]interface{}{"version": 1}}
+		}
+		v.secrets[path] = body
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		data, ok := v.secrets[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}
+}
+
+func startVault() (addr, token string, stop func()) {
+	if os.Getenv(setting.ENVVaultAddr) != "" {
+		return os.Getenv(setting.ENVVaultAddr), os.Getenv(setting.ENVVaultToken), func() {}
+	}
+
+	v := &fakeVault{token: "root", secrets: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(v)
+	return server.URL, v.token, server.Close
+}
+
+func writeVault(addr, token, path string, body interface{}) {
+	_, err := httpclient.New(httpclient.SetHostURL(addr)).Post("/v1/"+path,
+		httpclient.SetHeader(vaultTokenHeader, token),
+		httpclient.SetBody(body),
+	)
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+var _ = ginkgo.Describe("Parse", func() {
+	DescribeTable("valid references",
+		func(value string, expected *Ref) {
+			ref, err := Parse(value)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ref).To(Equal(expected))
+		},
+		Entry("vault", "secret://vault/secret/data/app#password", &Ref{Backend: BackendVault, Path: "secret/data/app", Key: "password"}),
+		Entry("k8s with namespace", "secret://k8s/prod/db#password", &Ref{Backend: BackendKubernetes, Path: "prod/db", Key: "password"}),
+		Entry("k8s without namespace", " secret://k8s/db#password ", &Ref{Backend: BackendKubernetes, Path: "db", Key: "password"}),
+	)
+
+	DescribeTable("invalid references",
+		func(value string) {
+			_, err := Parse(value)
+			Expect(err).Should(HaveOccurred())
+		},
+		Entry("not a reference", "password"),
+		Entry("missing key", "secret://vault/secret/app"),
+		Entry("empty key", "secret://vault/secret/app#"),
+		Entry("missing path", "secret://vault#password"),
+		Entry("unknown backend", "secret://aws/app#password"),
+		Entry("too many segments for k8s", "secret://k8s/a/b/c#password"),
+		Entry("parent segment", "secret://vault/secret/project1/../project2/app#password"),
+		Entry("escaped parent segment", "secret://vault/secret/project1/%2e%2e/project2/app#password"),
+		Entry("current segment", "secret://vault/secret/./project2/app#password"),
+		Entry("empty segment", "secret://vault/secret//project2/app#password"),
+	)
+})
+
+var _ = ginkgo.Describe("Resolver", func() {
+	var (
+		stop     func()
+		resolver *Resolver
+	)
+
+	ginkgo.BeforeEach(func() {
+		var addr, token string
+		addr, token, stop = startVault()
+		viper.Set(setting.ENVVaultAddr, addr)
+		viper.Set(setting.ENVVaultToken, token)
+
+		writeVault(addr, token, "secret/data/demo/app", map[string]interface{}{"data": map[string]string{"password": "kv2-pass"}})
+		writeVault(addr, token, "secret/data/other/app", map[string]interface{}{"data": map[string]string{"password": "other-pass"}})
+
+		projectLabels := map[string]string{setting.ProductLabel: "demo"}
+		kubeClient := fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: projectLabels},
+				Data:       map[string][]byte{"password": []byte("k8s-pass")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "zadig-aes-key", Namespace: "default"},
+				Data:       map[string][]byte{"aesKey": []byte("internal")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod", Labels: projectLabels},
+				Data:       map[string][]byte{"password": []byte("prod-pass")},
+			},
+		).Build()
+		resolver = NewResolver(kubeClient, "default", "demo")
+	})
+
+	ginkgo.AfterEach(func() {
+		stop()
+		viper.Set(setting.ENVVaultAddr, "")
+		viper.Set(setting.ENVVaultToken, "")
+	})
+
+	ginkgo.It("keeps plain values", func() {
+		Expect(resolver.Resolve("plain")).To(Equal("plain"))
+	})
+
+	ginkgo.It("reads vault KV version 2 secrets", func() {
+		Expect(resolver.Resolve("secret://vault/secret/data/demo/app#password")).To(Equal("kv2-pass"))
+	})
+
+	ginkgo.It("reads vault KV version 1 secrets", func() {
+		if os.Getenv(setting.ENVVaultAddr) != "" {
+			ginkgo.Skip("the dev server has no KV version 1 engine mounted")
+		}
+		writeVault(viper.GetString(setting.ENVVaultAddr), viper.GetString(setting.ENVVaultToken), "kv/demo/app", map[string]string{"token": "kv1-token"})
+		Expect(resolver.Resolve("secret://vault/kv/demo/app#token")).To(Equal("kv1-token"))
+	})
+
+	ginkgo.It("fails on missing vault keys", func() {
+		_, err := resolver.Resolve("secret://vault/secret/data/demo/app#missing")
+		Expect(err).Should(HaveOccurred())
+		_, err = resolver.Resolve("secret://vault/secret/data/demo/missing#password")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	ginkgo.It("rejects vault paths of other projects", func() {
+		for _, ref := range []string{
+			"secret://vault/secret/data/other/app#password",
+			"secret://vault/secret/data/app#password",
+			"secret://vault/secret/data/demo#password",
+			"secret://vault/secret/data/demo/../other/app#password",
+		} {
+			_, err := resolver.Resolve(ref)
+			Expect(err).Should(HaveOccurred(), ref)
+		}
+	})
+
+	ginkgo.It("uses the token of the project if it is configured", func() {
+		if os.Getenv(setting.ENVVaultAddr) != "" {
+			ginkgo.Skip("the dev server has only the root token")
+		}
+		viper.Set(setting.ENVVaultToken+"_DEMO", "invalid")
+		defer viper.Set(setting.ENVVaultToken+"_DEMO", "")
+
+		_, err := NewResolver(nil, "default", "demo").Resolve("secret://vault/secret/data/demo/app#password")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	ginkgo.It("reads kubernetes secrets", func() {
+		Expect(resolver.Resolve("secret://k8s/db#password")).To(Equal("k8s-pass"))
+		Expect(resolver.Resolve("secret://k8s/default/db#password")).To(Equal("k8s-pass"))
+
+		_, err := resolver.Resolve("secret://k8s/missing#password")
+		Expect(err).Should(HaveOccurred())
+		_, err = resolver.Resolve("secret://k8s/db#missing")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	ginkgo.It("rejects kubernetes secrets out of the namespace or the project", func() {
+		_, err := resolver.Resolve("secret://k8s/prod/db#password")
+		Expect(err).Should(HaveOccurred())
+		_, err = resolver.Resolve("secret://k8s/zadig-aes-key#aesKey")
+		Expect(err).Should(HaveOccurred())
+		_, err = NewResolver(resolver.kubeClient, "default", "other").Resolve("secret://k8s/db#password")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	ginkgo.It("fails if vault is not configured", func() {
+		viper.Set(setting.ENVVaultAddr, "")
+		_, err := NewResolver(nil, "", "demo").Resolve("secret://vault/secret/data/demo/app#password")
+		Expect(err).Should(HaveOccurred())
+	})
+})
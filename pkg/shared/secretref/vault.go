@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretref
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const vaultTokenHeader = "X-Vault-Token"
+
+type vaultClient struct {
+	client *httpclient.Client
+	token  string
+}
+
+func newVaultClient(address, token string) *vaultClient {
+	return &vaultClient{
+		client: httpclient.New(httpclient.SetHostURL(address)),
+		token:  token,
+	}
+}
+
+type vaultSecret struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// read reads a key of the secret, both KV version 1 and version 2 are supported.
+func (c *vaultClient) read(path, key string) (string, error) {
+	res := &vaultSecret{}
+	_, err := c.client.Get("/v1/"+path, httpclient.SetHeader(vaultTokenHeader, c.token), httpclient.SetResult(res))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %v", path, err)
+	}
+
+	data := res.Data
+	// KV version 2 wraps the secret in data.data with its metadata in data.metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s is not found in vault secret %s", key, path)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type BuildState string
+
+const (
+	BuildStateInProgress BuildState = "INPROGRESS"
+	BuildStateSuccessful BuildState = "SUCCESSFUL"
+	BuildStateFailed     BuildState = "FAILED"
+)
+
+// BuildStatus 提交的构建状态，同一个 Key 的状态会被覆盖
+type BuildStatus struct {
+	State       BuildState `json:"state"`
+	Key         string     `json:"key"`
+	Name        string     `json:"name,omitempty"`
+	URL         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+}
+
+func (c *Client) SetBuildStatus(commitID string, status *BuildStatus) error {
+	_, err := c.Post(fmt.Sprintf("/rest/build-status/1.0/commits/%s", commitID), httpclient.SetBody(status))
+	return err
+}
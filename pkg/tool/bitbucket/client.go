@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	apiPrefix = "/rest/api/1.0"
+	limit     = 100
+)
+
+// Client Bitbucket Server REST 客户端，使用 HTTP access token 认证。
+// 仓库的 owner 为 project key，repo 为仓库 slug
+type Client struct {
+	*httpclient.Client
+
+	address string
+}
+
+func NewClient(address, accessToken string) *Client {
+	address = strings.TrimSuffix(address, "/")
+	c := httpclient.New(
+		httpclient.SetAuthScheme("Bearer"),
+		httpclient.SetAuthToken(accessToken),
+		httpclient.SetHostURL(address),
+	)
+
+	return &Client{Client: c, address: address}
+}
+
+// page Bitbucket Server 分页结果
+type page struct {
+	Size          int  `json:"size"`
+	Limit         int  `json:"limit"`
+	IsLastPage    bool `json:"isLastPage"`
+	Start         int  `json:"start"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+type User struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	DisplayName  string `json:"displayName"`
+	Slug         string `json:"slug"`
+}
+
+type Project struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+}
+
+type Repository struct {
+	ID          int      `json:"id"`
+	Slug        string   `json:"slug"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Project     *Project `json:"project"`
+}
+
+// FullName 返回 {project key}/{repo slug}
+func (r *Repository) FullName() string {
+	if r.Project == nil {
+		return r.Slug
+	}
+	return r.Project.Key + "/" + r.Slug
+}
+
+type Ref struct {
+	ID           string      `json:"id"`
+	DisplayID    string      `json:"displayId"`
+	Type         string      `json:"type"`
+	LatestCommit string      `json:"latestCommit"`
+	IsDefault    bool        `json:"isDefault"`
+	Repository   *Repository `json:"repository"`
+}
+
+type Commit struct {
+	ID        string `json:"id"`
+	DisplayID string `json:"displayId"`
+	Message   string `json:"message"`
+	Author    *User  `json:"author"`
+}
+
+// AuthorName 返回提交作者
+func (c *Commit) AuthorName() string {
+	if c.Author == nil {
+		return ""
+	}
+	return c.Author.Name
+}
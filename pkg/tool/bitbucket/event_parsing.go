@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type EventType string
+
+const (
+	EventTypeRefsChanged      EventType = "repo:refs_changed"
+	EventTypePROpened         EventType = "pr:opened"
+	EventTypePRFromRefUpdated EventType = "pr:from_ref_updated"
+
+	eventKeyHeader  = "X-Event-Key"
+	signatureHeader = "X-Hub-Signature"
+
+	RefTypeBranch = "BRANCH"
+	RefTypeTag    = "TAG"
+
+	// ChangeTypeDelete 删除分支或 tag
+	ChangeTypeDelete = "DELETE"
+
+	zeroHash = "0000000000000000000000000000000000000000"
+)
+
+// HookEventType returns the event type of the given request.
+func HookEventType(r *http.Request) EventType {
+	return EventType(r.Header.Get(eventKeyHeader))
+}
+
+// ValidateSignature 校验请求签名，webhook 未配置 secret 时 Bitbucket 不会发送签名
+func ValidateSignature(r *http.Request, payload []byte, secret string) error {
+	signature := r.Header.Get(signatureHeader)
+	if secret == "" {
+		return nil
+	}
+	if signature == "" {
+		return fmt.Errorf("missing %s header", signatureHeader)
+	}
+
+	expected := Sign(payload, secret)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// Sign 返回 X-Hub-Signature 格式的签名
+func Sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseHook parses the raw json payload into an event struct.
+func ParseHook(eventType EventType, payload []byte) (interface{}, error) {
+	var event interface{}
+	switch eventType {
+	case EventTypeRefsChanged:
+		event = &RefsChangedEvent{}
+	case EventTypePROpened, EventTypePRFromRefUpdated:
+		event = &PullRequestEvent{}
+	default:
+		return nil, fmt.Errorf("unexpected event type: %s", eventType)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+type RefChange struct {
+	Ref      *Ref   `json:"ref"`
+	RefID    string `json:"refId"`
+	FromHash string `json:"fromHash"`
+	ToHash   string `json:"toHash"`
+	Type     string `json:"type"`
+}
+
+// IsCreate 新建分支或 tag 时 fromHash 为全 0
+func (c *RefChange) IsCreate() bool {
+	return c.FromHash == "" || c.FromHash == zeroHash
+}
+
+type RefsChangedEvent struct {
+	EventKey   EventType    `json:"eventKey"`
+	Date       string       `json:"date"`
+	Actor      *User        `json:"actor"`
+	Repository *Repository  `json:"repository"`
+	Changes    []*RefChange `json:"changes"`
+}
+
+type PullRequestEvent struct {
+	EventKey    EventType    `json:"eventKey"`
+	Date        string       `json:"date"`
+	Actor       *User        `json:"actor"`
+	PullRequest *PullRequest `json:"pullRequest"`
+}
+
+// BranchName 去掉 refs/heads/ 前缀
+func BranchName(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
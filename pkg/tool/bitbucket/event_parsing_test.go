@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSignature(t *testing.T) {
+	assert := require.New(t)
+
+	payload := []byte(`{"eventKey":"repo:refs_changed"}`)
+	r, _ := http.NewRequest(http.MethodPost, "/api/webhook", nil)
+
+	assert.NoError(ValidateSignature(r, payload, ""))
+	assert.Error(ValidateSignature(r, payload, "secret"))
+
+	r.Header.Set(signatureHeader, Sign(payload, "secret"))
+	assert.NoError(ValidateSignature(r, payload, "secret"))
+	assert.Error(ValidateSignature(r, payload, "another"))
+}
+
+func TestParseHook(t *testing.T) {
+	assert := require.New(t)
+
+	payload := []byte(`{
+  "eventKey": "pr:opened",
+  "actor": {"name": "admin"},
+  "pullRequest": {
+    "id": 12,
+    "title": "feature",
+    "fromRef": {"id": "refs/heads/feature", "displayId": "feature", "latestCommit": "abc123",
+      "repository": {"slug": "app", "project": {"key": "PRJ"}}},
+    "toRef": {"id": "refs/heads/master", "displayId": "master",
+      "repository": {"slug": "app", "project": {"key": "PRJ"}}}
+  }
+}`)
+	event, err := ParseHook(EventTypePROpened, payload)
+	assert.NoError(err)
+
+	pr, ok := event.(*PullRequestEvent)
+	assert.True(ok)
+	assert.Equal(12, pr.PullRequest.ID)
+	assert.Equal("abc123", pr.PullRequest.FromRef.LatestCommit)
+	assert.Equal("master", pr.PullRequest.ToRef.DisplayID)
+	assert.Equal("PRJ", pr.PullRequest.ToRef.Repository.Project.Key)
+
+	_, err = ParseHook("repo:forked", payload)
+	assert.Error(err)
+}
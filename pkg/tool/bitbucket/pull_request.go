@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type Participant struct {
+	User *User `json:"user"`
+}
+
+type PullRequest struct {
+	ID          int          `json:"id"`
+	Version     int          `json:"version"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	State       string       `json:"state"`
+	FromRef     *Ref         `json:"fromRef"`
+	ToRef       *Ref         `json:"toRef"`
+	Author      *Participant `json:"author"`
+	CreatedDate int64        `json:"createdDate"`
+	UpdatedDate int64        `json:"updatedDate"`
+}
+
+// AuthorName 返回 PR 创建者
+func (pr *PullRequest) AuthorName() string {
+	if pr.Author == nil || pr.Author.User == nil {
+		return ""
+	}
+	return pr.Author.User.Name
+}
+
+type Comment struct {
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// ListOpenedPullRequests 列出打开状态的 PR，targetBranch 不为空时只返回合入该分支的 PR
+func (c *Client) ListOpenedPullRequests(project, repo, targetBranch string) ([]*PullRequest, error) {
+	var res []*PullRequest
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*PullRequest `json:"values"`
+		}{}
+		params := map[string]string{"state": "OPEN"}
+		if targetBranch != "" {
+			params["at"] = "refs/heads/" + targetBranch
+			params["direction"] = "INCOMING"
+		}
+		if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests", apiPrefix, project, repo), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, params))); err != nil {
+			return nil, err
+		}
+		res = append(res, result.Values...)
+		return &result.page, nil
+	})
+	return res, err
+}
+
+func (c *Client) GetPullRequest(project, repo string, id int) (*PullRequest, error) {
+	res := &PullRequest{}
+	if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d", apiPrefix, project, repo, id), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListPullRequestFiles 返回 PR 修改的文件
+func (c *Client) ListPullRequestFiles(project, repo string, id int) ([]string, error) {
+	var res []string
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*browseChild `json:"values"`
+		}{}
+		if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d/changes", apiPrefix, project, repo, id), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, nil))); err != nil {
+			return nil, err
+		}
+		for _, v := range result.Values {
+			res = append(res, v.Path.ToString)
+		}
+		return &result.page, nil
+	})
+	return res, err
+}
+
+func (c *Client) CreatePullRequestComment(project, repo string, id int, text string) (*Comment, error) {
+	res := &Comment{}
+	url := fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d/comments", apiPrefix, project, repo, id)
+	if _, err := c.Post(url, httpclient.SetBody(map[string]string{"text": text}), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdatePullRequestComment 更新评论，Bitbucket Server 要求带上评论当前的版本号
+func (c *Client) UpdatePullRequestComment(project, repo string, id, commentID int, text string) (*Comment, error) {
+	url := fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d/comments/%d", apiPrefix, project, repo, id, commentID)
+	current := &Comment{}
+	if _, err := c.Get(url, httpclient.SetResult(current)); err != nil {
+		return nil, err
+	}
+
+	res := &Comment{}
+	body := map[string]interface{}{"text": text, "version": current.Version}
+	if _, err := c.Put(url, httpclient.SetBody(body), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
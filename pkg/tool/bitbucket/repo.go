@@ -0,0 +1,341 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/27149chen/afero"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/util"
+	fsutil "github.com/koderover/zadig/pkg/util/fs"
+)
+
+type browseChild struct {
+	Path struct {
+		ToString string `json:"toString"`
+		Name     string `json:"name"`
+	} `json:"path"`
+	Type string `json:"type"`
+	Size int    `json:"size"`
+}
+
+type browseResult struct {
+	Children struct {
+		page
+		Values []*browseChild `json:"values"`
+	} `json:"children"`
+}
+
+// TreeNode 目录下的文件或子目录
+type TreeNode struct {
+	Name  string
+	Path  string
+	IsDir bool
+	Size  int
+}
+
+// list 按页读取列表接口，fn 读取 start 开始的一页并返回分页信息
+func list(fn func(start int) (*page, error)) error {
+	start := 0
+	for {
+		p, err := fn(start)
+		if err != nil {
+			return err
+		}
+		if p.IsLastPage {
+			return nil
+		}
+		start = p.NextPageStart
+	}
+}
+
+func pageParams(start int, params map[string]string) map[string]string {
+	res := map[string]string{
+		"start": strconv.Itoa(start),
+		"limit": strconv.Itoa(limit),
+	}
+	for k, v := range params {
+		res[k] = v
+	}
+	return res
+}
+
+func (c *Client) ListProjects(keyword string) ([]*Project, error) {
+	var res []*Project
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*Project `json:"values"`
+		}{}
+		params := map[string]string{}
+		if keyword != "" {
+			params["name"] = keyword
+		}
+		if _, err := c.Get(apiPrefix+"/projects", httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, params))); err != nil {
+			return nil, err
+		}
+		res = append(res, result.Values...)
+		return &result.page, nil
+	})
+	return res, err
+}
+
+func (c *Client) ListRepositories(project, keyword string) ([]*Repository, error) {
+	var res []*Repository
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*Repository `json:"values"`
+		}{}
+		if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos", apiPrefix, project), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, nil))); err != nil {
+			return nil, err
+		}
+		for _, r := range result.Values {
+			if keyword != "" && !strings.Contains(r.Name, keyword) {
+				continue
+			}
+			res = append(res, r)
+		}
+		return &result.page, nil
+	})
+	return res, err
+}
+
+func (c *Client) GetDefaultBranch(project, repo string) (*Ref, error) {
+	res := &Ref{}
+	if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/branches/default", apiPrefix, project, repo), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListBranches(project, repo string) ([]*Ref, error) {
+	return c.listRefs(project, repo, "branches")
+}
+
+func (c *Client) ListTags(project, repo string) ([]*Ref, error) {
+	return c.listRefs(project, repo, "tags")
+}
+
+func (c *Client) listRefs(project, repo, kind string) ([]*Ref, error) {
+	var res []*Ref
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*Ref `json:"values"`
+		}{}
+		if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/%s", apiPrefix, project, repo, kind), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, nil))); err != nil {
+			return nil, err
+		}
+		res = append(res, result.Values...)
+		return &result.page, nil
+	})
+	return res, err
+}
+
+// GetBranch 按名称精确查找分支
+func (c *Client) GetBranch(project, repo, branch string) (*Ref, error) {
+	result := &struct {
+		page
+		Values []*Ref `json:"values"`
+	}{}
+	params := map[string]string{"filterText": branch}
+	if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/branches", apiPrefix, project, repo), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(0, params))); err != nil {
+		return nil, err
+	}
+	for _, ref := range result.Values {
+		if ref.DisplayID == branch {
+			return ref, nil
+		}
+	}
+	return nil, fmt.Errorf("branch %s not found in %s/%s", branch, project, repo)
+}
+
+func (c *Client) GetCommit(project, repo, commitID string) (*Commit, error) {
+	res := &Commit{}
+	if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/commits/%s", apiPrefix, project, repo, commitID), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetLatestRepositoryCommit 返回 ref 上修改了 path 的最新提交
+func (c *Client) GetLatestRepositoryCommit(project, repo, path, ref string) (*Commit, error) {
+	result := &struct {
+		page
+		Values []*Commit `json:"values"`
+	}{}
+	params := map[string]string{"until": ref}
+	if path != "" {
+		params["path"] = path
+	}
+	if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/commits", apiPrefix, project, repo), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(0, params))); err != nil {
+		return nil, err
+	}
+	if len(result.Values) == 0 {
+		return nil, fmt.Errorf("no commit found in %s/%s@%s:%s", project, repo, ref, path)
+	}
+	return result.Values[0], nil
+}
+
+// ListChangedFiles 返回 from 相对 to 修改的文件，to 为空时返回提交 from 本身修改的文件
+func (c *Client) ListChangedFiles(project, repo, from, to string) ([]string, error) {
+	url := fmt.Sprintf("%s/projects/%s/repos/%s/compare/changes", apiPrefix, project, repo)
+	params := map[string]string{"from": from, "to": to}
+	if to == "" {
+		url = fmt.Sprintf("%s/projects/%s/repos/%s/commits/%s/changes", apiPrefix, project, repo, from)
+		params = nil
+	}
+
+	var res []string
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*browseChild `json:"values"`
+		}{}
+		if _, err := c.Get(url, httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, params))); err != nil {
+			return nil, err
+		}
+		for _, v := range result.Values {
+			res = append(res, v.Path.ToString)
+		}
+		return &result.page, nil
+	})
+	return res, err
+}
+
+// ListTree 列出目录下的文件和子目录
+func (c *Client) ListTree(project, repo, dir, ref string) ([]*TreeNode, error) {
+	var res []*TreeNode
+	err := list(func(start int) (*page, error) {
+		result := &browseResult{}
+		params := map[string]string{"at": ref}
+		if _, err := c.Get(c.filePath("browse", project, repo, dir), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, params))); err != nil {
+			return nil, err
+		}
+		for _, v := range result.Children.Values {
+			res = append(res, &TreeNode{
+				Name:  v.Path.Name,
+				Path:  path.Join(dir, v.Path.ToString),
+				IsDir: v.Type == "DIRECTORY",
+				Size:  v.Size,
+			})
+		}
+		return &result.Children.page, nil
+	})
+	return res, err
+}
+
+func (c *Client) GetFileContent(project, repo, path, ref string) ([]byte, error) {
+	res, err := c.Get(c.filePath("raw", project, repo, path), httpclient.SetQueryParams(map[string]string{"at": ref}))
+	if err != nil {
+		return nil, err
+	}
+	return res.Body(), nil
+}
+
+// GetYAMLContents recursively gets all yaml contents under the given path. if split is true, manifests in the same file
+// will be split to separated ones.
+func (c *Client) GetYAMLContents(project, repo, path, ref string, isDir, split bool) ([]string, error) {
+	var res []string
+	if !isDir {
+		if !(strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")) {
+			return nil, nil
+		}
+
+		ct, err := c.GetFileContent(project, repo, path, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		content := string(ct)
+		if split {
+			res = util.SplitManifests(content)
+		} else {
+			res = []string{content}
+		}
+
+		return res, nil
+	}
+
+	treeNodes, err := c.ListTree(project, repo, path, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tn := range treeNodes {
+		r, err := c.GetYAMLContents(project, repo, tn.Path, ref, tn.IsDir, split)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, r...)
+	}
+
+	return res, nil
+}
+
+// GetTreeContents recursively gets all file contents under the given path, and writes to an in-memory file system.
+func (c *Client) GetTreeContents(project, repo, path, ref string) (afero.Fs, error) {
+	fs := afero.NewMemMapFs()
+	err := c.getTreeContents(project, repo, ref, path, path, true, fs)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+func (c *Client) getTreeContents(project, repo, ref, base, path string, isDir bool, fs afero.Fs) error {
+	if !isDir {
+		ct, err := c.GetFileContent(project, repo, path, ref)
+		if err != nil {
+			return err
+		}
+
+		return afero.WriteFile(fs, fsutil.ShortenFileBase(base, path), ct, 0644)
+	}
+
+	treeNodes, err := c.ListTree(project, repo, path, ref)
+	if err != nil {
+		return err
+	}
+
+	for _, tn := range treeNodes {
+		if err = c.getTreeContents(project, repo, ref, base, tn.Path, tn.IsDir, fs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) filePath(kind, project, repo, p string) string {
+	var segments []string
+	for _, s := range strings.Split(strings.Trim(p, "/"), "/") {
+		if s != "" {
+			segments = append(segments, url.PathEscape(s))
+		}
+	}
+	return fmt.Sprintf("%s/projects/%s/repos/%s/%s/%s", apiPrefix, project, repo, kind, strings.Join(segments, "/"))
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type Hook struct {
+	ID            int               `json:"id,omitempty"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Active        bool              `json:"active"`
+	Events        []EventType       `json:"events"`
+	Configuration map[string]string `json:"configuration,omitempty"`
+}
+
+func (c *Client) ListWebhooks(project, repo string) ([]*Hook, error) {
+	var res []*Hook
+	err := list(func(start int) (*page, error) {
+		result := &struct {
+			page
+			Values []*Hook `json:"values"`
+		}{}
+		if _, err := c.Get(fmt.Sprintf("%s/projects/%s/repos/%s/webhooks", apiPrefix, project, repo), httpclient.SetResult(result), httpclient.SetQueryParams(pageParams(start, nil))); err != nil {
+			return nil, err
+		}
+		res = append(res, result.Values...)
+		return &result.page, nil
+	})
+	return res, err
+}
+
+// AddWebhook 创建订阅推送和 PR 事件的 webhook，secret 用于对请求体签名
+func (c *Client) AddWebhook(project, repo, name, url, secret string) (*Hook, error) {
+	hook := &Hook{
+		Name:   name,
+		URL:    url,
+		Active: true,
+		Events: []EventType{EventTypeRefsChanged, EventTypePROpened, EventTypePRFromRefUpdated},
+	}
+	if secret != "" {
+		hook.Configuration = map[string]string{"secret": secret}
+	}
+
+	res := &Hook{}
+	if _, err := c.Post(fmt.Sprintf("%s/projects/%s/repos/%s/webhooks", apiPrefix, project, repo), httpclient.SetBody(hook), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteWebhook(project, repo string, id int) error {
+	_, err := c.Delete(fmt.Sprintf("%s/projects/%s/repos/%s/webhooks/%d", apiPrefix, project, repo, id))
+	return err
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// 内置时区数据，镜像中没有 tzdata 时也能解析 IANA 时区
+	_ "time/tzdata"
+
+	"github.com/rfyiamcool/cronlib"
+)
+
+// Schedule 带时区的 cron 表达式
+type Schedule struct {
+	// spec 补全秒字段后的 6 位表达式
+	spec     string
+	runner   cronlib.TimeRunner
+	location *time.Location
+}
+
+// Parse 解析 5 位（分 时 日 月 周）或 6 位（秒 分 时 日 月 周）的 cron 表达式
+// timeZone 为 IANA 时区名称，如 Asia/Shanghai，为空时使用服务所在时区
+func Parse(expr, timeZone string) (*Schedule, error) {
+	location := time.Local
+	if timeZone != "" {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", timeZone)
+		}
+		location = loc
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression %q must have 5 or 6 fields, found %d", expr, len(fields))
+	}
+
+	spec := strings.Join(fields, " ")
+	runner, err := cronlib.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+
+	s := &Schedule{spec: spec, runner: runner, location: location}
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never fires", expr)
+	}
+
+	return s, nil
+}
+
+// Spec 返回 6 位表达式
+func (s *Schedule) Spec() string {
+	return s.spec
+}
+
+// Location 返回表达式所在的时区
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Next 返回 t 之后的下一次触发时间，五年内不会触发时返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.runner.Next(t.In(s.location))
+}
+
+// NextN 返回 t 之后的 n 次触发时间
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for i := 0; i < n; i++ {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// run 按表达式所在时区等待到下一次触发时间后执行 f，直到 ctx 取消
+func (s *Schedule) run(ctx context.Context, f func()) {
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			// 定时器与取消同时就绪时 select 随机选择，执行前再确认一次
+			if ctx.Err() != nil {
+				return
+			}
+			f()
+		}
+	}
+}
+
+// Scheduler 按表达式自身的时区调度任务，cronlib 只能按服务所在时区计算触发时间
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]context.CancelFunc
+}
+
+// NewScheduler 创建调度器，任务注册后立即开始调度
+func NewScheduler() *Scheduler {
+	return &Scheduler{jobs: make(map[string]context.CancelFunc)}
+}
+
+// Register 注册任务，id 已存在时替换原有任务
+func (s *Scheduler) Register(id string, schedule *Schedule, f func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	if stop, ok := s.jobs[id]; ok {
+		stop()
+	}
+	s.jobs[id] = cancel
+	s.mu.Unlock()
+
+	go schedule.run(ctx, f)
+}
+
+// Stop 停止并删除任务，id 不存在时忽略
+func (s *Scheduler) Stop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stop, ok := s.jobs[id]; ok {
+		stop()
+		delete(s.jobs, id)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	ast := require.New(t)
+
+	_, err := Parse("* * * *", "")
+	ast.EqualError(err, `cron expression "* * * *" must have 5 or 6 fields, found 4`)
+
+	_, err = Parse("61 * * * *", "")
+	ast.Error(err)
+
+	_, err = Parse("0 9 * * *", "Mars/Olympus")
+	ast.EqualError(err, `unknown time zone "Mars/Olympus"`)
+
+	_, err = Parse("0 0 30 2 *", "")
+	ast.EqualError(err, `cron expression "0 0 30 2 *" never fires`)
+
+	s, err := Parse("30 9 * * 1-5", "Asia/Shanghai")
+	ast.Nil(err)
+	ast.Equal("0 30 9 * * 1-5", s.Spec())
+}
+
+func TestSchedule_NextN(t *testing.T) {
+	ast := require.New(t)
+
+	s, err := Parse("30 9 * * 1-5", "Asia/Shanghai")
+	ast.Nil(err)
+
+	// 2021-09-03 是周五
+	from := time.Date(2021, 9, 3, 0, 0, 0, 0, time.UTC)
+	times := s.NextN(from, 3)
+	ast.Len(times, 3)
+	ast.Equal("2021-09-03T09:30:00+08:00", times[0].Format(time.RFC3339))
+	ast.Equal("2021-09-06T09:30:00+08:00", times[1].Format(time.RFC3339))
+	ast.Equal("2021-09-07T09:30:00+08:00", times[2].Format(time.RFC3339))
+
+	// 触发时间点本身不算，从触发时间开始计算下一次
+	ast.Equal(times[1], s.Next(times[0]))
+	ast.Equal(times[0], s.Next(times[0].Add(-time.Second)))
+}
+
+func TestSchedule_Seconds(t *testing.T) {
+	ast := require.New(t)
+
+	s, err := Parse("15 */10 * * * *", "UTC")
+	ast.Nil(err)
+
+	next := s.Next(time.Date(2021, 9, 3, 2, 0, 0, 0, time.UTC))
+	ast.Equal("2021-09-03T02:00:15Z", next.Format(time.RFC3339))
+}
+
+func TestScheduler(t *testing.T) {
+	ast := require.New(t)
+
+	s, err := Parse("* * * * * *", "America/New_York")
+	ast.Nil(err)
+
+	fired := make(chan struct{}, 10)
+	scheduler := NewScheduler()
+	scheduler.Register("job", s, func() { fired <- struct{}{} })
+
+	select {
+	case <-fired:
+	case <-time.After(3 * time.Second):
+		ast.Fail("job was not fired")
+	}
+
+	scheduler.Stop("job")
+	// Stop 之前可能已经进入执行，等待其结束后清空
+	time.Sleep(100 * time.Millisecond)
+	for len(fired) > 0 {
+		<-fired
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	ast.Len(fired, 0)
+
+	scheduler.Stop("missing")
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	fsutil "github.com/koderover/zadig/pkg/util/fs"
+)
+
+const (
+	// DefaultKeyID is the ID of the key in etc/encryption/aes, it is used when no other key is configured.
+	DefaultKeyID = "default"
+
+	secretPrefix   = "enc:"
+	keyDir         = "etc/encryption/keys"
+	primaryKeyFile = "etc/encryption/primary"
+)
+
+// Keyring holds all keys which are able to decrypt stored secrets, new secrets are always encrypted
+// with the primary key. Encrypted secrets are in the form of `enc:<key id>:<cipher text>` so that the key
+// used is always known and keys can be rotated without a downtime.
+type Keyring struct {
+	primary string
+	keys    map[string]*Aes
+}
+
+// NewKeyring creates a Keyring from key ID to key.
+func NewKeyring(primary string, keys map[string]string) (*Keyring, error) {
+	kr := &Keyring{primary: primary, keys: make(map[string]*Aes, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		a, err := NewAes(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %v", id, err)
+		}
+		kr.keys[id] = a
+	}
+	if _, ok := kr.keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %s is not found", primary)
+	}
+
+	return kr, nil
+}
+
+// PrimaryKeyID returns the ID of the key which is used to encrypt new secrets.
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// HasKey returns true if the key with the given ID exists.
+func (k *Keyring) HasKey(id string) bool {
+	_, ok := k.keys[id]
+	return ok
+}
+
+// Encrypt encrypts the plaintext with the primary key. Empty string is kept as is.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	return k.EncryptWithKey(k.primary, plaintext)
+}
+
+// EncryptWithKey encrypts the plaintext with the given key. Empty string is kept as is.
+func (k *Keyring) EncryptWithKey(id, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	a, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("key %s is not found", id)
+	}
+
+	cipherText, err := a.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + id + ":" + cipherText, nil
+}
+
+// Decrypt decrypts a secret encrypted by Encrypt, secrets which are not encrypted (saved before the encryption
+// is introduced) are returned as is.
+func (k *Keyring) Decrypt(secret string) (string, error) {
+	id, cipherText, ok := parseSecret(secret)
+	if !ok {
+		return secret, nil
+	}
+	a, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("key %s is not found", id)
+	}
+
+	return a.Decrypt(cipherText)
+}
+
+// Reencrypt encrypts the secret with the given key, it is a no-op if the secret is already encrypted by this key.
+func (k *Keyring) Reencrypt(id, secret string) (string, bool, error) {
+	if SecretKeyID(secret) == id || secret == "" {
+		return secret, false, nil
+	}
+	plaintext, err := k.Decrypt(secret)
+	if err != nil {
+		return "", false, err
+	}
+	res, err := k.EncryptWithKey(id, plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return res, true, nil
+}
+
+// SecretKeyID returns the ID of the key which encrypts the secret, empty means the secret is not encrypted.
+func SecretKeyID(secret string) string {
+	id, _, _ := parseSecret(secret)
+	return id
+}
+
+func parseSecret(secret string) (string, string, bool) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(secret, secretPrefix), ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+var defaultKeyring *Keyring
+var keyringOnce sync.Once
+
+// DefaultKeyring loads keys from the mounted secret:
+//   - etc/encryption/aes is the key with ID `default`
+//   - every file in etc/encryption/keys is a key whose ID is the file name
+//   - etc/encryption/primary is the ID of the primary key, `default` is used if it does not exist
+func DefaultKeyring() *Keyring {
+	keyringOnce.Do(func() {
+		keys := map[string]string{DefaultKeyID: getAESKey()}
+
+		entries, err := fs.ReadDir(fsutil.Root(), keyDir)
+		if err == nil {
+			for _, entry := range entries {
+				// files with a leading dot are created by kubernetes when a secret is mounted
+				if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				key, err := fs.ReadFile(fsutil.Root(), path.Join(keyDir, entry.Name()))
+				if err != nil {
+					panic(fmt.Sprintf("Failed to read encryption key %s from secret", entry.Name()))
+				}
+				keys[entry.Name()] = strings.TrimSpace(string(key))
+			}
+		}
+
+		primary := DefaultKeyID
+		if b, err := fs.ReadFile(fsutil.Root(), primaryKeyFile); err == nil && strings.TrimSpace(string(b)) != "" {
+			primary = strings.TrimSpace(string(b))
+		}
+
+		defaultKeyring, err = NewKeyring(primary, keys)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to load encryption keys: %v", err))
+		}
+	})
+
+	return defaultKeyring
+}
+
+// EncryptSecrets encrypts the given fields in place with the primary key of the default keyring.
+func EncryptSecrets(fields ...*string) error {
+	kr := DefaultKeyring()
+	for _, f := range fields {
+		if SecretKeyID(*f) != "" {
+			continue
+		}
+		res, err := kr.Encrypt(*f)
+		if err != nil {
+			return err
+		}
+		*f = res
+	}
+
+	return nil
+}
+
+// DecryptSecrets decrypts the given fields in place with the default keyring.
+func DecryptSecrets(fields ...*string) error {
+	kr := DefaultKeyring()
+	for _, f := range fields {
+		res, err := kr.Decrypt(*f)
+		if err != nil {
+			return err
+		}
+		*f = res
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring_Crypt(t *testing.T) {
+	ast := require.New(t)
+
+	kr, err := NewKeyring("v2", map[string]string{
+		"v1": "aaaaaaaaaaaaaaaa",
+		"v2": "bbbbbbbbbbbbbbbb",
+	})
+	ast.Nil(err)
+
+	encrypted, err := kr.Encrypt("hello")
+	ast.Nil(err)
+	ast.Equal("v2", SecretKeyID(encrypted))
+
+	decrypted, err := kr.Decrypt(encrypted)
+	ast.Nil(err)
+	ast.Equal("hello", decrypted)
+
+	old, err := kr.EncryptWithKey("v1", "hello")
+	ast.Nil(err)
+	decrypted, err = kr.Decrypt(old)
+	ast.Nil(err)
+	ast.Equal("hello", decrypted)
+
+	empty, err := kr.Encrypt("")
+	ast.Nil(err)
+	ast.Equal("", empty)
+}
+
+func TestKeyring_DecryptPlaintext(t *testing.T) {
+	ast := require.New(t)
+
+	kr, err := NewKeyring("v1", map[string]string{"v1": "aaaaaaaaaaaaaaaa"})
+	ast.Nil(err)
+
+	for _, s := range []string{"password", "enc:", "enc:v1:not-hex", "enc::abcd"} {
+		ast.Equal("", SecretKeyID(s))
+		decrypted, err := kr.Decrypt(s)
+		ast.Nil(err)
+		ast.Equal(s, decrypted)
+	}
+
+	_, err = kr.Decrypt("enc:v9:abcd")
+	ast.NotNil(err)
+}
+
+func TestKeyring_Reencrypt(t *testing.T) {
+	ast := require.New(t)
+
+	kr, err := NewKeyring("v1", map[string]string{
+		"v1": "aaaaaaaaaaaaaaaa",
+		"v2": "bbbbbbbbbbbbbbbb",
+	})
+	ast.Nil(err)
+
+	old, err := kr.Encrypt("hello")
+	ast.Nil(err)
+
+	for _, s := range []string{old, "hello"} {
+		res, changed, err := kr.Reencrypt("v2", s)
+		ast.Nil(err)
+		ast.True(changed)
+		ast.Equal("v2", SecretKeyID(res))
+
+		decrypted, err := kr.Decrypt(res)
+		ast.Nil(err)
+		ast.Equal("hello", decrypted)
+
+		_, changed, err = kr.Reencrypt("v2", res)
+		ast.Nil(err)
+		ast.False(changed)
+	}
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	ast := require.New(t)
+
+	_, err := NewKeyring("v2", map[string]string{"v1": "aaaaaaaaaaaaaaaa"})
+	ast.NotNil(err)
+
+	_, err = NewKeyring("v1", map[string]string{"v1": "short"})
+	ast.NotNil(err)
+
+	_, err = NewKeyring("a:b", map[string]string{"a:b": "aaaaaaaaaaaaaaaa"})
+	ast.NotNil(err)
+}
@@ -273,6 +273,12 @@ var (
 	ErrCountTasks = NewHTTPError(6167, "工作流计数失败")
 
 	ErrCreateTaskFailed = NewHTTPError(6168, "创建工作流任务失败")
+	// ErrApproveTask ...
+	ErrApproveTask = NewHTTPError(6169, "审批工作流任务失败")
+	// ErrGetTaskApproval ...
+	ErrGetTaskApproval = NewHTTPError(6170, "获取工作流任务审批结果失败")
+	// ErrGetTaskSecurityReport ...
+	ErrGetTaskSecurityReport = NewHTTPError(6171, "获取工作流任务安全扫描报告失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// Keystore APIs Range: 6180 - 6189
@@ -455,6 +461,10 @@ var (
 	ErrDeleteTestModule = NewHTTPError(6533, "删除测试模块失败")
 	// ErrGetTestReport ...
 	ErrGetTestReport = NewHTTPError(6534, "获取html测试报告失败")
+	// ErrListTestCaseHistory ...
+	ErrListTestCaseHistory = NewHTTPError(6535, "获取测试用例历史结果失败")
+	// ErrListTestCoverage ...
+	ErrListTestCoverage = NewHTTPError(6536, "获取测试覆盖率失败")
 
 	// Workflow APIs Range: 6540 - 6550
 	//-----------------------------------------------------------------------------------------------
@@ -680,4 +690,27 @@ var (
 	ErrUpdateExternalLink = NewHTTPError(6842, "更新链接失败")
 	ErrDeleteExternalLink = NewHTTPError(6843, "删除链接失败")
 	ErrListExternalLink   = NewHTTPError(6844, "获取链接列表失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// env snapshot Error Range: 6850 - 6859
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateEnvSnapshot  = NewHTTPError(6851, "创建环境快照失败")
+	ErrListEnvSnapshots   = NewHTTPError(6852, "获取环境快照列表失败")
+	ErrGetEnvSnapshot     = NewHTTPError(6853, "获取环境快照失败")
+	ErrDeleteEnvSnapshot  = NewHTTPError(6854, "删除环境快照失败")
+	ErrRestoreEnvSnapshot = NewHTTPError(6855, "恢复环境快照失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// audit log Error Range: 6860 - 6869
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateAuditLog = NewHTTPError(6861, "添加审计日志失败")
+	ErrListAuditLog   = NewHTTPError(6862, "获取审计日志列表失败")
+	ErrExportAuditLog = NewHTTPError(6863, "导出审计日志失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// pod exec session Error Range: 6870 - 6879
+	//-----------------------------------------------------------------------------------------------
+	ErrCreatePodExecSession = NewHTTPError(6871, "保存终端录像失败")
+	ErrListPodExecSessions  = NewHTTPError(6872, "获取终端录像列表失败")
+	ErrGetPodExecSession    = NewHTTPError(6873, "获取终端录像失败")
 )
@@ -72,3 +72,19 @@ func (c *Client) CreateCommitDiscussion(owner, repo, commitHash, comment string)
 	_, err := wrap(c.Discussions.CreateCommitDiscussion(generateProjectName(owner, repo), commitHash, args))
 	return err
 }
+
+func (c *Client) CreateMergeRequestNote(owner, repo string, mrID int, body string) (*gitlab.Note, error) {
+	args := &gitlab.CreateMergeRequestNoteOptions{Body: &body}
+	note, err := wrap(c.Notes.CreateMergeRequestNote(generateProjectName(owner, repo), mrID, args))
+	if n, ok := note.(*gitlab.Note); ok {
+		return n, err
+	}
+
+	return nil, err
+}
+
+func (c *Client) UpdateMergeRequestNote(owner, repo string, mrID, noteID int, body string) error {
+	args := &gitlab.UpdateMergeRequestNoteOptions{Body: &body}
+	_, err := wrap(c.Notes.UpdateMergeRequestNote(generateProjectName(owner, repo), mrID, noteID, args))
+	return err
+}
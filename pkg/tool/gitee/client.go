@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitee
+
+import (
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	// DefaultAddress gitee 公有云地址
+	DefaultAddress = "https://gitee.com"
+
+	apiPrefix = "/api/v5"
+	perPage   = 100
+)
+
+// Client gitee open api v5 客户端，私有化部署的 gitee 通过 address 指定
+type Client struct {
+	*httpclient.Client
+}
+
+func NewClient(address, accessToken string) *Client {
+	if address == "" {
+		address = DefaultAddress
+	}
+	c := httpclient.New(
+		httpclient.SetHostURL(strings.TrimSuffix(address, "/") + apiPrefix),
+	)
+	c.SetQueryParam("access_token", accessToken)
+
+	return &Client{Client: c}
+}
+
+type User struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type Namespace struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type Organization struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+type Project struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Path          string     `json:"path"`
+	FullName      string     `json:"full_name"`
+	Description   string     `json:"description"`
+	DefaultBranch string     `json:"default_branch"`
+	Namespace     *Namespace `json:"namespace"`
+	Owner         *User      `json:"owner"`
+}
+
+type CommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Date  string `json:"date"`
+}
+
+type CommitDetail struct {
+	Message string        `json:"message"`
+	Author  *CommitAuthor `json:"author"`
+}
+
+type Commit struct {
+	SHA    string        `json:"sha"`
+	Commit *CommitDetail `json:"commit"`
+}
+
+// AuthorName 返回提交作者
+func (c *Commit) AuthorName() string {
+	if c.Commit == nil || c.Commit.Author == nil {
+		return ""
+	}
+	return c.Commit.Author.Name
+}
+
+// Message 返回提交信息
+func (c *Commit) Message() string {
+	if c.Commit == nil {
+		return ""
+	}
+	return c.Commit.Message
+}
+
+type Branch struct {
+	Name      string  `json:"name"`
+	Protected bool    `json:"protected"`
+	Commit    *Commit `json:"commit"`
+}
+
+type Tag struct {
+	Name    string  `json:"name"`
+	Message string  `json:"message"`
+	Commit  *Commit `json:"commit"`
+}
+
+type Content struct {
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Size     int    `json:"size"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Content  string `json:"content"`
+	SHA      string `json:"sha"`
+}
+
+// IsDir 是否为目录
+func (c *Content) IsDir() bool {
+	return c.Type == "dir"
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// EventType represents a Gitee event type.
+type EventType string
+
+// List of available event types.
+const (
+	EventTypeMergeRequest EventType = "Merge Request Hook"
+	EventTypePush         EventType = "Push Hook"
+	EventTypeTagPush      EventType = "Tag Push Hook"
+)
+
+const (
+	eventTypeHeader = "X-Gitee-Event"
+	tokenHeader     = "X-Gitee-Token"
+	timestampHeader = "X-Gitee-Timestamp"
+)
+
+// Merge request actions
+const (
+	MergeRequestActionOpen   = "open"
+	MergeRequestActionUpdate = "update"
+)
+
+// HookEventType returns the event type for the given request.
+func HookEventType(r *http.Request) EventType {
+	return EventType(r.Header.Get(eventTypeHeader))
+}
+
+// ValidateToken 校验 webhook 的密码或签名。
+// 使用密码时 X-Gitee-Token 即为密码；使用签名密钥时为 base64(HmacSHA256(timestamp + "\n" + secret))
+func ValidateToken(r *http.Request, secret string) error {
+	if secret == "" {
+		return nil
+	}
+
+	token := r.Header.Get(tokenHeader)
+	if hmac.Equal([]byte(token), []byte(secret)) {
+		return nil
+	}
+
+	timestamp := r.Header.Get(timestampHeader)
+	if timestamp != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "\n" + secret))
+		if hmac.Equal([]byte(token), []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("token is illegal")
+}
+
+func ParseHook(eventType EventType, payload []byte) (event interface{}, err error) {
+	switch eventType {
+	case EventTypeMergeRequest:
+		event = &MergeEvent{}
+	case EventTypePush, EventTypeTagPush:
+		event = &PushEvent{}
+	default:
+		return nil, fmt.Errorf("unexpected event type: %s", eventType)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+type WebhookRepository struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Path              string `json:"path"`
+	FullName          string `json:"full_name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Namespace         string `json:"namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	CloneURL          string `json:"clone_url"`
+	HTMLURL           string `json:"html_url"`
+}
+
+type WebhookUser struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Login    string `json:"login"`
+	Email    string `json:"email"`
+}
+
+type PushEventCommit struct {
+	ID        string       `json:"id"`
+	Message   string       `json:"message"`
+	Timestamp string       `json:"timestamp"`
+	URL       string       `json:"url"`
+	Author    *WebhookUser `json:"author"`
+	Added     []string     `json:"added"`
+	Removed   []string     `json:"removed"`
+	Modified  []string     `json:"modified"`
+}
+
+type PushEvent struct {
+	Ref        string             `json:"ref"`
+	Before     string             `json:"before"`
+	After      string             `json:"after"`
+	Created    bool               `json:"created"`
+	Deleted    bool               `json:"deleted"`
+	Commits    []*PushEventCommit `json:"commits"`
+	HeadCommit *PushEventCommit   `json:"head_commit"`
+	Repository *WebhookRepository `json:"repository"`
+	Pusher     *WebhookUser       `json:"pusher"`
+	Sender     *WebhookUser       `json:"sender"`
+}
+
+type MergeEventBranch struct {
+	Ref  string             `json:"ref"`
+	SHA  string             `json:"sha"`
+	Repo *WebhookRepository `json:"repo"`
+	User *WebhookUser       `json:"user"`
+}
+
+type MergeEventPullRequest struct {
+	ID     int               `json:"id"`
+	Number int               `json:"number"`
+	State  string            `json:"state"`
+	Title  string            `json:"title"`
+	Body   string            `json:"body"`
+	Head   *MergeEventBranch `json:"head"`
+	Base   *MergeEventBranch `json:"base"`
+	User   *WebhookUser      `json:"user"`
+}
+
+type MergeEvent struct {
+	Action       string                 `json:"action"`
+	ActionDesc   string                 `json:"action_desc"`
+	PullRequest  *MergeEventPullRequest `json:"pull_request"`
+	Repository   *WebhookRepository     `json:"repository"`
+	Sender       *WebhookUser           `json:"sender"`
+	SourceBranch string                 `json:"source_branch"`
+	TargetBranch string                 `json:"target_branch"`
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateToken(t *testing.T) {
+	assert := require.New(t)
+
+	r, _ := http.NewRequest(http.MethodPost, "/api/webhook", nil)
+	assert.NoError(ValidateToken(r, ""))
+	assert.Error(ValidateToken(r, "secret"))
+
+	r.Header.Set(tokenHeader, "secret")
+	assert.NoError(ValidateToken(r, "secret"))
+	assert.Error(ValidateToken(r, "another"))
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1600000000000\nsecret"))
+	r.Header.Set(timestampHeader, "1600000000000")
+	r.Header.Set(tokenHeader, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	assert.NoError(ValidateToken(r, "secret"))
+	assert.Error(ValidateToken(r, "another"))
+}
+
+func TestParseHook(t *testing.T) {
+	assert := require.New(t)
+
+	push := []byte(`{
+  "ref": "refs/heads/master",
+  "repository": {"path_with_namespace": "koderover/zadig"},
+  "commits": [{"id": "abc123", "modified": ["README.md"]}]
+}`)
+	event, err := ParseHook(EventTypePush, push)
+	assert.NoError(err)
+
+	pushEvent, ok := event.(*PushEvent)
+	assert.True(ok)
+	assert.Equal("refs/heads/master", pushEvent.Ref)
+	assert.Equal("koderover/zadig", pushEvent.Repository.PathWithNamespace)
+	assert.Equal([]string{"README.md"}, pushEvent.Commits[0].Modified)
+
+	tag := []byte(`{"ref": "refs/tags/v1.0.0", "repository": {"path_with_namespace": "koderover/zadig"}}`)
+	event, err = ParseHook(EventTypeTagPush, tag)
+	assert.NoError(err)
+	pushEvent, ok = event.(*PushEvent)
+	assert.True(ok)
+	assert.Equal("refs/tags/v1.0.0", pushEvent.Ref)
+
+	merge := []byte(`{
+  "action": "open",
+  "pull_request": {"number": 3, "head": {"sha": "abc123"}},
+  "repository": {"path_with_namespace": "koderover/zadig"},
+  "target_branch": "master"
+}`)
+	event, err = ParseHook(EventTypeMergeRequest, merge)
+	assert.NoError(err)
+
+	mergeEvent, ok := event.(*MergeEvent)
+	assert.True(ok)
+	assert.Equal(MergeRequestActionOpen, mergeEvent.Action)
+	assert.Equal(3, mergeEvent.PullRequest.Number)
+	assert.Equal("abc123", mergeEvent.PullRequest.Head.SHA)
+	assert.Equal("master", mergeEvent.TargetBranch)
+
+	_, err = ParseHook("Issue Hook", merge)
+	assert.Error(err)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitee
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type PullRequestRef struct {
+	Ref  string   `json:"ref"`
+	SHA  string   `json:"sha"`
+	Repo *Project `json:"repo"`
+}
+
+type PullRequest struct {
+	ID        int             `json:"id"`
+	Number    int             `json:"number"`
+	Title     string          `json:"title"`
+	State     string          `json:"state"`
+	Head      *PullRequestRef `json:"head"`
+	Base      *PullRequestRef `json:"base"`
+	User      *User           `json:"user"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type PullRequestFile struct {
+	SHA       string `json:"sha"`
+	Filename  string `json:"filename"`
+	Status    string `json:"status"`
+	Additions string `json:"additions"`
+	Deletions string `json:"deletions"`
+}
+
+type Comment struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+}
+
+// ListOpenedPullRequests 列出打开状态的 PR，targetBranch 不为空时只返回合入该分支的 PR
+func (c *Client) ListOpenedPullRequests(owner, repo, targetBranch string) ([]*PullRequest, error) {
+	var res []*PullRequest
+	params := map[string]string{
+		"state":    "open",
+		"per_page": strconv.Itoa(perPage),
+	}
+	if targetBranch != "" {
+		params["base"] = targetBranch
+	}
+	_, err := c.Get(fmt.Sprintf("/repos/%s/%s/pulls", owner, repo), httpclient.SetResult(&res), httpclient.SetQueryParams(params))
+	return res, err
+}
+
+func (c *Client) GetPullRequest(owner, repo string, number int) (*PullRequest, error) {
+	res := &PullRequest{}
+	if _, err := c.Get(fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, number), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListPullRequestFiles(owner, repo string, number int) ([]*PullRequestFile, error) {
+	var res []*PullRequestFile
+	_, err := c.Get(fmt.Sprintf("/repos/%s/%s/pulls/%d/files", owner, repo, number), httpclient.SetResult(&res))
+	return res, err
+}
+
+func (c *Client) CreatePullRequestComment(owner, repo string, number int, body string) (*Comment, error) {
+	res := &Comment{}
+	_, err := c.Post(fmt.Sprintf("/repos/%s/%s/pulls/%d/comments", owner, repo, number),
+		httpclient.SetBody(map[string]string{"body": body}), httpclient.SetResult(res))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) UpdatePullRequestComment(owner, repo string, commentID int, body string) error {
+	_, err := c.Patch(fmt.Sprintf("/repos/%s/%s/pulls/comments/%d", owner, repo, commentID),
+		httpclient.SetBody(map[string]string{"body": body}))
+	return err
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitee
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/27149chen/afero"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/util"
+	fsutil "github.com/koderover/zadig/pkg/util/fs"
+)
+
+func (c *Client) GetAuthenticatedUser() (*User, error) {
+	user := &User{}
+	if _, err := c.Get("/user", httpclient.SetResult(user)); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) ListOrganizations() ([]*Organization, error) {
+	var res []*Organization
+	_, err := c.Get("/user/orgs", httpclient.SetResult(&res), httpclient.SetQueryParams(map[string]string{
+		"per_page": strconv.Itoa(perPage),
+	}))
+	return res, err
+}
+
+// ListProjects 列出当前用户有权限的仓库，namespace 不为空时只返回该命名空间下的仓库
+func (c *Client) ListProjects(namespace, keyword string) ([]*Project, error) {
+	var res []*Project
+	for page := 1; ; page++ {
+		var projects []*Project
+		params := map[string]string{
+			"page":     strconv.Itoa(page),
+			"per_page": strconv.Itoa(perPage),
+			"sort":     "full_name",
+		}
+		if keyword != "" {
+			params["q"] = keyword
+		}
+		if _, err := c.Get("/user/repos", httpclient.SetResult(&projects), httpclient.SetQueryParams(params)); err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			if namespace != "" && (p.Namespace == nil || p.Namespace.Path != namespace) {
+				continue
+			}
+			res = append(res, p)
+		}
+		if len(projects) < perPage {
+			return res, nil
+		}
+	}
+}
+
+func (c *Client) ListBranches(owner, repo string) ([]*Branch, error) {
+	var res []*Branch
+	_, err := c.Get(fmt.Sprintf("/repos/%s/%s/branches", owner, repo), httpclient.SetResult(&res))
+	return res, err
+}
+
+func (c *Client) GetBranch(owner, repo, branch string) (*Branch, error) {
+	res := &Branch{}
+	if _, err := c.Get(fmt.Sprintf("/repos/%s/%s/branches/%s", owner, repo, url.PathEscape(branch)), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListTags(owner, repo string) ([]*Tag, error) {
+	var res []*Tag
+	_, err := c.Get(fmt.Sprintf("/repos/%s/%s/tags", owner, repo), httpclient.SetResult(&res))
+	return res, err
+}
+
+// GetLatestRepositoryCommit 返回分支上修改了 path 的最新提交
+func (c *Client) GetLatestRepositoryCommit(owner, repo, path, branch string) (*Commit, error) {
+	var commits []*Commit
+	params := map[string]string{
+		"sha":      branch,
+		"page":     "1",
+		"per_page": "1",
+	}
+	if path != "" {
+		params["path"] = path
+	}
+	if _, err := c.Get(fmt.Sprintf("/repos/%s/%s/commits", owner, repo), httpclient.SetResult(&commits), httpclient.SetQueryParams(params)); err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commit found in %s/%s@%s:%s", owner, repo, branch, path)
+	}
+	return commits[0], nil
+}
+
+// ListTree 列出目录下的文件和子目录
+func (c *Client) ListTree(owner, repo, path, branch string) ([]*Content, error) {
+	res, err := c.Get(c.contentsURL(owner, repo, path), httpclient.SetQueryParams(map[string]string{"ref": branch}))
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []*Content
+	if err = json.Unmarshal(res.Body(), &contents); err != nil {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+	return contents, nil
+}
+
+func (c *Client) GetFileContent(owner, repo, path, branch string) ([]byte, error) {
+	content := &Content{}
+	if _, err := c.Get(c.contentsURL(owner, repo, path), httpclient.SetResult(content), httpclient.SetQueryParams(map[string]string{"ref": branch})); err != nil {
+		return nil, err
+	}
+	if content.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	return base64.StdEncoding.DecodeString(content.Content)
+}
+
+// GetYAMLContents recursively gets all yaml contents under the given path. if split is true, manifests in the same file
+// will be split to separated ones.
+func (c *Client) GetYAMLContents(owner, repo, path, branch string, isDir, split bool) ([]string, error) {
+	var res []string
+	if !isDir {
+		if !(strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")) {
+			return nil, nil
+		}
+
+		ct, err := c.GetFileContent(owner, repo, path, branch)
+		if err != nil {
+			return nil, err
+		}
+
+		content := string(ct)
+		if split {
+			res = util.SplitManifests(content)
+		} else {
+			res = []string{content}
+		}
+
+		return res, nil
+	}
+
+	contents, err := c.ListTree(owner, repo, path, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ct := range contents {
+		r, err := c.GetYAMLContents(owner, repo, ct.Path, branch, ct.IsDir(), split)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, r...)
+	}
+
+	return res, nil
+}
+
+// GetTreeContents recursively gets all file contents under the given path, and writes to an in-memory file system.
+func (c *Client) GetTreeContents(owner, repo, path, branch string) (afero.Fs, error) {
+	fs := afero.NewMemMapFs()
+	err := c.getTreeContents(owner, repo, branch, path, path, true, fs)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+func (c *Client) getTreeContents(owner, repo, branch, base, path string, isDir bool, fs afero.Fs) error {
+	if !isDir {
+		ct, err := c.GetFileContent(owner, repo, path, branch)
+		if err != nil {
+			return err
+		}
+
+		return afero.WriteFile(fs, fsutil.ShortenFileBase(base, path), ct, 0644)
+	}
+
+	contents, err := c.ListTree(owner, repo, path, branch)
+	if err != nil {
+		return err
+	}
+
+	for _, ct := range contents {
+		if err = c.getTreeContents(owner, repo, branch, base, ct.Path, ct.IsDir(), fs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) contentsURL(owner, repo, path string) string {
+	return fmt.Sprintf("/repos/%s/%s/contents/%s", owner, repo, strings.TrimPrefix(path, "/"))
+}
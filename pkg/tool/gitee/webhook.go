@@ -0,0 +1,46 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitee
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type Hook struct {
+	ID                  int    `json:"id"`
+	URL                 string `json:"url"`
+	Password            string `json:"password,omitempty"`
+	PushEvents          bool   `json:"push_events"`
+	TagPushEvents       bool   `json:"tag_push_events"`
+	MergeRequestsEvents bool   `json:"merge_requests_events"`
+}
+
+// AddWebhook 创建仓库 webhook，password 会原样放在请求头 X-Gitee-Token 中
+func (c *Client) AddWebhook(owner, repo string, hook *Hook) (*Hook, error) {
+	res := &Hook{}
+	if _, err := c.Post(fmt.Sprintf("/repos/%s/%s/hooks", owner, repo), httpclient.SetBody(hook), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteWebhook(owner, repo string, hookID int) error {
+	_, err := c.Delete(fmt.Sprintf("/repos/%s/%s/hooks/%d", owner, repo, hookID))
+	return err
+}
@@ -0,0 +1,427 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	// OCIScheme is the URL scheme of charts stored in an OCI registry
+	OCIScheme = "oci"
+
+	ociManifestMediaType      = "application/vnd.oci.image.manifest.v1+json"
+	configMediaType           = "application/vnd.cncf.helm.config.v1+json"
+	chartLayerMediaType       = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	legacyChartLayerMediaType = "application/tar+gzip"
+)
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type ociManifest struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Config        *ociDescriptor   `json:"config"`
+	Layers        []*ociDescriptor `json:"layers"`
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ChartRepo is a chart repository which is either a classic HTTP repository serving an index.yaml
+// (ChartMuseum, Harbor chartrepo...) or an OCI registry addressed by an oci:// URL.
+type ChartRepo struct {
+	URL      string
+	Username string
+	Password string
+	// PlainHTTP talks to an OCI registry over http instead of https
+	PlainHTTP bool
+
+	// bearer token of the OCI registry
+	token string
+}
+
+// IsOCI reports whether the given repo URL points to an OCI registry.
+func IsOCI(repoURL string) bool {
+	return strings.HasPrefix(repoURL, OCIScheme+"://")
+}
+
+// ListChartVersions returns all published versions of the chart, the newest first.
+func (r *ChartRepo) ListChartVersions(chartName string) ([]string, error) {
+	var versions repo.ChartVersions
+	if IsOCI(r.URL) {
+		tags, err := r.listTags(chartName)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			// "+" is not allowed in OCI tags, helm pushes it as "_"
+			versions = append(versions, &repo.ChartVersion{Metadata: &chart.Metadata{Version: strings.ReplaceAll(tag, "_", "+")}})
+		}
+	} else {
+		index, err := r.loadIndex()
+		if err != nil {
+			return nil, err
+		}
+		versions = index.Entries[chartName]
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("chart %s not found in %s", chartName, r.URL)
+	}
+
+	sort.Sort(sort.Reverse(versions))
+	res := make([]string, 0, len(versions))
+	for _, v := range versions {
+		res = append(res, v.Version)
+	}
+	return res, nil
+}
+
+// PullChart downloads the chart package (.tgz) of the given version, the latest version is used if version is empty.
+func (r *ChartRepo) PullChart(chartName, version string) ([]byte, error) {
+	if IsOCI(r.URL) {
+		if version == "" {
+			versions, err := r.ListChartVersions(chartName)
+			if err != nil {
+				return nil, err
+			}
+			version = versions[0]
+		}
+		return r.pullOCIChart(chartName, version)
+	}
+
+	index, err := r.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	cv, err := index.Get(chartName, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(cv.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s-%s has no downloadable urls", chartName, cv.Version)
+	}
+	chartURL, err := repo.ResolveReferenceURL(r.URL, cv.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// the index may point to another host such as a CDN, which must not receive the credentials of the repo
+	res, err := r.newClient(r.isRepoHost(chartURL), httpclient.UnsetTimeout()).Get(chartURL, httpclient.SetHeader("Accept", "*/*"))
+	if err != nil {
+		return nil, err
+	}
+	return res.Body(), nil
+}
+
+// ExpandChart extracts the chart package into dir/<chart name>.
+func ExpandChart(data []byte, dir string) error {
+	return chartutil.Expand(dir, bytes.NewReader(data))
+}
+
+// PackageChart loads the chart from path (a chart directory or package), overrides its version and values.yaml
+// if they are given, and saves the package into outDir. It returns the path of the package.
+func PackageChart(chartPath, version string, values []byte, outDir string) (string, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return "", err
+	}
+
+	if version != "" {
+		c.Metadata.Version = version
+	}
+	if values != nil {
+		if c.Values, err = chartutil.ReadValues(values); err != nil {
+			return "", err
+		}
+		replaced := false
+		for _, f := range c.Raw {
+			if f.Name == chartutil.ValuesfileName {
+				f.Data = values
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Raw = append(c.Raw, &chart.File{Name: chartutil.ValuesfileName, Data: values})
+		}
+	}
+
+	return chartutil.Save(c, outDir)
+}
+
+// PushChart uploads the chart package to the repository.
+// HTTP repositories are expected to provide the ChartMuseum upload api, which Harbor chartrepo also implements.
+func (r *ChartRepo) PushChart(data []byte) error {
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if IsOCI(r.URL) {
+		return r.pushOCIChart(c.Metadata, data)
+	}
+
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join("/api", u.Path, "charts")
+
+	fileName := fmt.Sprintf("%s-%s.tgz", c.Name(), c.Metadata.Version)
+	_, err = r.newClient(true, httpclient.UnsetTimeout()).Post(u.String(), func(req *resty.Request) {
+		req.SetFileReader("chart", fileName, bytes.NewReader(data))
+	})
+	return err
+}
+
+func (r *ChartRepo) newClient(basicAuth bool, cfs ...httpclient.ClientFunc) *httpclient.Client {
+	if basicAuth && r.Username != "" {
+		cfs = append(cfs, httpclient.SetBasicAuth(r.Username, r.Password))
+	}
+	return httpclient.New(cfs...)
+}
+
+// isRepoHost reports whether u has the same scheme and host as the repo url.
+func (r *ChartRepo) isRepoHost(u string) bool {
+	repoURL, err := url.Parse(r.URL)
+	if err != nil {
+		return false
+	}
+	target, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return repoURL.Scheme == target.Scheme && repoURL.Host == target.Host
+}
+
+func (r *ChartRepo) loadIndex() (*repo.IndexFile, error) {
+	indexURL := strings.TrimSuffix(r.URL, "/") + "/index.yaml"
+	res, err := r.newClient(true).Get(indexURL, httpclient.SetHeader("Accept", "*/*"))
+	if err != nil {
+		return nil, err
+	}
+
+	index := &repo.IndexFile{}
+	if err = yaml.Unmarshal(res.Body(), index); err != nil {
+		return nil, fmt.Errorf("failed to parse index of %s: %v", r.URL, err)
+	}
+	index.SortEntries()
+	return index, nil
+}
+
+// ociRepository returns the registry base url and the repository name of the chart
+func (r *ChartRepo) ociRepository(chartName string) (string, string, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return "", "", err
+	}
+
+	scheme := "https"
+	if r.PlainHTTP {
+		scheme = "http"
+	}
+	name := strings.Trim(strings.Trim(u.Path, "/")+"/"+chartName, "/")
+	return fmt.Sprintf("%s://%s", scheme, u.Host), name, nil
+}
+
+func (r *ChartRepo) listTags(chartName string) ([]string, error) {
+	base, name, err := r.ociRepository(chartName)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := &struct {
+		Tags []string `json:"tags"`
+	}{}
+	if _, err = r.registryGet(fmt.Sprintf("%s/v2/%s/tags/list", base, name), "application/json", tags); err != nil {
+		return nil, err
+	}
+	return tags.Tags, nil
+}
+
+func (r *ChartRepo) pullOCIChart(chartName, version string) ([]byte, error) {
+	base, name, err := r.ociRepository(chartName)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ociManifest{}
+	tag := strings.ReplaceAll(version, "+", "_")
+	if _, err = r.registryGet(fmt.Sprintf("%s/v2/%s/manifests/%s", base, name, tag), ociManifestMediaType, manifest); err != nil {
+		return nil, err
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != chartLayerMediaType && layer.MediaType != legacyChartLayerMediaType {
+			continue
+		}
+		res, err := r.registryGet(fmt.Sprintf("%s/v2/%s/blobs/%s", base, name, layer.Digest), "*/*", nil)
+		if err != nil {
+			return nil, err
+		}
+		return res.Body(), nil
+	}
+
+	return nil, fmt.Errorf("no chart layer found in %s:%s", name, tag)
+}
+
+func (r *ChartRepo) pushOCIChart(meta *chart.Metadata, data []byte) error {
+	base, name, err := r.ociRepository(meta.Name)
+	if err != nil {
+		return err
+	}
+
+	config, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	configDesc, err := r.uploadBlob(base, name, configMediaType, config)
+	if err != nil {
+		return fmt.Errorf("failed to upload chart config: %v", err)
+	}
+	chartDesc, err := r.uploadBlob(base, name, chartLayerMediaType, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload chart content: %v", err)
+	}
+
+	manifest, err := json.Marshal(&ociManifest{
+		SchemaVersion: 2,
+		Config:        configDesc,
+		Layers:        []*ociDescriptor{chartDesc},
+	})
+	if err != nil {
+		return err
+	}
+	tag := strings.ReplaceAll(meta.Version, "+", "_")
+	_, err = r.registryRequest(resty.MethodPut, fmt.Sprintf("%s/v2/%s/manifests/%s", base, name, tag),
+		httpclient.SetHeader("Content-Type", ociManifestMediaType), httpclient.SetBody(manifest))
+	return err
+}
+
+// uploadBlob uploads the blob with a monolithic upload
+func (r *ChartRepo) uploadBlob(base, name, mediaType string, data []byte) (*ociDescriptor, error) {
+	res, err := r.registryRequest(resty.MethodPost, fmt.Sprintf("%s/v2/%s/blobs/uploads/", base, name))
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := url.Parse(res.Header().Get("Location"))
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	location = baseURL.ResolveReference(location)
+
+	desc := &ociDescriptor{
+		MediaType: mediaType,
+		Digest:    fmt.Sprintf("sha256:%x", sha256.Sum256(data)),
+		Size:      int64(len(data)),
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	_, err = r.registryRequest(resty.MethodPut, location.String(),
+		httpclient.SetHeader("Content-Type", "application/octet-stream"), httpclient.SetBody(data))
+	if err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// registryRequest sends a request to the registry, and retries with a bearer token if the registry asks for one.
+// The token is kept for the following requests until the registry rejects it.
+func (r *ChartRepo) registryRequest(method, u string, rfs ...httpclient.RequestFunc) (*resty.Response, error) {
+	cfs := []httpclient.ClientFunc{httpclient.UnsetTimeout(), httpclient.SetIgnoreCodes(http.StatusUnauthorized)}
+	if r.token != "" {
+		cfs = append(cfs, httpclient.SetAuthToken(r.token))
+	}
+	res, err := r.newClient(r.token == "", cfs...).Request(method, u, rfs...)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode() != http.StatusUnauthorized {
+		return res, nil
+	}
+
+	if r.token, err = r.registryToken(res.Header().Get("WWW-Authenticate")); err != nil {
+		return nil, err
+	}
+	return r.newClient(false, httpclient.UnsetTimeout(), httpclient.SetAuthToken(r.token)).Request(method, u, rfs...)
+}
+
+func (r *ChartRepo) registryGet(u, accept string, result interface{}) (*resty.Response, error) {
+	rfs := []httpclient.RequestFunc{httpclient.SetHeader("Accept", accept)}
+	if result != nil {
+		rfs = append(rfs, httpclient.SetResult(result), httpclient.ForceContentType("application/json"))
+	}
+	return r.registryRequest(resty.MethodGet, u, rfs...)
+}
+
+// registryToken requests a bearer token from the auth server announced in the WWW-Authenticate challenge.
+func (r *ChartRepo) registryToken(challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("unauthorized to access %s", r.URL)
+	}
+
+	params := make(map[string]string)
+	for _, m := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("no realm found in challenge %q", challenge)
+	}
+	delete(params, "realm")
+
+	token := &struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if _, err := r.newClient(true).Get(realm, httpclient.SetQueryParams(params), httpclient.SetResult(token), httpclient.ForceContentType("application/json")); err != nil {
+		return "", err
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmclient
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+func packageChart(t *testing.T, name, version string) []byte {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("replicas: 1\n")}},
+	}
+	c.Values = map[string]interface{}{"replicas": 1}
+
+	path, err := chartutil.Save(c, t.TempDir())
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestChartRepo_Index(t *testing.T) {
+	pkg := packageChart(t, "nginx", "1.2.0")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/charts/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `apiVersion: v1
+entries:
+  nginx:
+  - name: nginx
+    version: 1.1.0
+    urls: [nginx-1.1.0.tgz]
+  - name: nginx
+    version: 1.2.0
+    urls: [nginx-1.2.0.tgz]
+  - name: nginx
+    version: 1.10.0-rc.1
+    urls: [nginx-1.10.0-rc.1.tgz]
+`)
+	})
+	mux.HandleFunc("/charts/nginx-1.2.0.tgz", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(pkg)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	r := &ChartRepo{URL: s.URL + "/charts", Username: "admin", Password: "secret"}
+
+	versions, err := r.ListChartVersions("nginx")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.10.0-rc.1", "1.2.0", "1.1.0"}, versions)
+
+	_, err = r.ListChartVersions("redis")
+	require.Error(t, err)
+
+	// the latest stable version is pulled if no version is given
+	data, err := r.PullChart("nginx", "")
+	require.NoError(t, err)
+	require.Equal(t, pkg, data)
+
+	dir := t.TempDir()
+	require.NoError(t, ExpandChart(data, dir))
+	values, err := os.ReadFile(filepath.Join(dir, "nginx", chartutil.ValuesfileName))
+	require.NoError(t, err)
+	require.Equal(t, "replicas: 1\n", string(values))
+
+	_, err = (&ChartRepo{URL: s.URL + "/charts"}).ListChartVersions("nginx")
+	require.Error(t, err)
+}
+
+func TestChartRepo_PullChartFromOtherHost(t *testing.T) {
+	pkg := packageChart(t, "nginx", "1.2.0")
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(pkg)
+	}))
+	defer cdn.Close()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `apiVersion: v1
+entries:
+  nginx:
+  - name: nginx
+    version: 1.2.0
+    urls: [%s/nginx-1.2.0.tgz]
+`, cdn.URL)
+	}))
+	defer s.Close()
+
+	r := &ChartRepo{URL: s.URL + "/charts", Username: "admin", Password: "secret"}
+	require.True(t, r.isRepoHost(s.URL+"/charts/nginx-1.2.0.tgz"))
+	require.False(t, r.isRepoHost(cdn.URL+"/nginx-1.2.0.tgz"))
+
+	// credentials of the repo are not sent to the chart host
+	data, err := r.PullChart("nginx", "1.2.0")
+	require.NoError(t, err)
+	require.Equal(t, pkg, data)
+}
+
+// newFakeRegistry starts an in-memory OCI registry which requires a bearer token issued to admin:secret
+func newFakeRegistry(t *testing.T) *httptest.Server {
+	var (
+		s         *httptest.Server
+		mu        sync.Mutex
+		blobs     = map[string][]byte{}
+		manifests = map[string][]byte{}
+		uploads   = 0
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" || !strings.HasPrefix(r.URL.Query().Get("scope"), "repository:library/nginx:") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token":"t0ken-%s"}`, strings.TrimPrefix(r.URL.Query().Get("scope"), "repository:library/nginx:"))
+	})
+	mux.HandleFunc("/v2/library/nginx/", func(w http.ResponseWriter, r *http.Request) {
+		scope := "pull"
+		if r.Method != http.MethodGet {
+			scope = "pull,push"
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer t0ken-pull,push" && auth != "Bearer t0ken-"+scope {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:library/nginx:%s"`, s.URL, scope))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		p := strings.TrimPrefix(r.URL.Path, "/v2/library/nginx/")
+		switch {
+		case p == "tags/list":
+			tags := make([]string, 0, len(manifests))
+			for tag := range manifests {
+				tags = append(tags, fmt.Sprintf("%q", tag))
+			}
+			fmt.Fprintf(w, `{"name":"library/nginx","tags":[%s]}`, strings.Join(tags, ","))
+		case p == "blobs/uploads/" && r.Method == http.MethodPost:
+			uploads++
+			w.Header().Set("Location", fmt.Sprintf("/v2/library/nginx/blobs/uploads/%d?state=x", uploads))
+			w.WriteHeader(http.StatusAccepted)
+		case strings.HasPrefix(p, "blobs/uploads/") && r.Method == http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			digest := r.URL.Query().Get("digest")
+			require.Equal(t, "x", r.URL.Query().Get("state"))
+			require.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(data)), digest)
+			blobs[digest] = data
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(p, "blobs/"):
+			data, ok := blobs[strings.TrimPrefix(p, "blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case strings.HasPrefix(p, "manifests/") && r.Method == http.MethodPut:
+			require.Equal(t, ociManifestMediaType, r.Header.Get("Content-Type"))
+			manifests[strings.TrimPrefix(p, "manifests/")], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(p, "manifests/"):
+			data, ok := manifests[strings.TrimPrefix(p, "manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", ociManifestMediaType)
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	s = httptest.NewServer(mux)
+	return s
+}
+
+func TestChartRepo_OCI(t *testing.T) {
+	s := newFakeRegistry(t)
+	defer s.Close()
+
+	r := &ChartRepo{URL: "oci://" + strings.TrimPrefix(s.URL, "http://") + "/library", Username: "admin", Password: "secret", PlainHTTP: true}
+	require.True(t, IsOCI(r.URL))
+
+	_, err := r.ListChartVersions("nginx")
+	require.Error(t, err)
+
+	pkgs := map[string][]byte{}
+	for _, version := range []string{"1.1.0", "1.2.0+build.1"} {
+		pkgs[version] = packageChart(t, "nginx", version)
+		require.NoError(t, r.PushChart(pkgs[version]))
+	}
+
+	versions, err := (&ChartRepo{URL: r.URL, Username: "admin", Password: "secret", PlainHTTP: true}).ListChartVersions("nginx")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.2.0+build.1", "1.1.0"}, versions)
+
+	data, err := r.PullChart("nginx", "")
+	require.NoError(t, err)
+	require.Equal(t, pkgs["1.2.0+build.1"], data)
+
+	data, err = r.PullChart("nginx", "1.1.0")
+	require.NoError(t, err)
+	require.Equal(t, pkgs["1.1.0"], data)
+
+	_, err = r.PullChart("nginx", "1.0.0")
+	require.Error(t, err)
+
+	_, err = (&ChartRepo{URL: r.URL, PlainHTTP: true}).ListChartVersions("nginx")
+	require.Error(t, err)
+}
+
+func TestChartRepo_PushChartMuseum(t *testing.T) {
+	pkg := packageChart(t, "nginx", "1.2.0")
+	uploaded := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chartrepo/library/charts" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f, header, err := r.FormFile("chart")
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "nginx-1.2.0.tgz", header.Filename)
+		require.Equal(t, pkg, data)
+		uploaded = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	require.NoError(t, (&ChartRepo{URL: s.URL + "/chartrepo/library", Username: "admin", Password: "secret"}).PushChart(pkg))
+	require.True(t, uploaded)
+
+	require.Error(t, (&ChartRepo{URL: s.URL + "/chartrepo/library"}).PushChart(pkg))
+	require.Error(t, (&ChartRepo{URL: s.URL}).PushChart([]byte("not a chart")))
+}
+
+func TestPackageChart(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ExpandChart(packageChart(t, "nginx", "1.2.0"), dir))
+
+	path, err := PackageChart(filepath.Join(dir, "nginx"), "2.0.0", []byte("replicas: 3\n"), t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, "nginx-2.0.0.tgz", filepath.Base(path))
+
+	c, err := loader.Load(path)
+	require.NoError(t, err)
+	require.Equal(t, "2.0.0", c.Metadata.Version)
+	require.Equal(t, float64(3), c.Values["replicas"])
+
+	// version and values are kept if not given
+	path, err = PackageChart(path, "", nil, t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, "nginx-2.0.0.tgz", filepath.Base(path))
+
+	_, err = PackageChart(filepath.Join(dir, "nginx"), "not-semver", nil, t.TempDir())
+	require.Error(t, err)
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package updater
 
 import (
+	"encoding/json"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -53,3 +56,23 @@ func DeleteService(ns, name string, cl client.Client) error {
 		},
 	}, cl, &client.DeleteOptions{PropagationPolicy: &deletePolicy})
 }
+
+// UpdateServiceSelector replaces the whole selector of the service in one request,
+// a strategic merge patch can not remove the existing keys.
+func UpdateServiceSelector(ns, name string, selector map[string]string, cl client.Client) error {
+	patchBytes, err := json.Marshal([]map[string]interface{}{{
+		"op":    "replace",
+		"path":  "/spec/selector",
+		"value": selector,
+	}})
+	if err != nil {
+		return err
+	}
+
+	return patchObjectWithType(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      name,
+		},
+	}, patchBytes, types.JSONPatchType, cl)
+}
@@ -18,6 +18,7 @@ package s3
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -25,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/util/fs"
@@ -212,6 +214,18 @@ func (c *Client) Upload(bucketName, src string, objectKey string) error {
 	return err
 }
 
+// UploadStream uploads the content read from body to the bucket with the specified objectKey.
+// The body is sent in parts, so its size does not need to be known in advance.
+func (c *Client) UploadStream(bucketName, objectKey string, body io.Reader) error {
+	uploader := s3manager.NewUploaderWithClient(c.S3)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Body:   body,
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	return err
+}
+
 // ListFiles with given prefix
 func (c *Client) ListFiles(bucketName, prefix string, recursive bool) ([]string, error) {
 	ret := make([]string, 0)